@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// anonymizer maps real UUIDs to pseudonymous ones.  The same key
+// always gives the same mapping, so references between IDs survive.
+type anonymizer struct {
+	key     []byte
+	mapping map[UUID]UUID
+	// newPairs is written to with each mapping not seen before, if
+	// it isn't nil.
+	newPairs io.Writer
+}
+
+// pseudonym returns the V8 UUID u maps to, NewV8HMAC of u, so it
+// can't be reversed without the key.
+func (a *anonymizer) pseudonym(u UUID) UUID {
+	if p, ok := a.mapping[u]; ok {
+		return p
+	}
+	p := NewV8HMAC(a.key, u[:])
+	a.mapping[u] = p
+	if a.newPairs != nil {
+		fmt.Fprintf(a.newPairs, "%s,%s\n", u, p)
+	}
+	return p
+}
+
+// loadMapping reads original,pseudonym pairs written by a previous run.
+func (a *anonymizer) loadMapping(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		orig, pseudo, ok := strings.Cut(s.Text(), ",")
+		if !ok {
+			return fmt.Errorf("mapping line %d: missing comma", n)
+		}
+		u, err := Parse(orig)
+		if err != nil {
+			return fmt.Errorf("mapping line %d: %v", n, err)
+		}
+		p, err := Parse(pseudo)
+		if err != nil {
+			return fmt.Errorf("mapping line %d: %v", n, err)
+		}
+		a.mapping[u] = p
+	}
+	return s.Err()
+}
+
+// rewrite copies r to w, replacing every UUID with its pseudonym.
+// Upper case UUIDs stay upper case.
+func (a *anonymizer) rewrite(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		line = uuidPattern.ReplaceAllStringFunc(line, func(s string) string {
+			u, perr := Parse(s)
+			if perr != nil {
+				return s
+			}
+			p := a.pseudonym(u).String()
+			if strings.ToUpper(s) == s {
+				p = strings.ToUpper(p)
+			}
+			return p
+		})
+		if _, werr := io.WriteString(w, line); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func runAnonymize(args []string) error {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	keyHex := fs.String("key", os.Getenv("UUIDGEN_ANON_KEY"), "hex HMAC key, defaults to $UUIDGEN_ANON_KEY")
+	mapFile := fs.String("map", "", "file of original,pseudonym pairs to reuse and append to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen anonymize [flags] < in > out")
+		fmt.Fprintln(fs.Output(), "Replaces every UUID in the input with a consistent pseudonymous UUID.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	a := &anonymizer{mapping: map[UUID]UUID{}}
+	if *keyHex == "" {
+		a.key = make([]byte, 32)
+		safeRandom(a.key)
+		slog.Warn("no -key given, using a random one: pseudonyms will differ from run to run")
+	} else {
+		var err error
+		if a.key, err = hex.DecodeString(*keyHex); err != nil {
+			return fmt.Errorf("bad -key: %v", err)
+		}
+		if len(a.key) < 16 {
+			return errors.New("-key should be at least 16 bytes")
+		}
+	}
+
+	var mw *bufio.Writer
+	if *mapFile != "" {
+		f, err := os.OpenFile(*mapFile, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := a.loadMapping(f); err != nil {
+			return fmt.Errorf("%s: %v", *mapFile, err)
+		}
+		// Reading left us at the end, ready to append.
+		mw = bufio.NewWriter(f)
+		a.newPairs = mw
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err := a.rewrite(os.Stdin, w)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if mw != nil {
+		if ferr := mw.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
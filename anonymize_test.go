@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	id1, id2 := NewV4().String(), NewV1().String()
+	in := "user " + id1 + " bought " + id2 + "\n" +
+		"user " + strings.ToUpper(id1) + " again\n" +
+		"no ids here"
+
+	var mapping bytes.Buffer
+	a := &anonymizer{key: []byte("0123456789abcdef"), mapping: map[UUID]UUID{}, newPairs: &mapping}
+	var out bytes.Buffer
+	if err := a.rewrite(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	if strings.Contains(got, id1) || strings.Contains(got, id2) {
+		t.Errorf("real IDs left in output:\n%s", got)
+	}
+	lines := strings.Split(got, "\n")
+	p1 := strings.Fields(lines[0])[1]
+	if p2 := strings.Fields(lines[1])[1]; p2 != strings.ToUpper(p1) {
+		t.Errorf("same ID mapped to %s and %s", p1, p2)
+	}
+	if lines[2] != "no ids here" {
+		t.Errorf("last line changed to %q", lines[2])
+	}
+
+	// A second run with the saved mapping and a different key should
+	// give the same answer.
+	b := &anonymizer{key: []byte("fedcba9876543210"), mapping: map[UUID]UUID{}}
+	if err := b.loadMapping(&mapping); err != nil {
+		t.Fatal(err)
+	}
+	if p := b.pseudonym(mustParse(id1)).String(); p != p1 {
+		t.Errorf("loaded mapping gave %s, want %s", p, p1)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// AtomicGenerator makes V1 UUIDs without locks or a goroutine: its
+// only changing state is the timestamp of the last UUID, in one
+// uint64, which New advances with compare and swap.  Each UUID gets a
+// timestamp of its own, borrowing later ticks when the clock hasn't
+// moved on or has gone backwards, so the clock sequence never needs
+// bumping.
+//
+// How well this does is down to the CPU's atomics: on arm64 a CAS loop
+// is far cheaper with LSE's CASAL than with LL/SC, and on 386 a 64 bit
+// CAS is a CMPXCHG8B.  atomic_arm64_test.go and atomic_32bit_test.go
+// have the benchmarks and tests for those.
+type AtomicGenerator struct {
+	// last is an atomic.Uint64, rather than a uint64 used with
+	// atomic.CompareAndSwapUint64, so that it is 8 byte aligned even
+	// on 32 bit platforms.
+	last          atomic.Uint64
+	clockSequence uint16
+	hardwareAddr  [6]byte
+	epochFunc     func() uint64
+	counters      generatorCounters
+}
+
+func NewAtomicGenerator() *AtomicGenerator {
+	return newAtomicGenerator(unixTimeFunc)
+}
+
+var _ = registerExperiment(experiment{
+	Name:        "atomic",
+	Description: "compare and swap on the last timestamp, borrowing ticks instead of bumping the clock sequence",
+	New:         func(int) Generator { return NewAtomicGenerator() },
+})
+
+// newAtomicGenerator lets tests inject a fake clock.  epochFunc is
+// called from every goroutine that calls New.
+func newAtomicGenerator(epochFunc func() uint64) *AtomicGenerator {
+	return atomicGeneratorFrom(newV1Start(epochFunc))
+}
+
+func atomicGeneratorFrom(st v1Start) *AtomicGenerator {
+	return &AtomicGenerator{
+		epochFunc:     st.epochFunc,
+		clockSequence: st.clockSequence,
+		hardwareAddr:  st.hardwareAddr,
+	}
+}
+
+// next claims a timestamp no other call has had: the clock's, or one
+// tick after the last one claimed if the clock isn't past it.
+func (g *AtomicGenerator) next() uint64 {
+	for {
+		last := g.last.Load()
+		now := g.epochFunc()
+		t := now
+		if t <= last {
+			t = last + 1
+		}
+		if g.last.CompareAndSwap(last, t) {
+			if now < last {
+				g.counters.rollbacks.Add(1)
+			}
+			if t != now {
+				g.counters.borrowed.Add(1)
+			}
+			return t
+		}
+	}
+}
+
+// NewV1 returns a UUID based on the next timestamp and MAC address.
+func (g *AtomicGenerator) NewV1() UUID {
+	u := UUID{}
+	timeNow := g.next()
+
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+	binary.BigEndian.PutUint16(u[8:], g.clockSequence)
+
+	copy(u[10:], g.hardwareAddr[:])
+
+	u.SetVersion(1)
+	u.SetVariant()
+
+	g.counters.generated.Add(1)
+	return u
+}
+
+// New is NewV1, so that AtomicGenerator is a Generator.
+func (g *AtomicGenerator) New() UUID {
+	return g.NewV1()
+}
+
+func (g *AtomicGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	return g.last.Load(), g.clockSequence, g.hardwareAddr, true
+}
+
+// Stats reports on g.  It can be called at any time.
+func (g *AtomicGenerator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
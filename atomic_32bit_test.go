@@ -0,0 +1,35 @@
+//go:build 386 || arm || mips || mipsle
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// On 32 bit platforms 64 bit atomics fault on a field that isn't 8
+// byte aligned, which Go only promises for atomic.Uint64 and the
+// first word of an allocation.
+func TestAtomicGeneratorAlignment(t *testing.T) {
+	var v struct {
+		pad uint32
+		g   AtomicGenerator
+	}
+	if addr := uintptr(unsafe.Pointer(&v.g.last)); addr%8 != 0 {
+		t.Fatalf("last at %#x, not 8 byte aligned", addr)
+	}
+	v.g.last.Add(1)
+}
+
+// A 64 bit CAS here is a pair of 32 bit words, so a clock crossing
+// 1<<32 checks that neither half is ever seen torn from the other.
+func TestAtomicGeneratorHighWord(t *testing.T) {
+	var clock atomic.Uint64
+	clock.Store(1<<32 - 1000)
+	g := newAtomicGenerator(func() uint64 { return clock.Add(1) })
+	atomicUnique(t, g)
+	if last := g.last.Load(); last < 1<<32 {
+		t.Errorf("last timestamp %#x never crossed 1<<32", last)
+	}
+}
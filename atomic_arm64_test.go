@@ -0,0 +1,51 @@
+//go:build arm64
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// These compare the CAS loop AtomicGenerator uses with a plain atomic
+// add, which can't borrow ticks but is as cheap as a shared counter
+// gets.  Without LSE, both are LL/SC loops that retry under
+// contention; with it, whether detected at run time or promised with
+// GOARM64=v8.1, they are single CASAL and LDADDAL instructions, and the
+// gap between them and the mutex strategy should grow with -cpu.
+
+func BenchmarkArm64CASLoop(b *testing.B) {
+	var v atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for {
+				old := v.Load()
+				if v.CompareAndSwap(old, old+1) {
+					break
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkArm64Add(b *testing.B) {
+	var v atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			v.Add(1)
+		}
+	})
+}
+
+func BenchmarkArm64Strategies(b *testing.B) {
+	for _, name := range []string{"atomic", "mutex", "satori"} {
+		b.Run(name, func(b *testing.B) {
+			g := experiments[name].New(0)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					g.New()
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomicGenerator(t *testing.T) {
+	// Steps back 5 ticks every 3 calls, so most UUIDs need a borrowed
+	// tick.
+	g := newAtomicGenerator(newSkewedClock(3, 5).epoch)
+	var lastTS uint64
+	for n := 0; n < 1000; n++ {
+		ts, seq := v1Fields(g.New())
+		if n > 0 && ts <= lastTS {
+			t.Fatalf("UUID %d: timestamp %d after %d", n, ts, lastTS)
+		}
+		if seq != g.clockSequence&0x3fff {
+			t.Fatalf("UUID %d: clock sequence %d, want %d", n, seq, g.clockSequence&0x3fff)
+		}
+		lastTS = ts
+	}
+	if s := g.Stats(); s.Generated != 1000 || s.Rollbacks == 0 || s.Borrowed < s.Rollbacks || s.ClockSeqIncrements != 0 {
+		t.Errorf("%+v", s)
+	}
+}
+
+// atomicUnique checks that goroutines calling g.New at once never get
+// the same UUID.
+func atomicUnique(t *testing.T, g *AtomicGenerator) {
+	const goroutines, each = 8, 2000
+	results := make([][]UUID, goroutines)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < each; n++ {
+				results[i] = append(results[i], g.New())
+			}
+		}()
+	}
+	wg.Wait()
+	seen := make(map[UUID]bool, goroutines*each)
+	for _, us := range results {
+		for _, u := range us {
+			if seen[u] {
+				t.Fatalf("duplicate UUID %s", u)
+			}
+			seen[u] = true
+		}
+	}
+}
+
+func TestAtomicGeneratorConcurrent(t *testing.T) {
+	atomicUnique(t, NewAtomicGenerator())
+}
+
+func BenchmarkAtomicGenerator(b *testing.B) {
+	g := NewAtomicGenerator()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.New()
+		}
+	})
+}
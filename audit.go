@@ -0,0 +1,285 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultAuditMaxSize is the size an audit log is rotated at if
+// serve's audit_max_size isn't set.
+const defaultAuditMaxSize = 64 << 20
+
+// auditRecord is a line of the audit log: the UUIDs a server issued in
+// a minute with a version and strategy.  First and Last are the
+// earliest and latest times embedded in them, or for versions without
+// times, when they were issued.
+type auditRecord struct {
+	Minute   time.Time `json:"minute"`
+	Version  int       `json:"version"`
+	Strategy string    `json:"strategy,omitempty"`
+	Count    uint64    `json:"count"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+type auditKey struct {
+	minute   time.Time
+	version  int
+	strategy string
+}
+
+// auditLog appends a line to a file for each minute, version and
+// strategy a server issues UUIDs in, rather than a line per UUID, so
+// that there is a record of what was issued when, for capacity
+// planning and for working out where a bad ID came from, that stays
+// small.  When the file reaches maxSize, it is renamed with the time
+// and gzipped, keeping the newest keep of those, or all of them if
+// keep is 0.
+type auditLog struct {
+	path    string
+	maxSize int64
+	keep    int
+	nowFunc func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	ranges map[auditKey]*auditRecord
+
+	stop     chan struct{}
+	done     chan struct{}
+	compress sync.WaitGroup
+}
+
+// newAuditLog opens path to append to, and starts writing out each
+// minute's ranges once the minute is over.
+func newAuditLog(path string, maxSize int64, keep int, nowFunc func() time.Time) (*auditLog, error) {
+	if maxSize <= 0 {
+		maxSize = defaultAuditMaxSize
+	}
+	l := &auditLog{
+		path:    path,
+		maxSize: maxSize,
+		keep:    keep,
+		nowFunc: nowFunc,
+		ranges:  make(map[auditKey]*auditRecord),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	go l.run()
+	return l, nil
+}
+
+func (l *auditLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, fi.Size()
+	return nil
+}
+
+func (l *auditLog) run() {
+	defer close(l.done)
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.flush(false)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// record counts u as issued now by a generator of version and
+// strategy, which only versions 1 and 8 have.
+func (l *auditLog) record(version int, strategy string, u UUID) {
+	if version != 1 && version != 8 {
+		strategy = ""
+	}
+	now := l.nowFunc()
+	t, ok := u.Time()
+	if !ok {
+		if p, tagged := DecodeProvenance(u); tagged {
+			t = p.Time
+		} else {
+			t = now
+		}
+	}
+	t = t.UTC()
+	key := auditKey{minute: now.UTC().Truncate(time.Minute), version: version, strategy: strategy}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := l.ranges[key]
+	if r == nil {
+		r = &auditRecord{Minute: key.minute, Version: version, Strategy: strategy, First: t, Last: t}
+		l.ranges[key] = r
+	}
+	r.Count++
+	if t.Before(r.First) {
+		r.First = t
+	}
+	if t.After(r.Last) {
+		r.Last = t
+	}
+}
+
+// flush writes out the ranges of the minutes that are over, or all of
+// them if all is set, oldest first.  Those it fails to write are kept
+// for the next flush, which first reopens the file.
+func (l *auditLog) flush(all bool) error {
+	current := l.nowFunc().UTC().Truncate(time.Minute)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			slog.Error("audit log not reopened", "path", l.path, "err", err)
+			return err
+		}
+	}
+	var done []auditKey
+	for key := range l.ranges {
+		if all || key.minute.Before(current) {
+			done = append(done, key)
+		}
+	}
+	sort.Slice(done, func(i, j int) bool {
+		a, b := done[i], done[j]
+		if !a.minute.Equal(b.minute) {
+			return a.minute.Before(b.minute)
+		}
+		if a.version != b.version {
+			return a.version < b.version
+		}
+		return a.strategy < b.strategy
+	})
+	for _, key := range done {
+		b, err := json.Marshal(l.ranges[key])
+		if err != nil {
+			return err
+		}
+		n, err := l.file.Write(append(b, '\n'))
+		l.size += int64(n)
+		if err != nil {
+			slog.Error("audit log not written", "path", l.path, "err", err)
+			l.file.Close()
+			l.file = nil
+			return err
+		}
+		delete(l.ranges, key)
+	}
+	if l.size >= l.maxSize {
+		return l.rotate()
+	}
+	return nil
+}
+
+// rotate renames the log with the time, gzips it in the background,
+// and starts a new one.  If it can't rename the log, it goes on
+// appending to it.  l.mu must be held.
+func (l *auditLog) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err == nil {
+		rotated := l.path + "." + l.nowFunc().UTC().Format("20060102T150405.000000000Z")
+		if err = os.Rename(l.path, rotated); err == nil {
+			l.compress.Add(1)
+			go func() {
+				defer l.compress.Done()
+				if err := gzipFile(rotated); err != nil {
+					slog.Error("audit log not compressed", "path", rotated, "err", err)
+					return
+				}
+				l.prune()
+			}()
+		}
+	}
+	if oerr := l.open(); err == nil {
+		err = oerr
+	}
+	if err != nil {
+		slog.Error("audit log not rotated", "path", l.path, "err", err)
+	}
+	return err
+}
+
+// gzipFile replaces path with path.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune removes all but the newest keep compressed logs, whose names
+// sort by time.
+func (l *auditLog) prune() {
+	if l.keep <= 0 {
+		return
+	}
+	old, err := filepath.Glob(l.path + ".*.gz")
+	if err != nil {
+		return
+	}
+	sort.Strings(old)
+	for len(old) > l.keep {
+		if err := os.Remove(old[0]); err != nil && !os.IsNotExist(err) {
+			slog.Error("old audit log not removed", "path", old[0], "err", err)
+		}
+		old = old[1:]
+	}
+}
+
+// close writes out what is left, including the minute in progress,
+// and waits for compression to finish.
+func (l *auditLog) close() error {
+	close(l.stop)
+	<-l.done
+	err := l.flush(true)
+	l.compress.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return err
+	}
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
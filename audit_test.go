@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r *bufio.Scanner
+	if filepath.Ext(path) == ".gz" {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bufio.NewScanner(zr)
+	} else {
+		r = bufio.NewScanner(f)
+	}
+	var recs []auditRecord
+	for r.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(r.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit")
+	now := time.Date(2026, 10, 16, 12, 0, 30, 0, time.UTC)
+	l, err := newAuditLog(path, 0, 0, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	early, late := now.Add(-time.Hour), now.Add(-time.Second)
+	l.record(7, "mutex", NewV7At(late))
+	l.record(7, "mutex", NewV7At(early))
+	l.record(1, "atomic", NewV1At(late, 1, [6]byte{}))
+	l.record(4, "", NewV4())
+	if err := l.flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if recs := readAuditLog(t, path); len(recs) != 0 {
+		t.Fatalf("minute in progress written: %+v", recs)
+	}
+
+	now = now.Add(time.Minute)
+	l.record(4, "", NewV4())
+	if err := l.flush(false); err != nil {
+		t.Fatal(err)
+	}
+	minute := now.Add(-time.Minute).Truncate(time.Minute)
+	want := []auditRecord{
+		{Minute: minute, Version: 1, Strategy: "atomic", Count: 1, First: late, Last: late},
+		{Minute: minute, Version: 4, Count: 1, First: now.Add(-time.Minute), Last: now.Add(-time.Minute)},
+		{Minute: minute, Version: 7, Count: 2, First: early, Last: late},
+	}
+	got := readAuditLog(t, path)
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Minute.Equal(want[i].Minute) || got[i].Version != want[i].Version || got[i].Strategy != want[i].Strategy ||
+			got[i].Count != want[i].Count || !got[i].First.Equal(want[i].First) || !got[i].Last.Equal(want[i].Last) {
+			t.Errorf("%d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAuditLog(t, path); len(got) != 4 || got[3].Version != 4 || !got[3].Minute.Equal(now.Truncate(time.Minute)) {
+		t.Errorf("after close: %+v", got)
+	}
+}
+
+func TestAuditLogRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l, err := newAuditLog(path, 1, 2, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		l.record(7, "", NewV7At(now))
+		now = now.Add(time.Minute)
+		if err := l.flush(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 2 {
+		t.Fatalf("kept %q, want 2 of them", old)
+	}
+	// The newest two, each a minute's line.
+	for i, name := range old {
+		recs := readAuditLog(t, name)
+		if len(recs) != 1 || !recs[0].Minute.Equal(time.Date(2026, 10, 16, 12, 2+i, 0, 0, time.UTC)) {
+			t.Errorf("%s: %+v", name, recs)
+		}
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("current log: %v, %v", fi, err)
+	}
+}
+
+func TestAuditLogWriteFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l, err := newAuditLog(path, 0, 0, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.close()
+
+	// A write that fails keeps the minute's record for the next flush,
+	// which reopens the file.
+	l.record(7, "", NewV7At(now))
+	l.file.Close()
+	if err := l.flush(true); err == nil {
+		t.Fatal("flush to a closed file worked")
+	}
+	if err := l.flush(true); err != nil {
+		t.Fatal(err)
+	}
+	if recs := readAuditLog(t, path); len(recs) != 1 || recs[0].Count != 1 {
+		t.Errorf("got %+v", recs)
+	}
+}
+
+func TestAuditLogRotateFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l, err := newAuditLog(path, 1, 0, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.close()
+
+	// Rotating onto a directory that isn't empty fails, leaving the log
+	// where it was, still open.
+	rotated := path + "." + now.Format("20060102T150405.000000000Z")
+	if err := os.MkdirAll(filepath.Join(rotated, "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	l.record(7, "", NewV7At(now))
+	if err := l.flush(true); err == nil {
+		t.Fatal("rotate onto a directory worked")
+	}
+	l.record(7, "", NewV7At(now.Add(time.Minute)))
+	now = now.Add(time.Minute)
+	os.RemoveAll(rotated)
+	if err := l.flush(true); err != nil {
+		t.Fatal(err)
+	}
+	l.compress.Wait()
+	old, err := filepath.Glob(path + ".*.gz")
+	if err != nil || len(old) != 1 {
+		t.Fatalf("rotated %q, %v", old, err)
+	}
+	if recs := readAuditLog(t, old[0]); len(recs) != 2 {
+		t.Errorf("got %+v, want both minutes", recs)
+	}
+}
+
+func TestServeAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit")
+	s, err := newServer(serverConfig{Version: 6, Strategy: "mutex", AuditLog: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	get(t, ts, "/uuid?n=5", http.StatusOK)
+	ts.Close()
+	if err := s.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	recs := readAuditLog(t, path)
+	if len(recs) != 1 || recs[0].Version != 6 || recs[0].Strategy != "" || recs[0].Count != 5 || recs[0].First.After(recs[0].Last) {
+		t.Errorf("got %+v", recs)
+	}
+}
+
+func TestServeAuditLogTenant(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "keys")
+	os.WriteFile(keysFile, []byte("alice 0123456789abcdef\n"), 0o600)
+	tenantsFile := filepath.Join(dir, "tenants.json")
+	os.WriteFile(tenantsFile, []byte(`{"alice": {"node": "02:00:00:00:00:0a", "strategy": "atomic"}}`), 0o600)
+	path := filepath.Join(dir, "audit")
+	s, err := newServer(serverConfig{Version: 7, APIKeysFile: keysFile, TenantsFile: tenantsFile, AuditLog: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	req, _ := http.NewRequest("GET", ts.URL+"/tenant/uuid?n=3", nil)
+	req.Header.Set("X-API-Key", "0123456789abcdef")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	ts.Close()
+	if err := s.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	recs := readAuditLog(t, path)
+	if len(recs) != 1 || recs[0].Version != 1 || recs[0].Strategy != "atomic" || recs[0].Count != 3 {
+		t.Errorf("got %+v", recs)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// minAPIKeyLen is the shortest API key loadAPIKeys accepts.
+const minAPIKeyLen = 16
+
+// apiKeys maps the SHA-256 of each API key to its client's name.
+// Looking keys up by hash means a lookup's time says nothing about how
+// close a wrong key came to a right one.
+type apiKeys map[[sha256.Size]byte]string
+
+// loadAPIKeys reads API keys from a file with a client name and its key
+// on each line, space separated.  Blank lines and lines starting with
+// # are skipped.
+func loadAPIKeys(name string) (apiKeys, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := apiKeys{}
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		client, key, ok := strings.Cut(text, " ")
+		key = strings.TrimSpace(key)
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%s:%d: want a client name and a key", name, line)
+		case len(key) < minAPIKeyLen:
+			return nil, fmt.Errorf("%s:%d: key for %s is shorter than %d", name, line, client, minAPIKeyLen)
+		}
+		keys[sha256.Sum256([]byte(key))] = client
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", name)
+	}
+	return keys, nil
+}
+
+// client returns the name of the client r's key belongs to, from an
+// Authorization: Bearer or X-API-Key header.
+func (k apiKeys) client(r *http.Request) (string, bool) {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		key = r.Header.Get("X-API-Key")
+	}
+	if key == "" {
+		return "", false
+	}
+	name, ok := k[sha256.Sum256([]byte(key))]
+	return name, ok
+}
+
+// openPaths are the paths anyone may fetch, keys or not, so that
+// orchestrators can probe a server without one.
+var openPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// clientNameKey is the context key for the name of an authenticated
+// client.
+type clientNameKey struct{}
+
+// authenticate returns r with the name of its client, or false if the
+// server has keys and r doesn't have one of them.
+func (s *server) authenticate(r *http.Request) (*http.Request, bool) {
+	// The keys are never changed, only replaced, so there is no need
+	// to hold the state.
+	keys := s.state.Load().keys
+	if keys == nil || openPaths[r.URL.Path] {
+		return r, true
+	}
+	name, ok := keys.client(r)
+	if !ok {
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), clientNameKey{}, name)), true
+}
+
+// tlsConfig returns the TLS configuration cfg asks for, or nil for
+// plain HTTP.  With a ClientCA, clients must have a certificate it
+// signed.
+func (cfg serverConfig) tlsConfig() (*tls.Config, error) {
+	if cfg.TLSCert == "" && cfg.TLSKey == "" {
+		if cfg.ClientCA != "" {
+			return nil, errors.New("a client CA needs a certificate and key too")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCA != "" {
+		pem, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = x509.NewCertPool()
+		if !tc.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates", cfg.ClientCA)
+		}
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
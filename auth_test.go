@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "keys")
+	os.WriteFile(keysFile, []byte("# test keys\nalice 0123456789abcdef\n\nbob fedcba9876543210\n"), 0o600)
+	ts := newTestServer(t, serverConfig{APIKeysFile: keysFile})
+
+	status := func(path string, header ...string) int {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, test := range []struct {
+		path   string
+		header []string
+		want   int
+	}{
+		{"/uuid", nil, http.StatusUnauthorized},
+		{"/uuid", []string{"Authorization", "Bearer 0123456789abcdeX"}, http.StatusUnauthorized},
+		{"/uuid", []string{"Authorization", "Bearer 0123456789abcdef"}, http.StatusOK},
+		{"/uuid", []string{"X-API-Key", "fedcba9876543210"}, http.StatusOK},
+		{"/healthz", nil, http.StatusOK},
+	} {
+		if got := status(test.path, test.header...); got != test.want {
+			t.Errorf("%s %v: got %d, want %d", test.path, test.header, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "alice\n", "alice short\n"} {
+		os.WriteFile(keysFile, []byte(bad), 0o600)
+		if _, err := loadAPIKeys(keysFile); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+// writeCert writes a PEM certificate for cn, signed by parent or by
+// itself, and its key, to dir, returning the file names.
+func writeCert(t *testing.T, dir, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (certFile, keyFile string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, cn+".crt"), filepath.Join(dir, cn+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile, cert, key
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	serverCert, serverKey, _, _ := writeCert(t, dir, "server", ca, caKey)
+	clientCert, clientKey, _, _ := writeCert(t, dir, "client", ca, caKey)
+
+	cfg := serverConfig{Version: 4, TLSCert: serverCert, TLSKey: serverKey, ClientCA: caFile}
+	tc, err := cfg.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(s)
+	ts.TLS = tc
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(certs ...tls.Certificate) error {
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		resp, err := c.Get(ts.URL + "/uuid")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err := get(); err == nil {
+		t.Errorf("no client certificate accepted")
+	}
+	pair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(pair); err != nil {
+		t.Errorf("with a client certificate: %v", err)
+	}
+
+	if _, err := (serverConfig{ClientCA: caFile}).tlsConfig(); err == nil || !strings.Contains(err.Error(), "client CA") {
+		t.Errorf("client CA without a certificate: %v", err)
+	}
+}
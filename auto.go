@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+)
+
+// AutoGenerator makes V1 UUIDs with whichever strategy suits how it is
+// being used, which is what the bench results come down to: a mutex is
+// the fastest there is until goroutines start queueing for it, and
+// then compare and swap is.  So it starts out as StrategySatori, a
+// mutex around its own state, and counts how often the mutex is
+// already held when it goes to take it.  Once that is an eighth or
+// more of the calls in a window, it switches to an AtomicGenerator
+// for good: contention comes with the deployment, which doesn't
+// change from one minute to the next, and switching back and forth
+// would cost more than it saved.  Stats reports which is in use.
+type AutoGenerator struct {
+	switched atomic.Bool
+	atomic   *AtomicGenerator
+
+	// mu guards the rest while switched is false.
+	mu            sync.Mutex
+	clockSequence uint16
+	lastTime      uint64
+	hardwareAddr  [6]byte
+	epochFunc     func() uint64
+	// calls and contended count this window's calls, and those that
+	// found mu held.
+	calls, contended int
+
+	counters generatorCounters
+}
+
+const (
+	// autoWindow is how many calls AutoGenerator looks at at a time.
+	autoWindow = 1024
+	// autoContended is how many calls of a window finding the mutex
+	// held make AutoGenerator switch.
+	autoContended = autoWindow / 8
+)
+
+func NewAutoGenerator() *AutoGenerator {
+	return newAutoGenerator(unixTimeFunc)
+}
+
+var _ = registerExperiment(experiment{
+	Name:        "auto",
+	Description: "satori's mutex until it is contended, then atomic's compare and swap",
+	New:         func(int) Generator { return NewAutoGenerator() },
+})
+
+// newAutoGenerator lets tests inject a fake clock.  epochFunc is
+// called with the lock held until the switch, and from every goroutine
+// calling New after it.
+func newAutoGenerator(epochFunc func() uint64) *AutoGenerator {
+	return autoGeneratorFrom(newV1Start(epochFunc))
+}
+
+func autoGeneratorFrom(st v1Start) *AutoGenerator {
+	return &AutoGenerator{
+		atomic:        atomicGeneratorFrom(st),
+		epochFunc:     st.epochFunc,
+		clockSequence: st.clockSequence,
+		hardwareAddr:  st.hardwareAddr,
+	}
+}
+
+// NewV1 returns a UUID based on the current timestamp and MAC address.
+func (g *AutoGenerator) NewV1() UUID {
+	if g.switched.Load() {
+		return g.atomic.NewV1()
+	}
+
+	if !g.mu.TryLock() {
+		g.mu.Lock()
+		g.contended++
+	}
+	if g.switched.Load() {
+		// Switched while this call waited.
+		g.mu.Unlock()
+		return g.atomic.NewV1()
+	}
+
+	timeNow := g.epochFunc()
+	if g.counters.countV1Tick(timeNow, g.lastTime) {
+		g.clockSequence++
+	}
+	g.lastTime = timeNow
+	clockSeq := g.clockSequence
+
+	g.calls++
+	if g.calls == autoWindow {
+		if g.contended >= autoContended {
+			g.switchToAtomic()
+		}
+		g.calls, g.contended = 0, 0
+	}
+	g.mu.Unlock()
+
+	u := UUID{}
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+	binary.BigEndian.PutUint16(u[8:], clockSeq)
+
+	copy(u[10:], g.hardwareAddr[:])
+
+	u.SetVersion(1)
+	u.SetVariant()
+
+	g.counters.generated.Add(1)
+	return u
+}
+
+// switchToAtomic hands over to g.atomic.  It must be called with g.mu
+// held.  Within one clock sequence, the mutex only ever used
+// timestamps up to lastTime, bumping the sequence rather than going
+// back, so the atomic generator carries on with the same clock
+// sequence from the tick after lastTime.
+func (g *AutoGenerator) switchToAtomic() {
+	g.atomic.clockSequence = g.clockSequence
+	g.atomic.last.Store(g.lastTime)
+	g.switched.Store(true)
+}
+
+func (g *AutoGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	if g.switched.Load() {
+		return g.atomic.v1State()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.switched.Load() {
+		return g.atomic.v1State()
+	}
+	return g.lastTime, g.clockSequence, g.hardwareAddr, true
+}
+
+// New is NewV1, so that AutoGenerator is a Generator.
+func (g *AutoGenerator) New() UUID {
+	return g.NewV1()
+}
+
+// Stats reports on g, with Strategy saying which it is using now.  It
+// can be called at any time.
+func (g *AutoGenerator) Stats() GeneratorStats {
+	s := g.counters.stats()
+	a := g.atomic.counters.stats()
+	s.Generated += a.Generated
+	s.Rollbacks += a.Rollbacks
+	s.Borrowed += a.Borrowed
+	s.Strategy = StrategySatori
+	if g.switched.Load() {
+		s.Strategy = StrategyAtomic
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAutoGeneratorSwitch(t *testing.T) {
+	// A clock that keeps going back, so the mutex bumps the clock
+	// sequence and the atomic generator borrows, on both sides of the
+	// switch.
+	g := newAutoGenerator(newSkewedClock(3, 5).epoch)
+	seen := map[UUID]bool{}
+	gen := func(n int) {
+		for i := 0; i < n; i++ {
+			u := g.New()
+			if seen[u] {
+				t.Fatalf("%s twice", u)
+			}
+			seen[u] = true
+		}
+	}
+
+	gen(3 * autoWindow)
+	if s := g.Stats(); s.Strategy != StrategySatori {
+		t.Fatalf("uncontended, strategy %s", s.Strategy)
+	}
+
+	// Make the next call the end of a contended window.
+	g.mu.Lock()
+	g.calls, g.contended = autoWindow-1, autoContended
+	g.mu.Unlock()
+	gen(3 * autoWindow)
+	s := g.Stats()
+	if s.Strategy != StrategyAtomic {
+		t.Fatalf("contended, strategy %s", s.Strategy)
+	}
+	if s.Generated != 6*autoWindow || s.Borrowed == 0 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestAutoGeneratorConcurrent(t *testing.T) {
+	g := NewAutoGenerator()
+	var (
+		mu   sync.Mutex
+		seen = map[UUID]bool{}
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]UUID, 10000)
+			for i := range ids {
+				ids[i] = g.New()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, u := range ids {
+				if seen[u] {
+					t.Errorf("%s twice", u)
+				}
+				seen[u] = true
+			}
+		}()
+	}
+	wg.Wait()
+	if s := g.Stats(); s.Generated != 80000 {
+		t.Errorf("generated %d, using %s", s.Generated, s.Strategy)
+	}
+}
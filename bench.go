@@ -0,0 +1,499 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// benchResult is one strategy run at one level of parallelism.
+type benchResult struct {
+	Strategy    string        `json:"strategy"`
+	Parallelism int           `json:"parallelism"`
+	Duration    time.Duration `json:"duration_ns"`
+	Ops         int64         `json:"ops"`
+	NsPerOp     float64       `json:"ns_per_op"`
+	// Setting is the GC tuning the run was under, with -gogc or
+	// -memlimit.
+	Setting gcSetting `json:"setting"`
+	// Stats is nil for generators that don't keep any.
+	Stats *GeneratorStats `json:"stats,omitempty"`
+	// Profiles is only there with -profile.
+	Profiles *benchProfiles `json:"profiles,omitempty"`
+	// Failed is why the generator gave up part way, such as a panic
+	// on entropy failing under -flaky-entropy.
+	Failed string `json:"failed,omitempty"`
+}
+
+// benchReport is what -json writes, so runs from different machines
+// can be compared later.
+type benchReport struct {
+	Time      time.Time `json:"time"`
+	GoVersion string    `json:"go_version"`
+	GOOS      string    `json:"goos"`
+	GOARCH    string    `json:"goarch"`
+	NumCPU    int       `json:"num_cpu"`
+	ChanSize  int       `json:"chan_size"`
+	// Tags are labels from -tags, such as the machine or what was
+	// being tried.
+	Tags []string `json:"tags,omitempty"`
+	// Experiments are the runs' settings, one for each strategy, for
+	// bench -experiment to repeat.
+	Experiments []ExperimentConfig `json:"experiments,omitempty"`
+	Results     []benchResult      `json:"results"`
+	// IDs is only there with -ids, Staleness with -staleness, GC with
+	// -gc, and Idle with -idle.
+	IDs       []benchResult     `json:"ids,omitempty"`
+	Staleness []stalenessResult `json:"staleness,omitempty"`
+	GC        []gcResult        `json:"gc,omitempty"`
+	Idle      []idleResult      `json:"idle,omitempty"`
+}
+
+func newBenchReport(chanSize int) *benchReport {
+	return &benchReport{
+		Time:      time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		ChanSize:  chanSize,
+	}
+}
+
+// benchRun calls g.New from parallelism goroutines for d, and reports
+// wall clock time per UUID, like testing.B.RunParallel does.  If g
+// panics, the run stops there, with the panic in Failed.
+func benchRun(g Generator, parallelism int, d time.Duration) benchResult {
+	var stop atomic.Bool
+	var ops atomic.Int64
+	var wg sync.WaitGroup
+	var failed atomic.Pointer[string]
+
+	start := time.Now()
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := int64(0)
+			defer func() {
+				if err := recover(); err != nil {
+					msg := fmt.Sprint(err)
+					failed.CompareAndSwap(nil, &msg)
+					stop.Store(true)
+				}
+				ops.Add(n)
+			}()
+			// Only check for the end every so often, so that the
+			// check doesn't dominate the cheap strategies.
+			for !stop.Load() {
+				for j := 0; j < 64; j++ {
+					g.New()
+				}
+				n += 64
+			}
+		}()
+	}
+	for deadline := time.Now().Add(d); !stop.Load() && time.Now().Before(deadline); {
+		time.Sleep(min(10*time.Millisecond, time.Until(deadline)))
+	}
+	stop.Store(true)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := benchResult{
+		Parallelism: parallelism,
+		Duration:    elapsed,
+		Ops:         ops.Load(),
+	}
+	if r.Ops > 0 {
+		r.NsPerOp = float64(elapsed.Nanoseconds()) / float64(r.Ops)
+	}
+	if msg := failed.Load(); msg != nil {
+		r.Failed = *msg
+	}
+	if sr, ok := g.(statsReporter); ok {
+		stats := sr.Stats()
+		r.Stats = &stats
+	}
+	return r
+}
+
+// newBenchGenerator makes a generator with newGen, turning a panic,
+// such as from entropy failing under -flaky-entropy, into an error.
+func newBenchGenerator(newGen func() Generator) (g Generator, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	return newGen(), nil
+}
+
+// benchIDs are the generators of other kinds of ID that bench -ids
+// runs too, so that -flaky-entropy and -jumpy-clock show how they
+// cope.  Each makes a fresh generator, with worker or node 0.
+var benchIDs = map[string]func() Generator{
+	"snowflake": func() Generator {
+		g, _ := NewSnowflakeGenerator(0)
+		return idBenchGenerator{next: func() { g.Next() }, statsReporter: g}
+	},
+	"flake": func() Generator {
+		g := NewFlakeGenerator(&[6]byte{})
+		return idBenchGenerator{next: func() { g.Next() }, statsReporter: g}
+	},
+	"sonyflake": func() Generator {
+		g, err := NewSonyflakeGenerator(SonyflakeSettings{
+			MachineID: func() (uint16, error) { return 0, nil },
+		})
+		if err != nil {
+			panic(err)
+		}
+		return idBenchGenerator{next: func() { g.Next() }, statsReporter: g}
+	},
+	"tsid-mutex":   func() Generator { return newTSIDBenchGenerator(StrategyMutex) },
+	"tsid-channel": func() Generator { return newTSIDBenchGenerator(StrategyChannel) },
+	"tsid-atomic":  func() Generator { return newTSIDBenchGenerator(StrategyAtomic) },
+}
+
+func newTSIDBenchGenerator(s Strategy) Generator {
+	g, _ := NewTSIDGenerator(0, DefaultTSIDNodeBits, s)
+	return idBenchGenerator{next: func() { g.Next() }, statsReporter: g, close: g.Close}
+}
+
+// idBenchGenerator adapts a generator of some other kind of ID to a
+// Generator, for benchRun, which throws what New returns away anyway.
+type idBenchGenerator struct {
+	next func()
+	statsReporter
+	close func()
+}
+
+func (g idBenchGenerator) New() UUID {
+	g.next()
+	return UUID{}
+}
+
+// Close stops the generator, if it needs stopping.
+func (g idBenchGenerator) Close() {
+	if g.close != nil {
+		g.close()
+	}
+}
+
+// parseInts parses a comma separated list of positive ints.
+func parseInts(s string) ([]int, error) {
+	var ints []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("%d is not positive", n)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// parseStrategies parses a comma separated list of strategy names, or
+// "all".
+func parseStrategies(s string) ([]string, error) {
+	if s == "all" {
+		return sortedKeys(experiments), nil
+	}
+	names := strings.Split(s, ",")
+	for _, name := range names {
+		if _, ok := experiments[name]; !ok {
+			return nil, fmt.Errorf("unknown strategy %q", name)
+		}
+	}
+	return names, nil
+}
+
+// parseIDGenerators parses a comma separated list of benchIDs names,
+// or "all".
+func parseIDGenerators(s string) ([]string, error) {
+	if s == "all" {
+		return sortedKeys(benchIDs), nil
+	}
+	names := strings.Split(s, ",")
+	for _, name := range names {
+		if _, ok := benchIDs[name]; !ok {
+			return nil, fmt.Errorf("unknown ID generator %q", name)
+		}
+	}
+	return names, nil
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	strategyList := fs.String("strategies", "all", "comma separated V1 strategies, or all: "+strings.Join(sortedKeys(experiments), ", "))
+	list := fs.Bool("list", false, "describe the strategies and exit")
+	idList := fs.String("ids", "", "comma separated generators of other kinds of ID to run too, or all: "+strings.Join(sortedKeys(benchIDs), ", "))
+	tagList := fs.String("tags", "", "comma separated labels to put in the -json file, such as the machine")
+	parallelismList := fs.String("parallelism", "1", "comma separated numbers of goroutines to generate from")
+	runFor := fs.Duration("duration", time.Second, "how long to run each strategy at each parallelism")
+	chanSize := fs.Int("chansize", 10, "channel size for the channel strategy")
+	jsonFile := fs.String("json", "", "also write the results as JSON to this file")
+	stalenessList := fs.String("staleness", "", "also measure how old UUIDs are when they're handed out, with the channel strategy at each of these comma separated channel sizes")
+	idle := fs.Duration("idle", 0, "also measure each strategy's idle cost, the CPU it uses over this long with nothing taking UUIDs")
+	gc := fs.Bool("gc", false, "also measure allocations and GC at a fixed rate for -duration, 60s is a good choice")
+	rate := fs.Int("rate", 1000000, "UUIDs per second for -gc")
+	gogcList := fs.String("gogc", "", "comma separated GOGC values to repeat the runs under, such as 50,100,off")
+	limitList := fs.String("memlimit", "", "comma separated GOMEMLIMIT values to repeat the runs under, such as 16MiB,off")
+	profileDir := fs.String("profile", "", "capture CPU, mutex and block profiles of each run into this directory, best next to the -json file")
+	mutexFraction := fs.Int("mutex-profile-fraction", 10, "with -profile, profile 1 in this many mutex contention events")
+	blockRate := fs.Int("block-profile-rate", 1000, "with -profile, profile a blocking event every this many nanoseconds blocked")
+	flakyEntropy := fs.Float64("flaky-entropy", 0, "fail this fraction of reads from crypto/rand, to see how the strategies cope")
+	entropyStall := fs.Duration("entropy-stall", 0, "with -flaky-entropy, stall the faulty reads this long instead of failing them")
+	jumpyClock := fs.Float64("jumpy-clock", 0, "jump the clock on this fraction of readings, to see how the strategies cope")
+	maxJump := fs.Duration("max-jump", time.Second, "with -jumpy-clock, how far either way the clock jumps")
+	experimentFile := fs.String("experiment", "", "run the strategy, chansize, node, parallelism and duration in this JSON file, such as one of a -json file's experiments, instead of the flags")
+	fs.Parse(args)
+	if *list {
+		writeExperiments(os.Stdout)
+		return nil
+	}
+
+	var names []string
+	var parallelism []int
+	var node string
+	if *experimentFile != "" {
+		e, err := loadExperimentConfig(*experimentFile)
+		if err != nil {
+			return err
+		}
+		if len(e.Parallelism) == 0 {
+			e.Parallelism = []int{1}
+		}
+		if e.Duration == 0 {
+			e.Duration = duration(time.Second)
+		}
+		names, parallelism, node = []string{e.Strategy}, e.Parallelism, e.Node
+		*runFor, *chanSize = time.Duration(e.Duration), e.ChanSize
+		nodeID, _ := parseNode(node)
+		if nodeID != nil {
+			setNodeID(*nodeID)
+		}
+	} else {
+		var err error
+		if names, err = parseStrategies(*strategyList); err != nil {
+			return err
+		}
+		if parallelism, err = parseInts(*parallelismList); err != nil {
+			return fmt.Errorf("bad -parallelism: %v", err)
+		}
+	}
+	var idNames []string
+	if *idList != "" {
+		var err error
+		if idNames, err = parseIDGenerators(*idList); err != nil {
+			return err
+		}
+	}
+	if *runFor <= 0 {
+		return errors.New("-duration must be positive")
+	}
+	if *rate < 1 {
+		return errors.New("-rate must be positive")
+	}
+	if *idle < 0 {
+		return errors.New("-idle must not be negative")
+	}
+	if *flakyEntropy < 0 || *flakyEntropy > 1 || *jumpyClock < 0 || *jumpyClock > 1 {
+		return errors.New("-flaky-entropy and -jumpy-clock must be between 0 and 1")
+	}
+	if *flakyEntropy > 0 {
+		entropySource = FlakyEntropy(*flakyEntropy).Stalling(*entropyStall)
+	}
+	if *jumpyClock > 0 {
+		clockNow = JumpyClock(time.Now, *jumpyClock, *maxJump)
+	}
+	settings, err := parseGCSettings(*gogcList, *limitList)
+	if err != nil {
+		return err
+	}
+	var stalenessSizes []int
+	if *stalenessList != "" {
+		// parseInts rejects 0, but an unbuffered channel is worth
+		// measuring.
+		for _, f := range strings.Split(*stalenessList, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || n < 0 {
+				return fmt.Errorf("bad -staleness: %q", f)
+			}
+			stalenessSizes = append(stalenessSizes, n)
+		}
+	}
+
+	var prof *profiler
+	if *profileDir != "" {
+		if *mutexFraction < 1 || *blockRate < 1 {
+			return errors.New("profile rates must be positive")
+		}
+		if prof, err = newProfiler(*profileDir, *mutexFraction, *blockRate); err != nil {
+			return err
+		}
+	}
+
+	report := newBenchReport(*chanSize)
+	for _, name := range names {
+		report.Experiments = append(report.Experiments, ExperimentConfig{
+			Strategy:    name,
+			ChanSize:    *chanSize,
+			Node:        node,
+			Parallelism: parallelism,
+			Duration:    duration(*runFor),
+		})
+	}
+	if *tagList != "" {
+		for _, tag := range strings.Split(*tagList, ",") {
+			report.Tags = append(report.Tags, strings.TrimSpace(tag))
+		}
+	}
+	for _, setting := range settings {
+		if len(settings) > 1 {
+			fmt.Printf("\n%s\n", setting)
+		}
+		restore := setting.apply()
+
+		// Fixed width columns rather than a tabwriter, so that each
+		// row shows up as soon as it's done.
+		fmt.Printf("%-10s %10s %12s %10s %12s\n", "strategy", "goroutines", "UUIDs", "ns/op", "seq bumps")
+		for _, name := range names {
+			for _, p := range parallelism {
+				var r benchResult
+				run := func() {
+					g, err := newBenchGenerator(func() Generator { return experiments[name].New(*chanSize) })
+					if err != nil {
+						r = benchResult{Parallelism: p, Failed: err.Error()}
+						return
+					}
+					r = benchRun(g, p, *runFor)
+				}
+				if prof == nil {
+					run()
+				} else {
+					file := fmt.Sprintf("%s-p%d", name, p)
+					if len(settings) > 1 {
+						file += "-" + setting.String()
+					}
+					profiles, err := prof.run(file, run)
+					if err != nil {
+						return err
+					}
+					r.Profiles = profiles
+				}
+				r.Strategy, r.Setting = name, setting
+				report.Results = append(report.Results, r)
+				bumps := "-"
+				if r.Stats != nil {
+					bumps = strconv.FormatUint(r.Stats.ClockSeqIncrements, 10)
+				}
+				fmt.Printf("%-10s %10d %12d %10.1f %12s", r.Strategy, r.Parallelism, r.Ops, r.NsPerOp, bumps)
+				if r.Failed != "" {
+					fmt.Printf("  failed: %s", r.Failed)
+				}
+				fmt.Println()
+			}
+		}
+
+		if idNames != nil {
+			fmt.Printf("\n%-12s %10s %12s %10s %10s %10s\n", "IDs", "goroutines", "generated", "ns/op", "borrowed", "rollbacks")
+			for _, name := range idNames {
+				for _, p := range parallelism {
+					var r benchResult
+					g, err := newBenchGenerator(benchIDs[name])
+					if err != nil {
+						r = benchResult{Parallelism: p, Failed: err.Error()}
+					} else {
+						r = benchRun(g, p, *runFor)
+						if c, ok := g.(interface{ Close() }); ok {
+							c.Close()
+						}
+					}
+					r.Strategy, r.Setting = name, setting
+					report.IDs = append(report.IDs, r)
+					var stats GeneratorStats
+					if r.Stats != nil {
+						stats = *r.Stats
+					}
+					fmt.Printf("%-12s %10d %12d %10.1f %10d %10d", r.Strategy, r.Parallelism, r.Ops, r.NsPerOp, stats.Borrowed, stats.Rollbacks)
+					if r.Failed != "" {
+						fmt.Printf("  failed: %s", r.Failed)
+					}
+					fmt.Println()
+				}
+			}
+		}
+
+		if *gc {
+			fmt.Printf("\n%-10s %10s %10s %12s %10s %12s %6s %12s %12s\n", "strategy", "goroutines", "rate", "UUIDs", "mallocs", "bytes", "GCs", "total pause", "max pause")
+			for _, name := range names {
+				for _, p := range parallelism {
+					r := gcRun(experiments[name].New(*chanSize), p, *rate, *runFor)
+					r.Strategy, r.Setting = name, setting
+					report.GC = append(report.GC, r)
+					fmt.Printf("%-10s %10d %10d %12d %10d %12d %6d %12s %12s\n", r.Strategy, r.Parallelism, r.Rate, r.Ops, r.Mallocs, r.Bytes, r.GCCycles, r.PauseTotal, r.MaxPause)
+				}
+			}
+		}
+		restore()
+	}
+
+	if stalenessSizes != nil {
+		fmt.Printf("\n%-10s %8s %10s %10s %12s %12s %12s\n", "strategy", "chansize", "goroutines", "ns/op", "p50 age", "p99 age", "max age")
+		for _, name := range names {
+			sizes := stalenessSizes
+			if name != "channel" {
+				sizes = []int{*chanSize}
+			}
+			for _, size := range sizes {
+				for _, p := range parallelism {
+					r := stalenessRun(experiments[name].New(size), p, *runFor)
+					r.Strategy, r.ChanSize = name, size
+					report.Staleness = append(report.Staleness, r)
+					fmt.Printf("%-10s %8d %10d %10.1f %12s %12s %12s\n", r.Strategy, r.ChanSize, r.Parallelism, r.NsPerOp, r.P50, r.P99, r.Max)
+				}
+			}
+		}
+	}
+
+	if *idle > 0 {
+		fmt.Printf("\n%-10s %8s %12s %12s\n", "strategy", "chansize", "CPU", "idle cost")
+		// First with no generator, to show what the runtime costs on
+		// its own.
+		runs := append([]string{"none"}, names...)
+		for _, name := range runs {
+			var newGen func() Generator
+			if name != "none" {
+				newGen = func() Generator { return experiments[name].New(*chanSize) }
+			}
+			r, err := idleRun(newGen, *idle)
+			if err != nil {
+				return err
+			}
+			r.Strategy, r.ChanSize = name, *chanSize
+			report.Idle = append(report.Idle, r)
+			fmt.Printf("%-10s %8d %12s %10s/s\n", r.Strategy, r.ChanSize, r.CPU.Round(time.Microsecond), r.IdleCost.Round(time.Microsecond))
+		}
+	}
+
+	if *jsonFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*jsonFile, append(b, '\n'), 0644)
+}
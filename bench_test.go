@@ -0,0 +1,137 @@
+/**
+
+GC tuning
+
+uuidgen bench -duration 2s -gc -rate 2000000 -gogc 10,100,off -memlimit off
+
+on a 1 CPU Xeon VM, trimmed to ns/op flat out, and the GC numbers at
+a fixed 2M UUIDs/s:
+
+                     ns/op              mallocs  bytes  GCs
+             GOGC=10  GOGC=100  GOGC=off   (same for every GOGC)
+  channel     274.9    229.2     257.7        8-9     ~950    0
+  lockfree    322.7    247.5     348.5          6      360    0
+  mutex       134.7    124.5     152.1          6      360    0
+  satori      142.0    116.4     154.4          6      360    0
+
+Take-aways:
+
+ - None of the strategies allocate per UUID.  The handful of mallocs
+   per run are the ticker and goroutines of the harness itself, and
+   the channel strategy's channel.  So there were no GCs at all, and
+   nothing for GOGC or GOMEMLIMIT to tune.
+
+ - The ns/op differences between settings are run to run noise: they
+   don't follow GOGC, and GOGC=off is no faster than GOGC=10.
+
+ - GC only matters once something around the generator allocates,
+   such as formatting UUIDs as strings, which -gc doesn't measure.
+
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBenchRun(t *testing.T) {
+	r := benchRun(GeneratorFunc(NewV4), 2, 10*time.Millisecond)
+	if r.Ops == 0 || r.NsPerOp <= 0 {
+		t.Errorf("nothing generated: %+v", r)
+	}
+}
+
+func TestParseInts(t *testing.T) {
+	got, err := parseInts("1, 4,16")
+	if err != nil || len(got) != 3 || got[0] != 1 || got[1] != 4 || got[2] != 16 {
+		t.Errorf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "1,x", "0"} {
+		if _, err := parseInts(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestStalenessRun(t *testing.T) {
+	r := stalenessRun(NewChanneledGenerator(100), 1, 10*time.Millisecond)
+	if r.NsPerOp <= 0 || r.P50 > r.P99 || r.Max <= 0 {
+		t.Errorf("%+v", r)
+	}
+}
+
+func TestIdleRun(t *testing.T) {
+	r, err := idleRun(func() Generator { return NewChanneledGenerator(100) }, 20*time.Millisecond)
+	if errors.Is(err, errNoCPUTime) {
+		t.Skip(err)
+	}
+	if err != nil || r.Duration < 20*time.Millisecond || r.CPU < 0 || r.IdleCost < 0 {
+		t.Errorf("%+v, %v", r, err)
+	}
+}
+
+func TestStalenessPercentile(t *testing.T) {
+	r := &stalenessRecorder{}
+	for i := 0; i < 98; i++ {
+		r.buckets[10].Add(1)
+	}
+	r.buckets[20].Add(2)
+	if p := r.percentile(50); p != 1<<10 {
+		t.Errorf("p50 %s", p)
+	}
+	if p := r.percentile(99); p != 1<<20 {
+		t.Errorf("p99 %s", p)
+	}
+}
+
+func TestGCRun(t *testing.T) {
+	r := gcRun(NewSatoriGenerator(), 2, 100000, 50*time.Millisecond)
+	// 50 ticks of 50 UUIDs from each goroutine, give or take a tick.
+	if r.Ops < 4000 || r.Ops > 6000 {
+		t.Errorf("%d UUIDs, want about 5000", r.Ops)
+	}
+}
+
+func TestParseGCSettings(t *testing.T) {
+	settings, err := parseGCSettings("50, off", "64MiB,off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings) != 4 || settings[0] != (gcSetting{"50", "64MiB"}) || settings[3] != (gcSetting{"off", "off"}) {
+		t.Errorf("got %v", settings)
+	}
+	if settings, err := parseGCSettings("", ""); err != nil || len(settings) != 1 || settings[0] != (gcSetting{}) {
+		t.Errorf("defaults gave %v, %v", settings, err)
+	}
+	for _, bad := range [][2]string{{"x", ""}, {"-5", ""}, {"", "12QiB"}, {"", "0"}} {
+		if _, err := parseGCSettings(bad[0], bad[1]); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+	if n, _ := parseMemLimit("2GiB"); n != 2<<30 {
+		t.Errorf("2GiB is %d", n)
+	}
+}
+
+func TestBenchIDs(t *testing.T) {
+	defer func(old func() time.Time) { clockNow = old }(clockNow)
+	clockNow = JumpyClock(time.Now, 0.01, 50*time.Millisecond)
+
+	for _, name := range sortedKeys(benchIDs) {
+		g, err := newBenchGenerator(benchIDs[name])
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r := benchRun(g, 2, 10*time.Millisecond)
+		g.(idBenchGenerator).Close()
+		if r.Ops == 0 || r.Failed != "" || r.Stats == nil || r.Stats.Generated == 0 {
+			t.Errorf("%s: %+v", name, r)
+		}
+	}
+	if _, err := parseIDGenerators("snowflake,nope"); err == nil {
+		t.Error("unknown ID generator accepted")
+	}
+}
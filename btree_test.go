@@ -0,0 +1,139 @@
+/**
+
+Index locality
+
+The usual argument for time ordered UUIDs is that they are kinder to
+database indexes: new keys land at the right edge of the index
+instead of all over it.  This models the leaf level of a B+tree as a
+list of sorted pages and counts how many page splits and how many key
+shifts (keys moved to make room within a page) each kind of UUID
+causes.
+
+Raw results, inserting 1,000,000 pre-generated UUIDs into pages of
+128 keys:
+
+  BenchmarkIndexInsert/v1         	 1000000	       223.1 ns/op	         0 shifts/op	         0.01562 splits/op
+  BenchmarkIndexInsert/v4         	 1000000	       976.6 ns/op	        45.81 shifts/op	         0.01130 splits/op
+  BenchmarkIndexInsert/v6         	 1000000	       220.9 ns/op	         0 shifts/op	         0.01562 splits/op
+  BenchmarkIndexInsert/v7         	 1000000	       386.3 ns/op	        44.82 shifts/op	         0.01141 splits/op
+
+Some take-aways.
+
+1. V4 is the worst case, as expected: every insert lands somewhere
+   random and shifts a third of a page on average.
+2. V1 only looks as good as V6 because all the UUIDs were generated
+   within a fraction of a second, so only the low time bits changed.
+   time_low wraps about every 7 minutes, so a real table would see
+   V4-like behavior.
+3. V7 is time ordered, but only to the millisecond.  Generating this
+   fast puts thousands of UUIDs in each millisecond, and those are
+   random relative to each other.  A counter within the millisecond
+   would fix that.
+4. Appending in order leaves every page half full after a split, so
+   the ordered versions actually split more often than V4.  A real
+   database would special case right-edge splits.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+const indexPageSize = 128
+
+// leafIndex is the leaf level of a B+tree: sorted pages of sorted keys.
+type leafIndex struct {
+	pages  [][]UUID
+	splits int
+	shifts int
+}
+
+func less(a, b UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+func (idx *leafIndex) insert(u UUID) {
+	if len(idx.pages) == 0 {
+		idx.pages = append(idx.pages, make([]UUID, 0, indexPageSize+1))
+	}
+
+	// The last page whose first key is <= u, or the first page.  Only
+	// the first page can ever be empty.
+	p := sort.Search(len(idx.pages)-1, func(i int) bool {
+		return less(u, idx.pages[i+1][0])
+	})
+	page := idx.pages[p]
+
+	pos := sort.Search(len(page), func(i int) bool {
+		return !less(page[i], u)
+	})
+	idx.shifts += len(page) - pos
+	page = append(page, UUID{})
+	copy(page[pos+1:], page[pos:])
+	page[pos] = u
+	idx.pages[p] = page
+
+	if len(page) > indexPageSize {
+		idx.splits++
+		half := len(page) / 2
+		right := make([]UUID, len(page)-half, indexPageSize+1)
+		copy(right, page[half:])
+		idx.pages[p] = page[:half]
+		idx.pages = append(idx.pages, nil)
+		copy(idx.pages[p+2:], idx.pages[p+1:])
+		idx.pages[p+1] = right
+	}
+}
+
+func TestIndexInsertSorted(t *testing.T) {
+	idx := leafIndex{}
+	for n := 0; n < 10*indexPageSize; n++ {
+		idx.insert(NewV4())
+	}
+	var prev UUID
+	count := 0
+	for _, page := range idx.pages {
+		for _, u := range page {
+			if count > 0 && !less(prev, u) {
+				t.Fatalf("index out of order at key %d", count)
+			}
+			prev = u
+			count++
+		}
+	}
+	if count != 10*indexPageSize {
+		t.Errorf("index has %d keys, want %d", count, 10*indexPageSize)
+	}
+}
+
+var indexVersions = []struct {
+	name string
+	gen  func() UUID
+}{
+	{"v1", NewV1},
+	{"v4", NewV4},
+	{"v6", NewV6},
+	{"v7", NewV7},
+}
+
+func BenchmarkIndexInsert(b *testing.B) {
+	for _, v := range indexVersions {
+		b.Run(v.name, func(b *testing.B) {
+			ids := make([]UUID, b.N)
+			for n := range ids {
+				ids[n] = v.gen()
+			}
+			idx := leafIndex{}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				idx.insert(ids[n])
+			}
+			b.ReportMetric(float64(idx.shifts)/float64(b.N), "shifts/op")
+			b.ReportMetric(float64(idx.splits)/float64(b.N), "splits/op")
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+)
+
+// generationSource is the code UUIDs are made by, which fingerprint
+// hashes.
+//
+//go:embed uuid.go faults.go entropytimeout.go atomic.go auto.go pool.go libuuid.go versions.go provenance.go
+var generationSource embed.FS
+
+// commonSource is the code every generator goes through: storage,
+// safeRandom and the clock.
+var commonSource = []string{"uuid.go", "faults.go", "entropytimeout.go"}
+
+// strategySource is the code each V1 strategy adds to commonSource,
+// and versionSource the code for each other version, which for version
+// 8 is on top of its strategy's.
+var (
+	strategySource = map[string][]string{
+		"mutex":    nil,
+		"satori":   nil,
+		"channel":  nil,
+		"lockfree": nil,
+		"atomic":   {"atomic.go"},
+		"auto":     {"auto.go", "atomic.go"},
+		"pool":     {"pool.go"},
+		"libuuid":  {"libuuid.go"},
+	}
+	versionSource = map[int][]string{
+		4: {"versions.go"},
+		6: {"versions.go"},
+		7: {"versions.go"},
+		8: {"provenance.go"},
+	}
+)
+
+// fingerprint returns a SHA-256 of the version, the strategy and the
+// source of the code that generator runs, so that two builds with the
+// same fingerprint make UUIDs the same way, whatever else changed
+// between them.
+func fingerprint(version int, strategy string) (string, error) {
+	files := versionSource[version]
+	if version == 1 || version == 8 {
+		s, ok := strategySource[strategy]
+		if !ok {
+			return "", fmt.Errorf("unknown strategy %q", strategy)
+		}
+		files = append(s[:len(s):len(s)], files...)
+	} else if files == nil {
+		return "", fmt.Errorf("unsupported version %d", version)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "version %d strategy %q\n", version, strategy)
+	for _, name := range append(commonSource, files...) {
+		b, err := generationSource.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildInfo says which build, and which generator in it, makes a
+// server's or a command's UUIDs: the body of a /version response and
+// what uuidgen version prints.
+type buildInfo struct {
+	Module        string `json:"module"`
+	ModuleVersion string `json:"module_version"`
+	GoVersion     string `json:"go_version"`
+	Revision      string `json:"vcs_revision,omitempty"`
+	RevisionTime  string `json:"vcs_time,omitempty"`
+	Modified      bool   `json:"vcs_modified,omitempty"`
+
+	Version  int    `json:"version"`
+	Strategy string `json:"strategy,omitempty"`
+	// NodePolicy is where V1 and V6 node IDs come from, as nodePolicy
+	// says, the ordinal version 8 UUIDs are tagged with, and none for
+	// other versions.
+	NodePolicy  string `json:"node_policy"`
+	Node        string `json:"node,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// newBuildInfo describes this build, generating UUIDs of version with
+// strategy, and node, as parseNode takes it.
+func newBuildInfo(version int, strategy, node string) (buildInfo, error) {
+	if version != 1 && version != 8 {
+		strategy = ""
+	}
+	fp, err := fingerprint(version, strategy)
+	if err != nil {
+		return buildInfo{}, err
+	}
+	bi := buildInfo{
+		ModuleVersion: "(devel)",
+		Version:       version,
+		Strategy:      strategy,
+		NodePolicy:    "none",
+		Fingerprint:   fp,
+	}
+	switch version {
+	case 1, 6:
+		bi.NodePolicy = nodePolicy(node)
+		bi.Node = net.HardwareAddr(hardwareAddr[:]).String()
+	case 8:
+		bi.NodePolicy = fmt.Sprintf("ordinal %d", nodeOrdinal.Load())
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		bi.Module, bi.GoVersion = info.Main.Path, info.GoVersion
+		if info.Main.Version != "" {
+			bi.ModuleVersion = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				bi.Revision = s.Value
+			case "vcs.time":
+				bi.RevisionTime = s.Value
+			case "vcs.modified":
+				bi.Modified = s.Value == "true"
+			}
+		}
+	}
+	return bi, nil
+}
+
+// nodePolicy says where the node ID for a -node setting comes from:
+// a network interface, the one given, or random, either because it
+// was asked for or because there was no interface.
+func nodePolicy(node string) string {
+	switch {
+	case node == "random":
+		return "random"
+	case node != "":
+		return "fixed"
+	case hardwareAddr[0]&0x01 != 0:
+		return "random, no network interface"
+	}
+	return "network interface"
+}
+
+// handleVersion serves the server's buildInfo.
+func (s *server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	cfg := s.state.Load().cfg
+	bi, err := newBuildInfo(cfg.Version, cfg.Strategy, cfg.Node)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bi)
+}
+
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	version := fs.Int("version", 1, "UUID version: 1, 4, 6, 7 or 8")
+	strategy := fs.String("strategy", "mutex", "V1 strategy")
+	node := fs.String("node", "", "V1 and V6 node ID: a MAC address, \"random\", or empty for this machine's")
+	ordinal := fs.Uint("node-ordinal", 0, "the instance number version 8 UUIDs are tagged with")
+	asJSON := fs.Bool("json", false, "print JSON, as serve's /version does")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen version [flags]")
+		fmt.Fprintln(fs.Output(), "Prints this build's version, and which generator the flags choose, with a fingerprint of its code.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	n, err := parseNode(*node)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		setNodeID(*n)
+	}
+	if *ordinal > 255 {
+		return errors.New("-node-ordinal must be at most 255")
+	}
+	setNodeOrdinal(uint8(*ordinal))
+	bi, err := newBuildInfo(*version, *strategy, *node)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bi)
+	}
+
+	line := func(k, v string) {
+		if v != "" {
+			fmt.Printf("%-14s %s\n", k+":", v)
+		}
+	}
+	line("module", bi.Module)
+	line("version", bi.ModuleVersion)
+	line("go", bi.GoVersion)
+	rev := bi.Revision
+	if bi.Modified {
+		rev += " (modified)"
+	}
+	line("revision", rev)
+	line("revision time", bi.RevisionTime)
+	line("uuid version", fmt.Sprint(bi.Version))
+	line("strategy", bi.Strategy)
+	line("node policy", bi.NodePolicy)
+	line("node", bi.Node)
+	line("fingerprint", bi.Fingerprint)
+	return nil
+}
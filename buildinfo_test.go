@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	seen := map[string]string{}
+	check := func(version int, strategy string) {
+		fp, err := fingerprint(version, strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := fingerprint(version, strategy); again != fp {
+			t.Errorf("version %d %q: %s, then %s", version, strategy, fp, again)
+		}
+		if other, ok := seen[fp]; ok {
+			t.Errorf("version %d %q has the fingerprint of %s", version, strategy, other)
+		}
+		seen[fp] = fmt.Sprintf("version %d %q", version, strategy)
+	}
+	// Every strategy needs its code listed, or its fingerprint would
+	// miss changes to it.
+	for name := range experiments {
+		if _, ok := strategySource[name]; !ok {
+			t.Errorf("strategy %q has no strategySource", name)
+		}
+		check(1, name)
+	}
+	for v := range versionGenerators {
+		check(v, "")
+	}
+	check(8, "mutex")
+
+	if _, err := fingerprint(1, "bogus"); err == nil {
+		t.Error("unknown strategy: no error")
+	}
+	if _, err := fingerprint(2, ""); err == nil {
+		t.Error("version 2: no error")
+	}
+}
+
+func TestServeVersion(t *testing.T) {
+	ts := newTestServer(t, serverConfig{})
+	var bi buildInfo
+	if err := json.Unmarshal([]byte(get(t, ts, "/version", http.StatusOK)), &bi); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := fingerprint(1, "mutex")
+	if bi.Version != 1 || bi.Strategy != "mutex" || bi.Fingerprint != want || bi.Node == "" || bi.NodePolicy == "none" || bi.ModuleVersion == "" {
+		t.Errorf("version: %+v", bi)
+	}
+
+	ts = newTestServer(t, serverConfig{Version: 7})
+	bi = buildInfo{}
+	if err := json.Unmarshal([]byte(get(t, ts, "/version", http.StatusOK)), &bi); err != nil {
+		t.Fatal(err)
+	}
+	if bi.Version != 7 || bi.Strategy != "" || bi.NodePolicy != "none" || bi.Node != "" {
+		t.Errorf("V7 version: %+v", bi)
+	}
+}
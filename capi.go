@@ -0,0 +1,50 @@
+//go:build cshared
+
+// The C API, for services on the same host that aren't written in Go.
+// Build it with
+//
+//	go build -tags cshared -buildmode=c-shared -o libuuidgen.so .
+//
+// which also writes libuuidgen.h.  The calls are safe from any thread,
+// and like libuuid's, UUIDs are 16 byte arrays the caller owns.
+
+package main
+
+import "C"
+
+import "unsafe"
+
+// cUUID is the 16 bytes at p.
+func cUUID(p *C.uchar) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), len(UUID{}))
+}
+
+// uuid_new_v1 writes a V1 UUID, from the mutex strategy, to out.
+//
+//export uuid_new_v1
+func uuid_new_v1(out *C.uchar) {
+	u := NewV1()
+	copy(cUUID(out), u[:])
+}
+
+// uuid_new_v7 writes a V7 UUID to out.
+//
+//export uuid_new_v7
+func uuid_new_v7(out *C.uchar) {
+	u := NewV7()
+	copy(cUUID(out), u[:])
+}
+
+// uuid_to_string writes the canonical form of the UUID in to out,
+// which must have room for 37 bytes: 36 characters and a NUL, as with
+// libuuid's uuid_unparse.
+//
+//export uuid_to_string
+func uuid_to_string(in *C.uchar, out *C.char) {
+	var u UUID
+	copy(u[:], cUUID(in))
+	s := u.String()
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(out)), len(s)+1)
+	copy(buf, s)
+	buf[len(s)] = 0
+}
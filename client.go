@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client gets UUIDs from a serve server, like ChanneledGenerator but
+// across the network: a goroutine keeps a buffer of them topped up, a
+// batch at a time, so that Next almost never waits on a request.  The
+// goroutine fetches another batch whenever the buffer drops to the
+// low water mark.
+type Client struct {
+	url      string
+	http     *http.Client
+	apiKey   string
+	batch    int
+	lowWater int
+
+	ids    chan UUID
+	refill chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	// ctx is cancelled by Close, to cut short a fetch in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// A ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http.Client a Client fetches with.  The
+// default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithAPIKey makes a Client send key as a bearer token, for servers
+// started with -api-keys.
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithLowWater sets how few UUIDs a Client's buffer gets down to
+// before it fetches more.  The default is half a batch.
+func WithLowWater(n int) ClientOption {
+	return func(c *Client) {
+		c.lowWater = n
+	}
+}
+
+// Client retry delays after a failed fetch, doubling from the first to
+// the most.
+const (
+	clientMinRetry = 100 * time.Millisecond
+	clientMaxRetry = 5 * time.Second
+)
+
+// NewClient returns a Client for the server at baseURL, such as
+// http://localhost:8080, that fetches batch UUIDs at a time, which
+// must be between 1 and the most the server hands out at once.  It
+// starts fetching straight away; Close stops it.
+func NewClient(baseURL string, batch int, opts ...ClientOption) *Client {
+	c := &Client{
+		url:      strings.TrimSuffix(baseURL, "/") + "/uuid?n=",
+		http:     http.DefaultClient,
+		batch:    batch,
+		lowWater: batch / 2,
+		refill:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Room for a whole batch on top of the low water mark, so a fetch
+	// never has to wait for Next to make room.
+	c.ids = make(chan UUID, c.batch+c.lowWater)
+	go c.run()
+	c.wake()
+	return c
+}
+
+// wake asks the goroutine to top the buffer up, if it isn't already.
+func (c *Client) wake() {
+	select {
+	case c.refill <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns the next UUID, waiting for one if the buffer is empty,
+// until ctx is done.  The error then says why the buffer is empty, if
+// fetching has been failing.
+func (c *Client) Next(ctx context.Context) (UUID, error) {
+	select {
+	case u := <-c.ids:
+		if len(c.ids) <= c.lowWater {
+			c.wake()
+		}
+		return u, nil
+	default:
+	}
+
+	c.wake()
+	select {
+	case u := <-c.ids:
+		return u, nil
+	case <-ctx.Done():
+		if err := c.Err(); err != nil {
+			return UUID{}, fmt.Errorf("%w: last fetch: %v", ctx.Err(), err)
+		}
+		return UUID{}, ctx.Err()
+	}
+}
+
+// Err returns the error from the last fetch, or nil if it worked.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// Close stops fetching, abandoning any request in flight.  UUIDs
+// already in the buffer are thrown away, never to be used.
+func (c *Client) Close() {
+	close(c.stop)
+	c.cancel()
+	<-c.done
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	retry := clientMinRetry
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-c.refill:
+		}
+
+		for len(c.ids) <= c.lowWater {
+			wait, err := c.fetch(cap(c.ids) - len(c.ids))
+			c.mu.Lock()
+			c.lastErr = err
+			c.mu.Unlock()
+			if err == nil {
+				retry = clientMinRetry
+				continue
+			}
+
+			if wait < retry {
+				wait = retry
+			}
+			retry = min(retry*2, clientMaxRetry)
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+	}
+}
+
+// errNoUUIDs is what fetch returns for a response with none in it, so
+// that run backs off rather than asking again straight away.
+var errNoUUIDs = errors.New("server sent no UUIDs")
+
+// fetch gets up to n UUIDs into the buffer, returning how long the
+// server asked to be left alone for, if it did.
+func (c *Client) fetch(n int) (wait time.Duration, err error) {
+	n = min(n, c.batch)
+	req, err := http.NewRequestWithContext(c.ctx, "GET", c.url+strconv.Itoa(n), nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(secs) * time.Second
+		}
+		return wait, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	d := NewDecoder(resp.Body)
+	for got := 0; ; got++ {
+		u, err := d.Decode()
+		if errors.Is(err, io.EOF) {
+			if got == 0 {
+				return 0, errNoUUIDs
+			}
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		select {
+		case c.ids <- u:
+		case <-c.stop:
+			return 0, c.ctx.Err()
+		}
+	}
+}
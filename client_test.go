@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	s, err := newServer(serverConfig{Version: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, 100, WithHTTPClient(ts.Client()))
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	seen := map[UUID]bool{}
+	for i := 0; i < 1000; i++ {
+		u, err := c.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[u] || u.Version() != 7 {
+			t.Fatalf("UUID %d: %s, seen before: %v", i, u, seen[u])
+		}
+		seen[u] = true
+	}
+	// The first fetch fills the buffer, 150, and the rest top it up
+	// by 100 at a time.
+	if n := requests.Load(); n < 10 || n > 12 {
+		t.Errorf("%d requests for 1000 UUIDs in batches of 100", n)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	ts := newTestServer(t, serverConfig{})
+	// More than the server hands out at once.
+	c := NewClient(ts.URL, maxPerRequest+1, WithHTTPClient(ts.Client()))
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Next(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestClientEmpty(t *testing.T) {
+	var requests atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, 100, WithHTTPClient(ts.Client()))
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Next(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(c.Err(), errNoUUIDs) {
+		t.Errorf("got %v, last fetch %v", err, c.Err())
+	}
+	// Backing off 100ms, then 200ms, rather than asking again and
+	// again.
+	if n := requests.Load(); n > 3 {
+		t.Errorf("%d requests in 250ms for empty responses", n)
+	}
+}
+
+func TestClientCloseInFlight(t *testing.T) {
+	arrived := make(chan struct{})
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	c := NewClient(ts.URL, 100, WithHTTPClient(ts.Client()))
+	<-arrived
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the request in flight")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// comparison lines up bench results from several machines.  Raw ns/op
+// say more about the machines than the strategies, so each result is
+// also given relative to a baseline: one strategy's single goroutine
+// run on the same machine.
+type comparison struct {
+	Baseline string
+	Machines []string
+	// BaseNsPerOp is the baseline's ns/op on each machine.
+	BaseNsPerOp []float64
+	Rows        []comparisonRow
+}
+
+// comparisonRow is one strategy at one parallelism and GC setting,
+// across the machines.  A machine without that run has 0 for both.
+type comparisonRow struct {
+	Strategy    string
+	Parallelism int
+	Setting     gcSetting
+	NsPerOp     []float64
+	Relative    []float64
+}
+
+func (r comparisonRow) label() string {
+	s := fmt.Sprintf("%s p%d", r.Strategy, r.Parallelism)
+	if r.Setting != (gcSetting{}) {
+		s += " " + r.Setting.String()
+	}
+	return s
+}
+
+// machineLabel names the machine a report came from by its tags, or
+// failing those its platform.
+func machineLabel(r *benchReport) string {
+	if len(r.Tags) > 0 {
+		return strings.Join(r.Tags, ",")
+	}
+	return fmt.Sprintf("%s/%s %dcpu", r.GOOS, r.GOARCH, r.NumCPU)
+}
+
+// compareReports lines up the results in reports, read from files,
+// relative to baseline's single goroutine run on each machine.
+func compareReports(files []string, reports []*benchReport, baseline string) (*comparison, error) {
+	c := &comparison{Baseline: baseline}
+	type key struct {
+		strategy    string
+		parallelism int
+		setting     gcSetting
+	}
+	rows := map[key]*comparisonRow{}
+	seen := map[string]bool{}
+	for i, report := range reports {
+		label := machineLabel(report)
+		if seen[label] {
+			label += " " + filepath.Base(files[i])
+		}
+		seen[label] = true
+		c.Machines = append(c.Machines, label)
+
+		base := 0.0
+		for _, r := range report.Results {
+			if r.Strategy == baseline && r.Parallelism == 1 && (base == 0 || r.Setting == (gcSetting{})) {
+				base = r.NsPerOp
+			}
+		}
+		if base <= 0 {
+			return nil, fmt.Errorf("%s: no single goroutine %s run to compare with", files[i], baseline)
+		}
+		c.BaseNsPerOp = append(c.BaseNsPerOp, base)
+
+		for _, r := range report.Results {
+			k := key{r.Strategy, r.Parallelism, r.Setting}
+			row := rows[k]
+			if row == nil {
+				row = &comparisonRow{
+					Strategy:    r.Strategy,
+					Parallelism: r.Parallelism,
+					Setting:     r.Setting,
+					NsPerOp:     make([]float64, len(reports)),
+					Relative:    make([]float64, len(reports)),
+				}
+				rows[k] = row
+			}
+			row.NsPerOp[i] = r.NsPerOp
+			row.Relative[i] = r.NsPerOp / base
+		}
+	}
+
+	for _, row := range rows {
+		c.Rows = append(c.Rows, *row)
+	}
+	sort.Slice(c.Rows, func(i, j int) bool {
+		a, b := c.Rows[i], c.Rows[j]
+		if a.Strategy != b.Strategy {
+			return a.Strategy < b.Strategy
+		}
+		if a.Parallelism != b.Parallelism {
+			return a.Parallelism < b.Parallelism
+		}
+		return a.Setting.String() < b.Setting.String()
+	})
+	return c, nil
+}
+
+// writeText writes c as a table of relative ns/op, a run on each row
+// and a machine in each column.
+func (c *comparison) writeText(w io.Writer) {
+	width := len("baseline ns/op")
+	for _, row := range c.Rows {
+		width = max(width, len(row.label()))
+	}
+	cols := make([]int, len(c.Machines))
+	for i, m := range c.Machines {
+		cols[i] = max(len(m), 8)
+	}
+
+	fmt.Fprintf(w, "ns/op relative to %s with 1 goroutine on the same machine\n\n", c.Baseline)
+	fmt.Fprintf(w, "%-*s", width, "")
+	for i, m := range c.Machines {
+		fmt.Fprintf(w, "  %*s", cols[i], m)
+	}
+	fmt.Fprintf(w, "\n%-*s", width, "baseline ns/op")
+	for i, ns := range c.BaseNsPerOp {
+		fmt.Fprintf(w, "  %*.1f", cols[i], ns)
+	}
+	fmt.Fprintln(w)
+	for _, row := range c.Rows {
+		fmt.Fprintf(w, "%-*s", width, row.label())
+		for i, rel := range row.Relative {
+			if rel == 0 {
+				fmt.Fprintf(w, "  %*s", cols[i], "-")
+				continue
+			}
+			fmt.Fprintf(w, "  %*.2fx", cols[i]-1, rel)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeCSV writes c with a line for each run on each machine.
+func (c *comparison) writeCSV(w io.Writer) {
+	fmt.Fprintln(w, "machine,strategy,goroutines,gogc,gomemlimit,ns_per_op,relative")
+	for _, row := range c.Rows {
+		for i, m := range c.Machines {
+			if row.NsPerOp[i] == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s,%s,%d,%s,%s,%.2f,%.4f\n", csvField(m), row.Strategy, row.Parallelism, row.Setting.GOGC, row.Setting.MemLimit, row.NsPerOp[i], row.Relative[i])
+		}
+	}
+}
+
+// csvField quotes s if it has a comma or quote in it.
+func csvField(s string) string {
+	if !strings.ContainsAny(s, ",\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// writeGnuplot writes a gnuplot script charting c as clustered bars,
+// a cluster for each run and a bar in it for each machine.
+func (c *comparison) writeGnuplot(w io.Writer) {
+	fmt.Fprintln(w, "# gnuplot -p this-file")
+	fmt.Fprintln(w, "$data << EOD")
+	fmt.Fprint(w, "run")
+	for _, m := range c.Machines {
+		fmt.Fprintf(w, " %q", m)
+	}
+	fmt.Fprintln(w)
+	for _, row := range c.Rows {
+		fmt.Fprintf(w, "%q", row.label())
+		for _, rel := range row.Relative {
+			if rel == 0 {
+				fmt.Fprint(w, " NaN")
+				continue
+			}
+			fmt.Fprintf(w, " %.4f", rel)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "EOD")
+	fmt.Fprintln(w, "set style data histograms")
+	fmt.Fprintln(w, "set style fill solid border -1")
+	fmt.Fprintln(w, "set xtics rotate by -45")
+	fmt.Fprintf(w, "set ylabel \"ns/op relative to %s p1\"\n", c.Baseline)
+	fmt.Fprintf(w, "plot for [i=2:%d] $data using i:xtic(1) title columnheader(i)\n", len(c.Machines)+1)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareReports(t *testing.T) {
+	desktop := &benchReport{Tags: []string{"desktop"}, Results: []benchResult{
+		{Strategy: "mutex", Parallelism: 1, NsPerOp: 100},
+		{Strategy: "channel", Parallelism: 4, NsPerOp: 250},
+	}}
+	pi := &benchReport{GOOS: "linux", GOARCH: "arm64", NumCPU: 4, Results: []benchResult{
+		{Strategy: "mutex", Parallelism: 1, NsPerOp: 400, Setting: gcSetting{GOGC: "off"}},
+		{Strategy: "mutex", Parallelism: 1, NsPerOp: 500},
+		{Strategy: "mutex", Parallelism: 4, NsPerOp: 1500},
+	}}
+	c, err := compareReports([]string{"a.json", "b.json"}, []*benchReport{desktop, pi}, "mutex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.Machines, "|"); got != "desktop|linux/arm64 4cpu" {
+		t.Errorf("machines %s", got)
+	}
+	// The default setting's run is the baseline.
+	if c.BaseNsPerOp[0] != 100 || c.BaseNsPerOp[1] != 500 {
+		t.Errorf("baselines %v", c.BaseNsPerOp)
+	}
+	var labels []string
+	for _, row := range c.Rows {
+		labels = append(labels, row.label())
+	}
+	if got := strings.Join(labels, "|"); got != "channel p4|mutex p1|mutex p1 GOGC=off GOMEMLIMIT=default|mutex p4" {
+		t.Errorf("rows %s", got)
+	}
+	if r := c.Rows[0].Relative; r[0] != 2.5 || r[1] != 0 {
+		t.Errorf("channel p4 relative %v", r)
+	}
+	if r := c.Rows[3].Relative; r[0] != 0 || r[1] != 3 {
+		t.Errorf("mutex p4 relative %v", r)
+	}
+
+	var out strings.Builder
+	c.writeText(&out)
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) < 6 || strings.Join(strings.Fields(lines[4]), " ") != "channel p4 2.50x -" {
+		t.Errorf("text:\n%s", out.String())
+	}
+	out.Reset()
+	c.writeCSV(&out)
+	if !strings.Contains(out.String(), "linux/arm64 4cpu,mutex,4,,,1500.00,3.0000\n") || strings.Count(out.String(), "\n") != 6 {
+		t.Errorf("csv:\n%s", out.String())
+	}
+
+	if _, err := compareReports([]string{"a.json", "c.json"}, []*benchReport{desktop, desktop}, "satori"); err == nil {
+		t.Error("compared without a baseline")
+	}
+	c, _ = compareReports([]string{"a.json", "c.json"}, []*benchReport{desktop, desktop}, "mutex")
+	if c.Machines[1] != "desktop c.json" {
+		t.Errorf("same machine twice labelled %q", c.Machines[1])
+	}
+}
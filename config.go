@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// duration is a time.Duration that is a string like "1.5s" in JSON,
+// rather than a count of nanoseconds.
+type duration time.Duration
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	*d = duration(v)
+	return err
+}
+
+// defaultServerConfig is what serve does with no config file, flags or
+// environment variables.
+func defaultServerConfig() serverConfig {
+	return serverConfig{
+		Addr:          "localhost:8080",
+		Version:       1,
+		Strategy:      "mutex",
+		ChanSize:      10,
+		MaxBatch:      maxPerRequest,
+		CheckExpected: 10_000_000,
+		MaxClockBack:  duration(time.Second),
+		DrainTimeout:  duration(10 * time.Second),
+		LogFormat:     "text",
+		LogLevel:      "info",
+	}
+}
+
+// loadServerConfig reads a JSON config file over cfg.  Fields the file
+// doesn't mention are left alone, and ones cfg doesn't have are an
+// error, to catch typos.
+func loadServerConfig(name string, cfg *serverConfig) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(cfg); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// serveFlags returns the serve flags, set to change cfg, with what is
+// in cfg already as their defaults.
+func serveFlags(cfg *serverConfig, configFile *string, printConfig *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(configFile, "config", "", "read settings from this JSON file; environment variables and flags override it")
+	fs.BoolVar(printConfig, "print-config", false, "print the settings as a JSON config file and exit")
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on, or unix:path for a unix socket")
+	fs.DurationVar((*time.Duration)(&cfg.DrainTimeout), "drain-timeout", time.Duration(cfg.DrainTimeout), "how long to let requests finish when stopping")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log format: "+strings.Join(logFormats, " or "))
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info, warn or error")
+	fs.IntVar(&cfg.Version, "version", cfg.Version, "UUID version: 1, 4, 6, 7, or 8 for V1s tagged with their provenance")
+	fs.StringVar(&cfg.Strategy, "strategy", cfg.Strategy, "V1 strategy: "+strings.Join(sortedKeys(experiments), ", "))
+	fs.IntVar(&cfg.ChanSize, "chansize", cfg.ChanSize, "channel size for the channel strategy")
+	fs.StringVar(&cfg.Node, "node", cfg.Node, "V1 and V6 node ID: a MAC address, \"random\", or empty for this machine's")
+	fs.IntVar(&cfg.NodeOrdinal, "node-ordinal", cfg.NodeOrdinal, "the instance number, 0 to 255, version 8 UUIDs are tagged with")
+	fs.StringVar(&cfg.Experiment, "experiment", cfg.Experiment, "serve V1 UUIDs with the strategy, chansize and node in this bench experiment JSON file")
+	fs.IntVar(&cfg.MaxBatch, "max-batch", cfg.MaxBatch, "most UUIDs one /uuid request may ask for")
+	fs.BoolVar(&cfg.DisableStats, "no-stats", cfg.DisableStats, "don't serve /stats")
+	fs.IntVar(&cfg.CheckRecent, "check-recent", cfg.CheckRecent, "serve /check, remembering this many recent IDs exactly, 0 for no /check")
+	fs.IntVar(&cfg.CheckExpected, "check-expected", cfg.CheckExpected, "IDs /check remembers probably, at about 3.6 bytes each")
+	fs.Float64Var(&cfg.ClientRate, "client-rate", cfg.ClientRate, "UUIDs a second each client may have, by API key or else IP, 0 for no limit")
+	fs.Float64Var(&cfg.ClientBurst, "client-burst", cfg.ClientBurst, "UUIDs a client may save up, 0 for a second's worth")
+	fs.Float64Var(&cfg.GlobalRate, "global-rate", cfg.GlobalRate, "UUIDs a second for all clients together, 0 for no limit")
+	fs.Float64Var(&cfg.GlobalBurst, "global-burst", cfg.GlobalBurst, "UUIDs all clients may save up, 0 for a second's worth")
+	fs.StringVar(&cfg.ClockFile, "clock-file", cfg.ClockFile, "keep the latest time seen in this file, so /readyz notices the clock going back across restarts")
+	fs.StringVar(&cfg.StateDir, "state-dir", cfg.StateDir, "keep the generators' clock sequences, /check's IDs and the clock file in this directory, across restarts")
+	fs.StringVar(&cfg.HALease, "ha-lease", cfg.HALease, "share this lease with other servers, only handing out UUIDs while holding it: file:path or etcd:url/key; needs a shared -state-dir")
+	fs.DurationVar((*time.Duration)(&cfg.EntropyTimeout), "entropy-timeout", time.Duration(cfg.EntropyTimeout), "bound reads of crypto/rand to this long, falling back to a CSPRNG seeded at startup; 0 for no bound")
+	fs.DurationVar((*time.Duration)(&cfg.MaxClockBack), "max-clock-back", time.Duration(cfg.MaxClockBack), "how far the clock may go back before /readyz fails")
+	fs.StringVar(&cfg.AuditLog, "audit-log", cfg.AuditLog, "append how many UUIDs were issued each minute, and their times, to this file")
+	fs.Int64Var(&cfg.AuditMaxSize, "audit-max-size", cfg.AuditMaxSize, "rotate and gzip the audit log at this many bytes, 0 for 64MB")
+	fs.IntVar(&cfg.AuditKeep, "audit-keep", cfg.AuditKeep, "how many rotated audit logs to keep, 0 for all")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "serve HTTPS with this PEM certificate")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "PEM private key for -tls-cert")
+	fs.StringVar(&cfg.ClientCA, "client-ca", cfg.ClientCA, "require client certificates signed by a CA in this PEM file")
+	fs.StringVar(&cfg.APIKeysFile, "api-keys", cfg.APIKeysFile, "require a key from this file, of \"client key\" lines, as a bearer token or X-API-Key")
+	fs.StringVar(&cfg.TenantsFile, "tenants", cfg.TenantsFile, "give the clients in this JSON file node IDs, clock sequences and namespaces of their own, under /tenant/")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "serve net/http/pprof under /debug/pprof/; needs -api-keys unless -addr is local")
+	fs.IntVar(&cfg.MutexProfileFraction, "mutex-profile-fraction", cfg.MutexProfileFraction, "profile 1 in this many mutex contention events, 0 for none")
+	fs.IntVar(&cfg.BlockProfileRate, "block-profile-rate", cfg.BlockProfileRate, "profile a blocking event every this many nanoseconds blocked, 0 for none")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen serve [flags]")
+		fmt.Fprintln(fs.Output(), "Serves UUIDs over HTTP: GET /uuid?n=10 returns 10, one per line.")
+		fmt.Fprintln(fs.Output(), "Each flag can also be set with an environment variable, such as")
+		fmt.Fprintln(fs.Output(), "UUIDGEN_SERVE_CLIENT_RATE for -client-rate.")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// envName is the environment variable for a serve flag.
+func envName(flagName string) string {
+	return "UUIDGEN_SERVE_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// setFromEnv sets each of fs's flags that has an environment variable
+// set, as given by getenv.
+func setFromEnv(fs *flag.FlagSet, getenv func(string) string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if v := getenv(envName(f.Name)); v != "" && err == nil {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("%s: %v", envName(f.Name), serr)
+			}
+		}
+	})
+	return err
+}
+
+// parseServeArgs works out serve's config from, in increasing order of
+// precedence, the defaults, a config file, environment variables and
+// flags.
+func parseServeArgs(args []string, getenv func(string) string) (cfg serverConfig, printConfig bool, err error) {
+	// Once to find the config file, and again to put the environment
+	// and flags over it.
+	var configFile string
+	cfg = defaultServerConfig()
+	fs := serveFlags(&cfg, &configFile, &printConfig)
+	if err := setFromEnv(fs, getenv); err != nil {
+		return cfg, false, err
+	}
+	fs.Parse(args)
+	if configFile == "" {
+		if err := cfg.applyExperiment(); err != nil {
+			return cfg, false, err
+		}
+		return cfg, printConfig, cfg.validate()
+	}
+
+	cfg = defaultServerConfig()
+	if err := loadServerConfig(configFile, &cfg); err != nil {
+		return cfg, false, err
+	}
+	fs = serveFlags(&cfg, &configFile, &printConfig)
+	if err := setFromEnv(fs, getenv); err != nil {
+		return cfg, false, err
+	}
+	fs.Parse(args)
+	if err := cfg.applyExperiment(); err != nil {
+		return cfg, false, err
+	}
+	return cfg, printConfig, cfg.validate()
+}
+
+// applyExperiment puts the strategy, chan size and node of cfg's
+// Experiment file, if it has one, over cfg.
+func (cfg *serverConfig) applyExperiment() error {
+	if cfg.Experiment == "" {
+		return nil
+	}
+	e, err := loadExperimentConfig(cfg.Experiment)
+	if err != nil {
+		return err
+	}
+	cfg.Version, cfg.Strategy, cfg.ChanSize = 1, e.Strategy, e.ChanSize
+	if e.Node != "" {
+		cfg.Node = e.Node
+	}
+	return nil
+}
+
+// validate checks cfg for settings that make no sense.  Files are only
+// checked when they are opened.
+func (cfg serverConfig) validate() error {
+	var errs []error
+	// Not newGenerator, which would start the channel strategy's
+	// goroutine.
+	if _, ok := versionGenerators[cfg.Version]; !ok && cfg.Version != 1 && cfg.Version != 8 {
+		errs = append(errs, fmt.Errorf("unsupported version %d", cfg.Version))
+	}
+	if _, ok := experiments[cfg.Strategy]; !ok && (cfg.Version == 1 || cfg.Version == 8) {
+		errs = append(errs, fmt.Errorf("unknown strategy %q", cfg.Strategy))
+	}
+	if cfg.NodeOrdinal < 0 || cfg.NodeOrdinal > 255 {
+		errs = append(errs, errors.New("node_ordinal must be from 0 to 255"))
+	}
+	if _, err := newLogger(io.Discard, cfg.LogFormat, false); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseNode(cfg.Node); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.ChanSize < 0 {
+		errs = append(errs, errors.New("chansize must not be negative"))
+	}
+	if cfg.MaxBatch < 1 {
+		errs = append(errs, errors.New("max_batch must be positive"))
+	}
+	if cfg.CheckRecent < 0 || cfg.CheckExpected < 1 {
+		errs = append(errs, errors.New("check_recent must not be negative, and check_expected must be positive"))
+	}
+	if cfg.ClientRate < 0 || cfg.ClientBurst < 0 || cfg.GlobalRate < 0 || cfg.GlobalBurst < 0 {
+		errs = append(errs, errors.New("rates and bursts must not be negative"))
+	}
+	if cfg.DrainTimeout < 0 {
+		errs = append(errs, errors.New("drain_timeout must not be negative"))
+	}
+	if cfg.AuditMaxSize < 0 || cfg.AuditKeep < 0 {
+		errs = append(errs, errors.New("audit_max_size and audit_keep must not be negative"))
+	}
+	if cfg.MaxClockBack < 0 {
+		errs = append(errs, errors.New("max_clock_back must not be negative"))
+	}
+	if cfg.EntropyTimeout < 0 {
+		errs = append(errs, errors.New("entropy_timeout must not be negative"))
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		errs = append(errs, errors.New("tls_cert and tls_key go together"))
+	}
+	if cfg.ClientCA != "" && cfg.TLSCert == "" {
+		errs = append(errs, errors.New("client_ca needs tls_cert and tls_key"))
+	}
+	if cfg.TenantsFile != "" && cfg.APIKeysFile == "" {
+		errs = append(errs, errors.New("tenants needs api_keys, to tell tenants apart"))
+	}
+	if cfg.HALease != "" && cfg.StateDir == "" {
+		errs = append(errs, errors.New("ha_lease needs a state_dir the servers share"))
+	}
+	if cfg.Debug && cfg.APIKeysFile == "" && !isLocalAddr(cfg.Addr) {
+		errs = append(errs, errors.New("debug needs api_keys, or a local addr"))
+	}
+	if cfg.MutexProfileFraction < 0 || cfg.BlockProfileRate < 0 {
+		errs = append(errs, errors.New("profile rates must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// parseNode returns the node ID a -node setting asks for, nil for this
+// machine's own.
+func parseNode(s string) (*[6]byte, error) {
+	switch s {
+	case "":
+		return nil, nil
+	case "random":
+		var node [6]byte
+		safeRandom(node[:])
+		// The multicast bit marks it as not a real MAC address, as RFC
+		// 4122 recommends.
+		node[0] |= 0x01
+		return &node, nil
+	}
+	mac, err := net.ParseMAC(s)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("node %q is not \"random\" or a 6 byte MAC address", s)
+	}
+	return (*[6]byte)(mac), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServeArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "serve.json")
+	os.WriteFile(file, []byte(`{"version": 7, "client_rate": 10, "global_rate": 100, "max_clock_back": "250ms"}`), 0o644)
+	env := map[string]string{
+		"UUIDGEN_SERVE_CONFIG":      file,
+		"UUIDGEN_SERVE_CLIENT_RATE": "20",
+		"UUIDGEN_SERVE_GLOBAL_RATE": "200",
+	}
+
+	cfg, printConfig, err := parseServeArgs([]string{"-global-rate", "300", "-print-config"}, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultServerConfig()
+	want.Version = 7                                     // from the file
+	want.MaxClockBack = duration(250 * time.Millisecond) // from the file
+	want.ClientRate = 20                                 // from the environment
+	want.GlobalRate = 300                                // from a flag
+	if cfg != want || !printConfig {
+		t.Errorf("got %+v, %v\nwant %+v, true", cfg, printConfig, want)
+	}
+
+	// What -print-config prints reads back the same.
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(file, b, 0o644)
+	again := serverConfig{}
+	if err := loadServerConfig(file, &again); err != nil || again != cfg {
+		t.Errorf("round trip: got %+v, %v", again, err)
+	}
+
+	os.WriteFile(file, []byte(`{"verison": 7}`), 0o644)
+	if err := loadServerConfig(file, &again); err == nil || !strings.Contains(err.Error(), "verison") {
+		t.Errorf("misspelt field: %v", err)
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	for _, test := range []struct {
+		change func(*serverConfig)
+		want   string
+	}{
+		{func(c *serverConfig) {}, ""},
+		{func(c *serverConfig) { c.Node = "random" }, ""},
+		{func(c *serverConfig) { c.Node = "02:00:00:00:00:01" }, ""},
+		{func(c *serverConfig) { c.Node = "02:00:00:00:00:00:00:01" }, "node"},
+		{func(c *serverConfig) { c.Version = 2 }, "unsupported version"},
+		{func(c *serverConfig) { c.Strategy = "bogus" }, "unknown strategy"},
+		{func(c *serverConfig) { c.Version, c.Strategy = 7, "bogus" }, ""},
+		{func(c *serverConfig) { c.MaxBatch = 0 }, "max_batch"},
+		{func(c *serverConfig) { c.LogFormat = "xml" }, "log format"},
+		{func(c *serverConfig) { c.LogLevel = "loud" }, "level"},
+		{func(c *serverConfig) { c.ClientBurst = -1 }, "negative"},
+		{func(c *serverConfig) { c.TLSCert = "cert.pem" }, "go together"},
+		{func(c *serverConfig) { c.ClientCA = "ca.pem" }, "client_ca"},
+		{func(c *serverConfig) { c.Debug = true }, ""},
+		{func(c *serverConfig) { c.Debug, c.Addr = true, ":8080" }, "debug"},
+		{func(c *serverConfig) { c.Debug, c.Addr, c.APIKeysFile = true, ":8080", "keys" }, ""},
+	} {
+		cfg := defaultServerConfig()
+		test.change(&cfg)
+		err := cfg.validate()
+		if test.want == "" && err != nil || test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)) {
+			t.Errorf("%+v: got %v, want %q", cfg, err, test.want)
+		}
+	}
+}
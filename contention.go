@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// blockCauses name what a stack was blocked in, by the innermost frame
+// that contains one of their matches.
+var blockCauses = []struct{ match, cause string }{
+	{"runtime.chanrecv", "chan receive"},
+	{"runtime.chansend", "chan send"},
+	{"runtime.selectgo", "select"},
+	{"(*RWMutex).", "RWMutex"},
+	{"(*Mutex).", "mutex"},
+	{"(*WaitGroup).Wait", "WaitGroup"},
+	{"(*Cond).Wait", "Cond"},
+	{"runtime._LostContendedRuntimeLock", "runtime lock"},
+	{"runtime.unlock", "runtime lock"},
+}
+
+// isLibraryFrame reports whether fn is in the runtime or sync, rather
+// than the code that called into them.
+func isLibraryFrame(fn string) bool {
+	for _, prefix := range []string{"runtime.", "runtime/", "sync.", "sync/", "internal/"} {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockCause returns what stack was blocked in, such as "chan
+// receive", and the outermost function of it that is not in the
+// runtime or sync, which is where it blocked from.
+func blockCause(stack []string) (cause, site string) {
+	for _, fn := range stack {
+		if cause == "" {
+			for _, bc := range blockCauses {
+				if strings.Contains(fn, bc.match) {
+					cause = bc.cause
+					break
+				}
+			}
+		}
+		if !isLibraryFrame(fn) {
+			site = fn
+			break
+		}
+	}
+	if cause == "" && len(stack) > 0 {
+		cause = stack[0]
+	}
+	return cause, site
+}
+
+// contentionShare is the part of the time in a contention profile
+// spent in one cause from one site.
+type contentionShare struct {
+	Cause    string
+	Site     string
+	Delay    time.Duration
+	Fraction float64
+}
+
+func (s contentionShare) String() string {
+	if s.Site == "" {
+		return fmt.Sprintf("%.0f%% %s", 100*s.Fraction, s.Cause)
+	}
+	return fmt.Sprintf("%.0f%% %s in %s", 100*s.Fraction, s.Cause, s.Site)
+}
+
+// contentionSummary is where the time in a mutex or block profile went.
+type contentionSummary struct {
+	Delay  time.Duration
+	Events int64
+	// Shares are largest first.
+	Shares []contentionShare
+}
+
+// summarizeContention sums up a mutex or block profile p by cause and
+// site.  The runtime keeps running totals of these profiles, so base,
+// if not nil, is an earlier profile to subtract.
+func summarizeContention(p, base *profileData) (contentionSummary, error) {
+	type totals struct{ delay, events int64 }
+	sum := func(p *profileData, sign int64, by map[string]*totals, stacks map[string][]string) error {
+		delay, events := p.valueIndex("delay"), p.valueIndex("contentions")
+		if delay < 0 || events < 0 {
+			return fmt.Errorf("not a contention profile: sample types %v", p.SampleTypes)
+		}
+		for _, s := range p.Samples {
+			if len(s.Values) <= delay || len(s.Values) <= events {
+				continue
+			}
+			key := strings.Join(s.Stack, "\n")
+			t := by[key]
+			if t == nil {
+				t = &totals{}
+				by[key], stacks[key] = t, s.Stack
+			}
+			t.delay += sign * s.Values[delay]
+			t.events += sign * s.Values[events]
+		}
+		return nil
+	}
+	byStack, stacks := map[string]*totals{}, map[string][]string{}
+	if err := sum(p, 1, byStack, stacks); err != nil {
+		return contentionSummary{}, err
+	}
+	if base != nil {
+		if err := sum(base, -1, byStack, stacks); err != nil {
+			return contentionSummary{}, err
+		}
+	}
+
+	var cs contentionSummary
+	byCause := map[[2]string]int64{}
+	for key, t := range byStack {
+		if t.delay <= 0 {
+			continue
+		}
+		cause, site := blockCause(stacks[key])
+		byCause[[2]string{cause, site}] += t.delay
+		cs.Delay += time.Duration(t.delay)
+		cs.Events += max(t.events, 0)
+	}
+	for k, d := range byCause {
+		cs.Shares = append(cs.Shares, contentionShare{
+			Cause:    k[0],
+			Site:     k[1],
+			Delay:    time.Duration(d),
+			Fraction: float64(d) / float64(cs.Delay),
+		})
+	}
+	sort.Slice(cs.Shares, func(i, j int) bool {
+		a, b := cs.Shares[i], cs.Shares[j]
+		if a.Delay != b.Delay {
+			return a.Delay > b.Delay
+		}
+		return a.Cause+a.Site < b.Cause+b.Site
+	})
+	return cs, nil
+}
+
+// narrative describes cs in a line, naming the top causes and lumping
+// the rest together.
+func (cs contentionSummary) narrative(top int) string {
+	if cs.Delay == 0 {
+		return "no contention"
+	}
+	var parts []string
+	rest := 1.0
+	for i, s := range cs.Shares {
+		if i == top {
+			if rest >= 0.005 {
+				parts = append(parts, fmt.Sprintf("%.0f%% elsewhere", 100*rest))
+			}
+			break
+		}
+		parts = append(parts, s.String())
+		rest -= s.Fraction
+	}
+	return fmt.Sprintf("%s over %d events: %s", cs.Delay, cs.Events, strings.Join(parts, ", "))
+}
+
+// readContention summarizes the profile in file, less the one in
+// baseFile if that isn't empty.
+func readContention(file, baseFile string) (contentionSummary, error) {
+	read := func(name string) (*profileData, error) {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		p, err := readProfile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return p, nil
+	}
+	p, err := read(file)
+	if err != nil {
+		return contentionSummary{}, err
+	}
+	var base *profileData
+	if baseFile != "" {
+		if base, err = read(baseFile); err != nil {
+			return contentionSummary{}, err
+		}
+	}
+	return summarizeContention(p, base)
+}
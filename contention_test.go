@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockProfile returns the block profile as it stands.
+func blockProfile(t *testing.T) *profileData {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("block").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	p, err := readProfile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSummarizeContention(t *testing.T) {
+	defer setProfileRates(0, 0)
+	setProfileRates(0, 1)
+	base := blockProfile(t)
+
+	ch := make(chan int)
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(2 * time.Millisecond)
+			ch <- i
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		<-ch
+	}
+
+	cs, err := summarizeContention(blockProfile(t), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.Shares) == 0 {
+		t.Fatal("no contention")
+	}
+	top := cs.Shares[0]
+	if top.Cause != "chan receive" || !strings.HasSuffix(top.Site, ".TestSummarizeContention") || top.Fraction < 0.5 {
+		t.Errorf("top share %+v of %+v", top, cs)
+	}
+	if cs.Delay < 5*time.Millisecond || cs.Events < 5 {
+		t.Errorf("%s over %d events", cs.Delay, cs.Events)
+	}
+	if n := cs.narrative(1); !strings.Contains(n, "% chan receive in ") {
+		t.Errorf("narrative %q", n)
+	}
+
+	if _, err := summarizeContention(&profileData{SampleTypes: []string{"samples", "cpu"}}, nil); err == nil {
+		t.Error("CPU profile summarized")
+	}
+}
+
+func TestBlockCause(t *testing.T) {
+	for _, tc := range []struct {
+		stack       []string
+		cause, site string
+	}{
+		{[]string{"sync.(*Mutex).Unlock", "main.(*MutexGenerator).New", "main.benchRun.func1"}, "mutex", "main.(*MutexGenerator).New"},
+		{[]string{"internal/sync.(*Mutex).Lock", "sync.(*Mutex).Lock", "main.f"}, "mutex", "main.f"},
+		{[]string{"runtime.selectgo", "main.(*ChanneledGenerator).produceUUIDs"}, "select", "main.(*ChanneledGenerator).produceUUIDs"},
+		{[]string{"runtime.unlock", "runtime.park_m"}, "runtime lock", ""},
+		{[]string{"main.g"}, "main.g", "main.g"},
+	} {
+		cause, site := blockCause(tc.stack)
+		if cause != tc.cause || site != tc.site {
+			t.Errorf("%q: got %q in %q, want %q in %q", tc.stack, cause, site, tc.cause, tc.site)
+		}
+	}
+}
+
+func TestWriteContention(t *testing.T) {
+	defer setProfileRates(0, 0)
+	dir := t.TempDir()
+	p, err := newProfiler(filepath.Join(dir, "profiles"), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := newBenchReport(10)
+	for _, name := range []string{"channel", "mutex"} {
+		var r benchResult
+		bp, err := p.run(name, func() { r = benchRun(experiments[name].New(10), 4, 20*time.Millisecond) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Strategy, r.Profiles = name, bp
+		report.Results = append(report.Results, r)
+	}
+	b, _ := json.Marshal(report)
+	file := filepath.Join(dir, "results.json")
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := writeContention(&out, file, report, 2); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"channel with 4 goroutines\n  mutex ", "\n  block ", "mutex with 4 goroutines\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("no %q in:\n%s", want, out.String())
+		}
+	}
+
+	report.Results[0].Profiles, report.Results[1].Profiles = nil, nil
+	if err := writeContention(&out, file, report, 2); err == nil {
+		t.Error("report without profiles accepted")
+	}
+}
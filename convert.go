@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// convert rewrites each line of r from one format to another.  Lines
+// that don't decode are reported to errw and skipped, and the number
+// of them is returned.
+func convert(r io.Reader, w io.Writer, errw io.Writer, from, to format) (int, error) {
+	bad := 0
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		u, err := from.decode(line)
+		if err != nil {
+			fmt.Fprintf(errw, "line %d: %v\n", n, err)
+			bad++
+			continue
+		}
+		io.WriteString(w, to.encode(u))
+		io.WriteString(w, "\n")
+	}
+	return bad, s.Err()
+}
+
+func runConvert(args []string) error {
+	names := strings.Join(sortedKeys(formats), ", ")
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fromName := fs.String("from", "canonical", "input format: "+names)
+	toName := fs.String("to", "canonical", "output format: "+names)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen convert -from format -to format < in > out")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	from, ok := formats[*fromName]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *fromName)
+	}
+	to, ok := formats[*toName]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *toName)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	bad, err := convert(os.Stdin, w, os.Stderr, from, to)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d lines could not be converted", bad)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// Measuring CPU time is only implemented for unix so far.
+
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/sha3"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A CUID2 is a string ID from https://github.com/paralleldrive/cuid2:
+// a random lower case letter, then base36 digits of a SHA-3 hash of
+// the time, a counter, random salt and a fingerprint of the host.
+// Hashing hides all of those, so unlike most of the IDs here, CUID2s
+// don't sort by time and say nothing about where they came from.
+type CUID2 string
+
+const (
+	// DefaultCUID2Length is the length of cuid2's IDs unless told
+	// otherwise.
+	DefaultCUID2Length = 24
+	minCUID2Length     = 2
+	maxCUID2Length     = 32
+	// cuid2BigLength is the length of a host fingerprint.
+	cuid2BigLength = 32
+)
+
+// ParseCUID2 checks that s looks like a CUID2: 2 to 32 characters, a
+// lower case letter followed by lower case letters and digits.  A
+// hash has no structure to check beyond that.
+func ParseCUID2(s string) (CUID2, error) {
+	if len(s) < minCUID2Length || len(s) > maxCUID2Length {
+		return "", fmt.Errorf("invalid CUID2 %q: %d characters, want %d to %d", s, len(s), minCUID2Length, maxCUID2Length)
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || i > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+		return "", fmt.Errorf("invalid CUID2 %q: bad character %q", s, c)
+	}
+	return CUID2(s), nil
+}
+
+func (id CUID2) String() string {
+	return string(id)
+}
+
+// Bytes returns id's characters.  CUID2s of the same length sort the
+// same way as strings or bytes, though the order means nothing.
+func (id CUID2) Bytes() []byte {
+	return []byte(id)
+}
+
+// Time returns ok false: a CUID2's time is hashed away.
+func (id CUID2) Time() (t time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+// CUID2Generator makes CUID2s of one length.  It is safe for
+// concurrent use.
+type CUID2Generator struct {
+	length      int
+	counter     atomic.Uint64
+	fingerprint string
+}
+
+// NewCUID2Generator returns a generator of CUID2s length characters
+// long, between 2 and 32.  DefaultCUID2Length suits most uses; shorter
+// ones are likelier to collide.
+func NewCUID2Generator(length int) (*CUID2Generator, error) {
+	if length < minCUID2Length || length > maxCUID2Length {
+		return nil, fmt.Errorf("CUID2 length %d is not between %d and %d", length, minCUID2Length, maxCUID2Length)
+	}
+	g := &CUID2Generator{length: length}
+	// cuid2 starts its counter at a random number below this.
+	g.counter.Store(cuid2Random(476782367))
+
+	// cuid2 fingerprints its host from the names of the JavaScript
+	// globals; the host name and process ID are the nearest thing.
+	host, _ := os.Hostname()
+	g.fingerprint = cuid2Hash(host + strconv.Itoa(os.Getpid()) + cuid2Entropy(cuid2BigLength))[:cuid2BigLength]
+	return g, nil
+}
+
+// Next returns a new CUID2.
+func (g *CUID2Generator) Next() CUID2 {
+	first := byte('a' + cuid2Random(26))
+	input := strconv.FormatInt(time.Now().UnixMilli(), 36) +
+		cuid2Entropy(g.length) +
+		strconv.FormatUint(g.counter.Add(1)-1, 36) +
+		g.fingerprint
+	return CUID2(string(first) + cuid2Hash(input)[1:g.length])
+}
+
+// cuid2Hash returns the SHA3-512 of s as a base36 number, dropping the
+// first digit, which is biased.
+func cuid2Hash(s string) string {
+	sum := sha3.Sum512([]byte(s))
+	return new(big.Int).SetBytes(sum[:]).Text(36)[1:]
+}
+
+// cuid2Entropy returns n random base36 digits.
+func cuid2Entropy(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = strconv.FormatUint(cuid2Random(36), 36)[0]
+	}
+	return string(b)
+}
+
+// cuid2Random returns a random number below n.
+func cuid2Random(n uint64) uint64 {
+	var buf [8]byte
+	for {
+		safeRandom(buf[:])
+		v := binary.BigEndian.Uint64(buf[:])
+		// Reject the top of the range that would make some numbers
+		// likelier than others.
+		if v < ^uint64(0)-^uint64(0)%n {
+			return v % n
+		}
+	}
+}
+
+// defaultCUID2s backs the registered "cuid2" family.
+var defaultCUID2s, _ = NewCUID2Generator(DefaultCUID2Length)
+
+func init() {
+	RegisterFamily("cuid2", Family{
+		New: func() ID { return defaultCUID2s.Next() },
+		Parse: func(s string) (ID, error) {
+			return ParseCUID2(s)
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCUID2Generator(t *testing.T) {
+	for _, length := range []int{2, 10, DefaultCUID2Length, 32} {
+		g, err := NewCUID2Generator(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[CUID2]bool{}
+		for i := 0; i < 100; i++ {
+			id := g.Next()
+			if len(id) != length {
+				t.Fatalf("%q is not %d characters", id, length)
+			}
+			if _, err := ParseCUID2(id.String()); err != nil {
+				t.Fatal(err)
+			}
+			if length >= 10 && seen[id] {
+				t.Fatalf("%q twice", id)
+			}
+			seen[id] = true
+		}
+	}
+	for _, length := range []int{1, 33} {
+		if _, err := NewCUID2Generator(length); err == nil {
+			t.Errorf("length %d accepted", length)
+		}
+	}
+}
+
+func TestParseCUID2(t *testing.T) {
+	// One of cuid2's own.
+	if _, err := ParseCUID2("tz4a98xxat96iws9zmbrgj3a"); err != nil {
+		t.Error(err)
+	}
+	for _, s := range []string{"", "a", "1abc", "aBc", "ab-c", "a" + strings.Repeat("b", 32)} {
+		if _, err := ParseCUID2(s); err == nil {
+			t.Errorf("ParseCUID2(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// UUIDArray is a Postgres uuid[], for passing a batch of UUIDs as one
+// parameter, as in
+//
+//	INSERT INTO orders (id) SELECT unnest($1::uuid[])
+//
+// It does what pq.Array does for a []UUID, without needing pq: Value
+// writes the array's text form, which pgx's database/sql driver takes
+// too, and Scan reads it back.
+type UUIDArray []UUID
+
+// Value implements driver.Valuer.
+func (a UUIDArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b := make([]byte, 0, 2+37*len(a))
+	b = append(b, '{')
+	for i, u := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, u.String()...)
+	}
+	return string(append(b, '}')), nil
+}
+
+// Scan implements sql.Scanner.  A NULL array scans as nil; NULL
+// elements are an error, having no UUID to be.
+func (a *UUIDArray) Scan(src any) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("cannot scan %T into a UUIDArray", src)
+	}
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return fmt.Errorf("invalid uuid[] %q", s)
+	}
+	s = s[1 : len(s)-1]
+	arr := UUIDArray{}
+	if s != "" {
+		for _, e := range strings.Split(s, ",") {
+			u, err := Parse(e)
+			if err != nil {
+				return fmt.Errorf("invalid uuid[] element: %v", err)
+			}
+			arr = append(arr, u)
+		}
+	}
+	*a = arr
+	return nil
+}
+
+// UUIDRows streams rows of a new UUID and the caller's own columns,
+// for seeding a table with pgx's CopyFrom:
+//
+//	conn.CopyFrom(ctx, pgx.Identifier{"orders"},
+//		[]string{"id", "customer", "total"},
+//		NewUUIDRows(g, 1000000, func(i int) ([]any, error) {
+//			return []any{customers[i%len(customers)], 100}, nil
+//		}))
+//
+// It implements pgx.CopyFromSource.  The UUID goes first, as a
+// [16]byte, which pgx encodes in binary without parsing.  Rows reuses
+// its slice between rows, as CopyFrom allows.
+type UUIDRows struct {
+	gen  Generator
+	n    int
+	row  func(i int) ([]any, error)
+	i    int
+	vals []any
+	err  error
+}
+
+// NewUUIDRows returns n rows, each with an ID from gen followed by what
+// row returns for it.  row may be nil, for a table of only IDs.
+func NewUUIDRows(gen Generator, n int, row func(i int) ([]any, error)) *UUIDRows {
+	return &UUIDRows{gen: gen, n: n, row: row, i: -1}
+}
+
+// Next moves to the next row, returning false at the end or after an
+// error from row.
+func (r *UUIDRows) Next() bool {
+	if r.err != nil || r.i+1 >= r.n {
+		return false
+	}
+	r.i++
+	r.vals = append(r.vals[:0], [16]byte(r.gen.New()))
+	if r.row != nil {
+		cols, err := r.row(r.i)
+		if err != nil {
+			r.err = fmt.Errorf("row %d: %w", r.i, err)
+			return false
+		}
+		r.vals = append(r.vals, cols...)
+	}
+	return true
+}
+
+// Values returns the current row.
+func (r *UUIDRows) Values() ([]any, error) {
+	return r.vals, r.err
+}
+
+// Err returns the error from row that stopped Next, if any.
+func (r *UUIDRows) Err() error {
+	return r.err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUUIDArray(t *testing.T) {
+	a := UUIDArray{NewV4(), NewV7()}
+	v, err := a.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{" + a[0].String() + "," + a[1].String() + "}"; v != want {
+		t.Errorf("Value %q, want %q", v, want)
+	}
+	var back UUIDArray
+	if err := back.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 2 || back[0] != a[0] || back[1] != a[1] {
+		t.Errorf("scanned %v, want %v", back, a)
+	}
+
+	for src, want := range map[string]int{"{}": 0, "{" + a[0].String() + "}": 1} {
+		if err := back.Scan(src); err != nil || len(back) != want {
+			t.Errorf("Scan(%q) gave %v, %v", src, back, err)
+		}
+	}
+	if err := back.Scan(nil); err != nil || back != nil {
+		t.Errorf("Scan(nil) gave %v, %v", back, err)
+	}
+	for _, src := range []any{"", "{NULL}", "{nope}", 7} {
+		if err := back.Scan(src); err == nil {
+			t.Errorf("Scan(%v) should fail", src)
+		}
+	}
+}
+
+func TestUUIDRows(t *testing.T) {
+	rows := NewUUIDRows(GeneratorFunc(NewV7), 3, func(i int) ([]any, error) {
+		return []any{i, "x"}, nil
+	})
+	seen := map[[16]byte]bool{}
+	n := 0
+	for rows.Next() {
+		vals, err := rows.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := vals[0].([16]byte)
+		if len(vals) != 3 || vals[1] != n || seen[id] {
+			t.Errorf("row %d: %v", n, vals)
+		}
+		seen[id] = true
+		n++
+	}
+	if n != 3 || rows.Err() != nil {
+		t.Errorf("%d rows, error %v", n, rows.Err())
+	}
+
+	boom := errors.New("boom")
+	rows = NewUUIDRows(GeneratorFunc(NewV4), 10, func(i int) ([]any, error) {
+		if i == 2 {
+			return nil, boom
+		}
+		return nil, nil
+	})
+	n = 0
+	for rows.Next() {
+		n++
+	}
+	if n != 2 || !errors.Is(rows.Err(), boom) {
+		t.Errorf("%d rows, error %v", n, rows.Err())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// maxCaptureSeconds caps the seconds of a CPU profile or execution
+// trace, so a forgotten capture doesn't run for ever.
+const maxCaptureSeconds = 60
+
+// handleDebug adds net/http/pprof, including its runtime/trace capture
+// at /debug/pprof/trace?seconds=5, to mux.  Captures are time boxed,
+// and only one runs at a time, since runtime/trace only allows one
+// anyway and they slow the server down.
+func handleDebug(mux *http.ServeMux) {
+	var capturing atomic.Bool
+	capture := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if v := r.FormValue("seconds"); v != "" {
+				if secs, err := strconv.Atoi(v); err != nil || secs < 1 || secs > maxCaptureSeconds {
+					http.Error(w, fmt.Sprintf("seconds must be between 1 and %d", maxCaptureSeconds), http.StatusBadRequest)
+					return
+				}
+			}
+			if !capturing.CompareAndSwap(false, true) {
+				http.Error(w, "another capture is running", http.StatusConflict)
+				return
+			}
+			defer capturing.Store(false)
+			h(w, r)
+		}
+	}
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/profile", capture(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/trace", capture(pprof.Trace))
+}
+
+// setProfileRates turns on mutex and block profiling, which are off by
+// default, since they cost something even when nobody is looking.
+// Zeros leave them off.
+func setProfileRates(mutexFraction, blockRate int) {
+	runtime.SetMutexProfileFraction(mutexFraction)
+	runtime.SetBlockProfileRate(blockRate)
+}
+
+// isLocalAddr reports whether addr only accepts connections from this
+// machine: a loopback address or a unix socket.
+func isLocalAddr(addr string) bool {
+	if strings.HasPrefix(addr, "unix:") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeDebug(t *testing.T) {
+	ts := newTestServer(t, serverConfig{})
+	get(t, ts, "/debug/pprof/", http.StatusNotFound)
+
+	ts = newTestServer(t, serverConfig{Debug: true})
+	if body := get(t, ts, "/debug/pprof/", http.StatusOK); !strings.Contains(body, "goroutine") {
+		t.Errorf("index: %.100s", body)
+	}
+	get(t, ts, "/debug/pprof/trace?seconds=0", http.StatusBadRequest)
+	get(t, ts, "/debug/pprof/profile?seconds=61", http.StatusBadRequest)
+
+	done := make(chan string)
+	go func() {
+		resp, err := ts.Client().Get(ts.URL + "/debug/pprof/trace?seconds=1")
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		var buf [16]byte
+		n, _ := resp.Body.Read(buf[:])
+		done <- string(buf[:n])
+	}()
+	time.Sleep(200 * time.Millisecond)
+	get(t, ts, "/debug/pprof/profile?seconds=1", http.StatusConflict)
+	if head := <-done; !strings.HasPrefix(head, "go 1.") {
+		t.Errorf("trace starts %q", head)
+	}
+}
+
+func TestIsLocalAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"localhost:8080":    true,
+		"127.0.0.1:8080":    true,
+		"[::1]:8080":        true,
+		"unix:/tmp/uuidgen": true,
+		":8080":             false,
+		"0.0.0.0:8080":      false,
+		"10.0.0.1:8080":     false,
+		"example.com:8080":  false,
+	} {
+		if got := isLocalAddr(addr); got != want {
+			t.Errorf("%s: got %v", addr, got)
+		}
+	}
+}
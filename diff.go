@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// uuidSource yields UUIDs in ascending byte order with no duplicates.
+type uuidSource interface {
+	// next returns false at the end.
+	next() (UUID, bool, error)
+}
+
+// sliceSource serves UUIDs from memory.
+type sliceSource struct {
+	ids []UUID
+}
+
+func (s *sliceSource) next() (UUID, bool, error) {
+	if len(s.ids) == 0 {
+		return UUID{}, false, nil
+	}
+	u := s.ids[0]
+	s.ids = s.ids[1:]
+	return u, true, nil
+}
+
+// newSortedSlice sorts ids and drops duplicates.
+func newSortedSlice(ids []UUID) *sliceSource {
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+	return &sliceSource{dedupe(ids)}
+}
+
+// streamSource reads UUIDs that are already sorted, so that inputs of
+// any size can be handled.  It fails if they turn out not to be
+// sorted, and skips duplicates.
+type streamSource struct {
+	name    string
+	d       *Decoder
+	prev    UUID
+	started bool
+}
+
+func (s *streamSource) next() (UUID, bool, error) {
+	for {
+		u, err := s.d.Decode()
+		if err == io.EOF {
+			return UUID{}, false, nil
+		}
+		if err != nil {
+			return UUID{}, false, fmt.Errorf("%s: %v", s.name, err)
+		}
+		if s.started {
+			switch c := u.Compare(s.prev); {
+			case c < 0:
+				return UUID{}, false, fmt.Errorf("%s: %s is out of order", s.name, u)
+			case c == 0:
+				continue
+			}
+		}
+		s.prev, s.started = u, true
+		return u, true, nil
+	}
+}
+
+// Which inputs a UUID was found in.
+const (
+	onlyA = iota
+	onlyB
+	inBoth
+)
+
+// mergeDiff walks two sources in step, calling emit with each UUID and
+// which of them it was in.
+func mergeDiff(a, b uuidSource, emit func(which int, u UUID) error) error {
+	ua, okA, err := a.next()
+	if err != nil {
+		return err
+	}
+	ub, okB, err := b.next()
+	if err != nil {
+		return err
+	}
+	for okA || okB {
+		var which int
+		switch {
+		case !okB || okA && ua.Compare(ub) < 0:
+			which = onlyA
+		case !okA || ub.Compare(ua) < 0:
+			which = onlyB
+		default:
+			which = inBoth
+		}
+
+		if which == onlyB {
+			err = emit(which, ub)
+		} else {
+			err = emit(which, ua)
+		}
+		if err != nil {
+			return err
+		}
+
+		if which != onlyB {
+			if ua, okA, err = a.next(); err != nil {
+				return err
+			}
+		}
+		if which != onlyA {
+			if ub, okB, err = b.next(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// openSource opens a file of UUIDs.  Unless sorted is true, the whole
+// file is read into memory and sorted.  binary files must be sorted,
+// and are mapped into memory.
+func openSource(name string, sorted, binary bool) (uuidSource, io.Closer, error) {
+	if binary {
+		m, err := OpenMapped(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &mappedSource{m: m}, m, nil
+	}
+	if sorted {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &streamSource{name: name, d: NewDecoder(f)}, f, nil
+	}
+
+	var ids []UUID
+	err := forEachUUID([]string{name}, false, func(u UUID) error {
+		ids = append(ids, u)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return newSortedSlice(ids), nopCloser{}, nil
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	op := fs.String("op", "diff", "diff, union, intersect or subtract (a minus b)")
+	sorted := fs.Bool("sorted", false, "inputs are already sorted in byte order (uuidgen sort), so stream them instead of loading them")
+	binary := fs.Bool("binary", false, "inputs are sorted raw 16 byte UUIDs (uuidgen sort -binary), to be mapped into memory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen diff [flags] a.txt b.txt")
+		fmt.Fprintln(fs.Output(), "Compares two lists of UUIDs as sets.  With -op diff, prints '< id' for IDs only in a")
+		fmt.Fprintln(fs.Output(), "and '> id' for IDs only in b, and exits non-zero if there are any.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var keep [3]bool
+	var prefix [3]string
+	switch *op {
+	case "diff":
+		keep = [3]bool{onlyA: true, onlyB: true}
+		prefix = [3]string{onlyA: "< ", onlyB: "> "}
+	case "union":
+		keep = [3]bool{true, true, true}
+	case "intersect":
+		keep = [3]bool{inBoth: true}
+	case "subtract":
+		keep = [3]bool{onlyA: true}
+	default:
+		return fmt.Errorf("unknown -op %q", *op)
+	}
+
+	a, ca, err := openSource(fs.Arg(0), *sorted, *binary)
+	if err != nil {
+		return err
+	}
+	defer ca.Close()
+	b, cb, err := openSource(fs.Arg(1), *sorted, *binary)
+	if err != nil {
+		return err
+	}
+	defer cb.Close()
+
+	var counts [3]int
+	w := bufio.NewWriter(os.Stdout)
+	err = mergeDiff(a, b, func(which int, u UUID) error {
+		counts[which]++
+		if !keep[which] {
+			return nil
+		}
+		w.WriteString(prefix[which])
+		w.WriteString(u.String())
+		return w.WriteByte('\n')
+	})
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "only in %s: %d, only in %s: %d, in both: %d\n",
+		fs.Arg(0), counts[onlyA], fs.Arg(1), counts[onlyB], counts[inBoth])
+	if *op == "diff" && counts[onlyA]+counts[onlyB] > 0 {
+		return fmt.Errorf("%s and %s differ", fs.Arg(0), fs.Arg(1))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeDiff(t *testing.T) {
+	ids := make([]UUID, 5)
+	for i := range ids {
+		ids[i] = UUID{15: byte(i)}
+	}
+	a := newSortedSlice([]UUID{ids[3], ids[0], ids[1], ids[3]})
+	b := newSortedSlice([]UUID{ids[4], ids[1], ids[2]})
+
+	var got [3][]UUID
+	err := mergeDiff(a, b, func(which int, u UUID) error {
+		got[which] = append(got[which], u)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [3][]UUID{
+		onlyA:  {ids[0], ids[3]},
+		onlyB:  {ids[2], ids[4]},
+		inBoth: {ids[1]},
+	}
+	for which := range want {
+		if len(got[which]) != len(want[which]) {
+			t.Fatalf("%d: got %v, want %v", which, got[which], want[which])
+		}
+		for i := range want[which] {
+			if got[which][i] != want[which][i] {
+				t.Errorf("%d: got %v, want %v", which, got[which], want[which])
+			}
+		}
+	}
+}
+
+func TestStreamSourceUnsorted(t *testing.T) {
+	in := UUID{15: 2}.String() + "\n" + UUID{15: 1}.String() + "\n"
+	s := &streamSource{name: "in", d: NewDecoder(strings.NewReader(in))}
+	if _, _, err := s.next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.next(); err == nil {
+		t.Errorf("out of order input accepted")
+	}
+}
@@ -0,0 +1,153 @@
+/**
+
+Differential testing
+
+These feed every V1 strategy the same clock readings, starting clock
+sequence and node, and check that they make the same UUIDs, so that a
+strategy's concurrency can't quietly change what it generates.
+
+AtomicGenerator is the exception.  It borrows the next tick when the
+clock hasn't moved on, where the others bump the clock sequence, so it
+only agrees with them while the clock keeps moving forward.  There is
+no sharded strategy to include yet.
+
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// scriptedClock is a fake epoch clock that reads out ticks, then
+// carries on one interval at a time from the last of them.  Like
+// skewedClock, it is not safe for concurrent use.
+type scriptedClock struct {
+	ticks []uint64
+	calls int
+}
+
+func (c *scriptedClock) epoch() uint64 {
+	c.calls++
+	if c.calls <= len(c.ticks) {
+		return c.ticks[c.calls-1]
+	}
+	return c.ticks[len(c.ticks)-1] + uint64(c.calls-len(c.ticks))
+}
+
+// clockScripts are the clock readings each strategy is fed.
+func clockScripts() map[string][]uint64 {
+	start := timeToEpoch(time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC))
+	scripts := map[string][]uint64{}
+
+	var steady, stalled, backwards, jittery []uint64
+	r := rand.New(rand.NewSource(1))
+	now := start
+	for i := 0; i < 1000; i++ {
+		now += uint64(1 + r.Intn(100))
+		steady = append(steady, now)
+		stalled = append(stalled, start+uint64(i/10))
+		backwards = append(backwards, start+uint64(i%250)) // and again
+		jittery = append(jittery, start+uint64(r.Intn(1000)))
+	}
+	scripts["steady"] = steady
+	scripts["stalled"] = stalled
+	scripts["backwards"] = backwards
+	scripts["jittery"] = jittery
+	return scripts
+}
+
+// differentialStream returns the first n UUIDs strategy s makes from
+// ticks, seq and node.
+func differentialStream(t *testing.T, s Strategy, ticks []uint64, seq uint16, node [6]byte, n int) []UUID {
+	clock := &scriptedClock{ticks: ticks}
+	var g Generator
+	switch s {
+	case StrategyMutex:
+		storageMutex.Lock()
+		oldEpoch, oldSeq, oldLast, oldNode := sharedEpochFunc, clockSequence, lastTime, hardwareAddr
+		sharedEpochFunc, clockSequence, lastTime, hardwareAddr = clock.epoch, seq, 0, node
+		storageMutex.Unlock()
+		defer func() {
+			storageMutex.Lock()
+			sharedEpochFunc, clockSequence, lastTime, hardwareAddr = oldEpoch, oldSeq, oldLast, oldNode
+			storageMutex.Unlock()
+		}()
+		g = GeneratorFunc(NewV1)
+	case StrategyLockFree:
+		StopLockFree()
+		oldEpoch, oldSeq, oldLast, oldNode := sharedEpochFunc, lockFreeClockSequence, lockFreeLastTime, hardwareAddr
+		sharedEpochFunc, lockFreeClockSequence, lockFreeLastTime, hardwareAddr = clock.epoch, seq, 0, node
+		defer func() {
+			StopLockFree()
+			sharedEpochFunc, lockFreeClockSequence, lockFreeLastTime, hardwareAddr = oldEpoch, oldSeq, oldLast, oldNode
+		}()
+		g = GeneratorFunc(NewV1LockFree)
+	default:
+		var entropy [2]byte
+		binary.BigEndian.PutUint16(entropy[:], seq)
+		var err error
+		g, err = NewGenerator(s,
+			WithClock(func() time.Time { return epochToTime(clock.epoch()) }),
+			WithEntropy(bytes.NewReader(entropy[:])),
+			WithNodeID(node),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, ok := g.(interface{ Close() }); ok {
+			defer c.Close()
+		}
+	}
+
+	ids := make([]UUID, n)
+	for i := range ids {
+		ids[i] = g.New()
+	}
+	return ids
+}
+
+func TestStrategiesAgree(t *testing.T) {
+	node := [6]byte{0x02, 0xd1, 0xff, 0, 0, 1}
+	strategies := []Strategy{StrategyMutex, StrategySatori, StrategyChannel, StrategyLockFree, StrategyAtomic}
+	for name, ticks := range clockScripts() {
+		// Across a wrap of the clock sequence as well as from 0.
+		for _, seq := range []uint16{0, 0x3ffe} {
+			want := differentialStream(t, StrategySatori, ticks, seq, node, len(ticks))
+			for _, s := range strategies {
+				if s == StrategyAtomic && name != "steady" {
+					continue
+				}
+				got := differentialStream(t, s, ticks, seq, node, len(ticks))
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("%s clock, clock sequence %#x: %s made %s at %d, satori %s", name, seq, s, got[i], i, want[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestAtomicDiverges pins down where AtomicGenerator parts company
+// with the others, so that it isn't mistaken for a bug.
+func TestAtomicDiverges(t *testing.T) {
+	node := [6]byte{0x02, 0xd1, 0xff, 0, 0, 1}
+	ticks := clockScripts()["stalled"]
+	satori := differentialStream(t, StrategySatori, ticks, 0, node, 20)
+	atomic := differentialStream(t, StrategyAtomic, ticks, 0, node, 20)
+	if satori[0] != atomic[0] {
+		t.Errorf("first UUIDs differ: satori %s, atomic %s", satori[0], atomic[0])
+	}
+	if _, seq := v1Fields(satori[1]); seq != 1 {
+		t.Errorf("satori clock sequence %d on a stalled clock, want 1", seq)
+	}
+	if ts, seq := v1Fields(atomic[1]); seq != 0 || ts != ticks[0]+1 {
+		t.Errorf("atomic %d, %d on a stalled clock, want the next tick and the same clock sequence", ts, seq)
+	}
+}
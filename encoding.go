@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// format is a text encoding of UUIDs.
+type format struct {
+	encode func(UUID) string
+	decode func(string) (UUID, error)
+}
+
+// formats maps the name of each supported text encoding to its
+// encoder and decoder.
+var formats = map[string]format{
+	"canonical": {UUID.String, Parse},
+	"braces": {
+		func(u UUID) string {
+			return "{" + u.String() + "}"
+		},
+		func(s string) (UUID, error) {
+			if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+				return UUID{}, fmt.Errorf("invalid braced UUID %q", s)
+			}
+			return Parse(s[1 : len(s)-1])
+		},
+	},
+	"urn": {
+		func(u UUID) string {
+			return "urn:uuid:" + u.String()
+		},
+		func(s string) (UUID, error) {
+			const prefix = "urn:uuid:"
+			if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+				return UUID{}, fmt.Errorf("invalid UUID URN %q", s)
+			}
+			return Parse(s[len(prefix):])
+		},
+	},
+	"hex": {
+		func(u UUID) string {
+			return hex.EncodeToString(u[:])
+		},
+		func(s string) (UUID, error) {
+			return decodeBytes(s, hex.DecodeString)
+		},
+	},
+	"base64": {
+		func(u UUID) string {
+			return base64.RawURLEncoding.EncodeToString(u[:])
+		},
+		func(s string) (UUID, error) {
+			return decodeBytes(s, base64.RawURLEncoding.DecodeString)
+		},
+	},
+	"base32": {
+		func(u UUID) string {
+			return base32NoPad.EncodeToString(u[:])
+		},
+		func(s string) (UUID, error) {
+			return decodeBytes(s, base32NoPad.DecodeString)
+		},
+	},
+	"base58": {encodeBase58, decodeBase58},
+	"ulid":   {encodeULID, decodeULID},
+}
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// decodeBytes decodes s with decode and checks that it came out to
+// exactly 16 bytes.
+func decodeBytes(s string, decode func(string) ([]byte, error)) (UUID, error) {
+	b, err := decode(s)
+	if err != nil {
+		return UUID{}, fmt.Errorf("invalid UUID %q: %v", s, err)
+	}
+	if len(b) != len(UUID{}) {
+		return UUID{}, fmt.Errorf("invalid UUID %q: %d bytes, want 16", s, len(b))
+	}
+	return UUID(b), nil
+}
+
+// Bitcoin's base58 alphabet, which leaves out 0, O, I and l.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encodeBase58 treats u as a 128 bit big-endian number.  Like
+// Bitcoin, each leading zero byte is written as a leading '1'.
+func encodeBase58(u UUID) string {
+	zeros := 0
+	for zeros < len(u) && u[zeros] == 0 {
+		zeros++
+	}
+
+	// At most 22 base58 digits fit in 128 bits.
+	var digits [22]byte
+	n := 0
+	num := u
+	for start := zeros; start < len(num); {
+		rem := 0
+		for i := start; i < len(num); i++ {
+			acc := rem<<8 | int(num[i])
+			num[i] = byte(acc / 58)
+			rem = acc % 58
+		}
+		digits[n] = base58Alphabet[rem]
+		n++
+		for start < len(num) && num[start] == 0 {
+			start++
+		}
+	}
+
+	buf := make([]byte, zeros+n)
+	for i := 0; i < zeros; i++ {
+		buf[i] = '1'
+	}
+	for i := 0; i < n; i++ {
+		buf[zeros+i] = digits[n-1-i]
+	}
+	return string(buf)
+}
+
+func decodeBase58(s string) (UUID, error) {
+	u := UUID{}
+	if s == "" {
+		return u, errors.New("invalid base58 UUID: empty")
+	}
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return UUID{}, fmt.Errorf("invalid base58 UUID %q: bad character %q", s, s[i])
+		}
+		carry := d
+		for j := len(u) - 1; j >= 0; j-- {
+			acc := int(u[j])*58 + carry
+			u[j] = byte(acc)
+			carry = acc >> 8
+		}
+		if carry != 0 {
+			return UUID{}, fmt.Errorf("invalid base58 UUID %q: more than 128 bits", s)
+		}
+	}
+
+	// Leading '1's stand for leading zero bytes, so there must be as
+	// many of them as there are zero bytes or the encoding wasn't
+	// canonical.
+	ones := 0
+	for ones < len(s) && s[ones] == '1' {
+		ones++
+	}
+	zeros := 0
+	for zeros < len(u) && u[zeros] == 0 {
+		zeros++
+	}
+	if ones != zeros {
+		return UUID{}, fmt.Errorf("invalid base58 UUID %q: not 16 bytes", s)
+	}
+	return u, nil
+}
+
+// Crockford's base32 alphabet, as used by ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeULID writes u as 26 Crockford base32 digits.  That is 130
+// bits, so the first digit is never more than 7.
+func encodeULID(u UUID) string {
+	buf := make([]byte, 26)
+	for i := range buf {
+		// Bit offset of this digit's most significant bit, counting
+		// from the 2 imaginary leading zero bits.
+		bit := i*5 - 2
+		v := 0
+		for b := bit; b < bit+5; b++ {
+			v <<= 1
+			if b >= 0 && u[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		buf[i] = crockfordAlphabet[v]
+	}
+	return string(buf)
+}
+
+// crockfordValue returns the value of c, accepting lower case and the
+// look-alike letters Crockford allows, or -1.
+func crockfordValue(c byte) int {
+	switch c {
+	case 'O', 'o':
+		return 0
+	case 'I', 'i', 'L', 'l':
+		return 1
+	}
+	if 'a' <= c && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	return strings.IndexByte(crockfordAlphabet, c)
+}
+
+func decodeULID(s string) (UUID, error) {
+	u := UUID{}
+	if len(s) != 26 {
+		return u, fmt.Errorf("invalid ULID %q: %d characters, want 26", s, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		v := crockfordValue(s[i])
+		if v < 0 {
+			return UUID{}, fmt.Errorf("invalid ULID %q: bad character %q", s, s[i])
+		}
+		if i == 0 && v > 7 {
+			return UUID{}, fmt.Errorf("invalid ULID %q: more than 128 bits", s)
+		}
+		bit := i*5 - 2
+		for b := bit; b < bit+5; b++ {
+			if b >= 0 && v&(0x10>>(b-bit)) != 0 {
+				u[b/8] |= 0x80 >> (b % 8)
+			}
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestFormatsRoundTrip(t *testing.T) {
+	ids := []UUID{
+		{},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0, 0, 1},
+		NewV1(),
+		NewV4(),
+		NewV7(),
+	}
+	for name, f := range formats {
+		for _, u := range ids {
+			s := f.encode(u)
+			got, err := f.decode(s)
+			if err != nil {
+				t.Errorf("%s: decode(%q): %v", name, s, err)
+				continue
+			}
+			if got != u {
+				t.Errorf("%s: decode(encode(%s)) = %s", name, u, got)
+			}
+		}
+	}
+}
+
+func TestFormatsKnown(t *testing.T) {
+	u, _ := Parse("0188a5eb-f0a4-7a13-9d60-b7a8e48cbab0")
+	for name, want := range map[string]string{
+		"braces": "{0188a5eb-f0a4-7a13-9d60-b7a8e48cbab0}",
+		"urn":    "urn:uuid:0188a5eb-f0a4-7a13-9d60-b7a8e48cbab0",
+		"hex":    "0188a5ebf0a47a139d60b7a8e48cbab0",
+		"ulid":   "01H2JYQW54F89STR5QN3J8SENG",
+	} {
+		if got := formats[name].encode(u); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+	if got := formats["base58"].encode(UUID{15: 57}); got != "111111111111111z" {
+		t.Errorf("base58: got %s", got)
+	}
+}
+
+func TestFormatsInvalid(t *testing.T) {
+	for name, s := range map[string]string{
+		"braces": "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"urn":    "uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"hex":    "6ba7b8109dad11d180b400c04fd430",
+		"base64": "a6e4EJ2tEdGAtADAT9Qw",
+		"base58": "0OIl",
+		"ulid":   "81H2JYQW54F89STR5QN3J8SEXG",
+	} {
+		if u, err := formats[name].decode(s); err == nil {
+			t.Errorf("%s: decode(%q) = %s, want error", name, s, u)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"text/tabwriter"
+)
+
+// randomMask returns which bits of byte pos are random in a V4 UUID.
+// The version and variant bits are fixed, so they are left out.
+func randomMask(pos int) byte {
+	switch pos {
+	case 6:
+		return 0x0f
+	case 8:
+		return 0x3f
+	default:
+		return 0xff
+	}
+}
+
+// byteStats holds the randomness checks for one byte position across
+// a run of UUIDs.  Both z scores should look like draws from a
+// standard normal distribution if the bytes are random.
+type byteStats struct {
+	pos    int
+	bits   int
+	chi2   float64
+	df     int
+	chi2Z  float64
+	serial float64
+	serZ   float64
+}
+
+// analyzeEntropy runs a chi-square test on the distribution of values
+// at each byte position, and a lag-1 serial correlation test on the
+// values at each byte position from one UUID to the next.
+func analyzeEntropy(ids []UUID) []byteStats {
+	stats := make([]byteStats, len(UUID{}))
+	for pos := range stats {
+		mask := randomMask(pos)
+		k := 1 << bits.OnesCount8(mask)
+
+		counts := make([]int, 256)
+		xs := make([]float64, len(ids))
+		for i, u := range ids {
+			v := u[pos] & mask
+			counts[v]++
+			xs[i] = float64(v)
+		}
+
+		expected := float64(len(ids)) / float64(k)
+		chi2 := 0.0
+		for v, c := range counts {
+			if byte(v)&^mask != 0 {
+				continue
+			}
+			d := float64(c) - expected
+			chi2 += d * d / expected
+		}
+
+		r := serialCorrelation(xs)
+		stats[pos] = byteStats{
+			pos:    pos,
+			bits:   bits.OnesCount8(mask),
+			chi2:   chi2,
+			df:     k - 1,
+			chi2Z:  chi2Z(chi2, k-1),
+			serial: r,
+			serZ:   r * math.Sqrt(float64(len(ids))),
+		}
+	}
+	return stats
+}
+
+// chi2Z converts a chi-square statistic to an approximate standard
+// normal z score using the Wilson-Hilferty transformation, which is
+// plenty accurate for the degrees of freedom we use.
+func chi2Z(chi2 float64, df int) float64 {
+	k := float64(df)
+	v := 2 / (9 * k)
+	return (math.Cbrt(chi2/k) - (1 - v)) / math.Sqrt(v)
+}
+
+// serialCorrelation returns the Pearson correlation between each
+// value and the one after it.
+func serialCorrelation(xs []float64) float64 {
+	n := len(xs) - 1
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxx, syy, sxy float64
+	for i := 0; i < n; i++ {
+		x, y := xs[i], xs[i+1]
+		sx += x
+		sy += y
+		sxx += x * x
+		syy += y * y
+		sxy += x * y
+	}
+	fn := float64(n)
+	den := math.Sqrt((fn*sxx - sx*sx) * (fn*syy - sy*sy))
+	if den == 0 {
+		return 0
+	}
+	return (fn*sxy - sx*sy) / den
+}
+
+func runEntropy(args []string) error {
+	fs := flag.NewFlagSet("entropy", flag.ExitOnError)
+	count := fs.Int("n", 100000, "number of V4 UUIDs to generate")
+	threshold := fs.Float64("threshold", 4, "flag z scores beyond this many standard deviations")
+	fs.Parse(args)
+
+	if *count < 1000 {
+		return errors.New("need at least 1000 UUIDs for the tests to mean anything")
+	}
+
+	ids := make([]UUID, *count)
+	for i := range ids {
+		ids[i] = NewV4()
+	}
+
+	anomalies := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "byte\tbits\tchi2\tdf\tz\tserial\tz\t\t")
+	for _, s := range analyzeEntropy(ids) {
+		flags := ""
+		if math.Abs(s.chi2Z) > *threshold {
+			flags += " chi2"
+		}
+		if math.Abs(s.serZ) > *threshold {
+			flags += " serial"
+		}
+		if flags != "" {
+			anomalies++
+			flags = "ANOMALY" + flags
+		}
+		fmt.Fprintf(w, "%d\t%d\t%.1f\t%d\t%.2f\t%.5f\t%.2f\t%s\t\n",
+			s.pos, s.bits, s.chi2, s.df, s.chi2Z, s.serial, s.serZ, flags)
+	}
+	w.Flush()
+
+	if anomalies > 0 {
+		return fmt.Errorf("%d of %d byte positions look non-random", anomalies, len(UUID{}))
+	}
+	return nil
+}
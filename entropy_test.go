@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyzeEntropyV4(t *testing.T) {
+	ids := make([]UUID, 20000)
+	for i := range ids {
+		ids[i] = NewV4()
+	}
+	// Generous threshold so this doesn't flake.
+	for _, s := range analyzeEntropy(ids) {
+		if math.Abs(s.chi2Z) > 6 || math.Abs(s.serZ) > 6 {
+			t.Errorf("byte %d looks non-random: chi2 z=%.2f serial z=%.2f", s.pos, s.chi2Z, s.serZ)
+		}
+	}
+}
+
+func TestAnalyzeEntropyBad(t *testing.T) {
+	ids := make([]UUID, 20000)
+	for i := range ids {
+		ids[i] = NewV4()
+		ids[i][3] = byte(i) // a counter, uniform but perfectly correlated
+		ids[i][9] &= 0x0f   // half the bits stuck at zero
+	}
+	stats := analyzeEntropy(ids)
+	if z := stats[3].serZ; math.Abs(z) < 6 {
+		t.Errorf("counter byte not flagged: serial z=%.2f", z)
+	}
+	if z := stats[9].chi2Z; math.Abs(z) < 6 {
+		t.Errorf("stuck bits not flagged: chi2 z=%.2f", z)
+	}
+}
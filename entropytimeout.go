@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	mrand "math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// entropyFallbacks counts the reads SetEntropyTimeout's fallback has
+// served.
+var entropyFallbacks atomic.Uint64
+
+// SetEntropyTimeout bounds safeRandom's reads to d, so that a VM
+// starved of entropy can't hang V4 generation, or anything else
+// needing randomness.  A read that takes longer, or fails, is served
+// instead by a ChaCha8 CSPRNG seeded from crypto/rand now, while it
+// still works; each such read is counted, in serve's /stats as
+// entropy_fallbacks, and the 1st, 2nd, 4th, 8th and so on are logged.
+// While a read is stuck, later ones go straight to the fallback,
+// rather than each leaving another goroutine waiting on the source.
+//
+// Each read then costs a goroutine and a timer, about a microsecond,
+// so this is for where a hang is worse than that.  Like the node ID,
+// it must be set before making any IDs.
+func SetEntropyTimeout(d time.Duration) error {
+	var seed [32]byte
+	if _, err := io.ReadFull(entropySource, seed[:]); err != nil {
+		return fmt.Errorf("seeding the entropy fallback: %v", err)
+	}
+	entropySource = &deadlineReader{r: entropySource, timeout: d, fallback: mrand.NewChaCha8(seed)}
+	return nil
+}
+
+type deadlineReader struct {
+	r       io.Reader
+	timeout time.Duration
+	// stuck is set while a read has outlasted its timeout.
+	stuck atomic.Bool
+
+	mu       sync.Mutex
+	fallback *mrand.ChaCha8
+}
+
+func (d *deadlineReader) Read(b []byte) (int, error) {
+	if !d.stuck.Load() {
+		// The read goes into a buffer of its own, which it may still
+		// be writing long after this returns.
+		buf := make([]byte, len(b))
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.ReadFull(d.r, buf)
+			done <- err
+		}()
+		timer := time.NewTimer(d.timeout)
+		defer timer.Stop()
+		select {
+		case err := <-done:
+			if err == nil {
+				return copy(b, buf), nil
+			}
+			d.fallBack(b, "entropy read failed", "err", err)
+			return len(b), nil
+		case <-timer.C:
+			if d.stuck.CompareAndSwap(false, true) {
+				go func() {
+					<-done
+					d.stuck.Store(false)
+				}()
+			}
+			d.fallBack(b, "entropy read timed out", "timeout", d.timeout)
+			return len(b), nil
+		}
+	}
+	d.fallBack(b, "entropy read still stuck")
+	return len(b), nil
+}
+
+// fallBack fills b from the fallback, logging why if this is a power
+// of two'th time.
+func (d *deadlineReader) fallBack(b []byte, why string, args ...any) {
+	d.mu.Lock()
+	d.fallback.Read(b)
+	d.mu.Unlock()
+	if n := entropyFallbacks.Add(1); bits.OnesCount64(n) == 1 {
+		slog.Warn(why+", using the fallback", append(args, "fallbacks", n)...)
+	}
+}
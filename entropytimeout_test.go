@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEntropyTimeout(t *testing.T) {
+	old, oldFallbacks := entropySource, entropyFallbacks.Load()
+	t.Cleanup(func() {
+		entropySource = old
+		entropyFallbacks.Store(oldFallbacks)
+	})
+
+	// Every read stalls for longer than the timeout.
+	entropySource = FlakyEntropy(1).Stalling(200 * time.Millisecond)
+	if err := SetEntropyTimeout(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	a, b := NewV4(), NewV4()
+	if took := time.Since(start); took > 100*time.Millisecond {
+		t.Errorf("two V4s took %s", took)
+	}
+	if a == b || a.Version() != 4 {
+		t.Errorf("%s then %s", a, b)
+	}
+	// The first timed out, and the second didn't wait, the first
+	// being stuck still.
+	if n := entropyFallbacks.Load() - oldFallbacks; n != 2 {
+		t.Errorf("%d fallbacks, want 2", n)
+	}
+
+	// Failures fall back too.
+	entropySource = FlakyEntropy(1)
+	if err := SetEntropyTimeout(time.Second); err == nil {
+		t.Error("seeded from a failing source")
+	}
+	entropySource = old
+	if err := SetEntropyTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	entropySource.(*deadlineReader).r = FlakyEntropy(1)
+	var buf [16]byte
+	safeRandom(buf[:])
+	if bytes.Equal(buf[:], make([]byte, 16)) {
+		t.Error("fallback gave zeros")
+	}
+	if n := entropyFallbacks.Load() - oldFallbacks; n != 3 {
+		t.Errorf("%d fallbacks, want 3", n)
+	}
+}
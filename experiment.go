@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// experiment is one way of generating V1 UUIDs, to be compared with
+// the others.  bench, gen and serve offer whatever is registered, so
+// trying out a new idea is a registerExperiment call next to it.
+type experiment struct {
+	Name        string
+	Description string
+	// Params names the bench flags the experiment pays attention to,
+	// such as "chansize".
+	Params []string
+	// New returns a generator, which the caller closes if it has a
+	// Close method.
+	New func(chanSize int) Generator
+}
+
+// experiments holds the registered experiments by name.  The -strategy
+// flags take these names.
+var experiments = map[string]experiment{}
+
+// registerExperiment adds e to experiments, panicking on a name that is
+// taken.  It returns true so that it can initialize a package level
+// variable, which happens before any init function, such as id.go's,
+// can look for e.
+func registerExperiment(e experiment) bool {
+	if e.Name == "" || e.New == nil {
+		panic("experiment needs a name and a constructor")
+	}
+	if _, dup := experiments[e.Name]; dup {
+		panic(fmt.Sprintf("experiment %q registered twice", e.Name))
+	}
+	experiments[e.Name] = e
+	return true
+}
+
+// writeExperiments lists the experiments, with what they are and the
+// flags they use.
+func writeExperiments(w io.Writer) {
+	for _, name := range sortedKeys(experiments) {
+		e := experiments[name]
+		fmt.Fprintf(w, "%-10s %s", e.Name, e.Description)
+		if len(e.Params) > 0 {
+			fmt.Fprintf(w, " (uses -%s)", strings.Join(e.Params, ", -"))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ExperimentConfig is one strategy's bench run, complete enough to be
+// repeated exactly, on this machine or another.  bench -json records
+// one for each strategy it ran, bench -experiment runs one, and serve
+// -experiment serves UUIDs from its generator.
+type ExperimentConfig struct {
+	Strategy string `json:"strategy"`
+	ChanSize int    `json:"chan_size"`
+	// Node is the node ID, as serve's -node takes it, empty for the
+	// machine's own.
+	Node        string   `json:"node,omitempty"`
+	Parallelism []int    `json:"parallelism,omitempty"`
+	Duration    duration `json:"duration,omitempty"`
+}
+
+// loadExperimentConfig reads an ExperimentConfig from a JSON file,
+// rejecting fields it doesn't have, to catch typos.
+func loadExperimentConfig(name string) (ExperimentConfig, error) {
+	var e ExperimentConfig
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return e, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&e); err != nil {
+		return e, fmt.Errorf("%s: %v", name, err)
+	}
+	if err := e.validate(); err != nil {
+		return e, fmt.Errorf("%s: %v", name, err)
+	}
+	return e, nil
+}
+
+// validate checks e for settings that make no sense.  Parallelism and
+// Duration may be left out, for serve, which doesn't use them.
+func (e ExperimentConfig) validate() error {
+	var errs []error
+	if _, ok := experiments[e.Strategy]; !ok {
+		errs = append(errs, fmt.Errorf("unknown strategy %q", e.Strategy))
+	}
+	if e.ChanSize < 0 {
+		errs = append(errs, errors.New("chan_size must not be negative"))
+	}
+	if _, err := parseNode(e.Node); err != nil {
+		errs = append(errs, err)
+	}
+	for _, p := range e.Parallelism {
+		if p < 1 {
+			errs = append(errs, errors.New("parallelism must be positive"))
+			break
+		}
+	}
+	if e.Duration < 0 {
+		errs = append(errs, errors.New("duration must not be negative"))
+	}
+	return errors.Join(errs...)
+}
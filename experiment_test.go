@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterExperiment(t *testing.T) {
+	registerExperiment(experiment{
+		Name:        "test",
+		Description: "NewV4 standing in for a V1 strategy",
+		Params:      []string{"chansize", "rate"},
+		New:         func(int) Generator { return GeneratorFunc(NewV4) },
+	})
+	defer delete(experiments, "test")
+
+	if g, err := newGenerator(1, "test", 0); err != nil || g.New().Version() != 4 {
+		t.Errorf("newGenerator: %v", err)
+	}
+	if _, err := parseStrategies("test,mutex"); err != nil {
+		t.Error(err)
+	}
+	var out strings.Builder
+	writeExperiments(&out)
+	if want := "test       NewV4 standing in for a V1 strategy (uses -chansize, -rate)\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("no %q in:\n%s", want, out.String())
+	}
+
+	for _, e := range []experiment{
+		{Name: "test", New: func(int) Generator { return nil }},
+		{Name: "nameless"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q registered", e.Name)
+				}
+			}()
+			registerExperiment(e)
+		}()
+	}
+}
+
+func TestExperimentConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "experiment.json")
+	want := ExperimentConfig{
+		Strategy:    "channel",
+		ChanSize:    100,
+		Node:        "02:00:00:00:00:01",
+		Parallelism: []int{1, 4},
+		Duration:    duration(2 * time.Second),
+	}
+	b, _ := json.Marshal(want)
+	os.WriteFile(file, b, 0o644)
+	got, err := loadExperimentConfig(file)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip: got %+v, %v", got, err)
+	}
+
+	cfg, _, err := parseServeArgs([]string{"-version", "7", "-experiment", file}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != 1 || cfg.Strategy != "channel" || cfg.ChanSize != 100 || cfg.Node != want.Node {
+		t.Errorf("serve config %+v", cfg)
+	}
+
+	for _, bad := range []string{
+		`{"strategy": "bogus"}`,
+		`{"strategy": "mutex", "chan_size": -1}`,
+		`{"strategy": "mutex", "parallelism": [0]}`,
+		`{"strategy": "mutex", "node": "nope"}`,
+		`{"strategy": "mutex", "goroutines": 4}`,
+	} {
+		os.WriteFile(file, []byte(bad), 0o644)
+		if _, err := loadExperimentConfig(file); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"container/heap"
+	"io"
+	"os"
+	"sort"
+)
+
+// ExternalSorter sorts more UUIDs than fit in memory, in byte order.
+// UUIDs are collected into chunks, each of which is sorted and written
+// to a temporary file, and WriteTo merges the files.  Call Close when
+// done to remove them.
+type ExternalSorter struct {
+	// ChunkSize is how many UUIDs to sort in memory at a time, 16
+	// bytes each.
+	ChunkSize int
+	// TempDir is where the sorted chunks go, os.TempDir() if empty.
+	TempDir string
+	// Unique drops duplicates.
+	Unique bool
+
+	chunk []UUID
+	runs  []*os.File
+}
+
+// Add adds u, writing out a sorted chunk if the current one is full.
+func (s *ExternalSorter) Add(u UUID) error {
+	s.chunk = append(s.chunk, u)
+	if len(s.chunk) >= s.ChunkSize {
+		return s.spill()
+	}
+	return nil
+}
+
+func (s *ExternalSorter) sortChunk() {
+	sort.Slice(s.chunk, func(i, j int) bool { return s.chunk[i].Compare(s.chunk[j]) < 0 })
+}
+
+// spill writes the current chunk to a temporary file.
+func (s *ExternalSorter) spill() error {
+	s.sortChunk()
+	f, err := os.CreateTemp(s.TempDir, "uuidsort-*")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, f)
+	if err := WriteAll(f, s.chunk); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.chunk = s.chunk[:0]
+	return nil
+}
+
+// runCursor is the next UUID from one sorted run.
+type runCursor struct {
+	cur  UUID
+	next func() (UUID, error)
+}
+
+// runHeap orders cursors by their current UUID.
+type runHeap []*runCursor
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].cur.Compare(h[j].cur) < 0 }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*runCursor)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// WriteTo writes all the UUIDs added so far to e, in order, and flushes
+// it.  The sorter can't be added to afterwards.
+func (s *ExternalSorter) WriteTo(e *Encoder) error {
+	s.sortChunk()
+	var h runHeap
+
+	mem := s.chunk
+	memNext := func() (UUID, error) {
+		if len(mem) == 0 {
+			return UUID{}, io.EOF
+		}
+		u := mem[0]
+		mem = mem[1:]
+		return u, nil
+	}
+	cursors := []func() (UUID, error){memNext}
+	for _, f := range s.runs {
+		d := NewDecoder(f)
+		d.SetBinary(true)
+		cursors = append(cursors, d.Decode)
+	}
+	for _, next := range cursors {
+		u, err := next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h = append(h, &runCursor{u, next})
+	}
+	heap.Init(&h)
+
+	var prev UUID
+	first := true
+	for len(h) > 0 {
+		c := h[0]
+		if !s.Unique || first || c.cur != prev {
+			if err := e.Encode(c.cur); err != nil {
+				return err
+			}
+		}
+		prev, first = c.cur, false
+
+		u, err := c.next()
+		switch {
+		case err == io.EOF:
+			heap.Pop(&h)
+		case err != nil:
+			return err
+		default:
+			c.cur = u
+			heap.Fix(&h, 0)
+		}
+	}
+	return e.Flush()
+}
+
+// Close removes the temporary files.
+func (s *ExternalSorter) Close() error {
+	var err error
+	for _, f := range s.runs {
+		f.Close()
+		if rerr := os.Remove(f.Name()); err == nil {
+			err = rerr
+		}
+	}
+	s.runs = nil
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestExternalSorter(t *testing.T) {
+	dir := t.TempDir()
+	for _, unique := range []bool{false, true} {
+		ids := make([]UUID, 1000)
+		for i := range ids {
+			ids[i] = NewV4()
+		}
+		// Some duplicates, which will land in different chunks.
+		ids = append(ids, ids[:100]...)
+
+		s := &ExternalSorter{ChunkSize: 64, TempDir: dir, Unique: unique}
+		for _, u := range ids {
+			if err := s.Add(u); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(s.runs) != len(ids)/64 {
+			t.Errorf("%d runs", len(s.runs))
+		}
+
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		e.SetBinary(true)
+		if err := s.WriteTo(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+		if unique {
+			ids = dedupe(ids)
+		}
+		got, _ := ReadAll(&buf, len(ids)+1)
+		if len(got) != len(ids) {
+			t.Fatalf("unique=%t: got %d UUIDs, want %d", unique, len(got), len(ids))
+		}
+		for i := range ids {
+			if got[i] != ids[i] {
+				t.Fatalf("unique=%t: %d is %s, want %s", unique, i, got[i], ids[i])
+			}
+		}
+	}
+
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("%d temporary files left behind", len(left))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	mrand "math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Fault injection, for seeing what each generator does when its
+// sources misbehave, in tests and with bench's -flaky-entropy and
+// -jumpy-clock, rather than finding out in production.
+
+// entropySource is where safeRandom reads: crypto/rand, unless a test
+// or bench -flaky-entropy has swapped it.  It must not be changed
+// while anything is making IDs.
+var entropySource io.Reader = rand.Reader
+
+// clockNow is the time the V1 generators' default clock, unixTimeFunc,
+// reads, and NewV7, V7Generator, NewULID, NewKSUID and the Snowflake,
+// Flake, Sonyflake and TSID generators too: time.Now, unless a test or
+// bench -jumpy-clock has swapped it.  It must not be changed while
+// anything is making IDs.
+var clockNow = time.Now
+
+var errEntropyFault = errors.New("injected entropy fault")
+
+// FlakyReader is crypto/rand with faults: a fraction of its reads fail,
+// or stall first.  It is safe for concurrent use.
+type FlakyReader struct {
+	r      io.Reader
+	p      float64
+	stall  time.Duration
+	faults atomic.Uint64
+}
+
+// FlakyEntropy returns crypto/rand's Reader, except that a fraction p
+// of reads fail.
+func FlakyEntropy(p float64) *FlakyReader {
+	return &FlakyReader{r: rand.Reader, p: p}
+}
+
+// Stalling makes f's faulty reads wait for d and then succeed, as an
+// entropy starved VM's do, instead of failing.  It returns f.
+func (f *FlakyReader) Stalling(d time.Duration) *FlakyReader {
+	f.stall = d
+	return f
+}
+
+func (f *FlakyReader) Read(b []byte) (int, error) {
+	if mrand.Float64() < f.p {
+		f.faults.Add(1)
+		if f.stall <= 0 {
+			return 0, errEntropyFault
+		}
+		time.Sleep(f.stall)
+	}
+	return f.r.Read(b)
+}
+
+// Faults returns how many of f's reads have failed or stalled.
+func (f *FlakyReader) Faults() uint64 {
+	return f.faults.Load()
+}
+
+// JumpyClock returns now, except that on a fraction p of calls it
+// jumps, as an NTP step or a VM migration would, to a new offset of up
+// to maxJump either way from now's time, where it stays until the next
+// jump.  It is safe for concurrent use if now is.
+func JumpyClock(now func() time.Time, p float64, maxJump time.Duration) func() time.Time {
+	var offset atomic.Int64
+	return func() time.Time {
+		if mrand.Float64() < p {
+			offset.Store(mrand.Int64N(2*int64(maxJump)+1) - int64(maxJump))
+		}
+		return now().Add(time.Duration(offset.Load()))
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// withEntropy swaps safeRandom's source for r until the test ends.
+func withEntropy(t *testing.T, r *FlakyReader) {
+	old := entropySource
+	entropySource = r
+	t.Cleanup(func() { entropySource = old })
+}
+
+func TestFlakyEntropy(t *testing.T) {
+	// The V1 generators only need entropy to start, so one made
+	// beforehand carries on regardless.
+	g := NewSatoriGenerator()
+	withEntropy(t, FlakyEntropy(1))
+	g.NewV1()
+
+	// Without a fallback, V4 panics.
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, errEntropyFault) {
+				t.Errorf("NewV4 with no entropy: recovered %v", err)
+			}
+		}()
+		NewV4()
+		t.Error("NewV4 with no entropy didn't panic")
+	}()
+
+	r := FlakyEntropy(1).Stalling(20 * time.Millisecond)
+	withEntropy(t, r)
+	start := time.Now()
+	NewV4()
+	if took := time.Since(start); took < 20*time.Millisecond || r.Faults() != 1 {
+		t.Errorf("stalled read took %s with %d faults", took, r.Faults())
+	}
+}
+
+func TestJumpyClock(t *testing.T) {
+	clock := JumpyClock(time.Now, 0.01, time.Second)
+	for _, s := range []Strategy{StrategySatori, StrategyChannel, StrategyAtomic, StrategyAuto} {
+		t.Run(string(s), func(t *testing.T) {
+			g, err := NewGenerator(s, WithClock(clock))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c, ok := g.(interface{ Close() }); ok {
+				defer c.Close()
+			}
+			seen := map[UUID]bool{}
+			for i := 0; i < 20000; i++ {
+				u := g.New()
+				if seen[u] {
+					t.Fatalf("%s twice", u)
+				}
+				seen[u] = true
+			}
+			if s := g.(statsReporter).Stats(); s.Rollbacks == 0 {
+				t.Errorf("the clock never went back: %+v", s)
+			}
+		})
+	}
+
+	// It stays within maxJump of the real time.
+	clock = JumpyClock(time.Now, 1, time.Minute)
+	for i := 0; i < 100; i++ {
+		if d := time.Until(clock()); d < -time.Minute-time.Second || d > time.Minute {
+			t.Fatalf("%s off", d)
+		}
+	}
+}
+
+func TestClockNow(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	defer func(old func() time.Time) { clockNow = old }(clockNow)
+	clockNow = func() time.Time { return at }
+
+	for name, u := range map[string]UUID{"NewV7": NewV7(), "V7Generator": NewV7Generator().New()} {
+		if got, _ := u.Time(); !got.Equal(at) {
+			t.Errorf("%s: time %s, want %s", name, got, at)
+		}
+	}
+	if got, _ := NewULID().Time(); !got.Equal(at) {
+		t.Errorf("NewULID: time %s, want %s", got, at)
+	}
+	if got, _ := NewKSUID().Time(); !got.Equal(at) {
+		t.Errorf("NewKSUID: time %s, want %s", got, at)
+	}
+
+	snowflakes, _ := NewSnowflakeGenerator(1)
+	sonyflakes, _ := NewSonyflakeGenerator(SonyflakeSettings{
+		MachineID: func() (uint16, error) { return 1, nil },
+	})
+	tsids, _ := NewTSIDGenerator(1, DefaultTSIDNodeBits, StrategyAtomic)
+	for name, id := range map[string]ID{
+		"SnowflakeGenerator": snowflakes.Next(),
+		"FlakeGenerator":     NewFlakeGenerator(&[6]byte{}).Next(),
+		"SonyflakeGenerator": sonyflakes.Next(),
+		"TSIDGenerator":      tsids.Next(),
+	} {
+		if got, _ := id.Time(); !got.Equal(at) {
+			t.Errorf("%s: time %s, want %s", name, got, at)
+		}
+	}
+}
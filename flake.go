@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Flake is Boundary's 128 bit ID: 64 bits of Unix milliseconds, a 48
+// bit worker ID, and a 16 bit sequence number.  See
+// https://github.com/boundary/flake.  Everything is big-endian, so
+// Flakes sort by time in binary and, since String writes fixed width
+// lower case hex, as strings too.  The worker ID is a V1 node ID,
+// chosen the same way, so that a machine needs no more configuring
+// for Flakes than for V1 UUIDs.
+type Flake [16]byte
+
+// ParseFlake parses the 32 hex digit form of a Flake, ignoring case.
+func ParseFlake(s string) (Flake, error) {
+	id := Flake{}
+	if len(s) != 32 {
+		return id, fmt.Errorf("invalid Flake %q: %d characters, want 32", s, len(s))
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return Flake{}, fmt.Errorf("invalid Flake %q: %v", s, err)
+	}
+	return id, nil
+}
+
+func (id Flake) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Bytes returns a copy of id's 16 bytes.
+func (id Flake) Bytes() []byte {
+	b := id
+	return b[:]
+}
+
+// Time returns the time id was made, to the millisecond.  ok is always
+// true.
+func (id Flake) Time() (t time.Time, ok bool) {
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(id[0:]))), true
+}
+
+// Worker returns the worker ID that made id.
+func (id Flake) Worker() [6]byte {
+	return [6]byte(id[8:14])
+}
+
+// OrdinalNode returns a node ID for the nth host, such as a
+// StatefulSet pod's ordinal from WorkerIDFromEnv, for when hosts
+// should have small predictable IDs rather than their MAC addresses.
+// It sets the locally administered bit, so it can't clash with a real
+// MAC address, nor, being unicast, with a "random" node.
+func OrdinalNode(n uint32) [6]byte {
+	node := [6]byte{0x02}
+	binary.BigEndian.PutUint32(node[2:], n)
+	return node
+}
+
+// FlakeGenerator makes Flakes for one worker ID.  Like
+// SnowflakeGenerator, when a millisecond's 65536 sequence numbers run
+// out, or the clock goes backwards, it borrows from the next
+// millisecond.
+type FlakeGenerator struct {
+	mu      sync.Mutex
+	worker  [6]byte
+	clock   msClock
+	nowFunc func() time.Time
+
+	counters generatorCounters
+}
+
+// NewFlakeGenerator returns a generator of Flakes for worker, or if it
+// is nil, for this machine's V1 node ID: the -node setting, or failing
+// that its MAC address.
+func NewFlakeGenerator(worker *[6]byte) *FlakeGenerator {
+	return newFlakeGenerator(worker, func() time.Time { return clockNow() })
+}
+
+// newFlakeGenerator lets tests inject a fake clock.  nowFunc is only
+// ever called with g.mu held.
+func newFlakeGenerator(worker *[6]byte, nowFunc func() time.Time) *FlakeGenerator {
+	g := &FlakeGenerator{clock: msClock{maxSeq: 1<<16 - 1, last: -1}, nowFunc: nowFunc}
+	if worker != nil {
+		g.worker = *worker
+	} else {
+		initHardwareAddr(&g.worker)
+	}
+	return g
+}
+
+// Next returns the next Flake.
+func (g *FlakeGenerator) Next() Flake {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms, seq := g.clock.next(g.nowFunc().UnixMilli(), &g.counters)
+	g.counters.generated.Add(1)
+
+	id := Flake{}
+	binary.BigEndian.PutUint64(id[0:], uint64(ms))
+	copy(id[8:], g.worker[:])
+	binary.BigEndian.PutUint16(id[14:], uint16(seq))
+	return id
+}
+
+// Stats reports on g.
+func (g *FlakeGenerator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
+
+// defaultFlakes backs the registered "flake" family.  It is made on
+// first use, so that it picks up the -node setting.
+var (
+	defaultFlakesOnce sync.Once
+	defaultFlakes     *FlakeGenerator
+)
+
+func init() {
+	RegisterFamily("flake", Family{
+		New: func() ID {
+			defaultFlakesOnce.Do(func() { defaultFlakes = NewFlakeGenerator(nil) })
+			return defaultFlakes.Next()
+		},
+		Parse: func(s string) (ID, error) {
+			return ParseFlake(s)
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFlakeGenerator(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	worker := OrdinalNode(3)
+	g := newFlakeGenerator(&worker, func() time.Time { return start })
+
+	// Unix milliseconds, the worker, then the sequence number, all
+	// big-endian, so that the bytes and the hex both sort.
+	var prev Flake
+	for seq := 0; seq < 3; seq++ {
+		id := g.Next()
+		want := fmt.Sprintf("%016x%x%04x", start.UnixMilli(), worker, seq)
+		if id.String() != want {
+			t.Errorf("ID %s, want %s", id, want)
+		}
+		if bytes.Compare(id[:], prev[:]) <= 0 {
+			t.Errorf("%s then %s", prev, id)
+		}
+		if ts, _ := id.Time(); !ts.Equal(start) || id.Worker() != worker {
+			t.Errorf("%s: time %s, worker %x", id, ts, id.Worker())
+		}
+		prev = id
+	}
+
+	// Without a worker it takes the V1 node ID.
+	node := [6]byte{0x02, 0xf1, 0xa3, 0, 0, 9}
+	storageMutex.Lock()
+	old := nodeOverride
+	nodeOverride = &node
+	storageMutex.Unlock()
+	defer func() {
+		storageMutex.Lock()
+		nodeOverride = old
+		storageMutex.Unlock()
+	}()
+	if w := NewFlakeGenerator(nil).Next().Worker(); w != node {
+		t.Errorf("worker %x, want the node ID %x", w, node)
+	}
+}
+
+func TestParseFlake(t *testing.T) {
+	want := NewFlakeGenerator(nil).Next()
+	id, err := ParseFlake(want.String())
+	if err != nil || id != want {
+		t.Errorf("round trip gave %s, %v, want %s", id, err, want)
+	}
+	if len(want.String()) != 32 {
+		t.Errorf("%q is not 32 characters", want)
+	}
+	for _, s := range []string{"", "0123", "0000000000000000000000000000000g"} {
+		if _, err := ParseFlake(s); err == nil {
+			t.Errorf("ParseFlake(%q) should fail", s)
+		}
+	}
+	if n := OrdinalNode(258); n != [6]byte{0x02, 0, 0, 0, 1, 2} {
+		t.Errorf("OrdinalNode(258) = %x", n)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// UUIDs from the channel generators are stamped when they go into the
+// channel, not when they come out.  If nothing reads the channel for a
+// while, the buffered ones are as old as that pause, so a consumer can
+// get UUIDs that claim to be from before a stall it just sat through.
+// The Fresh variants check, and swap stale UUIDs for new ones.
+
+// v1Ticks returns the timestamp of a V1 UUID, in 100ns ticks since the
+// UUID epoch.
+func v1Ticks(u UUID) uint64 {
+	ts := uint64(binary.BigEndian.Uint32(u[0:]))
+	ts |= uint64(binary.BigEndian.Uint16(u[4:])) << 32
+	ts |= uint64(binary.BigEndian.Uint16(u[6:])&0x0fff) << 48
+	return ts
+}
+
+// isStale says whether u's timestamp is more than maxAge old.
+func isStale(u UUID, maxAge time.Duration) bool {
+	return unixTimeFunc()-v1Ticks(u) > uint64(maxAge/100)
+}
+
+// NewV1LockFreeFresh is NewV1LockFree, except that a UUID more than
+// maxAge old is swapped for one from NewV1.  That takes the lock, but
+// only after a stall, when a UUID or two of lock wait won't be
+// noticed.  NewV1's storage has its own clock sequence, so the two
+// can't collide.
+func NewV1LockFreeFresh(maxAge time.Duration) UUID {
+	u := NewV1LockFree()
+	if isStale(u, maxAge) {
+		return NewV1()
+	}
+	return u
+}
+
+// NewV1Fresh is NewV1, except that a UUID more than maxAge old is
+// swapped for one from the package level NewV1, which has its own
+// clock sequence.  Each swap is counted in Stats as Restamped.
+func (g *ChanneledGenerator) NewV1Fresh(maxAge time.Duration) UUID {
+	u := g.NewV1()
+	if isStale(u, maxAge) {
+		g.counters.restamped.Add(1)
+		return NewV1()
+	}
+	return u
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestStalenessByChanSize stalls the consumer of a channel generator
+// and measures how old the UUIDs waiting for it are, with and without
+// NewV1Fresh.  Run with -v to see the numbers.
+func TestStalenessByChanSize(t *testing.T) {
+	const stall = 20 * time.Millisecond
+	const maxAge = time.Millisecond
+
+	for _, size := range []int{0, 1, 10, 100, 1000} {
+		t.Run(fmt.Sprintf("chansize=%d", size), func(t *testing.T) {
+			g := NewChanneledGenerator(size)
+			time.Sleep(stall)
+			worst := time.Duration(0)
+			for i := 0; i <= size; i++ {
+				if s := staleness(g.NewV1()); s > worst {
+					worst = s
+				}
+			}
+
+			time.Sleep(stall)
+			worstFresh := time.Duration(0)
+			for i := 0; i <= size; i++ {
+				if s := staleness(g.NewV1Fresh(maxAge)); s > worstFresh {
+					worstFresh = s
+				}
+			}
+			t.Logf("worst staleness %s, %s with NewV1Fresh, %d restamped", worst, worstFresh, g.Stats().Restamped)
+
+			// The producer stamps one more UUID than the buffer holds
+			// and then blocks sending it, so even an unbuffered
+			// channel hands out one stale UUID.
+			if worst < stall {
+				t.Errorf("worst staleness %s, want at least %s", worst, stall)
+			}
+			// Leave plenty of room for scheduling delays, but well
+			// short of the stall.
+			if worstFresh > stall/2 {
+				t.Errorf("worst staleness with NewV1Fresh %s", worstFresh)
+			}
+		})
+	}
+}
+
+func TestNewV1LockFreeFresh(t *testing.T) {
+	NewV1LockFree()
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < len(ch)+2; i++ {
+		if s := staleness(NewV1LockFreeFresh(time.Millisecond)); s > 3*time.Millisecond {
+			t.Errorf("staleness %s", s)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// gcResult is one strategy run at a fixed rate, as -gc reports it.
+// Running at a fixed rate rather than flat out means every strategy
+// makes the same number of UUIDs, so their allocation and GC numbers
+// can be compared directly.
+type gcResult struct {
+	Strategy    string        `json:"strategy"`
+	Setting     gcSetting     `json:"setting"`
+	Parallelism int           `json:"parallelism"`
+	Rate        int           `json:"rate"`
+	Duration    time.Duration `json:"duration_ns"`
+	Ops         int64         `json:"ops"`
+	// Mallocs and Bytes are heap allocations during the run.
+	Mallocs  uint64 `json:"mallocs"`
+	Bytes    uint64 `json:"bytes"`
+	GCCycles uint32 `json:"gc_cycles"`
+	// PauseTotal and MaxPause are stop the world time.
+	PauseTotal time.Duration `json:"pause_total_ns"`
+	MaxPause   time.Duration `json:"max_pause_ns"`
+}
+
+// gcTick is how often each goroutine makes its share of the UUIDs.
+const gcTick = time.Millisecond
+
+// gcRun calls g.New rate times a second, spread over parallelism
+// goroutines, for d, and reports what the runtime's memory stats did
+// meanwhile.  Everything else the process does shows up too, so it's
+// best run with nothing else going on.
+func gcRun(g Generator, parallelism, rate int, d time.Duration) gcResult {
+	perTick := rate / parallelism / int(time.Second/gcTick)
+	if perTick < 1 {
+		perTick = 1
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var wg sync.WaitGroup
+	var ops atomic.Int64
+	start := time.Now()
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := time.NewTicker(gcTick)
+			defer t.Stop()
+			n := int64(0)
+			for time.Since(start) < d {
+				<-t.C
+				for j := 0; j < perTick; j++ {
+					g.New()
+				}
+				n += int64(perTick)
+			}
+			ops.Add(n)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	r := gcResult{
+		Parallelism: parallelism,
+		Rate:        rate,
+		Duration:    elapsed,
+		Ops:         ops.Load(),
+		Mallocs:     after.Mallocs - before.Mallocs,
+		Bytes:       after.TotalAlloc - before.TotalAlloc,
+		GCCycles:    after.NumGC - before.NumGC,
+		PauseTotal:  time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+	// PauseNs is a ring of the last 256 pauses.
+	for c := before.NumGC + 1; c <= after.NumGC && after.NumGC-c < 256; c++ {
+		if p := time.Duration(after.PauseNs[(c+255)%256]); p > r.MaxPause {
+			r.MaxPause = p
+		}
+	}
+	return r
+}
+
+// gcSetting is a GOGC and GOMEMLIMIT to run benchmarks under, in the
+// same form as the environment variables.  Empty means leave it be.
+type gcSetting struct {
+	GOGC     string `json:"gogc,omitempty"`
+	MemLimit string `json:"gomemlimit,omitempty"`
+}
+
+func (s gcSetting) String() string {
+	gogc, limit := s.GOGC, s.MemLimit
+	if gogc == "" {
+		gogc = "default"
+	}
+	if limit == "" {
+		limit = "default"
+	}
+	return "GOGC=" + gogc + " GOMEMLIMIT=" + limit
+}
+
+// parseGCSettings returns every combination of the comma separated
+// GOGC values and memory limits.
+func parseGCSettings(gogcList, limitList string) ([]gcSetting, error) {
+	gogcs := strings.Split(gogcList, ",")
+	limits := strings.Split(limitList, ",")
+	for _, g := range gogcs {
+		if _, err := parseGOGC(g); err != nil && strings.TrimSpace(g) != "" {
+			return nil, err
+		}
+	}
+	for _, l := range limits {
+		if _, err := parseMemLimit(l); err != nil && strings.TrimSpace(l) != "" {
+			return nil, err
+		}
+	}
+	var settings []gcSetting
+	for _, g := range gogcs {
+		for _, l := range limits {
+			settings = append(settings, gcSetting{strings.TrimSpace(g), strings.TrimSpace(l)})
+		}
+	}
+	return settings, nil
+}
+
+// parseGOGC parses a GOGC value: a percentage or "off".
+func parseGOGC(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad GOGC %q", s)
+	}
+	return n, nil
+}
+
+// parseMemLimit parses a GOMEMLIMIT value: a number of bytes with an
+// optional KiB, MiB, GiB or TiB suffix, or "off".
+func parseMemLimit(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return math.MaxInt64, nil
+	}
+	mult := int64(1)
+	for i, suffix := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		if strings.HasSuffix(s, suffix) {
+			s = strings.TrimSuffix(s, suffix)
+			mult = 1 << (10 * (i + 1))
+			break
+		}
+	}
+	s = strings.TrimSuffix(s, "B")
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("bad GOMEMLIMIT %q", s)
+	}
+	return n * mult, nil
+}
+
+// apply sets s for the whole process, returning a function that puts
+// back what was there before.  It assumes s has been parsed already.
+func (s gcSetting) apply() (restore func()) {
+	oldGOGC, oldLimit := -2, int64(-1)
+	if s.GOGC != "" {
+		n, _ := parseGOGC(s.GOGC)
+		oldGOGC = debug.SetGCPercent(n)
+	}
+	if s.MemLimit != "" {
+		n, _ := parseMemLimit(s.MemLimit)
+		oldLimit = debug.SetMemoryLimit(n)
+	}
+	return func() {
+		if oldGOGC != -2 {
+			debug.SetGCPercent(oldGOGC)
+		}
+		if oldLimit >= 0 {
+			debug.SetMemoryLimit(oldLimit)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// versionGenerators holds the generators for versions other than 1,
+// which don't come in different strategies.
+var versionGenerators = map[int]Generator{
+	4: GeneratorFunc(NewV4),
+	6: v6Generator{},
+	7: GeneratorFunc(NewV7),
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newGenerator returns a generator for the given version.  strategy
+// and chanSize only matter for version 1, and version 8, which tags
+// the strategy's UUIDs with it and the node ordinal: see
+// ProvenanceGenerator.
+func newGenerator(version int, strategy string, chanSize int) (Generator, error) {
+	if version == 1 || version == 8 {
+		e, ok := experiments[strategy]
+		if !ok {
+			return nil, fmt.Errorf("unknown strategy %q", strategy)
+		}
+		if version == 8 {
+			return NewProvenanceGenerator(e.New(chanSize), strategy, uint8(nodeOrdinal.Load())), nil
+		}
+		return e.New(chanSize), nil
+	}
+	if g, ok := versionGenerators[version]; ok {
+		return g, nil
+	}
+	return nil, fmt.Errorf("unsupported version %d", version)
+}
+
+// templateData is what -output-template templates are executed with.
+type templateData struct {
+	// N counts the generated UUIDs, starting at 1.
+	N       int
+	UUID    UUID
+	Version byte
+	// Timestamp is the zero time for versions that don't have one.
+	Timestamp time.Time
+
+	Canonical, Braces, URN, Hex, Base64, Base32, Base58, ULID string
+}
+
+func newTemplateData(n int, u UUID) templateData {
+	t, _ := u.Time()
+	return templateData{
+		N:         n,
+		UUID:      u,
+		Version:   u.Version(),
+		Timestamp: t,
+		Canonical: u.String(),
+		Braces:    formats["braces"].encode(u),
+		URN:       formats["urn"].encode(u),
+		Hex:       formats["hex"].encode(u),
+		Base64:    formats["base64"].encode(u),
+		Base32:    formats["base32"].encode(u),
+		Base58:    formats["base58"].encode(u),
+		ULID:      formats["ulid"].encode(u),
+	}
+}
+
+// parseOutputTemplate parses the -output-template flag, which is
+// either a template or @ followed by the name of a file holding one.
+func parseOutputTemplate(s string) (*template.Template, error) {
+	if strings.HasPrefix(s, "@") {
+		b, err := os.ReadFile(s[1:])
+		if err != nil {
+			return nil, err
+		}
+		s = strings.TrimSuffix(string(b), "\n")
+	}
+	return template.New("output").Parse(s)
+}
+
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	count := fs.Int("n", 1, "number of UUIDs to generate")
+	version := fs.Int("version", 1, "UUID version: 1, 4, 6, 7, or 8 for V1s tagged with their provenance")
+	format := fs.String("format", "canonical", "output format: binary (raw 16 bytes), "+strings.Join(sortedKeys(formats), ", "))
+	strategy := fs.String("strategy", "mutex", "V1 strategy: "+strings.Join(sortedKeys(experiments), ", "))
+	chanSize := fs.Int("chansize", 10, "channel size for the channel strategy")
+	ordinal := fs.Uint("node-ordinal", 0, "the instance number, 0 to 255, version 8 UUIDs are tagged with")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	rate := fs.Float64("rate", 0, "generate at most this many UUIDs per second, 0 for no limit")
+	outputTemplate := fs.String("output-template", "", "text/template for each line, or @file; overrides -format\n"+
+		"fields: .N .UUID .Version .Timestamp .Canonical .Braces .URN .Hex .Base64 .Base32 .Base58 .ULID")
+	scheme := fs.String("scheme", "", "generate IDs of this scheme instead of UUIDs: "+strings.Join(Families(), ", "))
+	fs.Parse(args)
+
+	if *count < 0 {
+		return errors.New("-n must not be negative")
+	}
+	if *rate < 0 {
+		return errors.New("-rate must not be negative")
+	}
+	if *ordinal > 255 {
+		return errors.New("-node-ordinal must be at most 255")
+	}
+	setNodeOrdinal(uint8(*ordinal))
+	f, ok := formats[*format]
+	if !ok && *format != "binary" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	var (
+		family *Family
+		g      Generator
+		err    error
+	)
+	if *scheme != "" {
+		f, ok := LookupFamily(*scheme)
+		if !ok {
+			return fmt.Errorf("unknown scheme %q", *scheme)
+		}
+		var clash []string
+		fs.Visit(func(fl *flag.Flag) {
+			switch fl.Name {
+			case "version", "strategy", "chansize", "node-ordinal", "format", "output-template":
+				clash = append(clash, "-"+fl.Name)
+			}
+		})
+		if len(clash) > 0 {
+			return fmt.Errorf("-scheme can't be used with %s", strings.Join(clash, ", "))
+		}
+		family = &f
+	} else if g, err = newGenerator(*version, *strategy, *chanSize); err != nil {
+		return err
+	}
+	var tmpl *template.Template
+	if *outputTemplate != "" {
+		if tmpl, err = parseOutputTemplate(*outputTemplate); err != nil {
+			return err
+		}
+	}
+
+	out, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+
+	// Canonical and binary output go through an Encoder, which doesn't
+	// allocate per UUID.  Everything else is formatted into a plain
+	// buffered writer.
+	enc := NewEncoder(out)
+	enc.SetBinary(*format == "binary")
+	flush := enc.Flush
+	var w *bufio.Writer
+	if family != nil || tmpl != nil || (*format != "canonical" && *format != "binary") {
+		w = bufio.NewWriter(out)
+		flush = w.Flush
+	}
+
+	var throttle *Throttled
+	if *rate > 0 {
+		throttle = newThrottle(g, *rate, time.Now)
+	}
+
+	for n := 0; n < *count; n++ {
+		if throttle != nil {
+			// Don't leave rate limited output sitting in the buffer.
+			if err := flush(); err != nil {
+				out.Close()
+				return err
+			}
+			throttle.Wait(context.Background())
+		}
+
+		if family != nil {
+			w.WriteString(family.New().String())
+			if err := w.WriteByte('\n'); err != nil {
+				out.Close()
+				return err
+			}
+			continue
+		}
+
+		u := g.New()
+		switch {
+		case tmpl != nil:
+			err = tmpl.Execute(w, newTemplateData(n+1, u))
+			w.WriteByte('\n')
+		case w != nil:
+			w.WriteString(f.encode(u))
+			err = w.WriteByte('\n')
+		default:
+			err = enc.Encode(u)
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// nopCloser keeps commands from closing stdout.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// createOutput creates the named file, or returns stdout if name is
+// empty.
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
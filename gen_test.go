@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewGenerator(t *testing.T) {
+	for _, strategy := range sortedKeys(experiments) {
+		g, err := newGenerator(1, strategy, 1)
+		if err != nil {
+			t.Fatalf("strategy %s: %v", strategy, err)
+		}
+		if v := g.New().Version(); v != 1 {
+			t.Errorf("strategy %s: version %d", strategy, v)
+		}
+	}
+	for _, version := range []int{4, 6, 7} {
+		g, err := newGenerator(version, "", 0)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if v := g.New().Version(); int(v) != version {
+			t.Errorf("version %d: got version %d", version, v)
+		}
+	}
+	if _, err := newGenerator(1, "bogus", 0); err == nil {
+		t.Errorf("unknown strategy accepted")
+	}
+	if _, err := newGenerator(2, "", 0); err == nil {
+		t.Errorf("unsupported version accepted")
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	tmpl, err := parseOutputTemplate(`INSERT INTO t (n, id, v) VALUES ({{.N}}, '{{.Canonical}}', {{.Version}});`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	var out strings.Builder
+	if err := tmpl.Execute(&out, newTemplateData(3, u)); err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO t (n, id, v) VALUES (3, '6ba7b810-9dad-11d1-80b4-00c04fd430c8', 1);`
+	if out.String() != want {
+		t.Errorf("got %s, want %s", out.String(), want)
+	}
+}
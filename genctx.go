@@ -0,0 +1,21 @@
+package main
+
+import "context"
+
+// generatorKey is the context key for a Generator.
+type generatorKey struct{}
+
+// NewContext returns a copy of ctx carrying g, so that code far down a
+// call chain, such as a request handler's helpers, can be handed a
+// particular generator, such as a deterministic one in tests, without
+// it being threaded through every call.
+func NewContext(ctx context.Context, g Generator) context.Context {
+	return context.WithValue(ctx, generatorKey{}, g)
+}
+
+// FromContext returns the generator NewContext put in ctx, or nil if
+// there isn't one.
+func FromContext(ctx context.Context) Generator {
+	g, _ := ctx.Value(generatorKey{}).(Generator)
+	return g
+}
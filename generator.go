@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Strategy is a way of sharing a generator's state between
+// goroutines: for V1 UUIDs with NewGenerator, and for TSIDs with
+// NewTSIDGenerator.
+type Strategy string
+
+const (
+	// StrategyMutex is NewV1: package level state behind a mutex.
+	StrategyMutex Strategy = "mutex"
+	// StrategySatori is SatoriGenerator: its own state behind a mutex.
+	StrategySatori Strategy = "satori"
+	// StrategyChannel is ChanneledGenerator: a goroutine generating
+	// into a channel.
+	StrategyChannel Strategy = "channel"
+	// StrategyLockFree is NewV1LockFree: a package level goroutine
+	// generating into a channel.
+	StrategyLockFree Strategy = "lockfree"
+	// StrategyAtomic is AtomicGenerator: compare and swap on the last
+	// timestamp.
+	StrategyAtomic Strategy = "atomic"
+	// StrategyAuto is AutoGenerator: StrategySatori until it is
+	// contended, then StrategyAtomic.
+	StrategyAuto Strategy = "auto"
+)
+
+// defaultBatchSize is the channel size of StrategyChannel without
+// WithBatchSize, the same as bench's -chansize.
+const defaultBatchSize = 10
+
+// A GeneratorOption configures a generator made by NewGenerator.
+type GeneratorOption func(*generatorOptions)
+
+// generatorOptions holds what the GeneratorOptions set, nil for those
+// not given.
+type generatorOptions struct {
+	node    *[6]byte
+	now     func() time.Time
+	entropy io.Reader
+	batch   *int
+	store   StateStore
+}
+
+// WithNodeID makes the generator use node instead of this machine's
+// MAC address.
+func WithNodeID(node [6]byte) GeneratorOption {
+	return func(o *generatorOptions) {
+		o.node = &node
+	}
+}
+
+// WithClock makes the generator take its timestamps from now instead
+// of time.Now.
+func WithClock(now func() time.Time) GeneratorOption {
+	return func(o *generatorOptions) {
+		o.now = now
+	}
+}
+
+// WithEntropy makes the generator read its starting clock sequence
+// from r instead of crypto/rand.
+func WithEntropy(r io.Reader) GeneratorOption {
+	return func(o *generatorOptions) {
+		o.entropy = r
+	}
+}
+
+// WithBatchSize sets how many UUIDs StrategyChannel makes ahead of
+// time: its channel size, which may be 0.
+func WithBatchSize(n int) GeneratorOption {
+	return func(o *generatorOptions) {
+		o.batch = &n
+	}
+}
+
+// WithStateStore makes the generator restore its clock sequence from
+// store, if it has saved one for the same node, and save it there
+// while it runs, so that a restart with the clock set back can't
+// repeat its UUIDs.  The generator must be closed when done with, to
+// stop the saving.
+func WithStateStore(store StateStore) GeneratorOption {
+	return func(o *generatorOptions) {
+		o.store = store
+	}
+}
+
+// NewGenerator returns a V1 generator using strategy s, configured by
+// opts.  StrategyMutex and StrategyLockFree share package level state,
+// so they take no options, and only StrategyChannel takes
+// WithBatchSize.  It is an error to give an option the strategy
+// doesn't use.  Generators that have a Close method should be closed
+// when done with.
+func NewGenerator(s Strategy, opts ...GeneratorOption) (Generator, error) {
+	o := generatorOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch s {
+	case StrategyMutex, StrategySatori, StrategyChannel, StrategyLockFree, StrategyAtomic, StrategyAuto:
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", s)
+	}
+	shared := s == StrategyMutex || s == StrategyLockFree
+	switch {
+	case shared && (o.node != nil || o.now != nil || o.entropy != nil || o.store != nil):
+		return nil, fmt.Errorf("strategy %s shares package level state, so it can't take a node ID, clock, entropy or state store", s)
+	case o.batch != nil && s != StrategyChannel:
+		return nil, fmt.Errorf("strategy %s doesn't take a batch size", s)
+	case o.batch != nil && *o.batch < 0:
+		return nil, errors.New("batch size must not be negative")
+	}
+
+	switch s {
+	case StrategyMutex:
+		return mutexGenerator{}, nil
+	case StrategyLockFree:
+		return lockFreeGenerator{}, nil
+	}
+
+	epochFunc := unixTimeFunc
+	if o.now != nil {
+		epochFunc = func() uint64 { return timeToEpoch(o.now()) }
+	}
+	st := newV1Start(epochFunc)
+	if o.node != nil {
+		st.hardwareAddr = *o.node
+	}
+	if o.entropy != nil {
+		var buf [2]byte
+		if _, err := io.ReadFull(o.entropy, buf[:]); err != nil {
+			return nil, fmt.Errorf("reading the clock sequence: %v", err)
+		}
+		st.clockSequence = binary.BigEndian.Uint16(buf[:])
+	}
+
+	if o.store != nil {
+		if err := restoreState(o.store, &st); err != nil {
+			return nil, fmt.Errorf("restoring the generator state: %v", err)
+		}
+		g, err := newPersistedGenerator(generatorFrom(s, st, o.batch), o.store, st)
+		if err != nil {
+			return nil, fmt.Errorf("saving the generator state: %v", err)
+		}
+		return g, nil
+	}
+	return generatorFrom(s, st, o.batch), nil
+}
+
+// generatorFrom returns a generator of strategy s, one with state of
+// its own, starting from st.
+func generatorFrom(s Strategy, st v1Start, batchSize *int) Generator {
+	switch s {
+	case StrategyAtomic:
+		return atomicGeneratorFrom(st)
+	case StrategyAuto:
+		return autoGeneratorFrom(st)
+	case StrategyChannel:
+		batch := defaultBatchSize
+		if batchSize != nil {
+			batch = *batchSize
+		}
+		return channeledGeneratorFrom(batch, st)
+	}
+	return satoriGeneratorFrom(st)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewGeneratorOptions(t *testing.T) {
+	node := [6]byte{0x02, 1, 2, 3, 4, 5}
+	when := time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range []Strategy{StrategySatori, StrategyChannel, StrategyAtomic} {
+		g, err := NewGenerator(s,
+			WithNodeID(node),
+			WithClock(func() time.Time { return when }),
+			WithEntropy(bytes.NewReader([]byte{0x12, 0x34})),
+		)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		u := g.New()
+		if c, ok := g.(interface{ Close() }); ok {
+			c.Close()
+		}
+		if got, _ := u.Time(); !got.Equal(when) {
+			t.Errorf("%s: time %s, want %s", s, got, when)
+		}
+		if _, seq := v1Fields(u); seq != 0x1234 {
+			t.Errorf("%s: clock sequence %#x, want 0x1234", s, seq)
+		}
+		if !bytes.Equal(u[10:], node[:]) {
+			t.Errorf("%s: node %x", s, u[10:])
+		}
+	}
+
+	g, err := NewGenerator(StrategyChannel, WithBatchSize(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.(*ChanneledGenerator).Close()
+	if s := g.(*ChanneledGenerator).Stats(); s.ChanCap != 0 {
+		t.Errorf("channel size %d, want 0", s.ChanCap)
+	}
+	if g, err := NewGenerator(StrategyMutex); err != nil || g.New().Version() != 1 {
+		t.Errorf("mutex: %v", err)
+	}
+}
+
+func TestNewGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		s    Strategy
+		opts []GeneratorOption
+		want string
+	}{
+		{"bogus", nil, "unknown strategy"},
+		{StrategyMutex, []GeneratorOption{WithNodeID([6]byte{})}, "package level state"},
+		{StrategyLockFree, []GeneratorOption{WithClock(time.Now)}, "package level state"},
+		{StrategySatori, []GeneratorOption{WithBatchSize(5)}, "batch size"},
+		{StrategyChannel, []GeneratorOption{WithBatchSize(-1)}, "negative"},
+		{StrategyAtomic, []GeneratorOption{WithEntropy(strings.NewReader("x"))}, "clock sequence"},
+	}
+	for _, tt := range tests {
+		if _, err := NewGenerator(tt.s, tt.opts...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s with %d options: %v, want an error about %s", tt.s, len(tt.opts), err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+//go:build ignore
+
+// genresults reruns the benchmarks in uuid_test.go and rewrites the
+// raw results at the end of the comment at its top, so that they say
+// which commit and machine they came from.  Run it with go generate;
+// it takes a minute or so.  The take-aways above the results are
+// still written by hand, so read them over afterwards.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// resultsStart starts the part of the comment this rewrites, which
+// runs to the end of the comment.
+const resultsStart = "Below are the raw results"
+
+var benchFunc = regexp.MustCompile(`(?m)^func (Benchmark\w+)\(b \*testing\.B\)`)
+
+func main() {
+	file := flag.String("file", "uuid_test.go", "file whose benchmarks to run, and whose comment to rewrite")
+	benchtime := flag.String("benchtime", "1s", "go test -benchtime")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("genresults: ")
+
+	src, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start := bytes.Index(src, []byte(resultsStart))
+	end := bytes.Index(src, []byte("*/"))
+	if start < 0 || end < start {
+		log.Fatalf("%s: no %q in the comment at the top", *file, resultsStart)
+	}
+
+	var names []string
+	for _, m := range benchFunc.FindAllSubmatch(src, -1) {
+		names = append(names, string(m[1]))
+	}
+	if len(names) == 0 {
+		log.Fatalf("%s: no benchmarks", *file)
+	}
+	pattern := "^(" + strings.Join(names, "|") + ")$"
+	out, err := exec.Command("go", "test", "-run", "^$", "-bench", pattern, "-benchtime", *benchtime, ".").CombinedOutput()
+	if err != nil {
+		log.Fatalf("go test: %v\n%s", err, out)
+	}
+
+	// go test prints goos, goarch and cpu lines before the results.
+	var machine, results []string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "Benchmark"):
+			results = append(results, "  "+line)
+		case strings.HasPrefix(line, "goos:"), strings.HasPrefix(line, "goarch:"), strings.HasPrefix(line, "cpu:"):
+			machine = append(machine, "  "+line)
+		}
+	}
+	machine = append(machine, fmt.Sprintf("  cpus: %d", runtime.NumCPU()), "  go: "+goVersion())
+
+	var b bytes.Buffer
+	b.Write(src[:start])
+	fmt.Fprintf(&b, "%s, from go generate at commit %s on %s, on:\n\n", resultsStart, commit(), time.Now().UTC().Format("2006-01-02"))
+	fmt.Fprintf(&b, "%s\n\n%s\n\n\n", strings.Join(machine, "\n"), strings.Join(results, "\n"))
+	b.Write(src[end:])
+	if err := os.WriteFile(*file, b.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// goVersion is the version of the go command, which can differ from
+// the one running this.
+func goVersion() string {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return runtime.Version()
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit is the short hash of HEAD, marked dirty if tracked files have
+// changed since.
+func commit() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "unknown"
+	}
+	hash := strings.TrimSpace(string(out))
+	if st, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output(); err == nil && len(bytes.TrimSpace(st)) > 0 {
+		hash += "-dirty"
+	}
+	return hash
+}
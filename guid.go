@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// A GUID is a UUID laid out as Windows' GUID struct.  The fields hold
+// the same numbers as the canonical string form, but Windows stores
+// Data1, Data2 and Data3 little-endian, so the raw bytes of a GUID are
+// not the raw bytes of the matching UUID.
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// GUIDFromUUID returns the GUID with the same string form as u.
+func GUIDFromUUID(u UUID) GUID {
+	g := GUID{
+		Data1: binary.BigEndian.Uint32(u[0:]),
+		Data2: binary.BigEndian.Uint16(u[4:]),
+		Data3: binary.BigEndian.Uint16(u[6:]),
+	}
+	copy(g.Data4[:], u[8:])
+	return g
+}
+
+// UUID returns the UUID with the same string form as g.
+func (g GUID) UUID() UUID {
+	u := UUID{}
+	binary.BigEndian.PutUint32(u[0:], g.Data1)
+	binary.BigEndian.PutUint16(u[4:], g.Data2)
+	binary.BigEndian.PutUint16(u[6:], g.Data3)
+	copy(u[8:], g.Data4[:])
+	return u
+}
+
+// GUIDFromBytes reads a GUID as Windows lays it out in memory, as
+// found in the registry's binary values, COM marshaling and Active
+// Directory's objectGUID.
+func GUIDFromBytes(b []byte) (GUID, error) {
+	if len(b) != 16 {
+		return GUID{}, fmt.Errorf("GUID is %d bytes, want 16", len(b))
+	}
+	g := GUID{
+		Data1: binary.LittleEndian.Uint32(b[0:]),
+		Data2: binary.LittleEndian.Uint16(b[4:]),
+		Data3: binary.LittleEndian.Uint16(b[6:]),
+	}
+	copy(g.Data4[:], b[8:])
+	return g, nil
+}
+
+// Bytes returns g as Windows lays it out in memory.
+func (g GUID) Bytes() []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint32(b[0:], g.Data1)
+	binary.LittleEndian.PutUint16(b[4:], g.Data2)
+	binary.LittleEndian.PutUint16(b[6:], g.Data3)
+	copy(b[8:], g.Data4[:])
+	return b
+}
+
+// String returns g in the braced upper case form the registry and
+// StringFromGUID2 use.
+func (g GUID) String() string {
+	return "{" + strings.ToUpper(g.UUID().String()) + "}"
+}
+
+// ParseGUID parses a GUID with or without braces, in either case.
+func ParseGUID(s string) (GUID, error) {
+	if strings.HasPrefix(s, "{") {
+		u, err := formats["braces"].decode(s)
+		return GUIDFromUUID(u), err
+	}
+	u, err := Parse(s)
+	return GUIDFromUUID(u), err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGUID(t *testing.T) {
+	u := mustParse("00112233-4455-6677-8899-aabbccddeeff")
+	g := GUIDFromUUID(u)
+	want := GUID{0x00112233, 0x4455, 0x6677, [8]byte{0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}}
+	if g != want {
+		t.Fatalf("GUIDFromUUID = %+v, want %+v", g, want)
+	}
+	if g.UUID() != u {
+		t.Errorf("UUID() = %s", g.UUID())
+	}
+	if s := g.String(); s != "{00112233-4455-6677-8899-AABBCCDDEEFF}" {
+		t.Errorf("String() = %s", s)
+	}
+
+	le := []byte{0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	if b := g.Bytes(); !bytes.Equal(b, le) {
+		t.Errorf("Bytes() = % x", b)
+	}
+	if got, err := GUIDFromBytes(le); err != nil || got != g {
+		t.Errorf("GUIDFromBytes = %+v, %v", got, err)
+	}
+	if _, err := GUIDFromBytes(le[1:]); err == nil {
+		t.Error("GUIDFromBytes of 15 bytes should fail")
+	}
+
+	for _, s := range []string{g.String(), u.String()} {
+		if got, err := ParseGUID(s); err != nil || got != g {
+			t.Errorf("ParseGUID(%s) = %+v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseGUID("{00112233-4455-6677-8899-aabbccddeeff"); err == nil {
+		t.Error("ParseGUID with an unmatched brace should fail")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// haRenewEvery is how often a server with an ha_lease renews it, if
+// it leads, or tries to take it, if it follows.
+var haRenewEvery = 2 * time.Second
+
+// issuingPaths are the paths that hand out UUIDs, which only the
+// leader serves.
+var issuingPaths = map[string]bool{
+	"/uuid":        true,
+	"/generate":    true,
+	"/stream":      true,
+	"/tenant/uuid": true,
+}
+
+// following reports whether s shares a lease that another server holds,
+// so must not hand out UUIDs.
+func (s *server) following() bool {
+	return s.lease != nil && !s.leader.Load()
+}
+
+// checkLeader is /readyz's check that the server leads, so that load
+// balancers send requests to the leader alone.
+func (s *server) checkLeader() error {
+	if s.following() {
+		return errors.New("following")
+	}
+	return nil
+}
+
+// notLeader turns away a request for UUIDs made to a follower.
+func notLeader(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "not the leader", http.StatusServiceUnavailable)
+}
+
+// runLease tends the lease every haRenewEvery, until shutdown.
+func (s *server) runLease() {
+	defer close(s.leaseDone)
+	t := time.NewTicker(haRenewEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.tendLease()
+		case <-s.leaseStop:
+			return
+		}
+	}
+}
+
+// tendLease renews the lease, stepping down if it has been lost, or
+// tries to take it, taking over if it can.
+func (s *server) tendLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), haRenewEvery)
+	defer cancel()
+	if s.leader.Load() {
+		if err := s.lease.Renew(ctx); err != nil {
+			s.leader.Store(false)
+			s.log.Error("lease lost, following", "err", err)
+		}
+		return
+	}
+	ok, err := s.lease.Acquire(ctx)
+	if err != nil {
+		s.log.Warn("lease not acquired", "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := s.takeOver(); err != nil {
+		s.log.Error("not taking over", "err", err)
+		s.lease.Release(ctx)
+		return
+	}
+	s.leader.Store(true)
+	s.log.Info("leading")
+}
+
+// takeOver readies a server that has just taken the lease to lead: it
+// starts a new generator with the clock sequence after the one the
+// last leader saved in the shared state directory, whatever the clocks
+// say, since the last leader may have been handing out UUIDs right up
+// to now, by a clock ahead of this one, and restarts the tenants'
+// generators, whose haStateStores do the same.  It then saves their
+// states, so the next leader moves on from them in turn.
+func (s *server) takeOver() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	old := s.state.Load()
+	cfg := old.cfg
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	store := FileStateStore{Path: filepath.Join(cfg.StateDir, stateGeneratorFile)}
+	saved, ok, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The new generator is made before the old one is stopped, so that
+	// if it can't be, this server stays a follower with the old one.
+	// The lock-free strategy's clock sequence is the exception: it is
+	// shared by both, and can only be set once the old one has stopped.
+	resume := ok && saved.Node == hardwareAddr
+	seq := (saved.ClockSequence + 1) & 0x3fff
+	strategy := cfg.Strategy
+	if cfg.Version == 6 {
+		strategy = "mutex"
+	}
+	lockFree := strategy == "lockfree"
+	if resume && !lockFree {
+		setClockSequence(strategy, seq)
+	}
+	st := &serverState{cfg: cfg, keys: old.keys}
+	if st.tenants, err = restartTenants(old.tenants); err != nil {
+		return err
+	}
+	if st.g, err = newGenerator(cfg.Version, cfg.Strategy, cfg.ChanSize); err != nil {
+		closeTenants(st.tenants)
+		return err
+	}
+	// nil, to stop the lock-free producer too, for its clock sequence.
+	old.close(nil)
+	closeTenants(old.tenants)
+	for name, t := range st.tenants {
+		t.carryCounts(old.tenants[name])
+	}
+	if resume && lockFree {
+		setClockSequence(strategy, seq)
+	}
+	s.state.Store(st)
+
+	var errs []error
+	if gs, ok := stateOf(st.g, hardwareAddr, clockNow()); ok {
+		errs = append(errs, store.Save(ctx, gs))
+	}
+	for name, t := range st.tenants {
+		if gs, ok := stateOf(t.g, t.node, clockNow()); ok {
+			errs = append(errs, FileStateStore{Path: filepath.Join(cfg.StateDir, tenantStateFile(name))}.Save(ctx, gs))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// haStateStore is a tenant's state store with an ha_lease.  It only
+// saves while the server leads, so that followers, which share the
+// state directory, don't overwrite the leader's state with theirs.
+// It loads the clock sequence after the one saved, with no time, so
+// that a generator starts from that whatever its clock says, for the
+// reason takeOver gives.
+type haStateStore struct {
+	FileStateStore
+	s *server
+}
+
+func (h haStateStore) Load(ctx context.Context) (GeneratorState, bool, error) {
+	st, ok, err := h.FileStateStore.Load(ctx)
+	if ok {
+		st.ClockSequence, st.Time = (st.ClockSequence+1)&0x3fff, time.Time{}
+	}
+	return st, ok, err
+}
+
+func (h haStateStore) Save(ctx context.Context, st GeneratorState) error {
+	if h.s.following() {
+		return nil
+	}
+	return h.FileStateStore.Save(ctx, st)
+}
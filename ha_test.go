@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHATakeOver(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultServerConfig()
+	cfg.Strategy, cfg.StateDir, cfg.HALease = "satori", dir, "file:"+filepath.Join(dir, "leader.lock")
+
+	// The first server leads, and the second follows.
+	a, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsA := httptest.NewServer(a)
+	defer tsA.Close()
+	b, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsB := httptest.NewServer(b)
+	defer tsB.Close()
+	defer b.shutdown()
+
+	get(t, tsA, "/uuid", http.StatusOK)
+	get(t, tsB, "/uuid", http.StatusServiceUnavailable)
+	var rd readiness
+	if err := json.Unmarshal([]byte(get(t, tsB, "/readyz", http.StatusServiceUnavailable)), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Checks["leader"] != "following" {
+		t.Errorf("follower's /readyz: %+v", rd)
+	}
+	var stats serverStats
+	if err := json.Unmarshal([]byte(get(t, tsA, "/stats", http.StatusOK)), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Role != "leader" {
+		t.Errorf("leader's role: %q", stats.Role)
+	}
+
+	// When the leader goes, the follower takes over with the clock
+	// sequence after the one it left behind.
+	if err := a.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	saved, ok, err := FileStateStore{Path: filepath.Join(dir, stateGeneratorFile)}.Load(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("leader's state: %v, %v", ok, err)
+	}
+	b.tendLease()
+	u, err := Parse(strings.TrimSpace(get(t, tsB, "/uuid", http.StatusOK)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := u.ClockSequence(); seq != (saved.ClockSequence+1)&0x3fff {
+		t.Errorf("new leader's clock sequence %d, want %d", seq, (saved.ClockSequence+1)&0x3fff)
+	}
+	get(t, tsB, "/readyz", http.StatusOK)
+}
+
+func TestHAConfig(t *testing.T) {
+	for name, set := range map[string]func(*serverConfig){
+		"no state_dir": func(cfg *serverConfig) {
+			cfg.HALease = "file:lock"
+		},
+	} {
+		cfg := defaultServerConfig()
+		set(&cfg)
+		if err := cfg.validate(); err == nil {
+			t.Errorf("%s: valid", name)
+		}
+	}
+}
+
+func TestHATakeOverFails(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultServerConfig()
+	cfg.Strategy, cfg.StateDir, cfg.HALease = "channel", dir, "file:"+filepath.Join(dir, "leader.lock")
+	a, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.shutdown()
+
+	// A config newGenerator rejects, which validate would have caught,
+	// makes the follower stay one, with its generator still running.
+	old := b.state.Load()
+	bad := old.cfg
+	bad.Strategy = "bogus"
+	st := &serverState{cfg: bad, g: old.g, keys: old.keys}
+	b.state.Store(st)
+	if err := a.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	b.tendLease()
+	if !b.following() || b.state.Load() != st {
+		t.Fatal("took over without a generator")
+	}
+	held := b.acquire()
+	if err := SelfTest(held.g); err != nil {
+		t.Error(err)
+	}
+	held.release()
+	b.state.Store(old)
+
+	// It let go of the lease for another to take.
+	l := &FileLease{Path: filepath.Join(dir, "leader.lock")}
+	if ok, err := l.Acquire(context.Background()); !ok || err != nil {
+		t.Errorf("lease still held: %v, %v", ok, err)
+	}
+	l.Release(context.Background())
+}
+
+func TestHATenants(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "keys")
+	os.WriteFile(keysFile, []byte("alice 0123456789abcdef\n"), 0o600)
+	tenantsFile := filepath.Join(dir, "tenants.json")
+	os.WriteFile(tenantsFile, []byte(`{"alice": {"node": "02:00:00:00:00:0a"}}`), 0o600)
+	cfg := defaultServerConfig()
+	cfg.Strategy, cfg.StateDir, cfg.HALease = "satori", dir, "file:"+filepath.Join(dir, "leader.lock")
+	cfg.APIKeysFile, cfg.TenantsFile = keysFile, tenantsFile
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	a, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.shutdown()
+
+	store := FileStateStore{Path: filepath.Join(dir, tenantStateFile("alice"))}
+	ctx := context.Background()
+	leaders, _, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, _ := a.state.Load().tenants["alice"].g.New().ClockSequence()
+	if leaders.ClockSequence != seq {
+		t.Fatalf("leader saved clock sequence %d, uses %d", leaders.ClockSequence, seq)
+	}
+
+	// The follower's tenant doesn't save over the leader's state.
+	if err := b.state.Load().tenants["alice"].g.(*persistedGenerator).save(); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _, _ := store.Load(ctx); saved != leaders {
+		t.Errorf("follower saved %+v over %+v", saved, leaders)
+	}
+
+	// Taking over, it moves on from the leader's clock sequence.
+	if err := a.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	b.tendLease()
+	if b.following() {
+		t.Fatal("didn't take over")
+	}
+	want := (leaders.ClockSequence + 1) & 0x3fff
+	if seq, _ := b.state.Load().tenants["alice"].g.New().ClockSequence(); seq != want {
+		t.Errorf("new leader's tenant clock sequence %d, want %d", seq, want)
+	}
+	if saved, _, _ := store.Load(ctx); saved.ClockSequence != want {
+		t.Errorf("new leader saved clock sequence %d, want %d", saved.ClockSequence, want)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entropyTimeout is how long /readyz waits for crypto/rand, which can
+// block early in boot, before declaring it unavailable.
+const entropyTimeout = time.Second
+
+// checkEntropy reads a little from crypto/rand, failing if that takes
+// too long or comes back all zeros.
+func checkEntropy() error {
+	done := make(chan error, 1)
+	go func() {
+		var buf [16]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			done <- err
+			return
+		}
+		if buf == [16]byte{} {
+			done <- errors.New("crypto/rand returned all zeros")
+			return
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(entropyTimeout):
+		return fmt.Errorf("crypto/rand took more than %s", entropyTimeout)
+	}
+}
+
+// clockGuard notices the wall clock going backwards, comparing it with
+// the latest time it has seen, which it keeps in a file, if it has
+// one, so that it carries over restarts.
+type clockGuard struct {
+	file    string
+	maxBack time.Duration
+	nowFunc func() time.Time
+
+	mu   sync.Mutex
+	high time.Time
+}
+
+// newClockGuard returns a clockGuard that complains about the clock
+// going back more than maxBack, loading the latest time from file if
+// that exists.
+func newClockGuard(file string, maxBack time.Duration, nowFunc func() time.Time) (*clockGuard, error) {
+	g := &clockGuard{file: file, maxBack: maxBack, nowFunc: nowFunc}
+	if file == "" {
+		return g, nil
+	}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g.high, err = time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b))); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return g, nil
+}
+
+// check compares the clock with the latest time seen, and records it
+// if it is later.
+func (g *clockGuard) check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.nowFunc()
+	if back := g.high.Sub(now); back > g.maxBack {
+		return fmt.Errorf("clock is %s behind the latest time seen, %s", back, g.high.Format(time.RFC3339Nano))
+	}
+	return g.record(now)
+}
+
+// flush records the time now, if it is the latest, so that the next
+// run compares the clock with when this one stopped.
+func (g *clockGuard) flush() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.record(g.nowFunc())
+}
+
+// latest returns the latest time seen.
+func (g *clockGuard) latest() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.high
+}
+
+// record makes now the latest time seen, if it is.  g.mu must be held.
+func (g *clockGuard) record(now time.Time) error {
+	if !now.After(g.high) {
+		return nil
+	}
+	g.high = now
+	if g.file == "" {
+		return nil
+	}
+	return os.WriteFile(g.file, []byte(now.Format(time.RFC3339Nano)+"\n"), 0o644)
+}
+
+// readiness is the body of a /readyz response.  Checks maps each
+// check to "ok" or what went wrong.
+type readiness struct {
+	Ready    bool              `json:"ready"`
+	Node     string            `json:"node,omitempty"`
+	Version  int               `json:"version"`
+	Strategy string            `json:"strategy,omitempty"`
+	Checks   map[string]string `json:"checks"`
+}
+
+// handleHealthz says the process is up, and nothing more.
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz says whether this node should be handed requests: not
+// if it can't get entropy, its clock has gone backwards, its generator
+// fails SelfTest, or it is following another server's lead.
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	cfg := s.state.Load().cfg
+	rd := readiness{
+		Ready:   true,
+		Version: cfg.Version,
+		Checks:  map[string]string{},
+	}
+	if cfg.Version == 1 || cfg.Version == 8 {
+		rd.Strategy = cfg.Strategy
+	}
+	// Versions 1 and 6 embed the node ID.
+	if cfg.Version == 1 || cfg.Version == 6 {
+		rd.Node = net.HardwareAddr(hardwareAddr[:]).String()
+	}
+
+	checks := map[string]func() error{
+		"entropy": checkEntropy,
+		"clock":   s.clock.check,
+		"selftest": func() error {
+			st := s.acquire()
+			defer st.release()
+			return SelfTest(st.g)
+		},
+	}
+	if s.lease != nil {
+		checks["leader"] = s.checkLeader
+	}
+	for name, check := range checks {
+		rd.Checks[name] = "ok"
+		if err := check(); err != nil {
+			rd.Checks[name] = err.Error()
+			rd.Ready = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !rd.Ready {
+		s.log.Warn("not ready", "checks", rd.Checks)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(rd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A BlockStore hands out blocks of sequence numbers, shared by every
+// process using the same store.
+type BlockStore interface {
+	// Reserve returns the first of n sequence numbers that no other
+	// call, in this process or any other, has been or will be given.
+	Reserve(n uint64) (uint64, error)
+}
+
+// HiLoGenerator hands out unique 64 bit IDs, only going to its store
+// once per block of them.  IDs are unique across every generator
+// sharing a store, but only roughly ordered: each generator works
+// through its own block, and IDs left in a block when a process exits
+// are never used.
+type HiLoGenerator struct {
+	mu        sync.Mutex
+	store     BlockStore
+	blockSize uint64
+	next, end uint64
+}
+
+// NewHiLoGenerator returns a HiLoGenerator reserving blockSize IDs at
+// a time from store.
+func NewHiLoGenerator(store BlockStore, blockSize uint64) (*HiLoGenerator, error) {
+	if blockSize < 1 {
+		return nil, errors.New("hilo block size must be at least 1")
+	}
+	return &HiLoGenerator{store: store, blockSize: blockSize}, nil
+}
+
+// Next returns the next ID, reserving a new block first if the current
+// one is used up.
+func (g *HiLoGenerator) Next() (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.next == g.end {
+		start, err := g.store.Reserve(g.blockSize)
+		if err != nil {
+			return 0, err
+		}
+		g.next, g.end = start, start+g.blockSize
+	}
+	id := g.next
+	g.next++
+	return id, nil
+}
+
+// FileBlockStore keeps the next free sequence number in a file, locked
+// while it is updated so that processes on one machine can share it.
+// Sequence numbers start at 1.
+type FileBlockStore struct {
+	Name string
+}
+
+func (s FileBlockStore) Reserve(n uint64) (uint64, error) {
+	f, err := os.OpenFile(s.Name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return 0, fmt.Errorf("locking %s: %v", s.Name, err)
+	}
+	defer unlockFile(f)
+
+	b, err := os.ReadFile(s.Name)
+	if err != nil {
+		return 0, err
+	}
+	start := uint64(1)
+	if text := strings.TrimSpace(string(b)); text != "" {
+		if start, err = strconv.ParseUint(text, 10, 64); err != nil {
+			return 0, fmt.Errorf("%s: %v", s.Name, err)
+		}
+	}
+	if start+n < start {
+		return 0, fmt.Errorf("%s: sequence numbers used up", s.Name)
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt([]byte(strconv.FormatUint(start+n, 10)+"\n"), 0); err != nil {
+		return 0, err
+	}
+	// Once the block is handed out it must never be handed out again,
+	// so the new value has to be on disk first.
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return start, nil
+}
+
+// SQLBlockStore keeps the next free sequence number for each Name in a
+// table, for sharing a sequence across machines.  The table needs
+// creating first:
+//
+//	CREATE TABLE hilo (name TEXT PRIMARY KEY, next BIGINT NOT NULL);
+//	INSERT INTO hilo VALUES ('orders', 1);
+//
+// The query uses Postgres placeholders and RETURNING.
+type SQLBlockStore struct {
+	DB   *sql.DB
+	Name string
+}
+
+func (s SQLBlockStore) Reserve(n uint64) (uint64, error) {
+	// A single UPDATE is atomic, so there is no need for a transaction.
+	var next uint64
+	err := s.DB.QueryRow(`UPDATE hilo SET next = next + $1 WHERE name = $2 RETURNING next`, n, s.Name).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("no hilo row named %q", s.Name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return next - n, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisTimeout bounds each of RedisBlockStore's round trips.
+const redisTimeout = 5 * time.Second
+
+// RedisBlockStore keeps the next free sequence number in a Redis key,
+// for sharing a sequence across machines without a database.  It
+// speaks just enough of Redis's protocol for INCRBY, which is atomic,
+// so it needs nothing but net.  Sequence numbers start at 1.
+type RedisBlockStore struct {
+	// Addr is the server's host:port, such as localhost:6379.
+	Addr string
+	Key  string
+	// Password, if set, is sent with AUTH first.
+	Password string
+}
+
+func (s RedisBlockStore) Reserve(n uint64) (uint64, error) {
+	if n > 1<<63-1 {
+		return 0, fmt.Errorf("redis %s: block of %d too big", s.Key, n)
+	}
+	conn, err := net.DialTimeout("tcp", s.Addr, redisTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+	r := bufio.NewReader(conn)
+
+	if s.Password != "" {
+		if _, err := redisCall(conn, r, "AUTH", s.Password); err != nil {
+			return 0, err
+		}
+	}
+	reply, err := redisCall(conn, r, "INCRBY", s.Key, strconv.FormatUint(n, 10))
+	if err != nil {
+		return 0, fmt.Errorf("redis %s: %v", s.Key, err)
+	}
+	next, err := strconv.ParseInt(reply, 10, 64)
+	if err != nil || next < int64(n) {
+		return 0, fmt.Errorf("redis %s: INCRBY returned %q", s.Key, reply)
+	}
+	// The key holds the last number handed out, so a missing one, which
+	// INCRBY takes as 0, starts the sequence at 1.
+	return uint64(next) - n + 1, nil
+}
+
+// redisCall sends a command and returns its reply, which must be a
+// simple string or an integer.
+func redisCall(conn net.Conn, r *bufio.Reader, args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	}
+	return "", fmt.Errorf("unexpected reply %q", line)
+}
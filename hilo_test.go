@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHiLoGenerator(t *testing.T) {
+	store := FileBlockStore{filepath.Join(t.TempDir(), "seq")}
+
+	// Several generators sharing one file, as if they were separate
+	// processes.
+	const gens, perGen = 8, 1024
+	results := make([][]uint64, gens)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g, err := NewHiLoGenerator(store, 64)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for n := 0; n < perGen; n++ {
+				id, err := g.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[i] = append(results[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for _, ids := range results {
+		for _, id := range ids {
+			if id == 0 || seen[id] {
+				t.Fatalf("got %d twice, or 0", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	// Each generator used exactly 16 blocks of 64, so the next block
+	// starts right after all of them.
+	next, err := store.Reserve(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != gens*perGen+1 {
+		t.Errorf("next block starts at %d", next)
+	}
+}
+
+func TestHiLoBlockSize(t *testing.T) {
+	if _, err := NewHiLoGenerator(FileBlockStore{filepath.Join(t.TempDir(), "seq")}, 0); err == nil {
+		t.Error("block size 0 accepted")
+	}
+}
+
+func TestRedisBlockStore(t *testing.T) {
+	addr := fakeRedis(t, "secret")
+	g, err := NewHiLoGenerator(RedisBlockStore{Addr: addr, Key: "orders", Password: "secret"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := uint64(1); want <= 7; want++ {
+		if id, err := g.Next(); id != want || err != nil {
+			t.Fatalf("Next() = %d, %v, want %d", id, err, want)
+		}
+	}
+	// The generator took three blocks of 3.
+	if start, err := (RedisBlockStore{Addr: addr, Key: "orders", Password: "secret"}).Reserve(1); start != 10 || err != nil {
+		t.Errorf("Reserve(1) = %d, %v, want 10", start, err)
+	}
+	if _, err := (RedisBlockStore{Addr: addr, Key: "orders"}).Reserve(1); err == nil {
+		t.Error("Reserve without the password worked")
+	}
+}
+
+// fakeRedis serves just AUTH and INCRBY, for want of Redis, returning
+// its address.
+func fakeRedis(t *testing.T, password string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	keys := map[string]int64{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				authed := false
+				for {
+					args, err := readRedisCommand(r)
+					if err != nil {
+						return
+					}
+					var reply string
+					switch {
+					case args[0] == "AUTH" && len(args) == 2:
+						authed = args[1] == password
+						reply = "+OK"
+						if !authed {
+							reply = "-WRONGPASS invalid password"
+						}
+					case args[0] == "INCRBY" && len(args) == 3 && !authed:
+						reply = "-NOAUTH Authentication required."
+					case args[0] == "INCRBY" && len(args) == 3:
+						n, _ := strconv.ParseInt(args[2], 10, 64)
+						mu.Lock()
+						keys[args[1]] += n
+						reply = fmt.Sprintf(":%d", keys[args[1]])
+						mu.Unlock()
+					default:
+						reply = "-ERR unknown command"
+					}
+					fmt.Fprintf(conn, "%s\r\n", reply)
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// readRedisCommand reads a command sent as an array of bulk strings.
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // the length
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
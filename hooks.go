@@ -0,0 +1,66 @@
+package main
+
+import "time"
+
+// Hooks are called when a generator runs into something unusual, so
+// that applications can log or alert on it without this package
+// choosing how.  Any of them may be nil.  They are called after the
+// generator has let go of its lock, but before the UUID is returned,
+// so they should be quick.
+type Hooks struct {
+	// OnRollback is called when the clock has gone backwards since
+	// the last UUID, with how far.
+	OnRollback func(by time.Duration)
+	// OnSequenceWrap is called when a tick's worth of sequence
+	// numbers runs out and the generator borrows the next tick.
+	OnSequenceWrap func()
+	// OnSlowEntropy is called when reading crypto/rand takes longer
+	// than SlowEntropy, with how long it took.
+	OnSlowEntropy func(took time.Duration)
+	// SlowEntropy defaults to 10ms.
+	SlowEntropy time.Duration
+}
+
+// WithHooks sets the hooks a V7Generator calls.
+func WithHooks(h Hooks) V7Option {
+	return func(g *V7Generator) {
+		g.hooks = h
+	}
+}
+
+// hookEvents saves up what happened during one call, so that the
+// hooks can be called once the lock is released.
+type hookEvents struct {
+	rolledBack  time.Duration
+	wrapped     bool
+	slowEntropy time.Duration
+}
+
+// random is randomBits, timed if there is an OnSlowEntropy hook.
+func (h *Hooks) random(bits uint, e *hookEvents) uint64 {
+	if h.OnSlowEntropy == nil {
+		return randomBits(bits)
+	}
+	start := time.Now()
+	r := randomBits(bits)
+	slow := h.SlowEntropy
+	if slow == 0 {
+		slow = 10 * time.Millisecond
+	}
+	if took := time.Since(start); took > slow && took > e.slowEntropy {
+		e.slowEntropy = took
+	}
+	return r
+}
+
+func (h *Hooks) fire(e hookEvents) {
+	if e.rolledBack > 0 && h.OnRollback != nil {
+		h.OnRollback(e.rolledBack)
+	}
+	if e.wrapped && h.OnSequenceWrap != nil {
+		h.OnSequenceWrap()
+	}
+	if e.slowEntropy > 0 {
+		h.OnSlowEntropy(e.slowEntropy)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestV7Hooks(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	var rollbacks []time.Duration
+	wraps, slow := 0, 0
+	g := newV7Generator(func() time.Time { return now },
+		WithV7Method(V7Counter),
+		WithHooks(Hooks{
+			OnRollback:     func(by time.Duration) { rollbacks = append(rollbacks, by) },
+			OnSequenceWrap: func() { wraps++ },
+			OnSlowEntropy:  func(time.Duration) { slow++ },
+			// So that every read counts as slow.
+			SlowEntropy: time.Nanosecond,
+		}))
+
+	// Counters start below 2048, so 5000 UUIDs in one millisecond
+	// has to wrap at least once.
+	for i := 0; i < 5000; i++ {
+		g.New()
+	}
+	if wraps == 0 {
+		t.Error("no sequence wraps")
+	}
+	if slow == 0 {
+		t.Error("no slow entropy")
+	}
+
+	now = now.Add(-3 * time.Millisecond)
+	g.New()
+	if len(rollbacks) != 1 || rollbacks[0] != 3*time.Millisecond {
+		t.Errorf("rollbacks %v", rollbacks)
+	}
+}
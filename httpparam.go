@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// A ParamSource looks up a router's path parameter by name, returning
+// "" if there isn't one, so that RequireUUIDs works with any router.
+// PathValue is the one for net/http's ServeMux.  For chi it is
+//
+//	func(r *http.Request, name string) string { return chi.URLParam(r, name) }
+//
+// and for gorilla/mux
+//
+//	func(r *http.Request, name string) string { return mux.Vars(r)[name] }
+type ParamSource func(r *http.Request, name string) string
+
+// PathValue is the ParamSource for ServeMux patterns such as
+// "GET /orders/{id}".
+func PathValue(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
+
+type uuidParamsKey struct{}
+
+// RequireUUIDs returns middleware that checks the named parameters are
+// UUIDs before the handler runs, answering 400 Bad Request if any is
+// missing or malformed.  Each is looked for in the path, using src,
+// or nil for PathValue, and then in the query string.  Handlers get
+// the parsed UUIDs with UUIDParam.
+//
+// With a router that only sets path parameters once it has matched a
+// route, such as chi, the middleware must go on the route rather than
+// around the router.
+func RequireUUIDs(src ParamSource, names ...string) func(http.Handler) http.Handler {
+	if src == nil {
+		src = PathValue
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params := make(map[string]UUID, len(names))
+			for _, name := range names {
+				s := src(r, name)
+				if s == "" {
+					s = r.URL.Query().Get(name)
+				}
+				if s == "" {
+					http.Error(w, fmt.Sprintf("missing %s", name), http.StatusBadRequest)
+					return
+				}
+				u, err := Parse(s)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("%s: %v", name, err), http.StatusBadRequest)
+					return
+				}
+				params[name] = u
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), uuidParamsKey{}, params)))
+		})
+	}
+}
+
+// UUIDParam returns the UUID parameter name of r: the one RequireUUIDs
+// checked, or failing that, the ServeMux path parameter or the query
+// parameter of that name, parsed.
+func UUIDParam(r *http.Request, name string) (UUID, error) {
+	if params, ok := r.Context().Value(uuidParamsKey{}).(map[string]UUID); ok {
+		if u, ok := params[name]; ok {
+			return u, nil
+		}
+	}
+	s := r.PathValue(name)
+	if s == "" {
+		s = r.URL.Query().Get(name)
+	}
+	if s == "" {
+		return UUID{}, fmt.Errorf("missing %s", name)
+	}
+	return Parse(s)
+}
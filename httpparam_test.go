@@ -0,0 +1,65 @@
+// Built without a go.mod, the default GODEBUG is Go 1.20's, whose
+// ServeMux has no patterns.
+//go:debug httpmuxgo121=0
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireUUIDs(t *testing.T) {
+	order, item := NewV4(), NewV7()
+	show := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o, err := UUIDParam(r, "order")
+		if err != nil {
+			t.Error(err)
+		}
+		i, err := UUIDParam(r, "item")
+		if err != nil {
+			t.Error(err)
+		}
+		fmt.Fprintf(w, "%s %s", o, i)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /orders/{order}", RequireUUIDs(nil, "order", "item")(show))
+	// A gorilla style router that keeps its variables somewhere else.
+	vars := map[string]string{"order": order.String()}
+	other := RequireUUIDs(func(r *http.Request, name string) string { return vars[name] }, "order", "item")(show)
+
+	tests := []struct {
+		h          http.Handler
+		path       string
+		wantStatus int
+	}{
+		{mux, "/orders/" + order.String() + "?item=" + item.String(), http.StatusOK},
+		{other, "/anything?item=" + item.String(), http.StatusOK},
+		{mux, "/orders/" + order.String(), http.StatusBadRequest},
+		{mux, "/orders/nope?item=" + item.String(), http.StatusBadRequest},
+		{mux, "/orders/" + order.String() + "?item=" + item.String()[1:], http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d: %s", tt.path, w.Code, tt.wantStatus, w.Body)
+			continue
+		}
+		if want := order.String() + " " + item.String(); w.Code == http.StatusOK && w.Body.String() != want {
+			t.Errorf("%s: %q, want %q", tt.path, w.Body, want)
+		}
+	}
+
+	// Without the middleware.
+	r := httptest.NewRequest("GET", "/?id="+order.String(), nil)
+	if u, err := UUIDParam(r, "id"); err != nil || u != order {
+		t.Errorf("UUIDParam: %s, %v", u, err)
+	}
+	if _, err := UUIDParam(r, "other"); err == nil {
+		t.Error("missing parameter found")
+	}
+}
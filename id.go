@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An ID is any of the kinds of ID this package knows about, so that
+// tools can handle them without knowing which they have.
+type ID interface {
+	String() string
+	// Bytes returns the ID's binary form, big-endian so that byte
+	// order is sort order.
+	Bytes() []byte
+	// Time returns the time embedded in the ID.  ok is false if there
+	// isn't one.
+	Time() (t time.Time, ok bool)
+}
+
+// CompareIDs returns -1, 0 or 1 as a sorts before, the same as, or
+// after b, by their binary forms.  It is a function rather than an ID
+// method because UUID.Compare already takes a UUID.
+func CompareIDs(a, b ID) int {
+	return bytes.Compare(a.Bytes(), b.Bytes())
+}
+
+// A Family makes and parses one kind of ID.
+type Family struct {
+	New   func() ID
+	Parse func(s string) (ID, error)
+}
+
+var (
+	familiesMu sync.RWMutex
+	families   = map[string]Family{}
+)
+
+// RegisterFamily makes f available by name.  Like sql.Register, it
+// panics if name is already taken.
+func RegisterFamily(name string, f Family) {
+	familiesMu.Lock()
+	defer familiesMu.Unlock()
+	if _, dup := families[name]; dup {
+		panic(fmt.Sprintf("RegisterFamily called twice for %q", name))
+	}
+	families[name] = f
+}
+
+// LookupFamily returns the family registered as name.
+func LookupFamily(name string) (Family, bool) {
+	familiesMu.RLock()
+	defer familiesMu.RUnlock()
+	f, ok := families[name]
+	return f, ok
+}
+
+// Families returns the registered family names, sorted.
+func Families() []string {
+	familiesMu.RLock()
+	defer familiesMu.RUnlock()
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Bytes returns a copy of u's 16 bytes.
+func (u UUID) Bytes() []byte {
+	b := u
+	return b[:]
+}
+
+func parseUUIDID(s string) (ID, error) {
+	return Parse(s)
+}
+
+func init() {
+	for _, version := range []int{1, 4, 6, 7} {
+		g, _ := newGenerator(version, "mutex", 0)
+		RegisterFamily(fmt.Sprintf("uuid%d", version), Family{
+			New:   func() ID { return g.New() },
+			Parse: parseUUIDID,
+		})
+	}
+}
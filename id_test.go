@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFamilies(t *testing.T) {
+	for _, name := range Families() {
+		f, _ := LookupFamily(name)
+		a := f.New()
+		got, err := f.Parse(a.String())
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if CompareIDs(a, got) != 0 {
+			t.Errorf("%s: %s parsed as %s", name, a, got)
+		}
+		if ts, ok := a.Time(); ok && time.Since(ts) > time.Minute {
+			t.Errorf("%s: %s has time %s", name, a, ts)
+		}
+	}
+	if _, ok := LookupFamily("ksuid"); !ok {
+		t.Error("ksuid is not registered")
+	}
+}
+
+func TestKSUID(t *testing.T) {
+	// From the KSUID README.
+	const s = "0ujtsYcgvSTl8PAuAdqWYSMnLOv"
+	id, err := ParseKSUID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != s {
+		t.Errorf("round trip gave %s", id)
+	}
+	if ts, _ := id.Time(); ts.UTC().Format(time.RFC3339) != "2017-10-10T04:00:47Z" {
+		t.Errorf("time %s", ts.UTC())
+	}
+
+	max := KSUID{}
+	for i := range max {
+		max[i] = 0xff
+	}
+	if max.String() != "aWgEPTl1tmebfsQzFP4bxwgy80V" {
+		t.Errorf("max KSUID is %s", max)
+	}
+	if _, err := ParseKSUID("aWgEPTl1tmebfsQzFP4bxwgy80W"); err == nil {
+		t.Error("parsing more than 160 bits should fail")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Conversions between the 128 bit families.  Each says whether it is
+// lossless: a lossless conversion can be undone to get back exactly
+// what went in, and one that would lose bits returns an error rather
+// than quietly dropping them.
+//
+//	UUID  -> ULID   lossless, the same 16 bytes
+//	ULID  -> UUID   lossless, the same 16 bytes, though seldom a valid
+//	                version; ULIDToV7 for one that is
+//	ULID  -> V7     errors unless the version and variant bits already
+//	                say V7, since setting them would overwrite 6 random
+//	                bits
+//	UUID  -> KSUID  lossless, the UUID as the payload under a timestamp
+//	                taken from it; see UUIDToKSUID
+//	KSUID -> UUID   errors unless the KSUID is such an embedding, since
+//	                otherwise 32 bits wouldn't fit
+//
+// xid isn't a family here, so there is nothing to convert it to or
+// from.
+
+// errLossy is wrapped by the errors of conversions that would lose
+// bits.
+var errLossy = errors.New("conversion would lose bits")
+
+// UUIDToULID returns u's bytes as a ULID.  For a V7 UUID the ULID has
+// the same time; for other versions its time is meaningless.
+func UUIDToULID(u UUID) ULID {
+	return ULID(u)
+}
+
+// ULIDToUUID returns id's bytes as a UUID.  Its version and variant
+// bits are whatever id's random bits happened to be, so it is seldom
+// a valid UUID; use ULIDToV7 for that.
+func ULIDToUUID(id ULID) UUID {
+	return UUID(id)
+}
+
+// ULIDToV7 returns id as a V7 UUID with the same time and random bits.
+// It fails if that would change id, which it does for all but about 1
+// in 64 ULIDs: only those that happen to have the V7 version and
+// variant bits convert.
+func ULIDToV7(id ULID) (UUID, error) {
+	u := UUID(id)
+	u.SetVersion(7)
+	u.SetVariant()
+	if u != UUID(id) {
+		return UUID{}, fmt.Errorf("ULID %s to V7 UUID: %w", id, errLossy)
+	}
+	return u, nil
+}
+
+// UUIDToKSUID embeds u in a KSUID: u is the KSUID's 128 bit payload,
+// and its timestamp is u's time to the second, so that the KSUIDs of
+// time based UUIDs sort by time.  UUIDs without a time, or with one
+// before 2014 or after 2150 that KSUIDs can't hold, get a timestamp of
+// 0.
+func UUIDToKSUID(u UUID) KSUID {
+	id := KSUID{}
+	binary.BigEndian.PutUint32(id[0:], ksuidStamp(u))
+	copy(id[4:], u[:])
+	return id
+}
+
+// KSUIDToUUID returns the UUID embedded in id by UUIDToKSUID.  It
+// fails for any other KSUID, whose timestamp would be lost.
+func KSUIDToUUID(id KSUID) (UUID, error) {
+	u := UUID{}
+	copy(u[:], id[4:])
+	if binary.BigEndian.Uint32(id[0:]) != ksuidStamp(u) {
+		return UUID{}, fmt.Errorf("KSUID %s to UUID: not an embedded UUID: %w", id, errLossy)
+	}
+	return u, nil
+}
+
+// ksuidStamp returns the KSUID timestamp UUIDToKSUID gives u.
+func ksuidStamp(u UUID) uint32 {
+	t, ok := u.Time()
+	if !ok {
+		return 0
+	}
+	s := t.Unix() - ksuidEpoch
+	if s < 0 || s > 1<<32-1 {
+		return 0
+	}
+	return uint32(s)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestConvertLossless(t *testing.T) {
+	ulid := func(u UUID) bool {
+		return ULIDToUUID(UUIDToULID(u)) == u
+	}
+	ksuid := func(u UUID) bool {
+		back, err := KSUIDToUUID(UUIDToKSUID(u))
+		return err == nil && back == u
+	}
+	for name, f := range map[string]any{"ulid": ulid, "ksuid": ksuid} {
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// The time based versions keep their times, to each format's
+	// precision.
+	for _, u := range []UUID{NewV1(), NewV6(), NewV7()} {
+		want, _ := u.Time()
+		if got, _ := UUIDToKSUID(u).Time(); !got.Equal(want.Truncate(time.Second)) {
+			t.Errorf("V%d KSUID time %s, want %s", u.Version(), got, want)
+		}
+	}
+	u := NewV7()
+	want, _ := u.Time()
+	if got, _ := UUIDToULID(u).Time(); !got.Equal(want) {
+		t.Errorf("ULID time %s, want %s", got, want)
+	}
+	if back, err := ULIDToV7(UUIDToULID(u)); err != nil || back != u {
+		t.Errorf("V7 to ULID and back: %s, %v", back, err)
+	}
+}
+
+func TestConvertLossy(t *testing.T) {
+	v7 := func(id ULID) bool {
+		u, err := ULIDToV7(id)
+		if err != nil {
+			return errors.Is(err, errLossy)
+		}
+		return UUIDToULID(u) == id
+	}
+	ksuid := func(id KSUID) bool {
+		u, err := KSUIDToUUID(id)
+		if err != nil {
+			return errors.Is(err, errLossy)
+		}
+		return UUIDToKSUID(u) == id
+	}
+	for name, f := range map[string]any{"v7": v7, "ksuid": ksuid} {
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// Most freshly made ones have bits that don't fit.
+	failed := 0
+	for i := 0; i < 100; i++ {
+		if _, err := ULIDToV7(NewULID()); err != nil {
+			failed++
+		}
+	}
+	if failed < 80 {
+		t.Errorf("only %d of 100 ULIDs failed to convert to V7", failed)
+	}
+	if _, err := KSUIDToUUID(NewKSUID()); err == nil {
+		t.Error("converted a random KSUID")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// NamespaceIdempotency is the namespace of idempotency keys, so that
+// they can't collide with other name based UUIDs of the same names.
+var NamespaceIdempotency = NewV5(NamespaceURL, "https://github.com/ginabythebay/go-notes/idempotency")
+
+// MaxIdempotencyPayload is the largest payload IdempotencyKey hashes
+// itself.  Anything bigger is likely to be a request body, whose bytes
+// can differ between retries of the same request, in field order or
+// white space, so the caller should hash a canonical form of it and
+// use IdempotencyKeyHashed.
+const MaxIdempotencyPayload = 4 << 10
+
+// IdempotencyKey returns the idempotency key for a small payload: see
+// IdempotencyKeyHashed.  It fails for payloads over
+// MaxIdempotencyPayload.
+func IdempotencyKey(version byte, tenant, operation string, payload []byte) (UUID, error) {
+	if len(payload) > MaxIdempotencyPayload {
+		return UUID{}, fmt.Errorf("payload is %d bytes, more than %d: hash a canonical form of it with SHA-256 and use IdempotencyKeyHashed", len(payload), MaxIdempotencyPayload)
+	}
+	return IdempotencyKeyHashed(version, tenant, operation, sha256.Sum256(payload))
+}
+
+// IdempotencyKeyHashed returns a UUID that is always the same for the
+// same tenant, operation and payload, and different otherwise, for
+// use as an idempotency key: a retried request gets the key of the
+// original.  payloadHash is the SHA-256 of the payload.  version is 5,
+// for SHA-1, or 8, for SHA-256 in the same 122 bits.
+//
+// The name hashed in NamespaceIdempotency is the tenant and operation,
+// each as a uvarint length followed by its bytes, then payloadHash, so
+// that no two different inputs serialize alike, and other languages
+// can make the same keys.
+func IdempotencyKeyHashed(version byte, tenant, operation string, payloadHash [sha256.Size]byte) (UUID, error) {
+	if tenant == "" || operation == "" {
+		return UUID{}, errors.New("idempotency keys need a tenant and an operation")
+	}
+	name := make([]byte, 0, 2*binary.MaxVarintLen64+len(tenant)+len(operation)+len(payloadHash))
+	name = binary.AppendUvarint(name, uint64(len(tenant)))
+	name = append(name, tenant...)
+	name = binary.AppendUvarint(name, uint64(len(operation)))
+	name = append(name, operation...)
+	name = append(name, payloadHash[:]...)
+
+	switch version {
+	case 5:
+		return NewV5(NamespaceIdempotency, string(name)), nil
+	case 8:
+		u := newFromHash(sha256.New(), NamespaceIdempotency, string(name))
+		u.SetVersion(8)
+		u.SetVariant()
+		return u, nil
+	}
+	return UUID{}, fmt.Errorf("idempotency keys are version 5 or 8, not %d", version)
+}
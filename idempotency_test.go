@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	for _, version := range []byte{5, 8} {
+		key, err := IdempotencyKey(version, "acme", "charge", []byte(`{"amount":100}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Version() != version {
+			t.Errorf("version %d key %s", version, key)
+		}
+		// The same request again gets the same key.
+		again, _ := IdempotencyKeyHashed(version, "acme", "charge", sha256.Sum256([]byte(`{"amount":100}`)))
+		if again != key {
+			t.Errorf("version %d: %s then %s", version, key, again)
+		}
+
+		// Anything else different gets a different one, including
+		// where the boundary between tenant and operation falls.
+		for _, other := range [][2]string{{"acme", "refund"}, {"acmec", "harge"}, {"acm", "echarge"}, {"other", "charge"}} {
+			k, _ := IdempotencyKey(version, other[0], other[1], []byte(`{"amount":100}`))
+			if k == key {
+				t.Errorf("version %d: %v has the same key", version, other)
+			}
+		}
+		if k, _ := IdempotencyKey(version, "acme", "charge", []byte(`{"amount":101}`)); k == key {
+			t.Errorf("version %d: another payload has the same key", version)
+		}
+	}
+
+	// Pinned, since keys are stored and must never change.  Python's
+	// uuid and hashlib agree.
+	key, _ := IdempotencyKey(5, "acme", "charge", nil)
+	if got, want := key.String(), "9998350e-be75-52b3-bc24-ac0353c80c86"; got != want {
+		t.Errorf("key %s, want %s", got, want)
+	}
+
+	big := bytes.Repeat([]byte("x"), MaxIdempotencyPayload+1)
+	if _, err := IdempotencyKey(5, "acme", "charge", big); err == nil || !strings.Contains(err.Error(), "IdempotencyKeyHashed") {
+		t.Errorf("big payload: %v", err)
+	}
+	if _, err := IdempotencyKey(5, "", "charge", nil); err == nil {
+		t.Error("key without a tenant")
+	}
+	if _, err := IdempotencyKey(4, "acme", "charge", nil); err == nil {
+		t.Error("version 4 key")
+	}
+}
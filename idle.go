@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+// idleResult is one strategy's idle cost, as -idle reports it: the CPU
+// the process used while the generator sat there with nothing taking
+// UUIDs.  That is the channel strategy's producer filling its buffer,
+// and whatever the runtime does in the background, which the "none"
+// row, with no generator at all, shows.
+type idleResult struct {
+	Strategy string        `json:"strategy"`
+	ChanSize int           `json:"chan_size"`
+	Duration time.Duration `json:"duration_ns"`
+	CPU      time.Duration `json:"cpu_ns"`
+	// IdleCost is CPU a second of Duration.
+	IdleCost time.Duration `json:"idle_cost_ns_per_s"`
+}
+
+var errNoCPUTime = errors.New("measuring CPU time isn't supported here")
+
+// idleRun makes a generator with newGen, or none if it is nil, and
+// measures the CPU used from just before until d later, when it closes
+// the generator if it has a Close method.
+func idleRun(newGen func() Generator, d time.Duration) (idleResult, error) {
+	// Collect earlier runs' garbage now, not on this run's time.
+	runtime.GC()
+	start, ok := processCPUTime()
+	if !ok {
+		return idleResult{}, errNoCPUTime
+	}
+	began := time.Now()
+	var g Generator
+	if newGen != nil {
+		g = newGen()
+	}
+	time.Sleep(d)
+	end, _ := processCPUTime()
+	elapsed := time.Since(began)
+	if c, ok := g.(interface{ Close() }); ok {
+		c.Close()
+	}
+	return idleResult{
+		Duration: elapsed,
+		CPU:      end - start,
+		IdleCost: time.Duration(float64(end-start) / elapsed.Seconds()),
+	}, nil
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+/**
+
+Idle CPU of the channel strategy
+
+A ChanneledGenerator behind a server spends most of its life nearly
+idle: a request now and then takes a UUID or a few.  Its producer used
+to send a UUID, block until a reader took one, then wake to make the
+next, so every UUID taken cost a goroutine wake-up, however slowly
+they were taken.  Now it sleeps until the channel is down to a
+quarter full, then refills it in one go.
+
+This takes a UUID, then sleeps, from a channel of 100, and counts
+the process's CPU time, user and system, per UUID.  every-take is the
+old behavior, waking on each UUID taken; watermark is the new.  On a
+1 CPU Xeon VM:
+
+BenchmarkChannelIdleCPU/every-take   1094713 ns/op   29481 cpu-ns/op   1.000 refills/op
+BenchmarkChannelIdleCPU/watermark    1086740 ns/op   22291 cpu-ns/op   0.01335 refills/op
+
+(Medians of three.)  The 20µs sleeps take a millisecond on that VM,
+and sleeping and waking the reader is most of the CPU either way.  Of
+what's left, the producer's wake-ups drop from one a UUID to one per
+75, the gap between full and the watermark, and CPU time per UUID
+drops by about a quarter.
+
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// cpuTime returns the CPU time the process has used so far.
+func cpuTime(b *testing.B) time.Duration {
+	d, ok := processCPUTime()
+	if !ok {
+		b.Fatal(errNoCPUTime)
+	}
+	return d
+}
+
+func BenchmarkChannelIdleCPU(b *testing.B) {
+	for _, v := range []struct {
+		name     string
+		lowWater func(size int) int
+	}{
+		{"every-take", func(size int) int { return size - 1 }},
+		{"watermark", func(size int) int { return size / 4 }},
+	} {
+		b.Run(v.name, func(b *testing.B) {
+			g := NewChanneledGenerator(100)
+			defer g.Close()
+			g.lowWater = v.lowWater(cap(g.ch))
+			start := cpuTime(b)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.NewV1()
+				time.Sleep(20 * time.Microsecond)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(cpuTime(b)-start)/float64(b.N), "cpu-ns/op")
+			b.ReportMetric(float64(g.Stats().Refills)/float64(b.N), "refills/op")
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+)
+
+var versionNames = map[byte]string{
+	1: "time based",
+	2: "DCE security",
+	3: "name based, MD5",
+	4: "random",
+	5: "name based, SHA-1",
+	6: "reordered time based",
+	7: "Unix time based",
+	8: "custom",
+}
+
+var variantNames = map[int]string{
+	VariantNCS:       "NCS, reserved",
+	VariantRFC4122:   "RFC 4122",
+	VariantMicrosoft: "Microsoft, reserved",
+	VariantFuture:    "reserved for future use",
+}
+
+// inspect writes a description of everything we know about u.
+func inspect(w io.Writer, u UUID) {
+	fmt.Fprintln(w, u)
+	fmt.Fprintf(w, "  %-10s %s\n", "variant:", variantNames[u.Variant()])
+	if u.Variant() != VariantRFC4122 {
+		// The version bits only mean something for RFC 4122 UUIDs.
+		return
+	}
+
+	v := u.Version()
+	name, ok := versionNames[v]
+	if !ok {
+		name = "unknown"
+	}
+	fmt.Fprintf(w, "  %-10s %d (%s)\n", "version:", v, name)
+	if t, ok := u.Time(); ok {
+		fmt.Fprintf(w, "  %-10s %s\n", "time:", t.UTC().Format(time.RFC3339Nano))
+	}
+	if seq, ok := u.ClockSequence(); ok {
+		fmt.Fprintf(w, "  %-10s %d\n", "clock seq:", seq)
+	}
+	if node, ok := u.Node(); ok {
+		// Random node IDs have the multicast bit set, which a real
+		// network card's address never does.
+		kind := "real MAC"
+		if node[0]&0x01 != 0 {
+			kind = "random"
+		}
+		fmt.Fprintf(w, "  %-10s %s (%s)\n", "node:", net.HardwareAddr(node[:]), kind)
+	}
+	if p, ok := DecodeProvenance(u); ok {
+		fmt.Fprintf(w, "  %-10s %s\n", "made by:", p)
+	}
+	for _, name := range sortedKeys(formats) {
+		fmt.Fprintf(w, "  %-10s %s\n", name+":", formats[name].encode(u))
+	}
+}
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen inspect [uuid ...]")
+		fmt.Fprintln(fs.Output(), "Describes each UUID, reading them from stdin if none are given.")
+	}
+	fs.Parse(args)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	bad := 0
+	each := func(s string) error {
+		u, err := Parse(s)
+		if err != nil {
+			slog.Warn("skipping", "err", err)
+			bad++
+			return nil
+		}
+		inspect(w, u)
+		return nil
+	}
+
+	if fs.NArg() > 0 {
+		for _, s := range fs.Args() {
+			each(s)
+		}
+	} else if err := forEachLine(nil, each); err != nil {
+		return err
+	}
+
+	if bad > 0 {
+		return fmt.Errorf("%d invalid UUIDs", bad)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A KSUID is a 32 bit count of seconds since ksuidEpoch followed by
+// 128 random bits, written as 27 base62 characters.  See
+// https://github.com/segmentio/ksuid.
+type KSUID [20]byte
+
+// ksuidEpoch is when KSUID time starts, in Unix seconds: 2014-05-13.
+const ksuidEpoch = 1400000000
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// NewKSUID returns a KSUID for the current time.
+func NewKSUID() KSUID {
+	id := KSUID{}
+	binary.BigEndian.PutUint32(id[0:], uint32(clockNow().Unix()-ksuidEpoch))
+	safeRandom(id[4:])
+	return id
+}
+
+// ParseKSUID parses the 27 character form of a KSUID.
+func ParseKSUID(s string) (KSUID, error) {
+	id := KSUID{}
+	if len(s) != 27 {
+		return id, fmt.Errorf("invalid KSUID %q: %d characters, want 27", s, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base62Alphabet, s[i])
+		if d < 0 {
+			return KSUID{}, fmt.Errorf("invalid KSUID %q: bad character %q", s, s[i])
+		}
+		carry := d
+		for j := len(id) - 1; j >= 0; j-- {
+			acc := int(id[j])*62 + carry
+			id[j] = byte(acc)
+			carry = acc >> 8
+		}
+		if carry != 0 {
+			return KSUID{}, fmt.Errorf("invalid KSUID %q: more than 160 bits", s)
+		}
+	}
+	return id, nil
+}
+
+// String returns id as a 160 bit big-endian number in base62, padded
+// with leading zeros to 27 characters.
+func (id KSUID) String() string {
+	buf := make([]byte, 27)
+	num := id
+	for i := len(buf) - 1; i >= 0; i-- {
+		rem := 0
+		for j := range num {
+			acc := rem<<8 | int(num[j])
+			num[j] = byte(acc / 62)
+			rem = acc % 62
+		}
+		buf[i] = base62Alphabet[rem]
+	}
+	return string(buf)
+}
+
+// Bytes returns a copy of id's 20 bytes.
+func (id KSUID) Bytes() []byte {
+	b := id
+	return b[:]
+}
+
+// Time returns the time id was made, to the second.  ok is always true.
+func (id KSUID) Time() (t time.Time, ok bool) {
+	return time.Unix(ksuidEpoch+int64(binary.BigEndian.Uint32(id[0:])), 0), true
+}
+
+func init() {
+	RegisterFamily("ksuid", Family{
+		New: func() ID { return NewKSUID() },
+		Parse: func(s string) (ID, error) {
+			return ParseKSUID(s)
+		},
+	})
+}
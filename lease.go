@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A Lease is a lock that servers share, so that only one of them, the
+// leader, hands out UUIDs at a time, and another can take over if it
+// goes: see serve's ha_lease.
+type Lease interface {
+	// Acquire takes the lease if nobody holds it, reporting whether it
+	// did, without waiting.
+	Acquire(ctx context.Context) (bool, error)
+	// Renew keeps the lease, returning an error if it has been lost.
+	Renew(ctx context.Context) error
+	// Release gives it up.
+	Release(ctx context.Context) error
+}
+
+// parseLease returns the Lease an ha_lease setting names:
+//
+//	file:/path/to/lock     an flock on a file the servers share
+//	etcd:http://host/key   a key in etcd, kept alive while held
+//
+// There is deliberately no SQL advisory lock setting: uuidgen imports
+// no database driver, so a PostgresLease is only for programs that do.
+func parseLease(spec string) (Lease, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	if arg == "" {
+		return nil, fmt.Errorf("ha_lease %q: want file:path or etcd:url", spec)
+	}
+	switch kind {
+	case "file":
+		return &FileLease{Path: arg}, nil
+	case "etcd":
+		u, err := url.Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		if u.Path == "" || u.Path == "/" {
+			return nil, fmt.Errorf("ha_lease %q: no key", spec)
+		}
+		key := u.Path
+		u.Path = ""
+		return &EtcdLease{Endpoint: u.String(), Key: key}, nil
+	}
+	return nil, fmt.Errorf("ha_lease %q: unknown kind %q", spec, kind)
+}
+
+// FileLease is an flock on a file, which suits servers on one machine,
+// or sharing a file system whose locks work across machines: not all
+// network file systems' do.  The lock goes when the process does, so
+// there is nothing to renew.
+type FileLease struct {
+	Path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (l *FileLease) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		return true, nil
+	}
+	f, err := os.OpenFile(l.Path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, err
+	}
+	ok, err := tryLockFile(f)
+	if !ok {
+		f.Close()
+		return false, err
+	}
+	// Who holds it, for whoever looks.
+	host, _ := os.Hostname()
+	f.Truncate(0)
+	fmt.Fprintf(f, "%s %d\n", host, os.Getpid())
+	l.f = f
+	return true, nil
+}
+
+func (l *FileLease) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return errors.New("lease not held")
+	}
+	return nil
+}
+
+func (l *FileLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
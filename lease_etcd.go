@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultEtcdLeaseTTL is how long an EtcdLease lasts without renewal
+// if its TTL isn't set: long enough for a few of serve's renewals to
+// fail first.
+const defaultEtcdLeaseTTL = 10 * time.Second
+
+// EtcdLease is a key in etcd, attached to an etcd lease that is kept
+// alive while it is held, so that the key goes if the server does.  It
+// talks to etcd's JSON gateway, under /v3/, rather than gRPC, to need
+// nothing but net/http.
+type EtcdLease struct {
+	// Endpoint is an etcd member's client URL, such as
+	// http://localhost:2379.
+	Endpoint string
+	Key      string
+	// TTL is defaultEtcdLeaseTTL if 0.
+	TTL time.Duration
+
+	// Client is http.DefaultClient if nil.
+	Client *http.Client
+
+	mu sync.Mutex
+	id string
+}
+
+func (l *EtcdLease) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.id != "" {
+		return true, nil
+	}
+	ttl := l.TTL
+	if ttl == 0 {
+		ttl = defaultEtcdLeaseTTL
+	}
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := l.call(ctx, "/v3/lease/grant", map[string]any{"TTL": strconv.Itoa(int(ttl.Seconds()))}, &grant); err != nil {
+		return false, err
+	}
+
+	// Put the key, with the lease, only if it isn't there: that is,
+	// if no one else holds it.
+	key := base64.StdEncoding.EncodeToString([]byte(l.Key))
+	host, _ := os.Hostname()
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err := l.call(ctx, "/v3/kv/txn", map[string]any{
+		"compare": []any{map[string]any{"key": key, "target": "CREATE", "result": "EQUAL", "create_revision": "0"}},
+		"success": []any{map[string]any{"request_put": map[string]any{
+			"key":   key,
+			"value": base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s %d", host, os.Getpid()))),
+			"lease": grant.ID,
+		}}},
+	}, &txn)
+	if err != nil || !txn.Succeeded {
+		l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": grant.ID}, nil)
+		return false, err
+	}
+	l.id = grant.ID
+	return true, nil
+}
+
+// Renew keeps the etcd lease alive.  If it can't, it gives the lease
+// up for lost, since it may expire before the next try, so that
+// Acquire asks etcd again rather than trusting it is still held.
+func (l *EtcdLease) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.id == "" {
+		return errors.New("lease not held")
+	}
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := l.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": l.id}, &resp); err != nil {
+		l.id = ""
+		return err
+	}
+	// A lease that has expired comes back with no TTL.
+	if ttl, _ := strconv.Atoi(resp.Result.TTL); ttl <= 0 {
+		l.id = ""
+		return errors.New("etcd lease expired")
+	}
+	return nil
+}
+
+// Release revokes the etcd lease, which deletes the key.
+func (l *EtcdLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.id == "" {
+		return nil
+	}
+	err := l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": l.id}, nil)
+	l.id = ""
+	return err
+}
+
+// call posts req to path as JSON, decoding the response into resp if
+// it isn't nil.
+func (l *EtcdLease) call(ctx context.Context, path string, req, resp any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(l.Endpoint, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("etcd %s: %s: %s", path, res.Status, bytes.TrimSpace(msg))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
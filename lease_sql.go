@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// PostgresLease is a PostgreSQL session advisory lock, held on a
+// connection of its own, so that it goes if the server does, when the
+// database notices the connection has.  Like SQLiteStateStore, it only
+// uses database/sql, so it is for programs that import a driver
+// themselves.  serve's ha_lease deliberately has no SQL lease, since
+// uuidgen imports no driver to take one with.
+type PostgresLease struct {
+	DB  *sql.DB
+	Key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func (l *PostgresLease) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		return true, nil
+	}
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.Key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return false, err
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Renew checks that the connection, and so the lock, is still there.
+func (l *PostgresLease) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return errors.New("lease not held")
+	}
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (l *PostgresLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.Key)
+	if cerr := l.conn.Close(); err == nil {
+		err = cerr
+	}
+	l.conn = nil
+	return err
+}
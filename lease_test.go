@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testLease checks that a and b, leases on the same thing, exclude each
+// other.
+func testLease(t *testing.T, a, b Lease) {
+	t.Helper()
+	ctx := context.Background()
+	if ok, err := a.Acquire(ctx); !ok || err != nil {
+		t.Fatalf("first Acquire: %v, %v", ok, err)
+	}
+	if ok, err := a.Acquire(ctx); !ok || err != nil {
+		t.Errorf("Acquire while holding: %v, %v", ok, err)
+	}
+	if err := a.Renew(ctx); err != nil {
+		t.Errorf("Renew: %v", err)
+	}
+	if ok, err := b.Acquire(ctx); ok || err != nil {
+		t.Fatalf("Acquire of a held lease: %v, %v", ok, err)
+	}
+	if err := b.Renew(ctx); err == nil {
+		t.Error("Renew of a lease not held worked")
+	}
+	if err := a.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.Acquire(ctx); !ok || err != nil {
+		t.Fatalf("Acquire after Release: %v, %v", ok, err)
+	}
+	if err := b.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	testLease(t, &FileLease{Path: path}, &FileLease{Path: path})
+}
+
+func TestEtcdLease(t *testing.T) {
+	etcd := newFakeEtcd()
+	ts := httptest.NewServer(etcd)
+	defer ts.Close()
+	a := &EtcdLease{Endpoint: ts.URL, Key: "/uuidgen/leader"}
+	b := &EtcdLease{Endpoint: ts.URL, Key: "/uuidgen/leader"}
+	testLease(t, a, b)
+
+	// A lease etcd has let expire is lost.
+	ctx := context.Background()
+	if ok, err := a.Acquire(ctx); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	etcd.expireAll()
+	if err := a.Renew(ctx); err == nil {
+		t.Error("Renew of an expired lease worked")
+	}
+	if ok, err := b.Acquire(ctx); !ok || err != nil {
+		t.Errorf("Acquire after expiry: %v, %v", ok, err)
+	}
+
+	// A server whose keepalives fail, and whose lease expires meanwhile,
+	// doesn't go on thinking it holds it once another has taken the key.
+	if err := b.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := a.Acquire(ctx); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	etcd.setKeepaliveFails(true)
+	if err := a.Renew(ctx); err == nil {
+		t.Error("Renew worked with keepalive failing")
+	}
+	etcd.expireAll()
+	etcd.setKeepaliveFails(false)
+	if ok, err := b.Acquire(ctx); !ok || err != nil {
+		t.Fatalf("Acquire after a lost keepalive: %v, %v", ok, err)
+	}
+	if ok, err := a.Acquire(ctx); ok || err != nil {
+		t.Errorf("Acquire of a lease another took: %v, %v", ok, err)
+	}
+}
+
+func TestPostgresLease(t *testing.T) {
+	db, err := sql.Open("fakelock", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	const key = 0x75756964 // "uuid" in ASCII
+	testLease(t, &PostgresLease{DB: db, Key: key}, &PostgresLease{DB: db, Key: key})
+}
+
+func TestParseLease(t *testing.T) {
+	l, err := parseLease("etcd:http://etcd:2379/uuidgen/leader")
+	if e, ok := l.(*EtcdLease); err != nil || !ok || e.Endpoint != "http://etcd:2379" || e.Key != "/uuidgen/leader" {
+		t.Errorf("etcd: %#v, %v", l, err)
+	}
+	if l, err := parseLease("file:/var/lib/uuidgen/lock"); err != nil || l.(*FileLease).Path != "/var/lib/uuidgen/lock" {
+		t.Errorf("file: %#v, %v", l, err)
+	}
+	for _, spec := range []string{"", "file:", "etcd:http://etcd:2379", "postgres:host=db", "zookeeper:x"} {
+		if _, err := parseLease(spec); err == nil {
+			t.Errorf("%q: no error", spec)
+		}
+	}
+}
+
+// fakeEtcd is just enough of etcd's JSON gateway for EtcdLease.
+type fakeEtcd struct {
+	mu     sync.Mutex
+	nextID int
+	leases map[string]bool
+	keys   map[string]string // to the lease holding each
+	// keepaliveFails makes keepalives fail, as if etcd couldn't be
+	// reached.
+	keepaliveFails bool
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{leases: map[string]bool{}, keys: map[string]string{}}
+}
+
+func (e *fakeEtcd) expireAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leases, e.keys = map[string]bool{}, map[string]string{}
+}
+
+func (e *fakeEtcd) setKeepaliveFails(fail bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keepaliveFails = fail
+}
+
+func (e *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID      string `json:"ID"`
+		Compare []struct {
+			Key string `json:"key"`
+		} `json:"compare"`
+		Success []struct {
+			RequestPut struct {
+				Key   string `json:"key"`
+				Lease string `json:"lease"`
+			} `json:"request_put"`
+		} `json:"success"`
+	}
+	b, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(b, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var resp any
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		e.nextID++
+		id := strconv.Itoa(e.nextID)
+		e.leases[id] = true
+		resp = map[string]string{"ID": id, "TTL": "10"}
+	case "/v3/kv/txn":
+		_, taken := e.keys[req.Compare[0].Key]
+		if !taken {
+			put := req.Success[0].RequestPut
+			e.keys[put.Key] = put.Lease
+		}
+		resp = map[string]bool{"succeeded": !taken}
+	case "/v3/lease/keepalive":
+		if e.keepaliveFails {
+			http.Error(w, "etcdserver: request timed out", http.StatusServiceUnavailable)
+			return
+		}
+		ttl := "0"
+		if e.leases[req.ID] {
+			ttl = "10"
+		}
+		resp = map[string]any{"result": map[string]string{"ID": req.ID, "TTL": ttl}}
+	case "/v3/lease/revoke":
+		delete(e.leases, req.ID)
+		for k, id := range e.keys {
+			if id == req.ID {
+				delete(e.keys, k)
+			}
+		}
+		resp = map[string]string{}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fakeLockDriver is a database/sql driver that understands just
+// PostgresLease's advisory lock statements, for want of PostgreSQL.
+type fakeLockDriver struct {
+	mu      sync.Mutex
+	holders map[string]*fakeLockConn // by DSN and key
+}
+
+func init() {
+	sql.Register("fakelock", &fakeLockDriver{holders: map[string]*fakeLockConn{}})
+}
+
+func (d *fakeLockDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeLockConn{d: d, dsn: dsn}, nil
+}
+
+type fakeLockConn struct {
+	d   *fakeLockDriver
+	dsn string
+}
+
+func (c *fakeLockConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeLockStmt{c: c, query: query}, nil
+}
+func (c *fakeLockConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+// Close lets go of the connection's locks, as the end of a session
+// does.
+func (c *fakeLockConn) Close() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	for k, holder := range c.d.holders {
+		if holder == c {
+			delete(c.d.holders, k)
+		}
+	}
+	return nil
+}
+
+type fakeLockStmt struct {
+	c     *fakeLockConn
+	query string
+}
+
+func (s *fakeLockStmt) Close() error  { return nil }
+func (s *fakeLockStmt) NumInput() int { return strings.Count(s.query, "$") }
+
+func (s *fakeLockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.Query(args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeLockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := s.c.dsn + " " + strconv.FormatInt(args[0].(int64), 10)
+	var ok bool
+	switch s.query {
+	case "SELECT pg_try_advisory_lock($1)":
+		if holder := d.holders[key]; holder == nil || holder == s.c {
+			d.holders[key], ok = s.c, true
+		}
+	case "SELECT pg_advisory_unlock($1)":
+		if ok = d.holders[key] == s.c; ok {
+			delete(d.holders, key)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	return &fakeLockRows{row: []driver.Value{ok}}, nil
+}
+
+type fakeLockRows struct {
+	row []driver.Value
+}
+
+func (r *fakeLockRows) Columns() []string { return []string{"ok"} }
+func (r *fakeLockRows) Close() error      { return nil }
+func (r *fakeLockRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build libuuid && cgo
+
+// The system's libuuid, as a baseline from outside Go for the V1
+// strategies to be compared with.  Build with -tags libuuid, which
+// needs the libuuid headers, uuid-dev on Debian.
+
+package main
+
+/*
+#cgo LDFLAGS: -luuid
+#include <uuid/uuid.h>
+*/
+import "C"
+
+import "unsafe"
+
+var _ = registerExperiment(experiment{
+	Name:        "libuuid",
+	Description: "the system libuuid's uuid_generate_time, through cgo",
+	New:         func(int) Generator { return GeneratorFunc(libuuidTime) },
+})
+
+// libuuidTime returns a V1 UUID from uuid_generate_time, which may ask
+// the uuidd daemon for it.
+func libuuidTime() UUID {
+	var u UUID
+	C.uuid_generate_time((*C.uchar)(unsafe.Pointer(&u[0])))
+	return u
+}
+
+// libuuidRandom returns a V4 UUID from uuid_generate_random.
+func libuuidRandom() UUID {
+	var u UUID
+	C.uuid_generate_random((*C.uchar)(unsafe.Pointer(&u[0])))
+	return u
+}
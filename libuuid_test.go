@@ -0,0 +1,29 @@
+//go:build libuuid && cgo
+
+package main
+
+import "testing"
+
+func TestLibuuid(t *testing.T) {
+	if v := libuuidTime().Version(); v != 1 {
+		t.Errorf("uuid_generate_time made version %d", v)
+	}
+	if v := libuuidRandom().Version(); v != 4 {
+		t.Errorf("uuid_generate_random made version %d", v)
+	}
+	if libuuidTime() == libuuidTime() {
+		t.Error("uuid_generate_time repeated itself")
+	}
+}
+
+func BenchmarkLibuuidTime(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		libuuidTime()
+	}
+}
+
+func BenchmarkLibuuidRandom(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		libuuidRandom()
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logLevel is the level of every logger newLogger makes, so that serve
+// can change it on reload.
+var logLevel = new(slog.LevelVar)
+
+// logFormats are the -log-format values.
+var logFormats = []string{"text", "json"}
+
+// newLogger returns a logger writing to w in the given format, at
+// logLevel.  Without times, which are noise on a terminal and which
+// whatever collects a server's logs will usually add anyway.
+func newLogger(w io.Writer, format string, withTime bool) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	if !withTime {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, want %s", format, strings.Join(logFormats, " or "))
+}
+
+// parseLogLevel parses debug, info, warn or error.
+func parseLogLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
+
+// setupCLILogging makes the default logger the one $UUIDGEN_LOG_FORMAT
+// and $UUIDGEN_LOG_LEVEL ask for, text at info by default.
+func setupCLILogging() {
+	format := os.Getenv("UUIDGEN_LOG_FORMAT")
+	if format == "" {
+		format = "text"
+	}
+	logger, err := newLogger(os.Stderr, format, false)
+	if err != nil {
+		logger, _ = newLogger(os.Stderr, "text", false)
+		logger.Warn("ignoring UUIDGEN_LOG_FORMAT", "err", err)
+	}
+	slog.SetDefault(logger)
+
+	if s := os.Getenv("UUIDGEN_LOG_LEVEL"); s != "" {
+		l, err := parseLogLevel(s)
+		if err != nil {
+			slog.Warn("ignoring UUIDGEN_LOG_LEVEL", "err", err)
+			return
+		}
+		logLevel.Set(l)
+	}
+}
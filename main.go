@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const usage = `usage: uuidgen [command] [flags]
+
+With no command, prints a V1 UUID.
+
+Commands:
+  anonymize  replace UUIDs in text with consistent pseudonyms
+  bench      compare the generator strategies
+  convert    convert UUIDs between text formats
+  diff       compare two lists of UUIDs as sets
+  entropy    check the randomness of generated V4 UUIDs
+  gen        generate UUIDs
+  inspect    describe UUIDs
+  migrate    rewrite V1 UUIDs as V7 UUIDs with the same timestamps
+  ns         derive name based V3 and V5 UUIDs
+  report     summarize the profiles of bench -json results
+  restore    unpack a server snapshot to serve from on another machine
+  serve      hand out UUIDs over HTTP
+  snapshot   fetch a snapshot of a server's state
+  sort       sort UUIDs by bytes or embedded time
+  timeline   histogram of the times embedded in UUIDs
+  validate   check that lines of input are UUIDs
+  version    print the build and generator fingerprint
+
+Run 'uuidgen <command> -h' for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"anonymize": runAnonymize,
+	"bench":     runBench,
+	"convert":   runConvert,
+	"diff":      runDiff,
+	"entropy":   runEntropy,
+	"gen":       runGen,
+	"inspect":   runInspect,
+	"migrate":   runMigrate,
+	"ns":        runNS,
+	"report":    runReport,
+	"restore":   runRestore,
+	"serve":     runServe,
+	"snapshot":  runSnapshot,
+	"sort":      runSort,
+	"timeline":  runTimeline,
+	"validate":  runValidate,
+	"version":   runVersion,
+}
+
+func main() {
+	serveJS()
+	if len(os.Args) < 2 {
+		os.Args = append(os.Args, "gen")
+	}
+
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	setupCLILogging()
+	if err := run(os.Args[2:]); err != nil {
+		slog.Error("failed", "command", os.Args[1], "err", err)
+		os.Exit(1)
+	}
+}
+
+// forEachLine calls f with each non-blank line, trimmed, read from the
+// named files, or from stdin if there are none.
+func forEachLine(files []string, f func(line string) error) error {
+	scan := func(r io.Reader) error {
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			if line == "" {
+				continue
+			}
+			if err := f(line); err != nil {
+				return err
+			}
+		}
+		return s.Err()
+	}
+
+	if len(files) == 0 {
+		return scan(os.Stdin)
+	}
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = scan(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachUUID calls f with each UUID decoded from the named files, or
+// from stdin if there are none.
+func forEachUUID(files []string, binary bool, f func(u UUID) error) error {
+	decode := func(r io.Reader) error {
+		d := NewDecoder(r)
+		d.SetBinary(binary)
+		for {
+			u, err := d.Decode()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := f(u); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return decode(os.Stdin)
+	}
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = decode(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
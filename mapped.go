@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// MappedUUIDs is a file of sorted, packed 16 byte UUIDs, such as
+// uuidgen sort -binary writes, mapped into memory rather than read
+// into the heap.  The OS pages it in as it is used, so files much
+// bigger than memory can be searched.
+type MappedUUIDs struct {
+	name string
+	data []byte
+}
+
+// OpenMapped maps the named file.  It doesn't check the file is
+// sorted, since that means reading all of it; see CheckSorted.
+func OpenMapped(name string) (*MappedUUIDs, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size()%16 != 0 {
+		return nil, fmt.Errorf("%s: %d bytes is not a whole number of UUIDs", name, fi.Size())
+	}
+	m := &MappedUUIDs{name: name}
+	if fi.Size() > 0 {
+		if m.data, err = mmapFile(f, int(fi.Size())); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return m, nil
+}
+
+// Close unmaps the file.  m must not be used afterwards.
+func (m *MappedUUIDs) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := munmapFile(m.data)
+	m.data = nil
+	return err
+}
+
+// Len returns how many UUIDs there are.
+func (m *MappedUUIDs) Len() int {
+	return len(m.data) / 16
+}
+
+// At returns the i'th UUID.
+func (m *MappedUUIDs) At(i int) UUID {
+	return UUID(m.data[i*16 : i*16+16])
+}
+
+// Search returns the index of the first UUID not less than u, and
+// whether it is u.
+func (m *MappedUUIDs) Search(u UUID) (int, bool) {
+	i := sort.Search(m.Len(), func(i int) bool { return m.At(i).Compare(u) >= 0 })
+	return i, i < m.Len() && m.At(i) == u
+}
+
+// Contains reports whether u is in the file.
+func (m *MappedUUIDs) Contains(u UUID) bool {
+	_, ok := m.Search(u)
+	return ok
+}
+
+// CheckSorted returns an error if the UUIDs are out of order.
+// Duplicates are allowed.
+func (m *MappedUUIDs) CheckSorted() error {
+	for i := 1; i < m.Len(); i++ {
+		if m.At(i).Compare(m.At(i-1)) < 0 {
+			return fmt.Errorf("%s: UUID %d, %s, is out of order", m.name, i, m.At(i))
+		}
+	}
+	return nil
+}
+
+// mappedSource serves a MappedUUIDs to mergeDiff, checking the order
+// as it goes and skipping duplicates, like streamSource.
+type mappedSource struct {
+	m *MappedUUIDs
+	i int
+}
+
+func (s *mappedSource) next() (UUID, bool, error) {
+	for ; s.i < s.m.Len(); s.i++ {
+		u := s.m.At(s.i)
+		if s.i > 0 {
+			switch c := u.Compare(s.m.At(s.i - 1)); {
+			case c < 0:
+				return UUID{}, false, fmt.Errorf("%s: %s is out of order", s.m.name, u)
+			case c == 0:
+				continue
+			}
+		}
+		s.i++
+		return u, true, nil
+	}
+	return UUID{}, false, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeIDs writes ids to a temporary file, in the given order.
+func writeIDs(t *testing.T, ids []UUID) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "ids.bin")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAll(f, ids); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestMappedUUIDs(t *testing.T) {
+	ids := make([]UUID, 1000)
+	for i := range ids {
+		ids[i] = NewV4()
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+	m, err := OpenMapped(writeIDs(t, ids))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if m.Len() != len(ids) || m.At(500) != ids[500] {
+		t.Fatalf("len %d, At(500) %s", m.Len(), m.At(500))
+	}
+	if err := m.CheckSorted(); err != nil {
+		t.Error(err)
+	}
+	for _, i := range []int{0, 1, 499, 999} {
+		if j, ok := m.Search(ids[i]); !ok || j != i {
+			t.Errorf("Search(ids[%d]) = %d, %t", i, j, ok)
+		}
+	}
+	if m.Contains(NewV4()) {
+		t.Error("found a UUID that isn't there")
+	}
+
+	// A diff against itself minus one UUID.
+	b, err := OpenMapped(writeIDs(t, ids[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var onlyInA []UUID
+	err = mergeDiff(&mappedSource{m: m}, &mappedSource{m: b}, func(which int, u UUID) error {
+		if which != inBoth {
+			onlyInA = append(onlyInA, u)
+		}
+		return nil
+	})
+	if err != nil || len(onlyInA) != 1 || onlyInA[0] != ids[0] {
+		t.Errorf("diff gave %v, %v", onlyInA, err)
+	}
+}
+
+func TestOpenMappedErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "odd.bin")
+	os.WriteFile(name, make([]byte, 17), 0644)
+	if _, err := OpenMapped(name); err == nil {
+		t.Error("17 bytes should fail")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.bin")
+	os.WriteFile(empty, nil, 0644)
+	m, err := OpenMapped(empty)
+	if err != nil || m.Len() != 0 || m.Contains(UUID{}) {
+		t.Errorf("empty file: %v", err)
+	}
+	m.Close()
+
+	unsorted := writeIDs(t, []UUID{{2}, {1}})
+	if m, err := OpenMapped(unsorted); err != nil || m.CheckSorted() == nil {
+		t.Errorf("unsorted file wasn't caught: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// MigrateV7 returns the V7 UUID that the V1 or V6 UUID u maps to under
+// key.  The V7 timestamp is u's, and its random bits are an HMAC of u,
+// so migrating the same data twice with the same key gives the same
+// IDs, and foreign keys can be rewritten independently of the rows they
+// point at.
+//
+// V7 timestamps are only milliseconds, so the rest of u's 100
+// nanosecond time goes in the 12 bits after it, as RFC 9562 allows.
+// That keeps IDs from the same millisecond in their original order.
+func MigrateV7(u UUID, key []byte) (UUID, error) {
+	if v := u.Version(); v != 1 && v != 6 {
+		return UUID{}, fmt.Errorf("%s is version %d, not 1 or 6", u, v)
+	}
+	t, _ := u.Time()
+	if t.Unix() < 0 {
+		return UUID{}, fmt.Errorf("%s is from %s, before V7 timestamps start", u, t.UTC().Format("2006-01-02"))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(u[:])
+	sum := mac.Sum(nil)
+
+	m := UUID{}
+	ms := uint64(t.UnixMilli())
+	binary.BigEndian.PutUint16(m[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(m[2:], uint32(ms))
+	ticks := uint32(t.Nanosecond() % 1e6 / 100)
+	binary.BigEndian.PutUint16(m[6:], uint16(ticks<<12/10000))
+	copy(m[8:], sum)
+
+	m.SetVersion(7)
+	m.SetVariant()
+	return m, nil
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	keyHex := fs.String("key", os.Getenv("UUIDGEN_MIGRATE_KEY"), "hex HMAC key, defaults to $UUIDGEN_MIGRATE_KEY")
+	mapFile := fs.String("map", "", "also write old,new pairs to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen migrate -key hex [flags] [file ...] > new.txt")
+		fmt.Fprintln(fs.Output(), "Rewrites V1 and V6 UUIDs, one per line, as V7 UUIDs with the same timestamps.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// Unlike anonymize, there is no random default: a migration that
+	// can't be repeated can't be resumed or checked either.
+	if *keyHex == "" {
+		return errors.New("-key is required")
+	}
+	key, err := hex.DecodeString(*keyHex)
+	if err != nil {
+		return fmt.Errorf("bad -key: %v", err)
+	}
+	if len(key) < 16 {
+		return errors.New("-key should be at least 16 bytes")
+	}
+
+	var mw *bufio.Writer
+	if *mapFile != "" {
+		f, err := os.Create(*mapFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		mw = bufio.NewWriter(f)
+	}
+
+	e := NewEncoder(os.Stdout)
+	err = forEachUUID(fs.Args(), false, func(u UUID) error {
+		m, err := MigrateV7(u, key)
+		if err != nil {
+			return err
+		}
+		if mw != nil {
+			fmt.Fprintf(mw, "%s,%s\n", u, m)
+		}
+		return e.Encode(m)
+	})
+	if ferr := e.Flush(); err == nil {
+		err = ferr
+	}
+	if mw != nil {
+		if ferr := mw.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
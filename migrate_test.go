@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigrateV7(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	node := [6]byte{1, 2, 3, 4, 5, 6}
+	when := time.Date(2015, time.March, 1, 12, 0, 0, 500, time.UTC)
+
+	a := NewV1At(when, 1, node)
+	b := NewV1At(when.Add(100*time.Microsecond), 1, node)
+	ma, err := MigrateV7(a, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mb, err := MigrateV7(b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ma.Version() != 7 || ma.Variant() != VariantRFC4122 {
+		t.Errorf("%s: version %d variant %d", ma, ma.Version(), ma.Variant())
+	}
+	if got, _ := ma.Time(); !got.Equal(when.Truncate(time.Millisecond)) {
+		t.Errorf("time %s, want %s", got, when.Truncate(time.Millisecond))
+	}
+	if ma.Compare(mb) >= 0 {
+		t.Errorf("%s should sort before %s from later in the same millisecond", ma, mb)
+	}
+	if again, _ := MigrateV7(a, key); again != ma {
+		t.Errorf("migrating twice gave %s and %s", ma, again)
+	}
+	if other, _ := MigrateV7(a, []byte("fedcba9876543210")); other == ma {
+		t.Errorf("different keys gave the same %s", ma)
+	}
+
+	if _, err := MigrateV7(NewV4(), key); err == nil {
+		t.Error("migrating a V4 should fail")
+	}
+	if _, err := MigrateV7(NewV1At(time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), 1, node), key); err == nil {
+		t.Error("migrating a V1 from before 1970 should fail")
+	}
+}
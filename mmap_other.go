@@ -0,0 +1,21 @@
+//go:build !unix
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// Without mmap, the file is just read into memory, which works the
+// same but only for files that fit.
+
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	_, err := io.ReadFull(f, b)
+	return b, err
+}
+
+func munmapFile(b []byte) error {
+	return nil
+}
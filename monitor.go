@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Monitor wraps a Generator, remembering the UUIDs it hands out in a
+// bloom filter and calling OnDuplicate for any it seems to have handed
+// out before.  It is a safety net for experimental generators, the
+// lock-free ones especially, in production.  It is cheap in memory
+// but not in time: each UUID touches k, about 20, random cache lines,
+// which comes to around 900ns a UUID once the filter is bigger than
+// the CPU cache.
+//
+// A bloom filter never misses a duplicate, but can mistake a new UUID
+// for one, so treat a report as a reason to look, not proof.  Sized
+// for expected UUIDs, it does that at about the false positive rate it
+// was made with; past that it fills up, and reports more and more.
+type Monitor struct {
+	g Generator
+	// OnDuplicate is called with each suspected duplicate, from the
+	// goroutine that generated it.
+	OnDuplicate func(u UUID)
+
+	filter  *bloomFilter
+	suspect atomic.Uint64
+}
+
+// NewMonitor returns a Monitor for g that expects to see up to
+// expected UUIDs with the given false positive rate, such as 1e-6.
+// The filter takes about 3.6 bytes per expected UUID at 1e-6.
+func NewMonitor(g Generator, expected int, falsePositive float64, onDuplicate func(u UUID)) *Monitor {
+	return &Monitor{
+		g:           g,
+		OnDuplicate: onDuplicate,
+		filter:      newBloomFilter(expected, falsePositive),
+	}
+}
+
+// New returns the next UUID from the wrapped generator, checking it
+// first.
+func (m *Monitor) New() UUID {
+	u := m.g.New()
+	if m.filter.add(u) {
+		m.suspect.Add(1)
+		if m.OnDuplicate != nil {
+			m.OnDuplicate(u)
+		}
+	}
+	return u
+}
+
+// Suspects returns how many suspected duplicates there have been.
+func (m *Monitor) Suspects() uint64 {
+	return m.suspect.Load()
+}
+
+// bloomFilter is a set of UUIDs that can say a UUID is in it when it
+// isn't, but never the other way round.
+type bloomFilter struct {
+	bits  []atomic.Uint64
+	k     int
+	seeds [2][2]uint64
+}
+
+// newBloomFilter returns a filter sized for expected UUIDs at the
+// given false positive rate.
+func newBloomFilter(expected int, falsePositive float64) *bloomFilter {
+	// The standard sizes: m bits and k hashes.
+	m := math.Ceil(-float64(expected) * math.Log(falsePositive) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(expected) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	b := &bloomFilter{
+		bits: make([]atomic.Uint64, (int(m)+63)/64),
+		k:    k,
+	}
+	// Seeds of its own, rather than maphash's, so that a filter can be
+	// saved and loaded again: see issuedLog's snapshot.
+	var buf [32]byte
+	safeRandom(buf[:])
+	for i := range b.seeds {
+		b.seeds[i] = [2]uint64{binary.LittleEndian.Uint64(buf[16*i:]), binary.LittleEndian.Uint64(buf[16*i+8:])}
+	}
+	return b
+}
+
+// hash mixes u with seed as UUIDMap's hash does.
+func (b *bloomFilter) hash(u UUID, seed [2]uint64) uint64 {
+	hi, lo := bits.Mul64(binary.LittleEndian.Uint64(u[:8])^seed[0], binary.LittleEndian.Uint64(u[8:])^seed[1])
+	return hi ^ lo
+}
+
+// positions calls f with each of u's k bit positions.
+func (b *bloomFilter) positions(u UUID, f func(word int, mask uint64)) {
+	// Double hashing: the k bit positions are h1 + i*h2.
+	h1 := b.hash(u, b.seeds[0])
+	h2 := b.hash(u, b.seeds[1]) | 1
+	n := uint64(len(b.bits) * 64)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % n
+		f(int(bit/64), uint64(1)<<(bit%64))
+	}
+}
+
+// add sets u's bits, and returns whether they were all set already.
+// It doesn't lock, so if two goroutines add the same UUID at the same
+// moment neither may notice; duplicates from one goroutine, or far
+// enough apart, are always caught.
+func (b *bloomFilter) add(u UUID) bool {
+	seen := true
+	b.positions(u, func(word int, mask uint64) {
+		if b.bits[word].Or(mask)&mask == 0 {
+			seen = false
+		}
+	})
+	return seen
+}
+
+// contains reports whether u may have been added.
+func (b *bloomFilter) contains(u UUID) bool {
+	found := true
+	b.positions(u, func(word int, mask uint64) {
+		if b.bits[word].Load()&mask == 0 {
+			found = false
+		}
+	})
+	return found
+}
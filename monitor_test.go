@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestMonitor(t *testing.T) {
+	var dups []UUID
+	m := NewMonitor(GeneratorFunc(NewV4), 100000, 1e-6, func(u UUID) { dups = append(dups, u) })
+	ids := make([]UUID, 100000)
+	for i := range ids {
+		ids[i] = m.New()
+	}
+	// Expect about 100000 * 1e-6 / k false positives, which is next
+	// to nothing.
+	if len(dups) != 0 {
+		t.Errorf("%d false positives", len(dups))
+	}
+
+	// Now one that repeats itself.
+	repeat := ids[123]
+	m.g = GeneratorFunc(func() UUID { return repeat })
+	m.New()
+	if len(dups) != 1 || dups[0] != repeat || m.Suspects() != 1 {
+		t.Errorf("missed %s: %v", repeat, dups)
+	}
+}
+
+func BenchmarkMonitor(b *testing.B) {
+	g := NewSatoriGenerator()
+	m := NewMonitor(g, b.N+1, 1e-6, nil)
+	for i := 0; i < b.N; i++ {
+		m.New()
+	}
+}
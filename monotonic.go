@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// monotonic is the Generator Monotonic returns.
+type monotonic struct {
+	mu   sync.Mutex
+	g    Generator
+	last UUID
+}
+
+// Monotonic wraps g so that every UUID it returns compares greater
+// than the one before.  When g comes up with one that isn't, it
+// returns the one before plus one instead, carrying into the
+// timestamp, and so borrowing a future tick, if it has to.
+//
+// It is meant for time ordered generators, V6 and V7, which only need
+// the bump when several UUIDs land on the same tick or the clock goes
+// backwards.  A V6 UUID is bumped in its clock sequence, not its node
+// ID, which would make it another node's.  Wrapping a random generator
+// works, but soon turns it into a counter.
+func Monotonic(g Generator) Generator {
+	return &monotonic{g: g}
+}
+
+func (m *monotonic) New() UUID {
+	u := m.g.New()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.last.Compare(u) >= 0 {
+		if m.last.Version() == 6 {
+			u = bumpV6(m.last)
+		} else {
+			u = bump(m.last)
+		}
+	}
+	m.last = u
+	return u
+}
+
+// bump returns u plus one, skipping over the version and variant bits,
+// which it leaves alone.  The bits are, from most to least
+// significant, the 48 bits before the version, the 12 between the
+// version and the variant, and the 62 after the variant.
+func bump(u UUID) UUID {
+	low := binary.BigEndian.Uint64(u[8:])
+	if low&(1<<62-1) != 1<<62-1 {
+		binary.BigEndian.PutUint64(u[8:], low+1)
+		return u
+	}
+	binary.BigEndian.PutUint64(u[8:], low&^(1<<62-1))
+	return bumpHigh(u)
+}
+
+// bumpV6 returns the V6 UUID after u from the same node: u with its
+// clock sequence plus one, carrying into the timestamp if it wraps.
+func bumpV6(u UUID) UUID {
+	seq := binary.BigEndian.Uint16(u[8:])
+	if seq&0x3fff != 0x3fff {
+		binary.BigEndian.PutUint16(u[8:], seq+1)
+		return u
+	}
+	binary.BigEndian.PutUint16(u[8:], seq&^0x3fff)
+	return bumpHigh(u)
+}
+
+// bumpHigh adds one to the 60 bits before the variant, which are a
+// V6's or V7's timestamp, with some of a V7's random bits.
+func bumpHigh(u UUID) UUID {
+	mid := binary.BigEndian.Uint16(u[6:])
+	if mid&0xfff != 0xfff {
+		binary.BigEndian.PutUint16(u[6:], mid+1)
+		return u
+	}
+	binary.BigEndian.PutUint16(u[6:], mid&^0xfff)
+
+	for i := 5; i >= 0; i-- {
+		u[i]++
+		if u[i] != 0 {
+			break
+		}
+	}
+	return u
+}
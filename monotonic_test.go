@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBump(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"00000000-0000-7000-8000-000000000000", "00000000-0000-7000-8000-000000000001"},
+		{"00000000-0000-7000-bfff-ffffffffffff", "00000000-0000-7001-8000-000000000000"},
+		{"00000000-0000-7fff-bfff-ffffffffffff", "00000000-0001-7000-8000-000000000000"},
+		{"000000ff-ffff-7fff-bfff-ffffffffffff", "00000100-0000-7000-8000-000000000000"},
+	}
+	for _, tt := range tests {
+		if got := bump(mustParse(tt.in)).String(); got != tt.want {
+			t.Errorf("bump(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBumpV6(t *testing.T) {
+	// The node ID is all ones, so bump would carry into the clock
+	// sequence and give the next UUID node ID 0.
+	tests := []struct{ in, want string }{
+		{"1ec9414c-232a-6b00-8005-ffffffffffff", "1ec9414c-232a-6b00-8006-ffffffffffff"},
+		{"1ec9414c-232a-6b00-bfff-ffffffffffff", "1ec9414c-232a-6b01-8000-ffffffffffff"},
+		{"1ec9414c-232a-6fff-bfff-ffffffffffff", "1ec9414c-232b-6000-8000-ffffffffffff"},
+	}
+	for _, tt := range tests {
+		if got := bumpV6(mustParse(tt.in)).String(); got != tt.want {
+			t.Errorf("bumpV6(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	// Monotonic keeps a stuck V6 generator's node ID.
+	stuck := mustParse(tests[0].in)
+	g := Monotonic(GeneratorFunc(func() UUID { return stuck }))
+	g.New()
+	if u := g.New(); u.String() != tests[0].want {
+		t.Errorf("got %s, want %s", u, tests[0].want)
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	// A V7 generator whose clock is stuck, and which sometimes goes
+	// back a millisecond.
+	v7 := NewV7()
+	calls := 0
+	stuck := GeneratorFunc(func() UUID {
+		calls++
+		u := v7
+		safeRandom(u[8:])
+		if calls%10 == 0 {
+			u[5]--
+		}
+		u.SetVariant()
+		return u
+	})
+
+	g := Monotonic(stuck)
+	prev := g.New()
+	for i := 0; i < 10000; i++ {
+		u := g.New()
+		if prev.Compare(u) >= 0 {
+			t.Fatalf("%s then %s", prev, u)
+		}
+		if u.Version() != 7 || u.Variant() != VariantRFC4122 {
+			t.Fatalf("%s has version %d variant %d", u, u.Version(), u.Variant())
+		}
+		prev = u
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+// msClock is the clock of the Snowflake-like generators: the time of
+// each ID in ticks, milliseconds for most of them, with a sequence
+// number to tell apart the IDs of one tick.  When a tick's sequence
+// numbers run out, or the clock goes backwards, it borrows from the
+// next tick rather than waiting for the clock to catch up, so that IDs
+// always increase.
+type msClock struct {
+	// maxSeq is the largest sequence number a tick has room for.
+	maxSeq int64
+	// last and seq are the tick and sequence number of the last ID.
+	last int64
+	seq  int64
+	// lastClock is what the clock said last time, which is behind
+	// last while borrowing.
+	lastClock int64
+}
+
+// next returns the tick and sequence number for an ID made when the
+// clock says clock, counting clock rollbacks and borrowing in
+// counters.
+func (c *msClock) next(clock int64, counters *generatorCounters) (tick, seq int64) {
+	if clock < c.lastClock {
+		counters.rollbacks.Add(1)
+	}
+	c.lastClock = clock
+	c.last, c.seq = nextTick(clock, c.last, c.seq, c.maxSeq)
+	if c.last > clock {
+		counters.borrowed.Add(1)
+	}
+	return c.last, c.seq
+}
+
+// nextTick returns the tick and sequence number that follow last and
+// seq when the clock says clock: the clock's tick, with sequence 0, if
+// it has moved on from last, otherwise last's next sequence number, or
+// once there are no more of those, sequence 0 of the tick after last.
+// It is msClock.next for generators that keep their state packed in
+// one word for compare-and-swap.
+func nextTick(clock, last, seq, maxSeq int64) (tick, nextSeq int64) {
+	switch {
+	case clock > last:
+		return clock, 0
+	case seq < maxSeq:
+		return last, seq + 1
+	}
+	return last + 1, 0
+}
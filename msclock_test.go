@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestMSClock(t *testing.T) {
+	type step struct {
+		clock, tick, seq int64
+	}
+	for _, tc := range []struct {
+		name                string
+		steps               []step
+		borrowed, rollbacks uint64
+	}{
+		{
+			name:  "clock moving on",
+			steps: []step{{10, 10, 0}, {11, 11, 0}, {15, 15, 0}},
+		},
+		{
+			name:  "clock stuck",
+			steps: []step{{10, 10, 0}, {10, 10, 1}, {10, 10, 2}, {11, 11, 0}},
+		},
+		{
+			name:     "sequence running out",
+			steps:    []step{{10, 10, 0}, {10, 10, 1}, {10, 10, 2}, {10, 11, 0}, {10, 11, 1}, {11, 11, 2}, {11, 12, 0}, {13, 13, 0}},
+			borrowed: 3,
+		},
+		{
+			name:      "clock going backwards",
+			steps:     []step{{10, 10, 0}, {7, 10, 1}, {8, 10, 2}, {9, 11, 0}, {12, 12, 0}},
+			borrowed:  3,
+			rollbacks: 1,
+		},
+		{
+			name:      "clock going backwards twice",
+			steps:     []step{{10, 10, 0}, {7, 10, 1}, {5, 10, 2}, {11, 11, 0}},
+			borrowed:  2,
+			rollbacks: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := msClock{maxSeq: 2}
+			var counters generatorCounters
+			for i, s := range tc.steps {
+				tick, seq := c.next(s.clock, &counters)
+				if tick != s.tick || seq != s.seq {
+					t.Fatalf("step %d, clock %d: tick %d sequence %d, want %d %d", i, s.clock, tick, seq, s.tick, s.seq)
+				}
+			}
+			if st := counters.stats(); st.Borrowed != tc.borrowed || st.Rollbacks != tc.rollbacks {
+				t.Errorf("stats %+v, want %d borrowed, %d rollbacks", st, tc.borrowed, tc.rollbacks)
+			}
+		})
+	}
+}
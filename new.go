@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// An Option changes how New makes a UUID.
+type Option func(*options)
+
+// options holds what the Options set, nil for those not given.
+type options struct {
+	namespace *UUID
+	name      *string
+	at        *time.Time
+	ctx       context.Context
+}
+
+// WithNamespace sets the namespace of a V3 or V5 UUID.
+func WithNamespace(ns UUID) Option {
+	return func(o *options) {
+		o.namespace = &ns
+	}
+}
+
+// WithName sets the name of a V3 or V5 UUID.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = &name
+	}
+}
+
+// WithTime makes a V1 or V7 UUID for t rather than now, as NewV1At and
+// NewV7At do.  V1 UUIDs get a random clock sequence, since there is no
+// telling what else was made for t.
+func WithTime(t time.Time) Option {
+	return func(o *options) {
+		o.at = &t
+	}
+}
+
+// WithContext makes New use the generator NewContext put in ctx, if
+// there is one, rather than make the UUID itself.  The generator must
+// make UUIDs of the version asked for, and can't be combined with the
+// other options.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// New returns a UUID of the given version, so that the version can
+// come from configuration.  Versions 3 and 5 need WithNamespace and
+// WithName.  It is an error to give an option the version doesn't use.
+func New(version byte, opts ...Option) (UUID, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	named := version == 3 || version == 5
+	switch {
+	case named && !(o.namespace != nil && o.name != nil):
+		return UUID{}, fmt.Errorf("version %d needs WithNamespace and WithName", version)
+	case !named && (o.namespace != nil || o.name != nil):
+		return UUID{}, fmt.Errorf("version %d doesn't take a namespace or name", version)
+	case o.at != nil && version != 1 && version != 7:
+		return UUID{}, fmt.Errorf("version %d doesn't take a time", version)
+	}
+
+	if o.ctx != nil {
+		if g := FromContext(o.ctx); g != nil {
+			if o.namespace != nil || o.at != nil {
+				return UUID{}, errors.New("a generator from the context can't take a namespace, name or time")
+			}
+			u := g.New()
+			if v := u.Version(); v != version {
+				return UUID{}, fmt.Errorf("the context's generator made version %d, not %d", v, version)
+			}
+			return u, nil
+		}
+	}
+
+	switch version {
+	case 1:
+		if o.at != nil {
+			return NewV1At(*o.at, initClockSequence(), hardwareAddr), nil
+		}
+		return NewV1(), nil
+	case 3:
+		return NewV3(*o.namespace, *o.name), nil
+	case 4:
+		return NewV4(), nil
+	case 5:
+		return NewV5(*o.namespace, *o.name), nil
+	case 6:
+		return NewV6(), nil
+	case 7:
+		if o.at != nil {
+			return NewV7At(*o.at), nil
+		}
+		return NewV7(), nil
+	case 2:
+		return UUID{}, errors.New("version 2 (DCE security) is not supported")
+	}
+	return UUID{}, fmt.Errorf("unsupported version %d", version)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	when := time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		version byte
+		opts    []Option
+		want    UUID
+	}{
+		{1, nil, UUID{}},
+		{1, []Option{WithTime(when)}, UUID{}},
+		{3, []Option{WithNamespace(NamespaceDNS), WithName("python.org")}, NewV3(NamespaceDNS, "python.org")},
+		{4, nil, UUID{}},
+		{5, []Option{WithNamespace(NamespaceDNS), WithName("python.org")}, NewV5(NamespaceDNS, "python.org")},
+		{6, nil, UUID{}},
+		{7, nil, UUID{}},
+		{7, []Option{WithTime(when)}, UUID{}},
+	}
+	for _, tt := range tests {
+		u, err := New(tt.version, tt.opts...)
+		if err != nil {
+			t.Errorf("New(%d): %v", tt.version, err)
+			continue
+		}
+		if u.Version() != tt.version {
+			t.Errorf("New(%d) made version %d", tt.version, u.Version())
+		}
+		if tt.want != (UUID{}) && u != tt.want {
+			t.Errorf("New(%d) = %s, want %s", tt.version, u, tt.want)
+		}
+		if len(tt.opts) == 1 {
+			if got, _ := u.Time(); !got.Equal(when) {
+				t.Errorf("New(%d, WithTime) has time %s", tt.version, got)
+			}
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		version byte
+		opts    []Option
+	}{
+		{0, nil},
+		{2, nil},
+		{8, nil},
+		{3, []Option{WithName("x")}},
+		{5, nil},
+		{4, []Option{WithNamespace(NamespaceURL), WithName("x")}},
+		{4, []Option{WithTime(time.Now())}},
+		{6, []Option{WithTime(time.Now())}},
+	}
+	for _, tt := range tests {
+		if u, err := New(tt.version, tt.opts...); err == nil {
+			t.Errorf("New(%d) with %d options = %s, want an error", tt.version, len(tt.opts), u)
+		}
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	ctx := context.Background()
+	if g := FromContext(ctx); g != nil {
+		t.Errorf("empty context has generator %v", g)
+	}
+	if u, err := New(4, WithContext(ctx)); err != nil || u.Version() != 4 {
+		t.Errorf("New without a generator in the context = %s, %v", u, err)
+	}
+
+	fixed := NewV4()
+	ctx = NewContext(ctx, GeneratorFunc(func() UUID { return fixed }))
+	if u := FromContext(ctx).New(); u != fixed {
+		t.Errorf("FromContext's generator made %s", u)
+	}
+	if u, err := New(4, WithContext(ctx)); err != nil || u != fixed {
+		t.Errorf("New = %s, %v, want %s", u, err, fixed)
+	}
+	if _, err := New(7, WithContext(ctx)); err == nil {
+		t.Error("V4 generator accepted for V7")
+	}
+	if _, err := New(7, WithContext(ctx), WithTime(time.Now())); err == nil {
+		t.Error("generator from the context given a time")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+)
+
+var namespaces = map[string]UUID{
+	"dns":  NamespaceDNS,
+	"url":  NamespaceURL,
+	"oid":  NamespaceOID,
+	"x500": NamespaceX500,
+}
+
+func runNS(args []string) error {
+	fs := flag.NewFlagSet("ns", flag.ExitOnError)
+	nsName := fs.String("namespace", "dns", "namespace: dns, url, oid, x500 or a UUID")
+	name := fs.String("name", "", "name to hash; if empty, each line of stdin is a name")
+	version := fs.Int("version", 5, "UUID version: 3 (MD5) or 5 (SHA-1)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen ns [flags]")
+		fmt.Fprintln(fs.Output(), "Derives name based UUIDs, which are always the same for the same namespace and name.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ns, ok := namespaces[*nsName]
+	if !ok {
+		var err error
+		if ns, err = Parse(*nsName); err != nil {
+			return fmt.Errorf("namespace must be dns, url, oid, x500 or a UUID: %v", err)
+		}
+	}
+
+	var derive func(UUID, string) UUID
+	switch *version {
+	case 3:
+		derive = NewV3
+	case 5:
+		derive = NewV5
+	default:
+		return fmt.Errorf("unsupported version %d, want 3 or 5", *version)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	each := func(name string) error {
+		_, err := fmt.Fprintln(w, derive(ns, name))
+		return err
+	}
+	if *name != "" {
+		return each(*name)
+	}
+	return forEachLine(nil, each)
+}
@@ -0,0 +1,28 @@
+package main
+
+// ToOrdered rearranges a V1 UUID's time fields most significant
+// first: time_hi, time_mid, time_low, then the rest unchanged.  That's
+// MySQL's UUID_TO_BIN(u, 1), the trick Percona popularized, which
+// makes V1 UUIDs from one node increase over time and so keeps inserts
+// into an index on them together at the end.
+//
+// The result is only for storing; it isn't a valid UUID, since the
+// version bits have moved.  FromOrdered undoes it.
+func ToOrdered(u UUID) UUID {
+	o := UUID{}
+	copy(o[0:2], u[6:8])
+	copy(o[2:4], u[4:6])
+	copy(o[4:8], u[0:4])
+	copy(o[8:], u[8:])
+	return o
+}
+
+// FromOrdered returns the UUID that ToOrdered turned into o.
+func FromOrdered(o UUID) UUID {
+	u := UUID{}
+	copy(u[0:4], o[4:8])
+	copy(u[4:6], o[2:4])
+	copy(u[6:8], o[0:2])
+	copy(u[8:], o[8:])
+	return u
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+)
+
+// hexValues maps each hex digit to its value and everything else to
+// 0xff.
+var hexValues = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+	for i, c := range "0123456789abcdef" {
+		t[c] = byte(i)
+	}
+	for i, c := range "ABCDEF" {
+		t[c] = byte(10 + i)
+	}
+	return t
+}()
+
+// What a ParseError expected to find.
+const (
+	ExpectedHexDigit = "hex digit"
+	ExpectedDash     = "'-'"
+	ExpectedMore     = "more characters"
+	ExpectedEnd      = "end of input"
+)
+
+// ParseError is returned by Parse and ParseBytes, to say what was wrong
+// with the input and where.
+type ParseError struct {
+	// Input is the text that failed to parse.
+	Input string
+	// Offset is the byte offset in Input of the first bad character.
+	// It is len(Input) if Input is too short.
+	Offset int
+	// Expected is one of the Expected constants.
+	Expected string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid UUID %q: %s", e.Input, e.Detail())
+}
+
+// Detail describes the problem without repeating the input.
+func (e *ParseError) Detail() string {
+	found := "end of input"
+	if e.Offset < len(e.Input) {
+		found = fmt.Sprintf("%q", e.Input[e.Offset])
+	}
+	return fmt.Sprintf("byte %d: expected %s, found %s", e.Offset, e.Expected, found)
+}
+
+// Parse parses the canonical string representation of a UUID:
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.  On failure the error is a
+// *ParseError.
+func Parse(s string) (UUID, error) {
+	u, offset, expected := parse(s)
+	if offset >= 0 {
+		return UUID{}, &ParseError{s, offset, expected}
+	}
+	return u, nil
+}
+
+// ParseBytes is like Parse, but parses a byte slice without first
+// converting it to a string.  It only allocates on failure.
+func ParseBytes(b []byte) (UUID, error) {
+	u, offset, expected := parse(b)
+	if offset >= 0 {
+		return UUID{}, &ParseError{string(b), offset, expected}
+	}
+	return u, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, using the canonical
+// string representation.
+func (u UUID) MarshalText() ([]byte, error) {
+	buf := make([]byte, 36)
+	encodeCanonical(buf, u)
+	return buf, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.  It parses the
+// canonical string representation without allocating.
+func (u *UUID) UnmarshalText(b []byte) error {
+	id, err := ParseBytes(b)
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
+
+// parse is Parse, but on failure it reports the offset of the first
+// bad character and what was expected there.  offset is -1 on
+// success.  It takes bytes too, so that callers holding a []byte
+// don't have to copy it to a string.
+func parse[T string | []byte](s T) (u UUID, offset int, expected string) {
+	j := 0
+	for i := 0; i < len(s) && i < 36; i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != dash {
+				return UUID{}, i, ExpectedDash
+			}
+			continue
+		}
+		v := hexValues[s[i]]
+		if v == 0xff {
+			return UUID{}, i, ExpectedHexDigit
+		}
+		if j%2 == 0 {
+			u[j/2] = v << 4
+		} else {
+			u[j/2] |= v
+		}
+		j++
+	}
+
+	switch {
+	case len(s) < 36:
+		return UUID{}, len(s), ExpectedMore
+	case len(s) > 36:
+		return UUID{}, 36, ExpectedEnd
+	}
+	return u, -1, ""
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, gen := range []func() UUID{NewV1, NewV4, NewV6, NewV7} {
+		u := gen()
+		got, err := Parse(u.String())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", u, err)
+		}
+		if got != u {
+			t.Errorf("Parse(%q) = %s", u, got)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8a",
+		"6ba7b810x9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg",
+	} {
+		if u, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", s, u)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	u := NewV4()
+	got, err := ParseBytes([]byte(u.String()))
+	if err != nil || got != u {
+		t.Errorf("ParseBytes(%s) = %s, %v", u, got, err)
+	}
+	if _, err := ParseBytes([]byte("nope")); err == nil {
+		t.Errorf("ParseBytes accepted nope")
+	}
+
+	b := []byte(u.String())
+	if n := testing.AllocsPerRun(100, func() { ParseBytes(b) }); n != 0 {
+		t.Errorf("ParseBytes allocates %v times", n)
+	}
+}
+
+func TestTextMarshaling(t *testing.T) {
+	type record struct {
+		ID UUID `json:"id"`
+	}
+	in := record{NewV1()}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":"` + in.ID.String() + `"}`; string(b) != want {
+		t.Errorf("marshaled to %s, want %s", b, want)
+	}
+
+	var out record
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("got %s, want %s", out.ID, in.ID)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"nope"}`), &out); err == nil {
+		t.Errorf("unmarshaled an invalid UUID")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	s := NewV4().String()
+	for n := 0; n < b.N; n++ {
+		Parse(s)
+	}
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	s := []byte(NewV4().String())
+	for n := 0; n < b.N; n++ {
+		ParseBytes(s)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, tt := range []struct {
+		in       string
+		offset   int
+		expected string
+	}{
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430cg", 35, ExpectedHexDigit},
+		{"6ba7b810x9dad-11d1-80b4-00c04fd430c8", 8, ExpectedDash},
+		{"6ba7b810-9dad", 13, ExpectedMore},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8}", 36, ExpectedEnd},
+	} {
+		_, err := Parse(tt.in)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("Parse(%q): got %v, want a *ParseError", tt.in, err)
+		}
+		if perr.Input != tt.in || perr.Offset != tt.offset || perr.Expected != tt.expected {
+			t.Errorf("Parse(%q): got %+v", tt.in, perr)
+		}
+	}
+
+	_, err := NewDecoder(strings.NewReader("\nbad\n")).Decode()
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Input != "bad" {
+		t.Errorf("Decode: got %v, want a wrapped *ParseError", err)
+	}
+}
+
+func TestParseMany(t *testing.T) {
+	want := []UUID{NewV4(), NewV4(), NewV4()}
+	src := make([][]byte, len(want))
+	for i, u := range want {
+		src[i] = []byte(u.String())
+	}
+	// Upper case is fine too.
+	src[1] = []byte(strings.ToUpper(string(src[1])))
+
+	dst := make([]UUID, 5)
+	if err := ParseMany(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range want {
+		if dst[i] != u {
+			t.Errorf("%d: got %s, want %s", i, dst[i], u)
+		}
+	}
+
+	if err := ParseMany(dst[:2], src); err == nil {
+		t.Error("too small a dst should fail")
+	}
+	for _, bad := range []string{"6ba7b810-9dad-11d1-80b4-00c04fd430cg", "6ba7b810+9dad-11d1-80b4-00c04fd430c8", "6ba7b810"} {
+		src[2] = []byte(bad)
+		err := ParseMany(dst, src)
+		var perr *ParseError
+		if !errors.As(err, &perr) || !strings.HasPrefix(err.Error(), "id 2: ") {
+			t.Errorf("%s: got %v", bad, err)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	a, b, c := NewV4(), NewV4(), NewV4()
+	block := []byte(a.String() + "\n" + b.String() + "\r\n" + c.String())
+	dst := make([]UUID, 2)
+	n, err := ParseLines(dst, block)
+	if err != nil || n != 2 || dst[0] != a || dst[1] != b {
+		t.Fatalf("got %d %v, %v", n, dst, err)
+	}
+	dst = make([]UUID, 10)
+	if n, err := ParseLines(dst, block); err != nil || n != 3 || dst[2] != c {
+		t.Errorf("got %d %v, %v", n, dst, err)
+	}
+	if n, err := ParseLines(dst, []byte(a.String()+"\nnope\n")); n != 1 || err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("got %d, %v", n, err)
+	}
+}
+
+func BenchmarkParseMany(b *testing.B) {
+	src := make([][]byte, 1000)
+	for i := range src {
+		src[i] = []byte(NewV4().String())
+	}
+	dst := make([]UUID, len(src))
+
+	b.Run("ParseBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j, s := range src {
+				dst[j], _ = ParseBytes(s)
+			}
+		}
+	})
+	b.Run("ParseMany", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ParseMany(dst, src)
+		}
+	})
+}
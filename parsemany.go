@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// hexOffsets are where each of the 32 hex digits of a canonical UUID
+// are.
+var hexOffsets = [32]byte{
+	0, 1, 2, 3, 4, 5, 6, 7,
+	9, 10, 11, 12,
+	14, 15, 16, 17,
+	19, 20, 21, 22,
+	24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35,
+}
+
+// parseFixed parses exactly 36 bytes.  Taking an array pointer lets
+// the compiler drop the bounds checks, and it checks all the digits at
+// once at the end instead of one at a time.  ok is false if s is not a
+// canonical UUID, in which case parse says why.
+func parseFixed(s *[36]byte) (u UUID, ok bool) {
+	bad := byte(0)
+	for i := 0; i < 16; i++ {
+		hi := hexValues[s[hexOffsets[i*2]]]
+		lo := hexValues[s[hexOffsets[i*2+1]]]
+		// Any invalid digit is 0xff, which sets the high bit.
+		bad |= hi | lo
+		u[i] = hi<<4 | lo
+	}
+	ok = bad&0x80 == 0 && s[8] == dash && s[13] == dash && s[18] == dash && s[23] == dash
+	return u, ok
+}
+
+// ParseMany parses each of src into the same index of dst, which must
+// be at least as long.  It is meant for columns of millions of IDs:
+// it only allocates if it fails, when it says which ID was bad and why
+// with a wrapped *ParseError.
+func ParseMany(dst []UUID, src [][]byte) error {
+	if len(dst) < len(src) {
+		return fmt.Errorf("ParseMany: %d UUIDs won't fit in %d", len(src), len(dst))
+	}
+	dst = dst[:len(src)]
+	for i, s := range src {
+		if len(s) == 36 {
+			if u, ok := parseFixed((*[36]byte)(s)); ok {
+				dst[i] = u
+				continue
+			}
+		}
+		_, offset, expected := parse(s)
+		return fmt.Errorf("id %d: %w", i, &ParseError{string(s), offset, expected})
+	}
+	return nil
+}
+
+// ParseLines parses a block of canonical UUIDs, one per line, into
+// dst, returning how many it parsed.  Lines may end in \n or \r\n, and
+// the last needn't end at all.  Like ParseMany, it only allocates if
+// it fails, and it stops if dst is full, so a big block can be parsed
+// into a reused dst a piece at a time by calling it again on what's
+// left, block[n*37:] if every line is \n terminated.
+func ParseLines(dst []UUID, block []byte) (int, error) {
+	n := 0
+	for len(block) > 0 && n < len(dst) {
+		line := block
+		if i := bytes.IndexByte(block, '\n'); i >= 0 {
+			line, block = block[:i], block[i+1:]
+		} else {
+			block = nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if len(line) == 36 {
+			if u, ok := parseFixed((*[36]byte)(line)); ok {
+				dst[n] = u
+				n++
+				continue
+			}
+		}
+		_, offset, expected := parse(line)
+		return n, fmt.Errorf("line %d: %w", n+1, &ParseError{string(line), offset, expected})
+	}
+	return n, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import "encoding/binary"
+
+// PartitionKey returns u as a Kafka record key: its canonical string
+// form in UTF-8, as Kafka's own UUIDSerializer and the usual
+// StringSerializer of u.toString() write it, so that Java and Go
+// producers hash the same bytes.
+func PartitionKey(u UUID) []byte {
+	return []byte(u.String())
+}
+
+// Partition returns the partition of numPartitions that Kafka's
+// default partitioner puts a record with key in: the positive part of
+// its murmur2 hash, modulo numPartitions.  It panics if numPartitions
+// isn't positive.
+func Partition(key []byte, numPartitions int) int {
+	if numPartitions <= 0 {
+		panic("Partition needs at least one partition")
+	}
+	return int(murmur2(key)&0x7fffffff) % numPartitions
+}
+
+// murmur2 is MurmurHash2 as Kafka's Utils.murmur2 has it, with its
+// seed.
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	n := len(data)
+	h := uint32(seed) ^ uint32(n)
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestMurmur2(t *testing.T) {
+	// From Kafka's UtilsTest.
+	for s, want := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		if got := int32(murmur2([]byte(s))); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	u, err := Parse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := PartitionKey(u)
+	if string(key) != u.String() {
+		t.Errorf("key %q", key)
+	}
+	for _, n := range []int{1, 3, 12, 1000} {
+		if p := Partition(key, n); p < 0 || p >= n || p != Partition(PartitionKey(u), n) {
+			t.Errorf("partition %d of %d", p, n)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// PooledGenerator hands each goroutine a generator of its own, so that
+// making a UUID shares nothing with other goroutines at all: no lock,
+// no atomic, no channel.  Goroutines that make a lot of UUIDs should
+// Get a LocalGenerator once and keep it, say for a worker's lifetime,
+// then Put it back; New does all three for each UUID, which still only
+// costs the pool's per-P fast path.
+//
+// A LocalGenerator can't share the machine's node ID, as two of them
+// would make the same UUID in the same tick, and a sync.Pool drops its
+// contents at GC, so handing out clock sequences would soon run
+// through all 16384.  So each one gets a random node ID, with the
+// multicast bit set as RFC 4122 says for node IDs that aren't MAC
+// addresses, and a random clock sequence: 61 random bits, which two
+// generators would need to share before they could collide.
+type PooledGenerator struct {
+	pool      sync.Pool
+	epochFunc func() uint64
+	counters  generatorCounters
+}
+
+func NewPooledGenerator() *PooledGenerator {
+	return newPooledGenerator(unixTimeFunc)
+}
+
+var _ = registerExperiment(experiment{
+	Name:        "pool",
+	Description: "a generator of its own for each goroutine, from a sync.Pool",
+	New:         func(int) Generator { return NewPooledGenerator() },
+})
+
+// newPooledGenerator lets tests inject a fake clock.  epochFunc is
+// called from every goroutine with a LocalGenerator.
+func newPooledGenerator(epochFunc func() uint64) *PooledGenerator {
+	p := &PooledGenerator{epochFunc: epochFunc}
+	p.pool.New = func() any {
+		l := &LocalGenerator{parent: p}
+		safeRandom(l.hardwareAddr[:])
+		l.hardwareAddr[0] |= 0x01
+		l.clockSequence = initClockSequence()
+		return l
+	}
+	return p
+}
+
+// Get returns a LocalGenerator for the calling goroutine's own use.
+func (p *PooledGenerator) Get() *LocalGenerator {
+	return p.pool.Get().(*LocalGenerator)
+}
+
+// Put returns l to the pool.  l must not be used after.
+func (p *PooledGenerator) Put(l *LocalGenerator) {
+	p.counters.generated.Add(l.generated)
+	p.counters.borrowed.Add(l.borrowed)
+	l.generated, l.borrowed = 0, 0
+	p.pool.Put(l)
+}
+
+// New returns a UUID from a LocalGenerator it gets and puts back.
+func (p *PooledGenerator) New() UUID {
+	l := p.Get()
+	u := l.New()
+	p.Put(l)
+	return u
+}
+
+// Stats reports on p's UUIDs, as of the LocalGenerators last Put back.
+func (p *PooledGenerator) Stats() GeneratorStats {
+	return p.counters.stats()
+}
+
+// LocalGenerator makes V1 UUIDs for one goroutine.  It isn't safe for
+// concurrent use.  Like AtomicGenerator, when the clock hasn't moved
+// on, or has gone backwards, it borrows the tick after its last one
+// rather than bumping the clock sequence.
+type LocalGenerator struct {
+	parent        *PooledGenerator
+	lastTime      uint64
+	clockSequence uint16
+	hardwareAddr  [6]byte
+	// generated and borrowed are counted here, without atomics, until
+	// Put adds them to the parent's.
+	generated, borrowed uint64
+}
+
+// New returns a new V1 UUID.
+func (l *LocalGenerator) New() UUID {
+	timeNow := l.parent.epochFunc()
+	if timeNow <= l.lastTime {
+		timeNow = l.lastTime + 1
+		l.borrowed++
+	}
+	l.lastTime = timeNow
+	l.generated++
+
+	u := UUID{}
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+	binary.BigEndian.PutUint16(u[8:], l.clockSequence)
+
+	copy(u[10:], l.hardwareAddr[:])
+
+	u.SetVersion(1)
+	u.SetVariant()
+
+	return u
+}
@@ -0,0 +1,172 @@
+/**
+
+Pooled generators
+
+The usual way to take the contention out of a shared generator is to
+shard it: several generators, each behind its own lock, with callers
+spread across them.  PooledGenerator goes further and gives each
+goroutine a generator of its own, so there is nothing to share.  This
+compares them, and AtomicGenerator, at 1 to 1024 goroutines.  sharded
+is 4 SatoriGenerators a CPU, taken in turn with an atomic counter;
+pool-new gets and puts a LocalGenerator for every UUID; pool-local
+gets one per goroutine and keeps it.
+
+On a 1 CPU Xeon VM, ns/op:
+
+  goroutines      1      4     16     64    256   1024
+  sharded     134.9  143.6  132.3  137.0  138.8  129.1
+  atomic      105.7  106.1  102.5  106.0  105.1  103.1
+  pool-new    112.2  113.2  121.9  117.8  110.4  118.0
+  pool-local   94.3   88.7   91.6   95.2   85.2   91.3
+
+Take-aways:
+
+ - With one CPU, only one goroutine runs at a time, so nothing is
+   ever contended and the number of goroutines makes no difference.
+   What is left is the cost of each approach's bookkeeping.  That
+   needs repeating on a machine with many cores, where the sharded
+   locks and atomic's one cache line get fought over and a
+   LocalGenerator's state never leaves its core.
+
+ - Even so, a kept LocalGenerator is the cheapest, 30% under sharded
+   and 11% under atomic: reading the clock is most of what is left.
+
+ - Getting and putting one from the pool for every UUID gives back
+   most of that, to end up between atomic and sharded.  Keep one per
+   worker where the code allows.
+
+ - The sharded generator's atomic counter plus an uncontended lock
+   cost more than atomic's one compare and swap.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPooledGenerator(t *testing.T) {
+	// One tick for everyone, so every UUID after a LocalGenerator's
+	// first is borrowed.
+	now := unixTimeFunc()
+	p := newPooledGenerator(func() uint64 { return now })
+	var (
+		mu   sync.Mutex
+		seen = map[UUID]bool{}
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]UUID, 0, 2000)
+			l := p.Get()
+			for i := 0; i < 1000; i++ {
+				ids = append(ids, l.New())
+			}
+			p.Put(l)
+			for i := 0; i < 1000; i++ {
+				ids = append(ids, p.New())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, u := range ids {
+				if seen[u] || u.Version() != 1 || u[10]&0x01 == 0 {
+					t.Errorf("bad or repeated %s", u)
+				}
+				seen[u] = true
+			}
+		}()
+	}
+	wg.Wait()
+	if s := p.Stats(); s.Generated != 16000 || s.Borrowed == 0 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+// shardedGenerator is the baseline: SatoriGenerators with their own
+// node IDs, taken in turn.
+type shardedGenerator struct {
+	shards []*SatoriGenerator
+	next   atomic.Uint32
+}
+
+func newShardedGenerator(n int) *shardedGenerator {
+	s := &shardedGenerator{}
+	for i := 0; i < n; i++ {
+		g := NewSatoriGenerator()
+		safeRandom(g.hardwareAddr[:])
+		g.hardwareAddr[0] |= 0x01
+		s.shards = append(s.shards, g)
+	}
+	return s
+}
+
+func (s *shardedGenerator) New() UUID {
+	return s.shards[s.next.Add(1)%uint32(len(s.shards))].NewV1()
+}
+
+func BenchmarkPooledGenerator(b *testing.B) {
+	for _, goroutines := range []int{1, 4, 16, 64, 256, 1024} {
+		for _, v := range []struct {
+			name string
+			run  func(n int)
+		}{
+			{"sharded", func() func(int) {
+				g := newShardedGenerator(4 * runtime.GOMAXPROCS(0))
+				return func(n int) {
+					for i := 0; i < n; i++ {
+						g.New()
+					}
+				}
+			}()},
+			{"atomic", func() func(int) {
+				g := NewAtomicGenerator()
+				return func(n int) {
+					for i := 0; i < n; i++ {
+						g.New()
+					}
+				}
+			}()},
+			{"pool-new", func() func(int) {
+				p := NewPooledGenerator()
+				return func(n int) {
+					for i := 0; i < n; i++ {
+						p.New()
+					}
+				}
+			}()},
+			{"pool-local", func() func(int) {
+				p := NewPooledGenerator()
+				return func(n int) {
+					l := p.Get()
+					for i := 0; i < n; i++ {
+						l.New()
+					}
+					p.Put(l)
+				}
+			}()},
+		} {
+			b.Run(fmt.Sprintf("%s/goroutines=%d", v.name, goroutines), func(b *testing.B) {
+				var wg sync.WaitGroup
+				for w := 0; w < goroutines; w++ {
+					n := b.N / goroutines
+					if w < b.N%goroutines {
+						n++
+					}
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						v.run(n)
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// profileData is the part of a pprof profile that the contention
+// report needs: the sample types, and each sample's stack and values.
+// It is read by hand, rather than with github.com/google/pprof, to
+// keep this tree free of dependencies.
+type profileData struct {
+	// SampleTypes are the names of each sample's values, such as
+	// "contentions" and "delay".
+	SampleTypes []string
+	Samples     []profileSample
+}
+
+type profileSample struct {
+	// Stack holds function names, innermost first, with inlined
+	// functions as frames of their own.
+	Stack  []string
+	Values []int64
+}
+
+// valueIndex returns the index of the named sample type, or -1.
+func (p *profileData) valueIndex(name string) int {
+	for i, t := range p.SampleTypes {
+		if t == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// readProfile reads a profile as written by pprof's WriteTo with debug
+// 0: a gzipped profile.proto.
+func readProfile(r io.Reader) (*profileData, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	return parseProfileProto(b)
+}
+
+// Field numbers in profile.proto.
+const (
+	profSampleType  = 1
+	profSample      = 2
+	profLocation    = 4
+	profFunction    = 5
+	profStringTable = 6
+)
+
+// parseProfileProto decodes a profile.proto message.
+func parseProfileProto(b []byte) (*profileData, error) {
+	var (
+		strs        []string
+		typeIdx     []int64
+		rawSamples  []rawSample
+		locFuncs    = map[uint64][]uint64{}
+		funcNameIdx = map[uint64]int64{}
+	)
+	err := protoFields(b, func(num int, v uint64, data []byte) error {
+		switch num {
+		case profStringTable:
+			strs = append(strs, string(data))
+		case profSampleType:
+			return protoFields(data, func(num int, v uint64, _ []byte) error {
+				if num == 1 {
+					typeIdx = append(typeIdx, int64(v))
+				}
+				return nil
+			})
+		case profSample:
+			var s rawSample
+			err := protoFields(data, func(num int, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					return protoUints(v, data, func(id uint64) { s.locs = append(s.locs, id) })
+				case 2:
+					return protoUints(v, data, func(x uint64) { s.values = append(s.values, int64(x)) })
+				}
+				return nil
+			})
+			rawSamples = append(rawSamples, s)
+			return err
+		case profLocation:
+			var id uint64
+			var funcs []uint64
+			err := protoFields(data, func(num int, v uint64, data []byte) error {
+				switch num {
+				case 1:
+					id = v
+				case 4:
+					return protoFields(data, func(num int, v uint64, _ []byte) error {
+						if num == 1 {
+							funcs = append(funcs, v)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			locFuncs[id] = funcs
+			return err
+		case profFunction:
+			var id uint64
+			var name int64
+			err := protoFields(data, func(num int, v uint64, _ []byte) error {
+				switch num {
+				case 1:
+					id = v
+				case 2:
+					name = int64(v)
+				}
+				return nil
+			})
+			funcNameIdx[id] = name
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(strs)) {
+			return "?"
+		}
+		return strs[i]
+	}
+	p := &profileData{}
+	for _, i := range typeIdx {
+		p.SampleTypes = append(p.SampleTypes, str(i))
+	}
+	for _, rs := range rawSamples {
+		s := profileSample{Values: rs.values}
+		for _, loc := range rs.locs {
+			// A location's lines start with the innermost inlined
+			// function, just as samples start with the innermost
+			// location.
+			for _, fn := range locFuncs[loc] {
+				s.Stack = append(s.Stack, str(funcNameIdx[fn]))
+			}
+		}
+		p.Samples = append(p.Samples, s)
+	}
+	return p, nil
+}
+
+// rawSample is a sample before its location IDs are looked up.
+type rawSample struct {
+	locs   []uint64
+	values []int64
+}
+
+var errBadProto = errors.New("malformed protobuf")
+
+// protoFields calls f with each field of the protobuf message in b:
+// its number, and either its value, for varint and fixed width fields,
+// or its bytes, for length delimited ones.
+func protoFields(b []byte, f func(num int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errBadProto
+		}
+		b = b[n:]
+		var v uint64
+		var data []byte
+		switch key & 7 {
+		case 0:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errBadProto
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return errBadProto
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errBadProto
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return errBadProto
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return fmt.Errorf("%w: wire type %d", errBadProto, key&7)
+		}
+		if err := f(int(key>>3), v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoUints calls f with each value of a repeated varint field, which
+// may be packed into data or be the single value v.
+func protoUints(v uint64, data []byte, f func(uint64)) error {
+	if data == nil {
+		f(v)
+		return nil
+	}
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		x, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errBadProto
+		}
+		f(x)
+	}
+	return nil
+}
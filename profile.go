@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+)
+
+// benchProfiles names the profile files captured during one bench run.
+type benchProfiles struct {
+	CPU   string `json:"cpu"`
+	Mutex string `json:"mutex"`
+	Block string `json:"block"`
+	// The runtime only keeps running totals of mutex and block
+	// profiles, so these are the previous run's, for
+	// go tool pprof -diff_base to subtract from Mutex and Block.
+	MutexBase string `json:"mutex_base,omitempty"`
+	BlockBase string `json:"block_base,omitempty"`
+}
+
+// profiler captures profiles of bench runs into a directory.
+type profiler struct {
+	dir                  string
+	lastMutex, lastBlock string
+}
+
+// newProfiler returns a profiler writing to dir, turning on mutex and
+// block profiling at the given rates, as setProfileRates takes them.
+// Profiling slows the runs down, the mutex and block profiles by more
+// the higher their rates, so the numbers are best taken from runs
+// without it.
+func newProfiler(dir string, mutexFraction, blockRate int) (*profiler, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	setProfileRates(mutexFraction, blockRate)
+	return &profiler{dir: dir}, nil
+}
+
+// fileSafe replaces the characters of s that don't belong in a file
+// name with underscores.
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// run calls f with the CPU profiler on, and writes the CPU, mutex and
+// block profiles to files starting with name.
+func (p *profiler) run(name string, f func()) (*benchProfiles, error) {
+	base := filepath.Join(p.dir, fileSafe(name))
+	bp := &benchProfiles{
+		CPU:       base + ".cpu.pprof",
+		Mutex:     base + ".mutex.pprof",
+		Block:     base + ".block.pprof",
+		MutexBase: p.lastMutex,
+		BlockBase: p.lastBlock,
+	}
+
+	cpu, err := os.Create(bp.CPU)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, err
+	}
+	f()
+	pprof.StopCPUProfile()
+	if err := cpu.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, prof := range []struct{ name, file string }{
+		{"mutex", bp.Mutex},
+		{"block", bp.Block},
+	} {
+		out, err := os.Create(prof.file)
+		if err != nil {
+			return nil, err
+		}
+		err = pprof.Lookup(prof.name).WriteTo(out, 0)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.lastMutex, p.lastBlock = bp.Mutex, bp.Block
+	return bp, nil
+}
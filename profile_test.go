@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProfiler(t *testing.T) {
+	defer setProfileRates(0, 0)
+	p, err := newProfiler(t.TempDir(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first, second *benchProfiles
+	for _, bp := range []**benchProfiles{&first, &second} {
+		*bp, err = p.run("satori-p4-GOGC=50 GOMEMLIMIT=off", func() {
+			benchRun(NewSatoriGenerator(), 4, 20*time.Millisecond)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.CPU != filepath.Join(p.dir, "satori-p4-GOGC_50_GOMEMLIMIT_off.cpu.pprof") {
+		t.Errorf("CPU profile %s", first.CPU)
+	}
+	for _, name := range []string{first.CPU, first.Mutex, first.Block} {
+		if fi, err := os.Stat(name); err != nil || fi.Size() == 0 {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if first.MutexBase != "" || second.MutexBase != first.Mutex || second.BlockBase != first.Block {
+		t.Errorf("bases: first %+v, second %+v", first, second)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+
+// How UUIDs cross protobuf boundaries, without a protoc plugin: as a
+// string field in canonical form, or where size matters, a bytes
+// field holding the 16 bytes in order.  Either way the field's default
+// value, "" or empty bytes, is the nil UUID, so an unset field reads
+// as the zero UUID rather than an error, and the nil UUID is never
+// sent explicitly.  Name the fields for what they identify, such as
+// order_id, not for their encoding.
+const (
+	// ProtoStringLen is the length of a set UUID string field.
+	ProtoStringLen = 36
+	// ProtoBytesLen is the length of a set UUID bytes field.
+	ProtoBytesLen = 16
+)
+
+// ToProtoString returns u for a protobuf string field: in canonical
+// lower case form, or "" for the nil UUID.
+func ToProtoString(u UUID) string {
+	if u == (UUID{}) {
+		return ""
+	}
+	return u.String()
+}
+
+// FromProtoString returns the UUID in a protobuf string field, the nil
+// UUID if it is unset.
+func FromProtoString(s string) (UUID, error) {
+	if s == "" {
+		return UUID{}, nil
+	}
+	return Parse(s)
+}
+
+// ToProtoBytes returns u for a protobuf bytes field: its 16 bytes, or
+// nil for the nil UUID.
+func ToProtoBytes(u UUID) []byte {
+	if u == (UUID{}) {
+		return nil
+	}
+	return u.Bytes()
+}
+
+// FromProtoBytes returns the UUID in a protobuf bytes field, the nil
+// UUID if it is unset.
+func FromProtoBytes(b []byte) (UUID, error) {
+	switch len(b) {
+	case 0:
+		return UUID{}, nil
+	case ProtoBytesLen:
+		return UUID(b), nil
+	}
+	return UUID{}, fmt.Errorf("UUID bytes field is %d bytes, want %d", len(b), ProtoBytesLen)
+}
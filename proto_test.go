@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestProto(t *testing.T) {
+	u := NewV4()
+	s := ToProtoString(u)
+	if len(s) != ProtoStringLen {
+		t.Errorf("%q is not %d characters", s, ProtoStringLen)
+	}
+	if got, err := FromProtoString(s); err != nil || got != u {
+		t.Errorf("string round trip: %s, %v", got, err)
+	}
+	b := ToProtoBytes(u)
+	if got, err := FromProtoBytes(b); err != nil || got != u || len(b) != ProtoBytesLen {
+		t.Errorf("bytes round trip: %s, %v", got, err)
+	}
+
+	// Unset fields are the nil UUID, both ways.
+	if ToProtoString(UUID{}) != "" || ToProtoBytes(UUID{}) != nil {
+		t.Error("nil UUID was set")
+	}
+	if got, err := FromProtoString(""); err != nil || got != (UUID{}) {
+		t.Errorf("unset string: %s, %v", got, err)
+	}
+	if got, err := FromProtoBytes(nil); err != nil || got != (UUID{}) {
+		t.Errorf("unset bytes: %s, %v", got, err)
+	}
+
+	if _, err := FromProtoString("nope"); err == nil {
+		t.Error("parsed nope")
+	}
+	if _, err := FromProtoBytes(b[:15]); err == nil {
+		t.Error("parsed 15 bytes")
+	}
+}
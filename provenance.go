@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// provenanceStrategies gives each V1 strategy the code a provenance
+// tag records it by, its index.  Codes are in IDs already made, so
+// new strategies go on the end, and none is ever removed or moved.
+// 0 is for strategies without a code.
+var provenanceStrategies = []string{"", "mutex", "satori", "channel", "lockfree", "atomic", "auto", "pool", "libuuid"}
+
+// provenanceMarker is in the 6 bits after the variant of every tagged
+// UUID, so that DecodeProvenance can tell them from most other V8s.
+const provenanceMarker = 0x2a
+
+// nodeOrdinal is the instance number tagged UUIDs carry.
+var nodeOrdinal atomic.Uint32
+
+// setNodeOrdinal makes the provenance generators made after it tag
+// their UUIDs with n, which should be different for each instance
+// making them.
+func setNodeOrdinal(n uint8) {
+	nodeOrdinal.Store(uint32(n))
+}
+
+// ProvenanceGenerator makes V8 UUIDs that say where they came from, so
+// that a duplicate or malformed ID found in production can be traced
+// to the instance, and the strategy, that made it.  It takes the
+// timestamp and clock sequence of a V1 UUID from a generator of the
+// strategy, and lays them out with a tag, as DecodeProvenance reads
+// them:
+//
+//	bits 0-47    timestamp, 100ns ticks since 1582, high 48 bits
+//	bits 48-51   version, 8
+//	bits 52-63   timestamp, low 12 bits
+//	bits 64-65   variant
+//	bits 66-71   provenanceMarker
+//	bits 72-75   strategy code, from provenanceStrategies
+//	bits 76-83   node ordinal
+//	bits 84-97   clock sequence
+//	bits 98-127  random
+//
+// Like V6, they sort by time.  The V1 strategy's guarantees carry
+// over, with the node ordinal instead of the node ID, so instances
+// must have ordinals of their own; the random bits are only a further
+// guard.  It is safe for concurrent use if the V1 generator is.
+type ProvenanceGenerator struct {
+	g        Generator
+	strategy byte
+	node     uint8
+}
+
+// NewProvenanceGenerator returns a generator tagging g's UUIDs, which
+// must be V1, as made by strategy on the instance numbered node.
+func NewProvenanceGenerator(g Generator, strategy string, node uint8) *ProvenanceGenerator {
+	p := &ProvenanceGenerator{g: g, node: node}
+	for code, name := range provenanceStrategies {
+		if name == strategy {
+			p.strategy = byte(code)
+		}
+	}
+	return p
+}
+
+// v1State is the state of the V1 generator p tags.
+func (p *ProvenanceGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	if r, ok := p.g.(stateReporter); ok {
+		return r.v1State()
+	}
+	return 0, 0, [6]byte{}, false
+}
+
+// New returns a new tagged V8 UUID.
+func (p *ProvenanceGenerator) New() UUID {
+	v1 := p.g.New()
+	ticks := v1.ticks()
+	seq, _ := v1.ClockSequence()
+
+	u := UUID{}
+	safeRandom(u[12:])
+	binary.BigEndian.PutUint32(u[0:], uint32(ticks>>28))
+	binary.BigEndian.PutUint16(u[4:], uint16(ticks>>12))
+	binary.BigEndian.PutUint16(u[6:], uint16(ticks&0x0fff))
+	u[8] = provenanceMarker
+	u[9] = p.strategy<<4 | p.node>>4
+	// The node's low 4 bits, then the clock sequence, then the first 14
+	// random bits, kept.
+	binary.BigEndian.PutUint32(u[10:], uint32(p.node&0x0f)<<28|uint32(seq&0x3fff)<<14|binary.BigEndian.Uint32(u[10:])&0x3fff)
+
+	u.SetVersion(8)
+	u.SetVariant()
+
+	return u
+}
+
+// Stats reports on the V1 generator's UUIDs, if it keeps count.
+func (p *ProvenanceGenerator) Stats() GeneratorStats {
+	if sr, ok := p.g.(statsReporter); ok {
+		return sr.Stats()
+	}
+	return GeneratorStats{}
+}
+
+// Close stops the V1 generator, if it needs stopping.
+func (p *ProvenanceGenerator) Close() {
+	if c, ok := p.g.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// Provenance is what a ProvenanceGenerator's UUID says about where it
+// came from.
+type Provenance struct {
+	// Strategy is empty for a code this build doesn't know.
+	Strategy      string    `json:"strategy"`
+	StrategyCode  int       `json:"strategy_code"`
+	Node          uint8     `json:"node"`
+	Time          time.Time `json:"time"`
+	ClockSequence uint16    `json:"clock_sequence"`
+}
+
+func (p Provenance) String() string {
+	strategy := p.Strategy
+	if strategy == "" {
+		strategy = fmt.Sprintf("unknown strategy %d", p.StrategyCode)
+	}
+	return fmt.Sprintf("%s on node %d at %s, clock seq %d", strategy, p.Node, p.Time.UTC().Format(time.RFC3339Nano), p.ClockSequence)
+}
+
+// DecodeProvenance returns what a tagged UUID says about where it came
+// from.  ok is false for UUIDs that aren't V8 with the marker.  A V8
+// from elsewhere has the marker 1 time in 64, so a tag is only as good
+// as the knowledge that the UUID was made by a ProvenanceGenerator.
+func DecodeProvenance(u UUID) (p Provenance, ok bool) {
+	if u.Version() != 8 || u.Variant() != VariantRFC4122 || u[8]&0x3f != provenanceMarker {
+		return p, false
+	}
+	ticks := uint64(binary.BigEndian.Uint32(u[0:]))<<28 |
+		uint64(binary.BigEndian.Uint16(u[4:]))<<12 |
+		uint64(binary.BigEndian.Uint16(u[6:])&0x0fff)
+	rest := binary.BigEndian.Uint32(u[10:])
+	p = Provenance{
+		StrategyCode:  int(u[9] >> 4),
+		Node:          u[9]<<4 | byte(rest>>28),
+		Time:          epochToTime(ticks),
+		ClockSequence: uint16(rest>>14) & 0x3fff,
+	}
+	if p.StrategyCode > 0 && p.StrategyCode < len(provenanceStrategies) {
+		p.Strategy = provenanceStrategies[p.StrategyCode]
+	}
+	return p, true
+}
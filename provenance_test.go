@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	ticks := uint64(0x1ec9414c232a6b0)
+	g := NewProvenanceGenerator(GeneratorFunc(func() UUID {
+		ticks++
+		return NewV1At(epochToTime(ticks), 0x2abc, [6]byte{1, 2, 3, 4, 5, 6})
+	}), "atomic", 0xa5)
+
+	var last UUID
+	for i := 0; i < 100; i++ {
+		u := g.New()
+		if u.Version() != 8 || u.Variant() != VariantRFC4122 {
+			t.Fatalf("%s: version %d, variant %d", u, u.Version(), u.Variant())
+		}
+		if u.Compare(last) <= 0 {
+			t.Errorf("%s doesn't sort after %s", u, last)
+		}
+		last = u
+
+		p, ok := DecodeProvenance(u)
+		if !ok {
+			t.Fatalf("%s: not tagged", u)
+		}
+		want := Provenance{Strategy: "atomic", StrategyCode: 5, Node: 0xa5, Time: epochToTime(ticks), ClockSequence: 0x2abc}
+		if p != want {
+			t.Fatalf("%s: got %+v, want %+v", u, p, want)
+		}
+	}
+
+	if _, ok := DecodeProvenance(NewV7()); ok {
+		t.Error("V7 decoded as tagged")
+	}
+	u := g.New()
+	u[8] ^= 0x01
+	if _, ok := DecodeProvenance(u); ok {
+		t.Error("V8 without the marker decoded as tagged")
+	}
+}
+
+func TestProvenanceServed(t *testing.T) {
+	setNodeOrdinal(7)
+	t.Cleanup(func() { setNodeOrdinal(0) })
+	g, err := newGenerator(8, "channel", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.(*ProvenanceGenerator).Close()
+	if err := SelfTest(g); err != nil {
+		t.Fatal(err)
+	}
+	p, ok := DecodeProvenance(g.New())
+	if !ok || p.Strategy != "channel" || p.Node != 7 || time.Since(p.Time) > time.Minute {
+		t.Errorf("got %+v, %v", p, ok)
+	}
+}
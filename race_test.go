@@ -0,0 +1,115 @@
+//go:build race
+
+/**
+
+Race detector stress tests
+
+These only build with the race detector turned on:
+
+  go test -race -run Stress
+
+Every generator gets hammered from lots of goroutines at once.  The
+point is to make the thread safety assumptions explicit: the mutex
+versions lock, and the channel versions only ever touch their storage
+from a single producer goroutine.  If either of those stops being
+true, the race detector should complain here.
+
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+const (
+	stressGoroutines = 200
+	stressPerRoutine = 500
+)
+
+// stress calls gen from stressGoroutines goroutines at once and fails
+// if any UUID comes back more than once.
+func stress(t *testing.T, gen func() UUID) {
+	results := make([][]UUID, stressGoroutines)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids := make([]UUID, stressPerRoutine)
+			for n := range ids {
+				ids[n] = gen()
+			}
+			results[i] = ids
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[UUID]struct{}, stressGoroutines*stressPerRoutine)
+	for _, ids := range results {
+		for _, u := range ids {
+			if _, ok := seen[u]; ok {
+				t.Fatalf("duplicate UUID %s", u)
+			}
+			seen[u] = struct{}{}
+		}
+	}
+}
+
+func TestStressNewV1(t *testing.T) {
+	stress(t, NewV1)
+}
+
+func TestStressNewV1LockFree(t *testing.T) {
+	stress(t, NewV1LockFree)
+}
+
+func TestStressSatoriNewV1(t *testing.T) {
+	stress(t, NewSatoriGenerator().NewV1)
+}
+
+func TestStressChanneledNewV1(t *testing.T) {
+	for _, size := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("chansize=%d", size), func(t *testing.T) {
+			stress(t, NewChanneledGenerator(size).NewV1)
+		})
+	}
+}
+
+func TestStressAutoNewV1(t *testing.T) {
+	stress(t, NewAutoGenerator().NewV1)
+}
+
+func TestStressMonotonic(t *testing.T) {
+	stress(t, Monotonic(NewV7Generator()).New)
+}
+
+func TestStressMonitor(t *testing.T) {
+	m := NewMonitor(NewSatoriGenerator(), stressGoroutines*stressPerRoutine, 1e-6, nil)
+	stress(t, m.New)
+	if n := m.Suspects(); n != 0 {
+		t.Errorf("%d suspected duplicates", n)
+	}
+}
+
+// TestStressMixed runs every generator at the same time, since the
+// package level generators and the generator types used to share
+// storage with each other.
+func TestStressMixed(t *testing.T) {
+	gens := map[string]func() UUID{
+		"NewV1":         NewV1,
+		"NewV1LockFree": NewV1LockFree,
+		"Satori":        NewSatoriGenerator().NewV1,
+		"Channeled":     NewChanneledGenerator(10).NewV1,
+	}
+	for name, gen := range gens {
+		gen := gen
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			stress(t, gen)
+		})
+	}
+}
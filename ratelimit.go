@@ -0,0 +1,196 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenBucket allows rate tokens a second, saving up to burst of them.
+// It isn't safe for concurrent use.
+type tokenBucket struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// take takes n tokens if there are that many, and otherwise returns
+// how long until there will be.  Asking for more than burst costs a
+// full bucket, so that big requests are slowed rather than refused
+// forever.
+func (b *tokenBucket) take(n float64, now time.Time) (wait time.Duration, ok bool) {
+	b.refill(now)
+	n = math.Min(n, b.burst)
+	if b.tokens >= n {
+		b.tokens -= n
+		return 0, true
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second)), false
+}
+
+// reserve takes a token, going into debt if there isn't one, and
+// returns how long until the debt is paid off.  Each caller in turn
+// waits a token longer than the one before it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// full reports whether b is back to its burst, so that forgetting it
+// changes nothing.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// rateLimiter gives each client its own token bucket, and all of them
+// together a global one, so that one greedy client can't starve the
+// rest.  A zero rate turns that limit off.
+type rateLimiter struct {
+	clientRate, clientBurst float64
+
+	mu        sync.Mutex
+	global    *tokenBucket
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+	nowFunc   func() time.Time
+
+	limitedClient atomic.Uint64
+	limitedGlobal atomic.Uint64
+}
+
+// sweepEvery is how often a rateLimiter forgets clients whose buckets
+// have filled back up.
+const sweepEvery = time.Minute
+
+func newRateLimiter(clientRate, clientBurst, globalRate, globalBurst float64, nowFunc func() time.Time) *rateLimiter {
+	now := nowFunc()
+	l := &rateLimiter{
+		clientRate:  clientRate,
+		clientBurst: defaultBurst(clientRate, clientBurst),
+		clients:     make(map[string]*tokenBucket),
+		lastSweep:   now,
+		nowFunc:     nowFunc,
+	}
+	if globalRate > 0 {
+		l.global = newTokenBucket(globalRate, defaultBurst(globalRate, globalBurst), now)
+	}
+	return l
+}
+
+// setLimits changes l's limits, keeping what clients have saved up, up
+// to their new bursts.
+func (l *rateLimiter) setLimits(clientRate, clientBurst, globalRate, globalBurst float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.nowFunc()
+	l.clientRate, l.clientBurst = clientRate, defaultBurst(clientRate, clientBurst)
+	for _, b := range l.clients {
+		b.refill(now)
+		b.rate, b.burst = l.clientRate, l.clientBurst
+		b.tokens = math.Min(b.tokens, b.burst)
+	}
+	switch {
+	case globalRate <= 0:
+		l.global = nil
+	case l.global == nil:
+		l.global = newTokenBucket(globalRate, defaultBurst(globalRate, globalBurst), now)
+	default:
+		l.global.refill(now)
+		l.global.rate, l.global.burst = globalRate, defaultBurst(globalRate, globalBurst)
+		l.global.tokens = math.Min(l.global.tokens, l.global.burst)
+	}
+}
+
+// defaultBurst is a second's worth of tokens if burst isn't given.
+func defaultBurst(rate, burst float64) float64 {
+	if burst > 0 {
+		return burst
+	}
+	return math.Max(rate, 1)
+}
+
+// allow takes n tokens for client, returning how long to wait if
+// either its own limit or the global one says no.
+func (l *rateLimiter) allow(client string, n int) (wait time.Duration, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.nowFunc()
+	l.sweep(now)
+
+	var b *tokenBucket
+	if l.clientRate > 0 {
+		if b = l.clients[client]; b == nil {
+			b = newTokenBucket(l.clientRate, l.clientBurst, now)
+			l.clients[client] = b
+		}
+		if wait, ok := b.take(float64(n), now); !ok {
+			l.limitedClient.Add(1)
+			return wait, false
+		}
+	}
+	if l.global != nil {
+		if wait, ok := l.global.take(float64(n), now); !ok {
+			// Give the client back what it paid for nothing.
+			if b != nil {
+				b.tokens = math.Min(b.burst, b.tokens+math.Min(float64(n), b.burst))
+			}
+			l.limitedGlobal.Add(1)
+			return wait, false
+		}
+	}
+	return 0, true
+}
+
+// sweep forgets clients with full buckets, which are the same as new
+// ones, so that the map only holds recent clients.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepEvery {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.clients {
+		if b.full(now) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// clientKey identifies who sent r: the client its API key or
+// certificate belongs to, or else the address it came from.  Without
+// -api-keys, an X-API-Key header is taken at its word, so a client can
+// dodge its limit by making keys up; the global limit still holds.
+func clientKey(r *http.Request) string {
+	if name, ok := r.Context().Value(clientNameKey{}).(string); ok {
+		return "client:" + name
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
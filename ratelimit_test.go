@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newRateLimiter(10, 20, 15, 0, func() time.Time { return now })
+
+	if _, ok := l.allow("a", 20); !ok {
+		t.Fatalf("a's first burst refused")
+	}
+	wait, ok := l.allow("a", 1)
+	if ok || wait != 100*time.Millisecond {
+		t.Errorf("a over its limit: got %v, %v, want refused for 100ms", wait, ok)
+	}
+
+	// b has a bucket of its own, but the global one, with a burst of
+	// 15, was emptied by a.
+	if _, ok := l.allow("b", 1); ok {
+		t.Errorf("b allowed past the global limit")
+	}
+	if l.limitedClient.Load() != 1 || l.limitedGlobal.Load() != 1 {
+		t.Errorf("limited %d by client and %d globally, want 1 and 1", l.limitedClient.Load(), l.limitedGlobal.Load())
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := l.allow("b", 15); !ok {
+		t.Errorf("b refused after the global bucket refilled")
+	}
+	// b was refunded for the request the global limit refused, so it
+	// still has 5 of its 20.
+	if _, ok := l.allow("b", 5); ok {
+		t.Errorf("b allowed 5 more with the global bucket empty")
+	}
+
+	now = now.Add(time.Hour)
+	l.allow("c", 1)
+	if len(l.clients) != 1 {
+		t.Errorf("after a sweep, %d clients remembered, want 1", len(l.clients))
+	}
+
+	// c had 19 left, which is cut to its new burst of 5.
+	l.setLimits(1, 5, 0, 0)
+	if _, ok := l.allow("c", 5); !ok {
+		t.Errorf("c refused after the limits changed")
+	}
+	if _, ok := l.allow("c", 1); ok {
+		t.Errorf("c allowed more than its new burst")
+	}
+}
+
+func TestServeRateLimit(t *testing.T) {
+	ts := newTestServer(t, serverConfig{ClientRate: 1, ClientBurst: 10})
+	get(t, ts, "/uuid?n=10", http.StatusOK)
+	resp, err := ts.Client().Get(ts.URL + "/uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("got status %d, Retry-After %q, want 429 and 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if body := get(t, ts, "/stats", http.StatusOK); body != "{\"limited_client\":1,\"limited_global\":0,\"entropy_fallbacks\":0}\n" {
+		t.Errorf("stats: %s", body)
+	}
+}
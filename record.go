@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// recordMagic starts every recording, so that Replay can tell it
+// isn't reading something else.
+const recordMagic = "uuidrec1"
+
+// recordNode is set in a record's first byte, alongside the version,
+// when the record carries a node.  Nodes hardly ever change, so each
+// is only written when it differs from the one before.
+const recordNode = 0x10
+
+// A Recorder wraps a version 1 or 6 Generator, logging the timestamp,
+// clock sequence and node of every UUID it hands out, with the UUID
+// itself, so that Replay can later check that different bit packing
+// code makes the same UUIDs from the same fields.  Make a recording
+// with the code as it is before a refactor and replay it after.
+//
+// A record is a byte with the version in it, the timestamp as a
+// varint difference from the one before, the clock sequence, the node
+// when it has changed, and the UUID: usually 21 bytes in all.  The
+// log is buffered, so call Flush when done.
+type Recorder struct {
+	g Generator
+
+	mu    sync.Mutex
+	w     *bufio.Writer
+	err   error
+	n     int
+	ticks uint64
+	node  [6]byte
+	buf   [1 + binary.MaxVarintLen64 + 2 + 6 + 16]byte
+}
+
+// NewRecorder returns a Recorder for g logging to w.
+func NewRecorder(g Generator, w io.Writer) *Recorder {
+	r := &Recorder{g: g, w: bufio.NewWriter(w)}
+	_, r.err = r.w.WriteString(recordMagic)
+	return r
+}
+
+// New returns the next UUID from the wrapped generator, logging it
+// first.  Once logging fails the Recorder stops logging, but still
+// hands out UUIDs; Err says why.
+func (r *Recorder) New() UUID {
+	u := r.g.New()
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = r.record(u)
+	}
+	r.mu.Unlock()
+	return u
+}
+
+func (r *Recorder) record(u UUID) error {
+	v := u.Version()
+	if v != 1 && v != 6 {
+		return fmt.Errorf("can't record %s: only version 1 and 6 UUIDs have a timestamp, clock sequence and node", u)
+	}
+	ticks := u.ticks()
+	seq, _ := u.ClockSequence()
+	node, _ := u.Node()
+
+	b := r.buf[:1]
+	b[0] = v
+	if r.n == 0 || node != r.node {
+		b[0] |= recordNode
+	}
+	b = binary.AppendVarint(b, int64(ticks-r.ticks))
+	b = binary.BigEndian.AppendUint16(b, seq)
+	if b[0]&recordNode != 0 {
+		b = append(b, node[:]...)
+	}
+	b = append(b, u[:]...)
+	if _, err := r.w.Write(b); err != nil {
+		return err
+	}
+	r.n++
+	r.ticks = ticks
+	r.node = node
+	return nil
+}
+
+// Flush writes any buffered records, returning the first error the
+// Recorder has had.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+// Err returns the first error the Recorder has had, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// A PackFunc is bit packing code under test: it makes the UUID of the
+// given version from its timestamp, in 100ns intervals since the UUID
+// epoch, its clock sequence and its node.
+type PackFunc func(version byte, ticks uint64, clockSeq uint16, node [6]byte) UUID
+
+// Replay reads a recording made by a Recorder from r, calling pack
+// with each record's fields and checking it makes the recorded UUID.
+// It returns how many records matched, and an error for the first one
+// that didn't.
+func Replay(r io.Reader, pack PackFunc) (int, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != recordMagic {
+		return 0, errors.New("not a UUID recording")
+	}
+
+	var (
+		ticks uint64
+		node  [6]byte
+		buf   [2 + 6 + 16]byte
+	)
+	for n := 0; ; n++ {
+		flags, err := br.ReadByte()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if n == 0 && flags&recordNode == 0 {
+			return n, errors.New("record 0 has no node")
+		}
+		delta, err := binary.ReadVarint(br)
+		if err != nil {
+			return n, fmt.Errorf("record %d: %v", n, unexpected(err))
+		}
+		size := 2 + 16
+		if flags&recordNode != 0 {
+			size += 6
+		}
+		if _, err := io.ReadFull(br, buf[:size]); err != nil {
+			return n, fmt.Errorf("record %d: %v", n, unexpected(err))
+		}
+
+		ticks += uint64(delta)
+		seq := binary.BigEndian.Uint16(buf[0:])
+		rest := buf[2:size]
+		if flags&recordNode != 0 {
+			copy(node[:], rest)
+			rest = rest[6:]
+		}
+		var want UUID
+		copy(want[:], rest)
+
+		if got := pack(flags&0x0f, ticks, seq, node); got != want {
+			return n, fmt.Errorf("record %d: made %s, recorded %s", n, got, want)
+		}
+	}
+}
+
+// unexpected turns an io.EOF part way through a record into
+// io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var log bytes.Buffer
+	g := newSatoriGenerator(newSkewedClock(7, 3).epoch)
+	r := NewRecorder(g, &log)
+	var ids []UUID
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, r.New())
+	}
+	// Another node part way through.
+	g.hardwareAddr = [6]byte{2, 0, 0, 0, 0, 1}
+	for i := 0; i < 10; i++ {
+		ids = append(ids, r.New())
+	}
+	r.g = GeneratorFunc(NewV6)
+	r.New()
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if per := (log.Len() - len(recordMagic)) / 1011; per > 21 {
+		t.Errorf("%d bytes a record", per)
+	}
+
+	// The code as it stands, NewV1At for version 1 and the fields put
+	// back the other way round for version 6.
+	pack := func(version byte, ticks uint64, seq uint16, node [6]byte) UUID {
+		u := NewV1At(epochToTime(ticks), seq, node)
+		if version == 6 {
+			binary.BigEndian.PutUint32(u[0:], uint32(ticks>>28))
+			binary.BigEndian.PutUint16(u[4:], uint16(ticks>>12))
+			binary.BigEndian.PutUint16(u[6:], uint16(ticks&0x0fff))
+			u.SetVersion(6)
+		}
+		return u
+	}
+	recording := log.Bytes()
+	if n, err := Replay(bytes.NewReader(recording), pack); n != 1011 || err != nil {
+		t.Errorf("replayed %d: %v", n, err)
+	}
+
+	// A refactor that loses the timestamp's bottom bit.
+	broken := func(version byte, ticks uint64, seq uint16, node [6]byte) UUID {
+		return pack(version, ticks&^1, seq, node)
+	}
+	if n, err := Replay(bytes.NewReader(recording), broken); err == nil || n >= 1000 || !strings.Contains(err.Error(), ids[n].String()) {
+		t.Errorf("broken packing passed %d: %v", n, err)
+	}
+
+	if _, err := Replay(bytes.NewReader(recording[:len(recording)-3]), pack); err == nil || !strings.Contains(err.Error(), io.ErrUnexpectedEOF.Error()) {
+		t.Errorf("truncated: %v", err)
+	}
+	if _, err := Replay(strings.NewReader("nonsense"), pack); err == nil {
+		t.Error("replayed nonsense")
+	}
+
+	r = NewRecorder(GeneratorFunc(NewV4), io.Discard)
+	r.New()
+	if r.Err() == nil {
+		t.Error("recorded a V4 UUID")
+	}
+}
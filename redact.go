@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+)
+
+// Redacted returns a short stand-in for u, such as "3f2a…/b1", for
+// logs and error messages that shouldn't hold IDs verbatim: the first
+// 4 hex digits, enough to tell IDs apart by eye, and the first byte of
+// u's SHA-256, so that two with the same prefix usually still differ.
+// The same UUID always gives the same stand-in, so log lines about it
+// can still be matched up.
+func (u UUID) Redacted() string {
+	sum := sha256.Sum256(u[:])
+	buf := make([]byte, 0, len("3f2a…/b1"))
+	buf = hex.AppendEncode(buf, u[:2])
+	buf = append(buf, "…/"...)
+	buf = hex.AppendEncode(buf, sum[:1])
+	return string(buf)
+}
+
+// A RedactedUUID is a UUID that redacts itself wherever it is
+// printed: String, and so fmt's %v and %s, give Redacted, and so does
+// slog.  Use it for fields and arguments holding sensitive IDs, so
+// that the ID only comes out in full by asking for UUID().
+type RedactedUUID UUID
+
+// String returns the redacted form of id.
+func (id RedactedUUID) String() string {
+	return UUID(id).Redacted()
+}
+
+// LogValue makes slog log the redacted form of id.
+func (id RedactedUUID) LogValue() slog.Value {
+	return slog.StringValue(id.String())
+}
+
+// MarshalText encodes the redacted form of id, so that it doesn't come
+// out in full in JSON either.
+func (id RedactedUUID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UUID returns the UUID id holds.
+func (id RedactedUUID) UUID() UUID {
+	return UUID(id)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	u := mustParse("3f2a8b1c-0000-4000-8000-000000000001")
+	r := u.Redacted()
+	if !strings.HasPrefix(r, "3f2a…/") || len(r) != len("3f2a…/b1") {
+		t.Errorf("redacted %q", r)
+	}
+	if u.Redacted() != r {
+		t.Error("redacted form changed")
+	}
+	other := mustParse("3f2a8b1c-0000-4000-8000-000000000002")
+	if other.Redacted() == r {
+		t.Errorf("%s and %s both redact to %s", u, other, r)
+	}
+
+	id := RedactedUUID(u)
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	log.Info("charged", "id", id)
+	b, _ := json.Marshal(struct{ ID RedactedUUID }{id})
+	for name, s := range map[string]string{
+		"%v":   fmt.Sprintf("%v", id),
+		"%s":   fmt.Sprintf("%s", id),
+		"slog": buf.String(),
+		"json": string(b),
+	} {
+		if strings.Contains(s, u.String()[:8]) || !strings.Contains(s, r) {
+			t.Errorf("%s: %s", name, s)
+		}
+	}
+	if id.UUID() != u {
+		t.Errorf("UUID() = %s", id.UUID())
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readBenchReport reads a file that bench -json wrote.
+func readBenchReport(name string) (*benchReport, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var report benchReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return &report, nil
+}
+
+// profilePath finds a profile named in a report, which bench wrote
+// relative to the directory it ran in.  That is usually where the
+// report is read from, but failing that the profile is looked for
+// relative to the report.
+func profilePath(reportFile, name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	return filepath.Join(filepath.Dir(reportFile), name)
+}
+
+// writeContention writes, for each run in report that has profiles,
+// where its mutex and block time went.
+func writeContention(w io.Writer, reportFile string, report *benchReport, top int) error {
+	found := false
+	for _, r := range report.Results {
+		bp := r.Profiles
+		if bp == nil {
+			continue
+		}
+		found = true
+		fmt.Fprintf(w, "%s with %d goroutines", r.Strategy, r.Parallelism)
+		if r.Setting != (gcSetting{}) {
+			fmt.Fprintf(w, ", %s", r.Setting)
+		}
+		fmt.Fprintln(w)
+		for _, prof := range []struct{ kind, file, base string }{
+			{"mutex", bp.Mutex, bp.MutexBase},
+			{"block", bp.Block, bp.BlockBase},
+		} {
+			cs, err := readContention(profilePath(reportFile, prof.file), profilePath(reportFile, prof.base))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "  %-5s %s\n", prof.kind, cs.narrative(top))
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: no profiles, run bench with -profile", reportFile)
+	}
+	return nil
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	top := fs.Int("top", 3, "how many causes of contention to name for each profile")
+	compare := fs.Bool("compare", false, "compare the ns/op of results from different machines instead")
+	baseline := fs.String("baseline", "mutex", "with -compare, the strategy whose single goroutine run the others are relative to")
+	format := fs.String("format", "text", "with -compare, output format: text, csv or gnuplot")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen report [flags] results.json...")
+		fmt.Fprintln(fs.Output(), "Summarizes bench -json -profile results: where each run's mutex")
+		fmt.Fprintln(fs.Output(), "and block profile time went, such as \"87% chan receive in ...\".")
+		fmt.Fprintln(fs.Output(), "With -compare, lines up bench -json results from several machines,")
+		fmt.Fprintln(fs.Output(), "best told apart with bench -tags.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no results files")
+	}
+	if *top < 1 {
+		return errors.New("-top must be positive")
+	}
+	if *compare {
+		return runCompare(os.Stdout, fs.Args(), *baseline, *format)
+	}
+
+	for i, name := range fs.Args() {
+		report, err := readBenchReport(name)
+		if err != nil {
+			return err
+		}
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s: %s %s/%s, %d CPUs", name, report.GoVersion, report.GOOS, report.GOARCH, report.NumCPU)
+			if len(report.Tags) > 0 {
+				fmt.Printf(", tagged %s", strings.Join(report.Tags, ", "))
+			}
+			fmt.Println()
+		}
+		if err := writeContention(os.Stdout, name, report, *top); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCompare writes a comparison of the results in files in format.
+func runCompare(w io.Writer, files []string, baseline, format string) error {
+	var write func(*comparison, io.Writer)
+	switch format {
+	case "text":
+		write = (*comparison).writeText
+	case "csv":
+		write = (*comparison).writeCSV
+	case "gnuplot":
+		write = (*comparison).writeGnuplot
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	reports := make([]*benchReport, len(files))
+	for i, name := range files {
+		var err error
+		if reports[i], err = readBenchReport(name); err != nil {
+			return err
+		}
+	}
+	c, err := compareReports(files, reports, baseline)
+	if err != nil {
+		return err
+	}
+	write(c, w)
+	return nil
+}
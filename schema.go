@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UUIDSchema returns a JSON Schema fragment, which OpenAPI 3 takes
+// too, for a string field holding a canonical UUID of one of versions,
+// or of any version if none are given.  The pattern accepts exactly
+// what Parse and ValidateUUID do, so that an API's documented schema
+// and its handlers agree.
+func UUIDSchema(versions ...int) map[string]any {
+	const hex = "[0-9a-fA-F]"
+	pattern := "^" + hex + "{8}-" + hex + "{4}-" + hex + "{4}-" + hex + "{4}-" + hex + "{12}$"
+	schema := map[string]any{
+		"type":   "string",
+		"format": "uuid",
+	}
+	if len(versions) > 0 {
+		var digits strings.Builder
+		names := make([]string, len(versions))
+		for i, v := range versions {
+			digits.WriteString(strconv.FormatInt(int64(v), 16))
+			names[i] = strconv.Itoa(v)
+		}
+		pattern = "^" + hex + "{8}-" + hex + "{4}-[" + digits.String() + "]" + hex + "{3}-[89abAB]" + hex + "{3}-" + hex + "{12}$"
+		schema["description"] = "a version " + strings.Join(names, " or ") + " UUID"
+	}
+	schema["pattern"] = pattern
+	return schema
+}
+
+// ValidateUUID returns nil if s is a canonical UUID of one of
+// versions, or of any version if none are given, and otherwise says
+// what is wrong with it.  A version constraint also requires the RFC
+// 4122 variant, since the version bits mean nothing without it.
+func ValidateUUID(s string, versions ...int) error {
+	u, err := Parse(s)
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	if u.Variant() != VariantRFC4122 {
+		return fmt.Errorf("UUID %s has the %s variant, want RFC 4122", s, variantNames[u.Variant()])
+	}
+	for _, v := range versions {
+		if int(u.Version()) == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("UUID %s is version %d, want %s", s, u.Version(), strings.Trim(fmt.Sprint(versions), "[]"))
+}
+
+// UUIDValidationRules are rules for go-playground/validator struct
+// tags, by tag: "uuid" for any version, and "uuid1" to "uuid8" for one.
+// They override validator's own uuid tags so that struct validation
+// agrees with ValidateUUID.  Register them with
+//
+//	for tag, rule := range UUIDValidationRules {
+//		v.RegisterValidation(tag, func(fl validator.FieldLevel) bool { return rule(fl.Field()) })
+//	}
+//
+// The rules take string fields and UUID fields, which are valid if
+// their version matches.
+var UUIDValidationRules = func() map[string]func(field reflect.Value) bool {
+	rules := map[string]func(reflect.Value) bool{
+		"uuid": func(field reflect.Value) bool { return validUUIDField(field) },
+	}
+	for v := 1; v <= 8; v++ {
+		rules["uuid"+strconv.Itoa(v)] = func(field reflect.Value) bool { return validUUIDField(field, v) }
+	}
+	return rules
+}()
+
+func validUUIDField(field reflect.Value, versions ...int) bool {
+	switch {
+	case field.Type() == reflect.TypeFor[UUID]():
+		return ValidateUUID(field.Interface().(UUID).String(), versions...) == nil
+	case field.Kind() == reflect.String:
+		return ValidateUUID(field.String(), versions...) == nil
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUUIDSchema(t *testing.T) {
+	v4, v7 := NewV4().String(), NewV7().String()
+	tests := []struct {
+		versions []int
+		s        string
+	}{
+		{nil, v4},
+		{nil, strings.ToUpper(v7)},
+		{nil, "not a uuid"},
+		{nil, v4[:35]},
+		{[]int{4}, v4},
+		{[]int{4}, v7},
+		{[]int{4, 7}, v7},
+		{[]int{7}, strings.ToUpper(v7)},
+		// Version 7 bits without the RFC 4122 variant.
+		{[]int{7}, v7[:19] + "c" + v7[20:]},
+		{[]int{1}, "{" + v4 + "}"},
+	}
+	for _, tt := range tests {
+		schema := UUIDSchema(tt.versions...)
+		if schema["type"] != "string" || schema["format"] != "uuid" {
+			t.Errorf("%v: schema %v", tt.versions, schema)
+		}
+		matched := regexp.MustCompile(schema["pattern"].(string)).MatchString(tt.s)
+		valid := ValidateUUID(tt.s, tt.versions...) == nil
+		if matched != valid {
+			t.Errorf("%v %q: pattern says %v, ValidateUUID %v", tt.versions, tt.s, matched, valid)
+		}
+	}
+	if err := ValidateUUID(v7, 4); err == nil || !strings.Contains(err.Error(), "version 7, want 4") {
+		t.Errorf("ValidateUUID(v7, 4): %v", err)
+	}
+}
+
+func TestUUIDValidationRules(t *testing.T) {
+	v4 := NewV4()
+	for _, tt := range []struct {
+		tag   string
+		field any
+		want  bool
+	}{
+		{"uuid", v4.String(), true},
+		{"uuid4", v4.String(), true},
+		{"uuid4", v4, true},
+		{"uuid7", v4, false},
+		{"uuid", "nope", false},
+		{"uuid", 42, false},
+	} {
+		if got := UUIDValidationRules[tt.tag](reflect.ValueOf(tt.field)); got != tt.want {
+			t.Errorf("%s %v: got %v", tt.tag, tt.field, got)
+		}
+	}
+}
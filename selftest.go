@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// selfTestBatch is how many UUIDs SelfTest makes with the generator
+// it is given.
+const selfTestBatch = 1000
+
+// vectorTime is when RFC 9562's example UUIDs of versions 1, 6 and 7
+// were made.  A wall clock reading earlier than that can't be right.
+var vectorTime = time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+
+// SelfTest checks that this build and this machine can make good
+// UUIDs: that it reproduces RFC 9562's test vectors, that the
+// monotonic clock moves forwards and the wall clock is plausible, that
+// a node ID was found, and that a batch from g is unique, of one
+// version, of the RFC variant, and not from the future.  A nil g tests
+// NewV1.  It returns what failed, joined, or nil.  It takes a few
+// milliseconds, so is meant for startup, where serve runs it before
+// listening, and readiness checks, where /readyz runs it on each call.
+func SelfTest(g Generator) error {
+	if g == nil {
+		g = GeneratorFunc(NewV1)
+	}
+	return errors.Join(
+		selfTestVectors(),
+		selfTestClock(),
+		selfTestNode(),
+		selfTestBatchOf(g),
+	)
+}
+
+// selfTestVectors checks the examples in RFC 9562's appendix A, all
+// made at vectorTime.
+func selfTestVectors() error {
+	var errs []error
+	check := func(what string, got UUID, want string) {
+		if got.String() != want {
+			errs = append(errs, fmt.Errorf("%s: got %s, want %s", what, got, want))
+		}
+	}
+	check("V3 of www.example.com", NewV3(NamespaceDNS, "www.example.com"), "5df41881-3aed-3515-88a7-2f4a814cf09e")
+	check("V5 of www.example.com", NewV5(NamespaceDNS, "www.example.com"), "2ed6657d-e927-568b-95e1-2665a8aea6a2")
+	check("V1 at "+vectorTime.Format(time.RFC3339), NewV1At(vectorTime, 0x33c8, [6]byte{0x9f, 0x6b, 0xde, 0xce, 0xd8, 0x46}), "c232ab00-9414-11ec-b3c8-9f6bdeced846")
+	// The rest of a V7 is random.
+	if u := NewV7At(vectorTime); !strings.HasPrefix(u.String(), "017f22e2-79b0-7") {
+		errs = append(errs, fmt.Errorf("V7 at %s: got %s, want 017f22e2-79b0-7...", vectorTime.Format(time.RFC3339), u))
+	}
+
+	for _, s := range []string{
+		"c232ab00-9414-11ec-b3c8-9f6bdeced846",
+		"1ec9414c-232a-6b00-b3c8-9f6bdeced846",
+		"017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+	} {
+		u, err := Parse(s)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if u.String() != s {
+			errs = append(errs, fmt.Errorf("%s formats as %s", s, u))
+		}
+		if t, _ := u.Time(); !t.Equal(vectorTime) {
+			errs = append(errs, fmt.Errorf("%s: time %s, want %s", s, t.UTC().Format(time.RFC3339Nano), vectorTime.Format(time.RFC3339)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// selfTestClock checks that the monotonic clock doesn't go backwards
+// or stand still, and that the wall clock UUIDs are made from isn't
+// before the RFC's examples, as it is on machines that boot without a
+// real time clock and haven't yet set the time.
+func selfTestClock() error {
+	start := time.Now()
+	prev := start
+	for i := 0; i < 1000; i++ {
+		now := time.Now()
+		if now.Before(prev) {
+			return fmt.Errorf("monotonic clock went back %s", prev.Sub(now))
+		}
+		prev = now
+	}
+	time.Sleep(time.Millisecond)
+	if !time.Now().After(start) {
+		return errors.New("monotonic clock isn't moving")
+	}
+	if now := clockNow(); now.Before(vectorTime) {
+		return fmt.Errorf("wall clock reads %s, which can't be right", now.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// selfTestNode checks that V1 and V6 UUIDs have a node ID: the one
+// set, if one was, and otherwise one that isn't all zeros.
+func selfTestNode() error {
+	storageMutex.Lock()
+	defer storageMutex.Unlock()
+	if nodeOverride != nil && hardwareAddr != *nodeOverride {
+		return fmt.Errorf("node ID is %x, not the %x set", hardwareAddr, *nodeOverride)
+	}
+	if hardwareAddr == [6]byte{} {
+		return errors.New("no node ID")
+	}
+	return nil
+}
+
+// selfTestBatchOf checks a batch of g's UUIDs.  It doesn't check
+// timestamps against the clock, beyond their not being in the future,
+// as a ChanneledGenerator's can be as old as its last refill.
+func selfTestBatchOf(g Generator) error {
+	seen := NewUUIDMap[struct{}](selfTestBatch)
+	var version byte
+	limit := clockNow().Add(time.Minute)
+	for i := 0; i < selfTestBatch; i++ {
+		u := g.New()
+		if _, dup := seen.Get(u); dup {
+			return fmt.Errorf("duplicate UUID %s after %d", u, i)
+		}
+		seen.Set(u, struct{}{})
+		if u.Variant() != VariantRFC4122 {
+			return fmt.Errorf("%s isn't of the RFC variant", u)
+		}
+		switch {
+		case u.Version() < 1 || u.Version() > 8:
+			return fmt.Errorf("%s has no RFC version", u)
+		case i == 0:
+			version = u.Version()
+		case u.Version() != version:
+			return fmt.Errorf("%s is version %d, after version %d", u, u.Version(), version)
+		}
+		if t, ok := u.Time(); ok && t.After(limit) {
+			return fmt.Errorf("%s is from the future, %s", u, t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range sortedKeys(experiments) {
+		if err := SelfTest(experiments[name].New(16)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for v, g := range versionGenerators {
+		if err := SelfTest(g); err != nil {
+			t.Errorf("version %d: %v", v, err)
+		}
+	}
+}
+
+func TestSelfTestFailures(t *testing.T) {
+	same := NewV4()
+	for _, tc := range []struct {
+		name string
+		g    Generator
+		want string
+	}{
+		{"duplicates", GeneratorFunc(func() UUID { return same }), "duplicate"},
+		{"no version", GeneratorFunc(func() UUID { u := NewV4(); u[6] &= 0x0f; return u }), "no RFC version"},
+		{"mixed versions", GeneratorFunc(func() UUID {
+			if same[0]++; same[0]%2 == 0 {
+				return NewV4()
+			}
+			return NewV7()
+		}), "after version"},
+		{"wrong variant", GeneratorFunc(func() UUID { u := NewV4(); u[8] &= 0x3f; return u }), "variant"},
+	} {
+		err := SelfTest(tc.g)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,866 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// maxPerRequest is the default cap on the n of a /uuid request.
+const maxPerRequest = 10000
+
+// serverConfig is everything that decides how a server behaves.  It
+// is what a serve config file holds, as JSON.
+type serverConfig struct {
+	Addr string `json:"addr"`
+	// DrainTimeout is how long requests get to finish when the server
+	// is stopping.
+	DrainTimeout duration `json:"drain_timeout"`
+	// LogFormat is text or json, and LogLevel debug, info, warn or
+	// error.  Requests are logged at debug, unless they fail.
+	LogFormat string `json:"log_format"`
+	LogLevel  string `json:"log_level"`
+
+	Version  int    `json:"version"`
+	Strategy string `json:"strategy"`
+	ChanSize int    `json:"chansize"`
+	// Node is the V1 and V6 node ID, as parseNode takes it.
+	Node string `json:"node"`
+	// NodeOrdinal is the instance number version 8 UUIDs carry, which
+	// should be different for each server: see ProvenanceGenerator.
+	NodeOrdinal int `json:"node_ordinal"`
+	// Experiment, if set, is a file holding an ExperimentConfig whose
+	// strategy, chan size and node override the settings above, and
+	// make the version 1.
+	Experiment string `json:"experiment,omitempty"`
+	// MaxBatch caps the n of a /uuid request, 0 for maxPerRequest.
+	MaxBatch     int  `json:"max_batch"`
+	DisableStats bool `json:"no_stats"`
+
+	// CheckRecent, if not 0, turns on /check, which remembers the last
+	// CheckRecent IDs exactly and up to CheckExpected in a bloom filter.
+	CheckRecent   int `json:"check_recent"`
+	CheckExpected int `json:"check_expected"`
+
+	// Limits on UUIDs a second, for each client and for all of them
+	// together, and how many can be saved up.  0 is no limit, or for
+	// bursts, a second's worth.
+	ClientRate  float64 `json:"client_rate"`
+	ClientBurst float64 `json:"client_burst"`
+	GlobalRate  float64 `json:"global_rate"`
+	GlobalBurst float64 `json:"global_burst"`
+
+	// ClockFile, if set, keeps the latest time /readyz has seen across
+	// restarts, and /readyz fails if the clock goes back more than
+	// MaxClockBack from it.
+	ClockFile    string   `json:"clock_file"`
+	MaxClockBack duration `json:"max_clock_back"`
+
+	// StateDir, if set, is a directory the generator's clock sequence
+	// is kept in, and the tenants', so that they carry on from where
+	// they were after a restart, along with the IDs /check remembers,
+	// and the clock file, if ClockFile isn't set.  uuidgen restore
+	// makes one from a snapshot.
+	StateDir string `json:"state_dir"`
+	// HALease, if set, is a lease this server shares with others, as
+	// parseLease describes, so that only the one holding it, the
+	// leader, hands out UUIDs, and another takes over if it goes.  They
+	// must share StateDir too, for the new leader to move on from the
+	// old one's clock sequence.  There is no SQL lease, for want of a
+	// database driver.
+	HALease string `json:"ha_lease"`
+
+	// AuditLog, if set, is a file to append what was issued to, a line
+	// a minute, rotated at AuditMaxSize bytes, or 64MB if that is 0,
+	// and gzipped, keeping AuditKeep of those, or all if that is 0.
+	AuditLog     string `json:"audit_log"`
+	AuditMaxSize int64  `json:"audit_max_size"`
+	AuditKeep    int    `json:"audit_keep"`
+
+	// EntropyTimeout, if set, bounds reads of crypto/rand, falling
+	// back to a CSPRNG seeded at startup: see SetEntropyTimeout.
+	EntropyTimeout duration `json:"entropy_timeout"`
+
+	// TLSCert and TLSKey are PEM files to serve HTTPS with, and
+	// ClientCA, if set, is a PEM file of the CAs client certificates
+	// must be signed by.
+	TLSCert  string `json:"tls_cert"`
+	TLSKey   string `json:"tls_key"`
+	ClientCA string `json:"client_ca"`
+	// APIKeysFile, if set, holds the keys clients must present, as
+	// loadAPIKeys describes.
+	APIKeysFile string `json:"api_keys"`
+	// TenantsFile, if set, gives clients with API keys generators and
+	// namespaces of their own, as loadTenants describes, for the
+	// /tenant/ endpoints.
+	TenantsFile string `json:"tenants"`
+
+	// Debug serves net/http/pprof under /debug/pprof/, which, as it
+	// gives a lot away, needs API keys unless Addr is local.  The
+	// profile rates turn on mutex and block profiling.
+	Debug                bool `json:"debug"`
+	MutexProfileFraction int  `json:"mutex_profile_fraction"`
+	BlockProfileRate     int  `json:"block_profile_rate"`
+}
+
+// server hands out UUIDs over HTTP:
+//
+//	GET /uuid?n=10     n UUIDs, one per line
+//	GET /stream?rate=  a Server-Sent Events feed of UUIDs
+//	GET /check?id=...  whether this node issued id, if enabled
+//	GET /stats         what the server and its generator have done
+//	GET /healthz       200 if the process is up
+//	GET /readyz        200 if it should get traffic, 503 if not
+//	GET /version       the build, and a fingerprint of the generator
+//	GET /snapshot      a tar.gz of the server's state, for uuidgen restore,
+//	                   if it has API keys or a local addr
+//
+// and, if there is a tenants file, to clients that are tenants:
+//
+//	GET /tenant/uuid?n=10     n UUIDs from the tenant's generator
+//	GET /tenant/ns?name=...   the V5 of name in the tenant's namespace
+//	GET /tenant/stats         what the tenant has been given
+//	GET /debug/pprof/  profiles and traces, if enabled
+//
+// With an ha_lease, only the leader serves /uuid, /generate, /stream
+// and /snapshot: followers give 503s, and fail /readyz.
+type server struct {
+	// state holds what SIGHUP can change.  Everything else is fixed
+	// when the server is made.
+	state    atomic.Pointer[serverState]
+	reloadMu sync.Mutex
+	// closing is closed when the server starts shutting down, to end
+	// streams, which would otherwise hold up the drain.
+	closing chan struct{}
+	log     *slog.Logger
+	clock   *clockGuard
+	issued  *issuedLog
+	audit   *auditLog
+	limiter *rateLimiter
+	mux     *http.ServeMux
+
+	// stateDir is the config's StateDir, whose saving runStateSaver
+	// does until saverStop is closed.
+	stateDir  string
+	saverStop chan struct{}
+	saverDone chan struct{}
+
+	// lease, if not nil, is shared with other servers, and leader is
+	// set while this one holds it.  runLease tends it until leaseStop
+	// is closed.
+	lease     Lease
+	leader    atomic.Bool
+	leaseStop chan struct{}
+	leaseDone chan struct{}
+}
+
+// serverState is the part of a server that reload swaps out.
+// Handlers hold its read lock while they use it, so that reload can
+// wait for them to finish before stopping the old generator.
+type serverState struct {
+	cfg  serverConfig
+	g    Generator
+	keys apiKeys
+	// tenants only change when takeOver restarts their generators.
+	tenants map[string]*tenant
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newServer(cfg serverConfig) (*server, error) {
+	if err := resumeGenerator(cfg); err != nil {
+		return nil, err
+	}
+	st, err := newServerState(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	clockFile := cfg.ClockFile
+	if clockFile == "" && cfg.StateDir != "" {
+		clockFile = filepath.Join(cfg.StateDir, stateClockFile)
+	}
+	clock, err := newClockGuard(clockFile, time.Duration(cfg.MaxClockBack), time.Now)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{
+		clock:    clock,
+		limiter:  newRateLimiter(cfg.ClientRate, cfg.ClientBurst, cfg.GlobalRate, cfg.GlobalBurst, time.Now),
+		mux:      http.NewServeMux(),
+		closing:  make(chan struct{}),
+		log:      slog.Default().With("node_id", net.HardwareAddr(hardwareAddr[:]).String()),
+		stateDir: cfg.StateDir,
+	}
+	s.state.Store(st)
+	s.mux.HandleFunc("/uuid", s.handleUUID)
+	s.mux.HandleFunc("/generate", s.handleGenerate)
+	s.mux.HandleFunc("/stream", s.handleStream)
+	s.mux.HandleFunc("/stats", s.handleStats)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.mux.HandleFunc("/version", s.handleVersion)
+	if cfg.APIKeysFile != "" || isLocalAddr(cfg.Addr) {
+		s.mux.HandleFunc("/snapshot", s.handleSnapshot)
+	}
+	if cfg.Debug {
+		handleDebug(s.mux)
+	}
+	if cfg.CheckRecent > 0 {
+		if s.issued, err = loadIssued(cfg.StateDir, cfg.CheckRecent, cfg.CheckExpected); err != nil {
+			return nil, err
+		}
+		s.mux.HandleFunc("/check", s.handleCheck)
+	}
+	if cfg.HALease != "" {
+		// Parsed before the tenants are loaded, for their stores.
+		if s.lease, err = parseLease(cfg.HALease); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.TenantsFile != "" {
+		if st.tenants, err = loadTenants(cfg.TenantsFile, s.tenantStore); err != nil {
+			return nil, err
+		}
+		if err := checkTenants(st.tenants, st.keys); err != nil {
+			closeTenants(st.tenants)
+			return nil, err
+		}
+		s.mux.HandleFunc("/tenant/uuid", s.handleTenantUUID)
+		s.mux.HandleFunc("/tenant/ns", s.handleTenantNS)
+		s.mux.HandleFunc("/tenant/stats", s.handleTenantStats)
+	}
+	if cfg.AuditLog != "" {
+		if s.audit, err = newAuditLog(cfg.AuditLog, cfg.AuditMaxSize, cfg.AuditKeep, time.Now); err != nil {
+			return nil, err
+		}
+	}
+	if s.stateDir != "" {
+		if err := s.saveState(false); err != nil {
+			return nil, err
+		}
+		s.saverStop, s.saverDone = make(chan struct{}), make(chan struct{})
+		go s.runStateSaver()
+	}
+	// The lease is taken last, so that nothing after it can fail and
+	// leave it held.
+	if s.lease != nil {
+		// Try straight away, so that a server on its own leads from
+		// the start.
+		s.tendLease()
+		s.leaseStop, s.leaseDone = make(chan struct{}), make(chan struct{})
+		go s.runLease()
+	}
+	return s, nil
+}
+
+// newServerState returns the state for cfg, keeping old's generator
+// if the generator settings haven't changed.
+func newServerState(cfg serverConfig, old *serverState) (*serverState, error) {
+	st := &serverState{cfg: cfg}
+	if old != nil {
+		st.tenants = old.tenants
+	}
+	if old != nil && cfg.Version == old.cfg.Version && cfg.Strategy == old.cfg.Strategy && cfg.ChanSize == old.cfg.ChanSize {
+		st.g = old.g
+	} else {
+		var err error
+		if st.g, err = newGenerator(cfg.Version, cfg.Strategy, cfg.ChanSize); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.APIKeysFile != "" {
+		var err error
+		if st.keys, err = loadAPIKeys(cfg.APIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// acquire returns the current state, read locked.  Call release when
+// done with it.
+func (s *server) acquire() *serverState {
+	for {
+		st := s.state.Load()
+		st.mu.RLock()
+		if !st.closed {
+			return st
+		}
+		// reload swapped it out since the Load.
+		st.mu.RUnlock()
+	}
+}
+
+func (st *serverState) release() {
+	st.mu.RUnlock()
+}
+
+// reloadable lists the settings reload can change.  The rest need a
+// restart.
+const reloadable = "version, strategy, chansize, max_batch, no_stats, rate limits, api_keys and log_level"
+
+// reload changes the server to cfg, without dropping requests: those
+// already running finish with the old generator, which is then
+// stopped, if it has to be.
+func (s *server) reload(cfg serverConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	old := s.state.Load()
+	fixed := func(c serverConfig) serverConfig {
+		c.Version, c.Strategy, c.ChanSize, c.MaxBatch, c.DisableStats = 0, "", 0, 0, false
+		c.ClientRate, c.ClientBurst, c.GlobalRate, c.GlobalBurst = 0, 0, 0, 0
+		c.APIKeysFile, c.LogLevel = "", ""
+		return c
+	}
+	if fixed(cfg) != fixed(old.cfg) {
+		return fmt.Errorf("only %s can change without a restart", reloadable)
+	}
+
+	st, err := newServerState(cfg, old)
+	if err != nil {
+		return err
+	}
+	level, _ := parseLogLevel(cfg.LogLevel)
+	logLevel.Set(level)
+	s.limiter.setLimits(cfg.ClientRate, cfg.ClientBurst, cfg.GlobalRate, cfg.GlobalBurst)
+	s.state.Store(st)
+
+	old.close(st)
+	return nil
+}
+
+// close waits for the requests using st to finish, and stops them
+// being given st, and then stops its generator, unless next, the state
+// replacing it, shares it.  The lock-free producer is left running if
+// next uses it too, as V1 and V8 both can, since requests on next may
+// already be waiting on it.  With next nil, as at shutdown, everything
+// is stopped.
+func (st *serverState) close(next *serverState) {
+	st.mu.Lock()
+	st.closed = true
+	st.mu.Unlock()
+	if next != nil && next.g == st.g {
+		return
+	}
+	if c, ok := st.g.(interface{ Close() }); ok {
+		c.Close()
+	}
+	if usesLockFree(st.cfg) && (next == nil || !usesLockFree(next.cfg)) {
+		StopLockFree()
+	}
+}
+
+// usesLockFree reports whether cfg's generator is NewV1LockFree's
+// package level producer.
+func usesLockFree(cfg serverConfig) bool {
+	return (cfg.Version == 1 || cfg.Version == 8) && cfg.Strategy == "lockfree"
+}
+
+// shutdown is the last thing a server does, after the HTTP server
+// has stopped: it saves its state, stops the generator, gives up the
+// lease and flushes the clock file.  Requests still running, if the
+// drain timed out, get to finish first.
+func (s *server) shutdown() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	var err error
+	if s.stateDir != "" {
+		close(s.saverStop)
+		<-s.saverDone
+		err = s.saveState(true)
+	}
+	if s.lease != nil {
+		close(s.leaseStop)
+		<-s.leaseDone
+	}
+	st := s.state.Load()
+	st.close(nil)
+	closeTenants(st.tenants)
+	if s.lease != nil {
+		s.leader.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+		defer cancel()
+		if lerr := s.lease.Release(ctx); err == nil {
+			err = lerr
+		}
+	}
+	if cerr := s.clock.flush(); err == nil {
+		err = cerr
+	}
+	if s.audit != nil {
+		if aerr := s.audit.close(); err == nil {
+			err = aerr
+		}
+	}
+	return err
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	// Keep the caller's request ID, if it has a sane one, so that its
+	// logs and ours can be matched up.
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || len(id) > 128 {
+		id = NewV4().String()
+	}
+	w.Header().Set("X-Request-ID", id)
+
+	// Join the caller's trace, if it is in one, as a span of our own.
+	info := &requestInfo{timed: s.log.Enabled(r.Context(), slog.LevelDebug)}
+	if tc, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+		info.parent = tc
+	}
+	info.trace = info.parent.child()
+	r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		s.logRequest(r, id, sw.status, time.Since(start))
+	}()
+
+	r, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="uuidgen"`)
+		http.Error(sw, "missing or unknown API key", http.StatusUnauthorized)
+		return
+	}
+	if issuingPaths[r.URL.Path] && s.following() {
+		notLeader(sw)
+		return
+	}
+	s.mux.ServeHTTP(sw, r)
+}
+
+// logRequest logs a request at debug level if it worked, since there
+// are a lot of them, and higher if not.
+func (s *server) logRequest(r *http.Request, id string, status int, d time.Duration) {
+	level := slog.LevelDebug
+	switch {
+	case status >= 500:
+		level = slog.LevelWarn
+	case status >= 400:
+		level = slog.LevelInfo
+	}
+	if !s.log.Enabled(r.Context(), level) {
+		return
+	}
+	cfg := s.state.Load().cfg
+	attrs := []slog.Attr{
+		slog.String("request_id", id),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("duration", d),
+		slog.String("client", clientKey(r)),
+		slog.Int("version", cfg.Version),
+		slog.String("strategy", cfg.Strategy),
+	}
+	if info := requestInfoFrom(r.Context()); info != nil {
+		attrs = append(attrs,
+			slog.String("trace_id", hex.EncodeToString(info.trace.traceID[:])),
+			slog.String("span_id", hex.EncodeToString(info.trace.spanID[:])))
+		if info.parent.spanID != [8]byte{} {
+			attrs = append(attrs, slog.String("parent_span_id", hex.EncodeToString(info.parent.spanID[:])))
+		}
+		if info.n > 0 {
+			attrs = append(attrs, slog.Int("batch", info.n))
+			if info.timed {
+				attrs = append(attrs, slog.Duration("gen_wait", info.genWait))
+			}
+		}
+	}
+	s.log.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+// statusWriter remembers the status of a response, for logging.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController flush streams through w.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (s *server) handleUUID(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	n, ok := s.batchSize(w, r, st)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	info := requestInfoFrom(r.Context())
+	e := NewEncoder(w)
+	for i := 0; i < n; i++ {
+		if err := e.Encode(s.next(st, info)); err != nil {
+			return
+		}
+	}
+	e.Flush()
+}
+
+// batchSize returns the n asked for by r, checking it against the
+// batch size limit and the rate limits.  If ok is false it has already
+// written an error response.
+func (s *server) batchSize(w http.ResponseWriter, r *http.Request, st *serverState) (n int, ok bool) {
+	maxBatch := st.cfg.MaxBatch
+	if maxBatch == 0 {
+		maxBatch = maxPerRequest
+	}
+	n = 1
+	if v := r.FormValue("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 1 || n > maxBatch {
+			http.Error(w, fmt.Sprintf("n must be between 1 and %d", maxBatch), http.StatusBadRequest)
+			return 0, false
+		}
+	}
+	if wait, ok := s.limiter.allow(clientKey(r), n); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "too many IDs, slow down", http.StatusTooManyRequests)
+		return 0, false
+	}
+	return n, true
+}
+
+// handleGenerate serves n IDs of any registered family, named by the
+// scheme parameter, one per line.  They don't come from the server's
+// generator, so /check and /stats don't know about them.
+func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	scheme := r.FormValue("scheme")
+	f, ok := LookupFamily(scheme)
+	if !ok {
+		http.Error(w, fmt.Sprintf("scheme must be one of %s", strings.Join(Families(), ", ")), http.StatusBadRequest)
+		return
+	}
+	n, ok := s.batchSize(w, r, st)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for i := 0; i < n; i++ {
+		bw.WriteString(f.New().String())
+		if err := bw.WriteByte('\n'); err != nil {
+			return
+		}
+	}
+	bw.Flush()
+}
+
+// next returns the next UUID to hand out from st's generator, as
+// nextFrom does.
+func (s *server) next(st *serverState, info *requestInfo) UUID {
+	return s.nextFrom(st.g, st.cfg.Version, st.cfg.Strategy, info)
+}
+
+// nextFrom returns the next UUID to hand out from g, a generator of
+// version and strategy, recording it for /check and the audit log, and
+// in info, if it isn't nil.  Every UUID the server hands out comes
+// from here, tenants' too.
+func (s *server) nextFrom(g Generator, version int, strategy string, info *requestInfo) UUID {
+	var u UUID
+	switch {
+	case info == nil:
+		u = g.New()
+	case info.timed:
+		start := time.Now()
+		u = g.New()
+		info.genWait += time.Since(start)
+		info.n++
+	default:
+		u = g.New()
+		info.n++
+	}
+	if s.issued != nil {
+		s.issued.record(u)
+	}
+	if s.audit != nil {
+		s.audit.record(version, strategy, u)
+	}
+	return u
+}
+
+// serverStats is the body of a /stats response.
+type serverStats struct {
+	// Generator is nil for generators that don't keep stats.
+	Generator *GeneratorStats `json:"generator,omitempty"`
+	// LimitedClient and LimitedGlobal count the requests turned away
+	// by each kind of rate limit.
+	LimitedClient uint64 `json:"limited_client"`
+	LimitedGlobal uint64 `json:"limited_global"`
+	// EntropyFallbacks counts random reads served by the fallback
+	// because of entropy_timeout.
+	EntropyFallbacks uint64 `json:"entropy_fallbacks"`
+	// Tenants maps each tenant to its stats, if there are any.
+	Tenants map[string]tenantStats `json:"tenants,omitempty"`
+	// Role is leader or follower, with an ha_lease.
+	Role string `json:"role,omitempty"`
+}
+
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	state := s.acquire()
+	defer state.release()
+	if state.cfg.DisableStats {
+		http.NotFound(w, r)
+		return
+	}
+
+	st := serverStats{
+		LimitedClient:    s.limiter.limitedClient.Load(),
+		LimitedGlobal:    s.limiter.limitedGlobal.Load(),
+		EntropyFallbacks: entropyFallbacks.Load(),
+		Tenants:          tenantStatsOf(state.tenants),
+	}
+	switch {
+	case s.following():
+		st.Role = "follower"
+	case s.lease != nil:
+		st.Role = "leader"
+	}
+	if sr, ok := state.g.(statsReporter); ok {
+		gs := sr.Stats()
+		st.Generator = &gs
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// checkResult is the body of a /check response.
+type checkResult struct {
+	ID string `json:"id"`
+	// Recent is true if id is one of the last IDs issued, which is
+	// certain.
+	Recent bool `json:"recent"`
+	// Issued is true if id may have been issued.  When it is false the
+	// ID certainly wasn't issued since the server started; when true
+	// and Recent is false, it probably was.
+	Issued bool `json:"issued"`
+}
+
+func (s *server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	u, err := Parse(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recent, issued := s.issued.check(u)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(checkResult{ID: u.String(), Recent: recent, Issued: issued})
+}
+
+// issuedLog remembers the IDs a server hands out, for looking into
+// duplicate key incidents: was this ID really from this node?  The
+// most recent are kept in a ring and known for certain; the rest only
+// live on in a bloom filter, which is wrong about one in a million.
+type issuedLog struct {
+	mu     sync.Mutex
+	ring   []UUID
+	next   int
+	counts map[UUID]int
+	filter *bloomFilter
+}
+
+func newIssuedLog(recent, expected int) *issuedLog {
+	return &issuedLog{
+		ring:   make([]UUID, 0, recent),
+		counts: make(map[UUID]int, recent),
+		filter: newBloomFilter(expected, 1e-6),
+	}
+}
+
+func (l *issuedLog) record(u UUID) {
+	l.mu.Lock()
+	if len(l.ring) < cap(l.ring) {
+		l.ring = append(l.ring, u)
+	} else {
+		// Counted rather than a set, in case the generator really did
+		// repeat itself.
+		old := l.ring[l.next]
+		if l.counts[old]--; l.counts[old] == 0 {
+			delete(l.counts, old)
+		}
+		l.ring[l.next] = u
+		l.next = (l.next + 1) % len(l.ring)
+	}
+	l.counts[u]++
+	l.mu.Unlock()
+	l.filter.add(u)
+}
+
+// check reports whether u is among the recent IDs, and whether it may
+// have been issued at all.
+func (l *issuedLog) check(u UUID) (recent, issued bool) {
+	l.mu.Lock()
+	recent = l.counts[u] > 0
+	l.mu.Unlock()
+	return recent, recent || l.filter.contains(u)
+}
+
+func runServe(args []string) error {
+	cfg, printConfig, err := parseServeArgs(args, os.Getenv)
+	if err != nil {
+		return err
+	}
+	if printConfig {
+		b, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("%s\n", b)
+		return err
+	}
+
+	logger, err := newLogger(os.Stderr, cfg.LogFormat, true)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	level, _ := parseLogLevel(cfg.LogLevel)
+	logLevel.Set(level)
+
+	setProfileRates(cfg.MutexProfileFraction, cfg.BlockProfileRate)
+	node, err := parseNode(cfg.Node)
+	if err != nil {
+		return err
+	}
+	if node != nil {
+		setNodeID(*node)
+	}
+	setNodeOrdinal(uint8(cfg.NodeOrdinal))
+	if cfg.EntropyTimeout > 0 {
+		if err := SetEntropyTimeout(time.Duration(cfg.EntropyTimeout)); err != nil {
+			return err
+		}
+	}
+	tc, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+	ln, err := listen(cfg.Addr)
+	if err != nil {
+		return err
+	}
+	s, err := newServer(cfg)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	if err := SelfTest(s.state.Load().g); err != nil {
+		ln.Close()
+		// Giving up the lease, if it was taken, for another to lead.
+		s.shutdown()
+		return fmt.Errorf("self-test: %w", err)
+	}
+	hs := &http.Server{
+		Handler:           s,
+		TLSConfig:         tc,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// SIGHUP rereads the config file, environment and flags.  Notify
+	// with no signals would mean all of them.
+	hup := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(hup, reloadSignals...)
+	}
+	go func() {
+		for range hup {
+			cfg, _, err := parseServeArgs(args, os.Getenv)
+			if err == nil {
+				err = s.reload(cfg)
+			}
+			if err != nil {
+				s.log.Error("not reloaded", "err", err)
+				continue
+			}
+			s.log.Info("reloaded", "version", cfg.Version, "strategy", cfg.Strategy)
+		}
+	}()
+
+	// SIGINT and SIGTERM drain the server and stop it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s.log.Info("listening", "addr", ln.Addr().String(), "version", cfg.Version, "strategy", cfg.Strategy)
+	return s.serve(ctx, hs, ln, time.Duration(cfg.DrainTimeout))
+}
+
+// listen listens on addr, a host:port or unix: followed by the path of
+// a unix socket.
+func listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// A socket left behind by a server that didn't get to clean
+		// up would stop this one starting.
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// serve runs hs on ln until ctx is done, and then shuts down: it stops
+// accepting connections, gives the requests in flight up to drain to
+// finish, and stops s.
+func (s *server) serve(ctx context.Context, hs *http.Server, ln net.Listener, drain time.Duration) error {
+	hs.RegisterOnShutdown(func() { close(s.closing) })
+	errc := make(chan error, 1)
+	go func() {
+		if hs.TLSConfig != nil {
+			errc <- hs.ServeTLS(ln, "", "")
+		} else {
+			errc <- hs.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		s.shutdown()
+		return err
+	case <-ctx.Done():
+	}
+
+	s.log.Info("shutting down", "drain_timeout", drain)
+	dctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	err := hs.Shutdown(dctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("requests still running after %s", drain)
+	}
+	if serr := s.shutdown(); err == nil {
+		err = serr
+	}
+	return err
+}
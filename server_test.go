@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, cfg serverConfig) *httptest.Server {
+	t.Helper()
+	if cfg.Version == 0 {
+		cfg.Version, cfg.Strategy = 1, "mutex"
+	}
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// get returns the body of a GET of path, failing unless the status is
+// want.
+func get(t *testing.T, ts *httptest.Server, path string, want int) string {
+	t.Helper()
+	resp, err := ts.Client().Get(ts.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != want {
+		t.Fatalf("GET %s: status %d, want %d: %s", path, resp.StatusCode, want, body)
+	}
+	return string(body)
+}
+
+// checkID returns what /check says about id.
+func checkID(t *testing.T, ts *httptest.Server, id string) checkResult {
+	t.Helper()
+	var r checkResult
+	if err := json.Unmarshal([]byte(get(t, ts, "/check?id="+id, http.StatusOK)), &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestServeUUID(t *testing.T) {
+	ts := newTestServer(t, serverConfig{Version: 7})
+	lines := strings.Fields(get(t, ts, "/uuid?n=5", http.StatusOK))
+	if len(lines) != 5 {
+		t.Fatalf("got %d UUIDs, want 5", len(lines))
+	}
+	for _, line := range lines {
+		u, err := Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Version() != 7 {
+			t.Errorf("%s is version %d", u, u.Version())
+		}
+	}
+
+	for _, n := range []string{"0", "10001", "x"} {
+		get(t, ts, "/uuid?n="+n, http.StatusBadRequest)
+	}
+	get(t, ts, "/check?id="+lines[0], http.StatusNotFound)
+}
+
+func TestServeGenerate(t *testing.T) {
+	ts := newTestServer(t, serverConfig{})
+	lines := strings.Fields(get(t, ts, "/generate?scheme=ulid&n=3", http.StatusOK))
+	if len(lines) != 3 {
+		t.Fatalf("got %d ULIDs, want 3", len(lines))
+	}
+	for _, line := range lines {
+		if _, err := ParseULID(line); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Third party schemes work the same way.
+	RegisterFamily("test-scheme", Family{
+		New:   func() ID { return Snowflake(42) },
+		Parse: func(s string) (ID, error) { return ParseSnowflake(s) },
+	})
+	defer func() {
+		familiesMu.Lock()
+		delete(families, "test-scheme")
+		familiesMu.Unlock()
+	}()
+	if body := get(t, ts, "/generate?scheme=test-scheme", http.StatusOK); body != "42\n" {
+		t.Errorf("test-scheme: %q", body)
+	}
+
+	get(t, ts, "/generate?scheme=bogus", http.StatusBadRequest)
+	get(t, ts, "/generate", http.StatusBadRequest)
+	get(t, ts, "/generate?scheme=ksuid&n=0", http.StatusBadRequest)
+}
+
+func TestServeCheck(t *testing.T) {
+	ts := newTestServer(t, serverConfig{CheckRecent: 3, CheckExpected: 1000})
+	ids := strings.Fields(get(t, ts, "/uuid?n=5", http.StatusOK))
+
+	for i, id := range ids {
+		r := checkID(t, ts, id)
+		if recent := i >= 2; r.Recent != recent || !r.Issued {
+			t.Errorf("ID %d: got %+v, want recent %v and issued", i, r, recent)
+		}
+	}
+	if r := checkID(t, ts, NewV4().String()); r.Recent || r.Issued {
+		t.Errorf("random ID: got %+v", r)
+	}
+	get(t, ts, "/check?id=bogus", http.StatusBadRequest)
+}
+
+func TestServeHealth(t *testing.T) {
+	clockFile := filepath.Join(t.TempDir(), "clock")
+	ts := newTestServer(t, serverConfig{ClockFile: clockFile})
+	if body := get(t, ts, "/healthz", http.StatusOK); body != "ok\n" {
+		t.Errorf("healthz: %q", body)
+	}
+
+	var rd readiness
+	if err := json.Unmarshal([]byte(get(t, ts, "/readyz", http.StatusOK)), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if !rd.Ready || rd.Strategy != "mutex" || rd.Node == "" || rd.Checks["entropy"] != "ok" || rd.Checks["clock"] != "ok" || rd.Checks["selftest"] != "ok" {
+		t.Errorf("readyz: %+v", rd)
+	}
+
+	// A clock file from the future means the clock has gone back.
+	future := time.Now().Add(time.Hour).Format(time.RFC3339Nano)
+	if err := os.WriteFile(clockFile, []byte(future), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ts = newTestServer(t, serverConfig{Version: 4, ClockFile: clockFile, MaxClockBack: duration(time.Minute)})
+	rd = readiness{}
+	if err := json.Unmarshal([]byte(get(t, ts, "/readyz", http.StatusServiceUnavailable)), &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Ready || rd.Node != "" || !strings.Contains(rd.Checks["clock"], "behind") {
+		t.Errorf("readyz with the clock back: %+v", rd)
+	}
+}
+
+func TestClockGuard(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	g, err := newClockGuard("", 10*time.Millisecond, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		d  time.Duration
+		ok bool
+	}{
+		{time.Second, true},
+		{-5 * time.Millisecond, true},
+		{-10 * time.Millisecond, false},
+		{20 * time.Millisecond, true},
+	} {
+		now = now.Add(step.d)
+		if err := g.check(); (err == nil) != step.ok {
+			t.Errorf("after %s: got %v", step.d, err)
+		}
+	}
+}
+
+func TestServeStream(t *testing.T) {
+	ts := newTestServer(t, serverConfig{Version: 7, CheckRecent: 10, CheckExpected: 100})
+	start := time.Now()
+	resp, err := ts.Client().Get(ts.URL + "/stream?rate=100&n=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first comes straight away, and the rest 10ms apart.
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("5 UUIDs at 100 a second took %s", d)
+	}
+
+	events := strings.Split(strings.TrimSuffix(string(body), "\n\n"), "\n\n")
+	if len(events) != 5 {
+		t.Fatalf("got %d events, want 5: %q", len(events), body)
+	}
+	for _, ev := range events {
+		id, ok := strings.CutPrefix(ev, "data: ")
+		if !ok {
+			t.Fatalf("bad event %q", ev)
+		}
+		if !checkID(t, ts, id).Recent {
+			t.Errorf("%s wasn't recorded for /check", id)
+		}
+	}
+
+	for _, q := range []string{"", "rate=0", "rate=20000", "rate=1&n=-1"} {
+		get(t, ts, "/stream?"+q, http.StatusBadRequest)
+	}
+}
+
+func TestServeReload(t *testing.T) {
+	cfg := defaultServerConfig()
+	cfg.Strategy = "channel"
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// A request in flight keeps the old generator going until it is
+	// done.
+	held := s.acquire()
+	oldGen := held.g.(*ChanneledGenerator)
+
+	cfg.Version, cfg.MaxBatch, cfg.ClientRate = 7, 5, 1000
+	done := make(chan error)
+	go func() { done <- s.reload(cfg) }()
+
+	for s.state.Load() == held {
+		time.Sleep(time.Millisecond)
+	}
+	// The new state is in use straight away.
+	if u, _ := Parse(strings.TrimSpace(get(t, ts, "/uuid", http.StatusOK))); u.Version() != 7 {
+		t.Errorf("after reload, got version %d", u.Version())
+	}
+	get(t, ts, "/uuid?n=6", http.StatusBadRequest)
+	if u := s.next(held, nil); u.Version() != 1 {
+		t.Errorf("in flight request got version %d", u.Version())
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("reload returned with a request in flight: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	held.release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-oldGen.stop:
+	default:
+		t.Errorf("old generator not closed")
+	}
+
+	cfg.Addr = "localhost:9999"
+	if err := s.reload(cfg); err == nil || !strings.Contains(err.Error(), "restart") {
+		t.Errorf("changing addr: %v", err)
+	}
+}
+
+func TestServeReloadLockFree(t *testing.T) {
+	cfg := defaultServerConfig()
+	cfg.Strategy = "lockfree"
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.shutdown()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	get(t, ts, "/uuid", http.StatusOK)
+
+	// V8 uses the same producer, which requests on the new state may
+	// already be waiting on, so it is left running.
+	cfg.Version = 8
+	if err := s.reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !lockFreeRunning.Load() {
+		t.Error("lock-free producer stopped")
+	}
+	if u, _ := Parse(strings.TrimSpace(get(t, ts, "/uuid", http.StatusOK))); u.Version() != 8 {
+		t.Errorf("after reload, got version %d", u.Version())
+	}
+
+	cfg.Version = 7
+	if err := s.reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if lockFreeRunning.Load() {
+		t.Error("lock-free producer still running")
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultServerConfig()
+	cfg.Strategy, cfg.ClockFile = "channel", filepath.Join(dir, "clock")
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := listen("unix:" + filepath.Join(dir, "sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", ln.Addr().String())
+		},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.serve(ctx, &http.Server{Handler: s}, ln, time.Second)
+	}()
+
+	// An endless stream, which shutting down should end.
+	resp, err := c.Get("http://uuidd/stream?rate=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	if _, err := br.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still serving")
+	}
+	if _, err := io.ReadAll(br); err != nil {
+		t.Errorf("stream: %v", err)
+	}
+	select {
+	case <-s.state.Load().g.(*ChanneledGenerator).stop:
+	default:
+		t.Errorf("generator not closed")
+	}
+	if _, err := os.Stat(cfg.ClockFile); err != nil {
+		t.Errorf("clock not flushed: %v", err)
+	}
+}
+
+func TestServeLogging(t *testing.T) {
+	var buf strings.Builder
+	logger, err := newLogger(&buf, "json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+	defer logLevel.Set(logLevel.Level())
+	logLevel.Set(slog.LevelInfo)
+
+	ts := newTestServer(t, serverConfig{})
+	get(t, ts, "/uuid", http.StatusOK)
+	if buf.Len() != 0 {
+		t.Errorf("request that worked logged at info: %s", buf.String())
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/uuid?n=0", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if id := resp.Header.Get("X-Request-ID"); id != "req-1" {
+		t.Errorf("X-Request-ID %q", id)
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	for k, want := range map[string]any{
+		"level":      "INFO",
+		"msg":        "request",
+		"request_id": "req-1",
+		"status":     float64(400),
+		"strategy":   "mutex",
+	} {
+		if rec[k] != want {
+			t.Errorf("%s: got %v, want %v", k, rec[k], want)
+		}
+	}
+	if rec["node_id"] == "" || rec["time"] != nil {
+		t.Errorf("node_id %v, time %v", rec["node_id"], rec["time"])
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// js has no signals, so serve can't be told to reread its config.
+var reloadSignals []os.Signal
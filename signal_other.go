@@ -0,0 +1,11 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals make serve reread its config.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
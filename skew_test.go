@@ -0,0 +1,132 @@
+/**
+
+Clock skew
+
+These use a fake clock that steps backwards every so often, to check
+that each strategy bumps the clock sequence when it should and never
+hands out duplicates, and to see what skew does to throughput.
+
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// skewedClock is a fake epoch clock.  It moves forward one interval
+// per call, except every `every` calls it jumps back by stepBack.  It
+// is not safe for concurrent use, which is fine since every generator
+// only calls its clock from one goroutine at a time.
+type skewedClock struct {
+	now      uint64
+	calls    int
+	every    int
+	stepBack uint64
+}
+
+func newSkewedClock(every int, stepBack uint64) *skewedClock {
+	return &skewedClock{now: unixTimeFunc(), every: every, stepBack: stepBack}
+}
+
+func (c *skewedClock) epoch() uint64 {
+	c.calls++
+	if c.every > 0 && c.calls%c.every == 0 {
+		c.now -= c.stepBack
+	} else {
+		c.now++
+	}
+	return c.now
+}
+
+// v1Fields pulls the timestamp and clock sequence back out of a V1
+// UUID.  Only 14 bits of the clock sequence survive SetVariant.
+func v1Fields(u UUID) (uint64, uint16) {
+	ts := uint64(binary.BigEndian.Uint32(u[0:]))
+	ts |= uint64(binary.BigEndian.Uint16(u[4:])) << 32
+	ts |= uint64(binary.BigEndian.Uint16(u[6:])&0x0fff) << 48
+	return ts, binary.BigEndian.Uint16(u[8:]) & 0x3fff
+}
+
+type skewStrategy struct {
+	name string
+	new  func(epochFunc func() uint64) func() UUID
+}
+
+var skewStrategies = []skewStrategy{
+	{"satori", func(f func() uint64) func() UUID {
+		return newSatoriGenerator(f).NewV1
+	}},
+	{"chansize=0", func(f func() uint64) func() UUID {
+		return newChanneledGenerator(0, f).NewV1
+	}},
+	{"chansize=100", func(f func() uint64) func() UUID {
+		return newChanneledGenerator(100, f).NewV1
+	}},
+}
+
+var skewSchedules = []struct {
+	name     string
+	every    int
+	stepBack uint64
+}{
+	{"none", 0, 0},
+	{"midrun", 5000, 1000},
+	{"frequent", 3, 5},
+}
+
+func TestClockSkew(t *testing.T) {
+	const count = 10000
+	for _, strategy := range skewStrategies {
+		for _, sched := range skewSchedules {
+			name := fmt.Sprintf("%s/skew=%s", strategy.name, sched.name)
+			t.Run(name, func(t *testing.T) {
+				gen := strategy.new(newSkewedClock(sched.every, sched.stepBack).epoch)
+
+				seen := make(map[UUID]struct{}, count)
+				var lastTS uint64
+				var lastSeq uint16
+				bumps := 0
+				for n := 0; n < count; n++ {
+					u := gen()
+					if _, ok := seen[u]; ok {
+						t.Fatalf("duplicate UUID %s after %d", u, n)
+					}
+					seen[u] = struct{}{}
+
+					ts, seq := v1Fields(u)
+					if n > 0 {
+						want := lastSeq
+						if ts <= lastTS {
+							want = (lastSeq + 1) & 0x3fff
+							bumps++
+						}
+						if seq != want {
+							t.Fatalf("UUID %d: clock sequence %d, want %d", n, seq, want)
+						}
+					}
+					lastTS, lastSeq = ts, seq
+				}
+				if sched.every > 0 && bumps == 0 {
+					t.Errorf("clock went backwards but clock sequence never changed")
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkClockSkew(b *testing.B) {
+	for _, strategy := range skewStrategies {
+		for _, sched := range skewSchedules {
+			name := fmt.Sprintf("%s/skew=%s", strategy.name, sched.name)
+			b.Run(name, func(b *testing.B) {
+				gen := strategy.new(newSkewedClock(sched.every, sched.stepBack).epoch)
+				for n := 0; n < b.N; n++ {
+					gen()
+				}
+			})
+		}
+	}
+}
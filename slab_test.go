@@ -0,0 +1,138 @@
+/**
+
+Slabs
+
+Bulk consumers, say an importer that needs IDs for a batch of 1000
+rows, can either collect UUIDs one call at a time into a fresh slice,
+or have them written into a slab that is reused from a sync.Pool.
+Likewise for the text: a string per UUID, or one []byte arena per
+batch.
+
+Batches of 1000 from a SatoriGenerator, on a 1 CPU Xeon VM:
+
+BenchmarkSlab/values        137276 ns/op   50415 B/op    11 allocs/op
+BenchmarkSlab/slab          118516 ns/op       0 B/op     0 allocs/op
+BenchmarkSlab/slab-fill      97991 ns/op       0 B/op     0 allocs/op
+BenchmarkSlab/strings       252749 ns/op   83183 B/op  1010 allocs/op
+BenchmarkSlab/text-arena    145538 ns/op       0 B/op     0 allocs/op
+
+Take-aways:
+
+ - Returning UUIDs by value costs nothing; the allocations in values
+   are all append growing the slice.  A pooled slab gets rid of them
+   and saves about 14%.
+
+ - Fill, which takes the lock once per batch instead of once per UUID,
+   saves another 17%.  Uncontended locking is cheap, but not free.
+
+ - Text is where the garbage is: a string per UUID is 1000 allocations
+   and 83KB a batch, and it takes as long as generating.  Encoding
+   into a pooled arena is allocation free and 42% faster.
+
+*/
+
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+const slabSize = 1000
+
+var uuidSlabs = sync.Pool{
+	New: func() any {
+		s := make([]UUID, slabSize)
+		return &s
+	},
+}
+
+var textSlabs = sync.Pool{
+	New: func() any {
+		b := make([]byte, slabSize*37)
+		return &b
+	},
+}
+
+// sink keeps the compiler from optimizing the work away.
+var sink int
+
+func BenchmarkSlab(b *testing.B) {
+	g := NewSatoriGenerator()
+
+	b.Run("values", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var ids []UUID
+			for j := 0; j < slabSize; j++ {
+				ids = append(ids, g.NewV1())
+			}
+			sink += len(ids)
+		}
+	})
+
+	b.Run("slab", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ids := uuidSlabs.Get().(*[]UUID)
+			for j := range *ids {
+				(*ids)[j] = g.NewV1()
+			}
+			sink += len(*ids)
+			uuidSlabs.Put(ids)
+		}
+	})
+
+	b.Run("slab-fill", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ids := uuidSlabs.Get().(*[]UUID)
+			g.Fill(*ids)
+			sink += len(*ids)
+			uuidSlabs.Put(ids)
+		}
+	})
+
+	b.Run("strings", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var text []string
+			for j := 0; j < slabSize; j++ {
+				text = append(text, g.NewV1().String())
+			}
+			sink += len(text)
+		}
+	})
+
+	b.Run("text-arena", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ids := uuidSlabs.Get().(*[]UUID)
+			text := textSlabs.Get().(*[]byte)
+			g.Fill(*ids)
+			for j, u := range *ids {
+				encodeCanonical((*text)[j*37:], u)
+				(*text)[j*37+36] = '\n'
+			}
+			sink += len(*text)
+			textSlabs.Put(text)
+			uuidSlabs.Put(ids)
+		}
+	})
+}
+
+func TestSatoriFill(t *testing.T) {
+	g := newSatoriGenerator(newSkewedClock(7, 3).epoch)
+	ids := make([]UUID, 100)
+	g.Fill(ids)
+	seen := map[UUID]bool{}
+	for _, u := range ids {
+		if seen[u] || u.Version() != 1 {
+			t.Fatalf("%s is a duplicate or not V1", u)
+		}
+		seen[u] = true
+	}
+	if s := g.Stats(); s.Generated != 100 {
+		t.Errorf("%+v", s)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The files of a state directory, which are also what a snapshot
+// holds, along with a tenantStateFile for each tenant.
+const (
+	stateConfigFile    = "config.json"
+	stateGeneratorFile = "generator.json"
+	stateClockFile     = "clock"
+	stateIssuedFile    = "issued.bin"
+)
+
+// tenantStateFile is the file of a state directory that tenant's
+// generator state is kept in.
+func tenantStateFile(tenant string) string {
+	return "tenant-" + strings.ReplaceAll(tenant, "/", "%2F") + ".json"
+}
+
+// isStateFile reports whether name is one of a snapshot's files.
+func isStateFile(name string) bool {
+	switch name {
+	case stateConfigFile, stateGeneratorFile, stateClockFile, stateIssuedFile:
+		return true
+	}
+	return strings.HasPrefix(name, "tenant-") && strings.HasSuffix(name, ".json") && !strings.ContainsAny(name, `/\`)
+}
+
+// maxSnapshotFile bounds each file restore reads from a snapshot.  The
+// biggest, issued.bin, is about 3.6 bytes for each of check_expected.
+const maxSnapshotFile = 1 << 30
+
+// resumeGenerator carries the clock sequence of the generator cfg asks
+// for on from the one saved in cfg's state directory, if it was saved
+// for this node ID, by the rules restoreState follows.
+func resumeGenerator(cfg serverConfig) error {
+	if cfg.StateDir == "" {
+		return nil
+	}
+	strategy := cfg.Strategy
+	switch cfg.Version {
+	case 1, 8:
+	case 6:
+		strategy = "mutex"
+	default:
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	saved, ok, err := FileStateStore{Path: filepath.Join(cfg.StateDir, stateGeneratorFile)}.Load(ctx)
+	if err != nil || !ok || saved.Node != hardwareAddr {
+		return err
+	}
+	setClockSequence(strategy, resumedClockSequence(saved, clockNow()))
+	return nil
+}
+
+// generatorState returns the state of the server's generator, with ok
+// false if it has no clock sequence to save.
+func (s *server) generatorState() (GeneratorState, bool) {
+	st := s.acquire()
+	defer st.release()
+	return stateOf(st.g, hardwareAddr, clockNow())
+}
+
+// runStateSaver saves the generator's state to the state directory
+// every stateSaveEvery, until shutdown.
+func (s *server) runStateSaver() {
+	defer close(s.saverDone)
+	t := time.NewTicker(stateSaveEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.saveState(false); err != nil {
+				s.log.Warn("server state not saved", "err", err)
+			}
+		case <-s.saverStop:
+			return
+		}
+	}
+}
+
+// saveState saves the generator's state to the state directory, and
+// with all, the IDs /check remembers too, which are only saved at
+// shutdown, being much bigger.  Tenants save their own.  A follower
+// saves nothing, since the directory is the leader's.
+func (s *server) saveState(all bool) error {
+	if s.following() {
+		return nil
+	}
+	if gs, ok := s.generatorState(); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+		defer cancel()
+		if err := (FileStateStore{Path: filepath.Join(s.stateDir, stateGeneratorFile)}).Save(ctx, gs); err != nil {
+			return err
+		}
+	}
+	if !all || s.issued == nil {
+		return nil
+	}
+	b, err := s.issued.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.stateDir, stateIssuedFile), b)
+}
+
+// loadIssued returns the issuedLog saved in dir, if there is one, or
+// else a new one.
+func loadIssued(dir string, recent, expected int) (*issuedLog, error) {
+	l := newIssuedLog(recent, expected)
+	if dir == "" {
+		return l, nil
+	}
+	path := filepath.Join(dir, stateIssuedFile)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := l.UnmarshalBinary(b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return l, nil
+}
+
+// issuedLogFile is an issuedLog as snapshots and state directories
+// keep it, in gob.
+type issuedLogFile struct {
+	// Recent is the ring, oldest first.
+	Recent []UUID
+	K      int
+	Seeds  [2][2]uint64
+	Bits   []uint64
+}
+
+func (l *issuedLog) MarshalBinary() ([]byte, error) {
+	l.mu.Lock()
+	f := issuedLogFile{
+		Recent: append(append([]UUID(nil), l.ring[l.next:]...), l.ring[:l.next]...),
+		K:      l.filter.k,
+		Seeds:  l.filter.seeds,
+	}
+	l.mu.Unlock()
+	f.Bits = make([]uint64, len(l.filter.bits))
+	for i := range l.filter.bits {
+		f.Bits[i] = l.filter.bits[i].Load()
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(f)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary loads what MarshalBinary saved into l, which must be
+// new.  If l remembers fewer recent IDs, the oldest are dropped.  If
+// its filter is another size, only the recent IDs can be carried over
+// into it, so the rest are forgotten.
+func (l *issuedLog) UnmarshalBinary(b []byte) error {
+	var f issuedLogFile
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&f); err != nil {
+		return err
+	}
+	if len(f.Recent) > cap(l.ring) {
+		f.Recent = f.Recent[len(f.Recent)-cap(l.ring):]
+	}
+	for _, u := range f.Recent {
+		l.record(u)
+	}
+	if f.K != l.filter.k || len(f.Bits) != len(l.filter.bits) {
+		return nil
+	}
+	l.filter.seeds = f.Seeds
+	for i, w := range f.Bits {
+		l.filter.bits[i].Store(w)
+	}
+	return nil
+}
+
+// snapshot returns a gzipped tar of what a server taking over from this
+// one, as the same node, needs to carry on without repeating its
+// UUIDs: its config, its generator's state and its tenants', the
+// latest time its clock has shown, and the IDs /check remembers.
+func (s *server) snapshot() ([]byte, error) {
+	type file struct {
+		name string
+		data []byte
+	}
+	var files []file
+	addJSON := func(name string, v any) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err == nil {
+			files = append(files, file{name, append(b, '\n')})
+		}
+		return err
+	}
+
+	// The config is the one to run the replacement with: the node ID
+	// this one actually has, the experiment's settings, which are in
+	// it already, rather than the file, and the state where restore
+	// puts it.
+	cfg := s.state.Load().cfg
+	cfg.Node = net.HardwareAddr(hardwareAddr[:]).String()
+	cfg.Experiment, cfg.StateDir, cfg.ClockFile = "", "", ""
+	if err := addJSON(stateConfigFile, cfg); err != nil {
+		return nil, err
+	}
+	if gs, ok := s.generatorState(); ok {
+		if err := addJSON(stateGeneratorFile, gs); err != nil {
+			return nil, err
+		}
+	}
+	st := s.acquire()
+	defer st.release()
+	for _, name := range sortedKeys(st.tenants) {
+		t := st.tenants[name]
+		if gs, ok := stateOf(t.g, t.node, clockNow()); ok {
+			if err := addJSON(tenantStateFile(name), gs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.clock.flush(); err != nil {
+		return nil, err
+	}
+	files = append(files, file{stateClockFile, []byte(s.clock.latest().Format(time.RFC3339Nano) + "\n")})
+	if s.issued != nil {
+		b, err := s.issued.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{stateIssuedFile, b})
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleSnapshot serves a snapshot, which, since it gives the server's
+// state away, is only served to clients with API keys, or to this
+// machine.  A follower's generator isn't the one in use, so only the
+// leader has one worth taking.
+func (s *server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if s.following() {
+		notLeader(w)
+		return
+	}
+	b, err := s.snapshot()
+	if err != nil {
+		s.log.Error("snapshot failed", "err", err)
+		http.Error(w, "snapshot failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="uuidgen-snapshot.tar.gz"`)
+	w.Write(b)
+}
+
+// restoreSnapshot unpacks the snapshot r into the state directory dir,
+// with a config.json that serves from it.  dir must not have state in
+// it already, which the snapshot could be older than.
+func restoreSnapshot(r io.Reader, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isStateFile(hdr.Name) {
+			return fmt.Errorf("not a snapshot: it has %q", hdr.Name)
+		}
+		if hdr.Size > maxSnapshotFile {
+			return fmt.Errorf("%s is too big, at %d bytes", hdr.Name, hdr.Size)
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return err
+		}
+	}
+	if files[stateConfigFile] == nil {
+		return fmt.Errorf("not a snapshot: no %s", stateConfigFile)
+	}
+
+	cfg := defaultServerConfig()
+	if err := json.Unmarshal(files[stateConfigFile], &cfg); err != nil {
+		return fmt.Errorf("%s: %v", stateConfigFile, err)
+	}
+	cfg.StateDir = dir
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[stateConfigFile] = append(b, '\n')
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("%s already has %s: restore into an empty directory", dir, name)
+		}
+	}
+	for _, name := range sortedKeys(files) {
+		if err := writeFileAtomic(filepath.Join(dir, name), files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	serverURL := fs.String("server", "http://localhost:8080", "URL of the server to snapshot")
+	apiKey := fs.String("api-key", "", "API key, for a server started with -api-keys")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen snapshot [flags]")
+		fmt.Fprintln(fs.Output(), "Fetches a snapshot of a server's state, to move it to another machine with")
+		fmt.Fprintln(fs.Output(), "uuidgen restore: its config, generator state, clock and /check filter.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(*serverURL, "/")+"/snapshot", nil)
+	if err != nil {
+		return err
+	}
+	if *apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+*apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", req.URL, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dir := fs.String("dir", "", "state directory to restore into; it must not have state in it already")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen restore -dir dir snapshot.tar.gz")
+		fmt.Fprintln(fs.Output(), "Unpacks a uuidgen snapshot into a state directory, with a config.json to")
+		fmt.Fprintln(fs.Output(), "serve from it as the node the snapshot was taken of.  Stop that node first:")
+		fmt.Fprintln(fs.Output(), "two servers with one node ID can repeat each other's UUIDs.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *dir == "" || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := restoreSnapshot(f, *dir); err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+	fmt.Printf("restored; serve with: uuidgen serve -config %s\n", filepath.Join(*dir, stateConfigFile))
+	fmt.Println("files the config names, such as api_keys and tenants, must be copied over too")
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	cfg := defaultServerConfig()
+	cfg.Addr, cfg.Strategy, cfg.CheckRecent, cfg.CheckExpected, cfg.StateDir = "localhost:0", "satori", 10, 1000, oldDir
+	s, err := newServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	if _, err := os.Stat(filepath.Join(oldDir, stateGeneratorFile)); err != nil {
+		t.Errorf("generator state not saved at startup: %v", err)
+	}
+
+	issued, err := Parse(strings.TrimSpace(get(t, ts, "/uuid", http.StatusOK)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSeq, _ := issued.ClockSequence()
+	snap := get(t, ts, "/snapshot", http.StatusOK)
+	if err := s.shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{stateGeneratorFile, stateIssuedFile, stateClockFile} {
+		if _, err := os.Stat(filepath.Join(oldDir, name)); err != nil {
+			t.Errorf("%s not saved at shutdown: %v", name, err)
+		}
+	}
+
+	if err := restoreSnapshot(strings.NewReader(snap), newDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreSnapshot(strings.NewReader(snap), newDir); err == nil {
+		t.Error("restored over existing state")
+	}
+	restored := defaultServerConfig()
+	if err := loadServerConfig(filepath.Join(newDir, stateConfigFile), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.StateDir != newDir || restored.Strategy != "satori" || restored.CheckRecent != 10 {
+		t.Errorf("restored config: %+v", restored)
+	}
+	if node, err := parseNode(restored.Node); err != nil || node == nil || *node != hardwareAddr {
+		t.Errorf("restored node %q, want %x", restored.Node, hardwareAddr)
+	}
+
+	// The snapshot's time is ahead of the clock, so the replacement
+	// must move on to the next clock sequence.
+	s2, err := newServer(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts2 := httptest.NewServer(s2)
+	defer ts2.Close()
+	defer s2.shutdown()
+	u, err := Parse(strings.TrimSpace(get(t, ts2, "/uuid", http.StatusOK)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, _ := u.ClockSequence(); seq != (oldSeq+1)&0x3fff {
+		t.Errorf("restored clock sequence %d, want %d", seq, (oldSeq+1)&0x3fff)
+	}
+	var check checkResult
+	if err := json.Unmarshal([]byte(get(t, ts2, "/check?id="+issued.String(), http.StatusOK)), &check); err != nil {
+		t.Fatal(err)
+	}
+	if !check.Recent || !check.Issued {
+		t.Errorf("restored /check of %s: %+v", issued, check)
+	}
+	if s2.clock.latest().IsZero() {
+		t.Error("restored clock guard has no latest time")
+	}
+}
+
+func TestSnapshotNeedsKeysOrLocalAddr(t *testing.T) {
+	cfg := defaultServerConfig()
+	cfg.Addr = "0.0.0.0:8080"
+	ts := newTestServer(t, cfg)
+	get(t, ts, "/snapshot", http.StatusNotFound)
+}
+
+func TestRestoreRejects(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"no config":    {stateGeneratorFile: "{}"},
+		"unknown file": {stateConfigFile: "{}", "../../etc/passwd": ""},
+	} {
+		var buf bytes.Buffer
+		if err := writeTestSnapshot(&buf, files); err != nil {
+			t.Fatal(err)
+		}
+		if err := restoreSnapshot(&buf, t.TempDir()); err == nil {
+			t.Errorf("%s: restored", name)
+		}
+	}
+	if err := restoreSnapshot(strings.NewReader("not gzip"), t.TempDir()); err == nil {
+		t.Error("restored a file that isn't a snapshot")
+	}
+}
+
+// writeTestSnapshot writes a snapshot of files, in the order of their
+// names.
+func writeTestSnapshot(w io.Writer, files map[string]string) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	for _, name := range sortedKeys(files) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name]))}); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func TestIssuedLogMarshal(t *testing.T) {
+	l := newIssuedLog(3, 100)
+	var ids []UUID
+	for i := 0; i < 5; i++ {
+		u := NewV4()
+		ids = append(ids, u)
+		l.record(u)
+	}
+	b, err := l.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := newIssuedLog(3, 100)
+	if err := same.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range ids {
+		recent, issued := same.check(u)
+		if recent != (i >= 2) || !issued {
+			t.Errorf("ID %d: recent %v, issued %v", i, recent, issued)
+		}
+	}
+
+	// With a smaller ring and another size of filter, only the newest
+	// carry over.
+	smaller := newIssuedLog(2, 1000)
+	if err := smaller.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range ids {
+		if recent, _ := smaller.check(u); recent != (i >= 3) {
+			t.Errorf("ID %d: recent %v in the smaller log", i, recent)
+		}
+	}
+}
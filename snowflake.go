@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Snowflake is Twitter's 64 bit ID: 41 bits of milliseconds since
+// snowflakeEpoch, a 10 bit worker ID, and a 12 bit sequence number.
+// IDs from one worker always increase, and workers with different IDs
+// never collide, so each process needs its own worker ID; see
+// worker.go for ways to get one.
+type Snowflake int64
+
+// snowflakeEpoch is Twitter's, in Unix milliseconds: 2010-11-04.
+const snowflakeEpoch = 1288834974657
+
+const (
+	snowflakeWorkerBits   = 10
+	snowflakeSequenceBits = 12
+	// MaxWorkerID is the largest Snowflake worker ID.
+	MaxWorkerID = 1<<snowflakeWorkerBits - 1
+)
+
+// ParseSnowflake parses the decimal form of a Snowflake.
+func ParseSnowflake(s string) (Snowflake, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid Snowflake %q", s)
+	}
+	return Snowflake(n), nil
+}
+
+func (id Snowflake) String() string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+// Bytes returns id as 8 big-endian bytes.
+func (id Snowflake) Bytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+// Time returns the time id was made, to the millisecond.  ok is always
+// true.
+func (id Snowflake) Time() (t time.Time, ok bool) {
+	return time.UnixMilli(int64(id)>>(snowflakeWorkerBits+snowflakeSequenceBits) + snowflakeEpoch), true
+}
+
+// Worker returns the worker ID that made id.
+func (id Snowflake) Worker() int64 {
+	return int64(id) >> snowflakeSequenceBits & MaxWorkerID
+}
+
+// SnowflakeGenerator makes Snowflakes for one worker ID.  Like
+// V7Generator, when a millisecond's 4096 sequence numbers run out, or
+// the clock goes backwards, it borrows from the next millisecond.
+type SnowflakeGenerator struct {
+	mu      sync.Mutex
+	worker  int64
+	clock   msClock
+	nowFunc func() time.Time
+
+	counters generatorCounters
+}
+
+func NewSnowflakeGenerator(worker int64) (*SnowflakeGenerator, error) {
+	return newSnowflakeGenerator(worker, func() time.Time { return clockNow() })
+}
+
+// newSnowflakeGenerator lets tests inject a fake clock.  nowFunc is
+// only ever called with g.mu held.
+func newSnowflakeGenerator(worker int64, nowFunc func() time.Time) (*SnowflakeGenerator, error) {
+	if worker < 0 || worker > MaxWorkerID {
+		return nil, fmt.Errorf("worker ID %d is not between 0 and %d", worker, MaxWorkerID)
+	}
+	return &SnowflakeGenerator{
+		worker:  worker,
+		clock:   msClock{maxSeq: 1<<snowflakeSequenceBits - 1},
+		nowFunc: nowFunc,
+	}, nil
+}
+
+// Next returns the next Snowflake.
+func (g *SnowflakeGenerator) Next() Snowflake {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms, seq := g.clock.next(g.nowFunc().UnixMilli()-snowflakeEpoch, &g.counters)
+	g.counters.generated.Add(1)
+
+	return Snowflake(ms<<(snowflakeWorkerBits+snowflakeSequenceBits) | g.worker<<snowflakeSequenceBits | seq)
+}
+
+// Stats reports on g.
+func (g *SnowflakeGenerator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
+
+// defaultSnowflakes backs the registered "snowflake" family.  It uses
+// worker 0, so it is only good for tools, not for minting IDs shared
+// with other workers.
+var defaultSnowflakes, _ = NewSnowflakeGenerator(0)
+
+func init() {
+	RegisterFamily("snowflake", Family{
+		New: func() ID { return defaultSnowflakes.Next() },
+		Parse: func(s string) (ID, error) {
+			return ParseSnowflake(s)
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnowflakeGenerator(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	g, err := newSnowflakeGenerator(42, func() time.Time { return start })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Milliseconds since Twitter's epoch, worker 42, then the
+	// sequence number.
+	ms := Snowflake(start.UnixMilli() - snowflakeEpoch)
+	for seq := Snowflake(0); seq < 3; seq++ {
+		id := g.Next()
+		if want := ms<<22 | 42<<12 | seq; id != want {
+			t.Errorf("ID %d, want %d", id, want)
+		}
+		if ts, _ := id.Time(); !ts.Equal(start) || id.Worker() != 42 {
+			t.Errorf("%d: time %s, worker %d", id, ts, id.Worker())
+		}
+	}
+
+	if _, err := NewSnowflakeGenerator(MaxWorkerID + 1); err == nil {
+		t.Error("worker ID 1024 should be rejected")
+	}
+}
+
+func TestParseSnowflake(t *testing.T) {
+	when := time.Date(2022, time.November, 1, 12, 0, 0, 0, time.UTC)
+	want := Snowflake((when.UnixMilli()-snowflakeEpoch)<<22 | 5<<12 | 17)
+	id, err := ParseSnowflake(want.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != want {
+		t.Errorf("round trip gave %d, want %d", id, want)
+	}
+	if ts, _ := id.Time(); !ts.Equal(when) {
+		t.Errorf("time %s", ts.UTC())
+	}
+	if id.Worker() != 5 {
+		t.Errorf("worker %d", id.Worker())
+	}
+	for _, s := range []string{"", "-1", "x", "99999999999999999999"} {
+		if _, err := ParseSnowflake(s); err == nil {
+			t.Errorf("ParseSnowflake(%q) should fail", s)
+		}
+	}
+}
+
+func TestWorkerIDFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"7", 7, true},
+		{"uuidd-3", 3, true},
+		{"my-app-1023", 1023, true},
+		{"uuidd-1024", 0, false},
+		{"uuidd", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEST_WORKER_ID", tt.value)
+		got, err := WorkerIDFromEnv("TEST_WORKER_ID")
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%q: got %d, %v", tt.value, got, err)
+		}
+	}
+}
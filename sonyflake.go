@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Sonyflake is Sony's take on the Snowflake: 39 bits of 10ms units
+// since a start time, an 8 bit sequence number, and a 16 bit machine
+// ID.  See https://github.com/sony/sonyflake.  Its IDs last far longer
+// than Snowflakes and suit many more machines, but each machine only
+// gets 256 a unit, 25600 a second.
+type Sonyflake uint64
+
+// sonyflakeStart is Sonyflake's default start time: 2014-09-01.
+var sonyflakeStart = time.Date(2014, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	sonyflakeTimeUnit     = 10 * time.Millisecond
+	sonyflakeSequenceBits = 8
+	sonyflakeMachineBits  = 16
+)
+
+// ParseSonyflake parses the decimal form of a Sonyflake.
+func ParseSonyflake(s string) (Sonyflake, error) {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n>>63 != 0 {
+		return 0, fmt.Errorf("invalid Sonyflake %q", s)
+	}
+	return Sonyflake(n), nil
+}
+
+func (id Sonyflake) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// Bytes returns id as 8 big-endian bytes.
+func (id Sonyflake) Bytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+// Time returns the time id was made, to 10ms, assuming it came from a
+// generator with the default start time.  ok is always true.  Use the
+// generator's Time method for other start times.
+func (id Sonyflake) Time() (t time.Time, ok bool) {
+	return sonyflakeTime(sonyflakeStart, id), true
+}
+
+// Machine returns the machine ID that made id.
+func (id Sonyflake) Machine() uint16 {
+	return uint16(id)
+}
+
+func sonyflakeTime(start time.Time, id Sonyflake) time.Time {
+	units := sonyflakeUnits(start) + int64(id>>(sonyflakeSequenceBits+sonyflakeMachineBits))
+	return time.Unix(0, units*int64(sonyflakeTimeUnit))
+}
+
+// SonyflakeSettings configures a SonyflakeGenerator, like sonyflake's
+// Settings, so that existing Sonyflake users can keep their IDs.
+type SonyflakeSettings struct {
+	// StartTime is when the IDs' time starts.  The zero value means
+	// Sonyflake's default, 2014-09-01.
+	StartTime time.Time
+	// MachineID returns the machine ID.  nil means
+	// SonyflakeMachineID, the bottom 16 bits of the private IP address.
+	MachineID func() (uint16, error)
+}
+
+// SonyflakeMachineID returns the bottom 16 bits of this machine's
+// private IPv4 address, Sonyflake's default machine ID.  Machines on
+// the same /16 get different IDs.
+func SonyflakeMachineID() (uint16, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		ip := ipnet.IP.To4()
+		if ip != nil && (ip[0] == 10 || ip[0] == 172 && ip[1] >= 16 && ip[1] < 32 || ip[0] == 192 && ip[1] == 168) {
+			return uint16(ip[2])<<8 | uint16(ip[3]), nil
+		}
+	}
+	return 0, errors.New("no private IPv4 address for a Sonyflake machine ID")
+}
+
+// SonyflakeGenerator makes Sonyflakes for one machine ID.  IDs match
+// those of sonyflake with the same settings, but like
+// SnowflakeGenerator, when a unit's 256 sequence numbers run out, or
+// the clock goes backwards, it borrows from the next unit rather than
+// sleeping.
+type SonyflakeGenerator struct {
+	mu      sync.Mutex
+	start   time.Time
+	machine uint16
+	// clock ticks in units since start.
+	clock   msClock
+	nowFunc func() time.Time
+
+	counters generatorCounters
+}
+
+func NewSonyflakeGenerator(st SonyflakeSettings) (*SonyflakeGenerator, error) {
+	return newSonyflakeGenerator(st, func() time.Time { return clockNow() })
+}
+
+// newSonyflakeGenerator lets tests inject a fake clock.  nowFunc is
+// only ever called with g.mu held.
+func newSonyflakeGenerator(st SonyflakeSettings, nowFunc func() time.Time) (*SonyflakeGenerator, error) {
+	start := st.StartTime
+	if start.IsZero() {
+		start = sonyflakeStart
+	}
+	if start.After(nowFunc()) {
+		return nil, fmt.Errorf("Sonyflake start time %s is in the future", start)
+	}
+	machineID := st.MachineID
+	if machineID == nil {
+		machineID = SonyflakeMachineID
+	}
+	machine, err := machineID()
+	if err != nil {
+		return nil, err
+	}
+	return &SonyflakeGenerator{
+		start:   start,
+		machine: machine,
+		clock:   msClock{maxSeq: 1<<sonyflakeSequenceBits - 1, last: -1},
+		nowFunc: nowFunc,
+	}, nil
+}
+
+// Next returns the next Sonyflake.
+func (g *SonyflakeGenerator) Next() Sonyflake {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	unit, seq := g.clock.next(sonyflakeUnits(g.nowFunc())-sonyflakeUnits(g.start), &g.counters)
+	g.counters.generated.Add(1)
+
+	return Sonyflake(uint64(unit)<<(sonyflakeSequenceBits+sonyflakeMachineBits) | uint64(seq)<<sonyflakeMachineBits | uint64(g.machine))
+}
+
+// sonyflakeUnits returns t in 10ms units, as sonyflake counts them.
+func sonyflakeUnits(t time.Time) int64 {
+	return t.UnixNano() / int64(sonyflakeTimeUnit)
+}
+
+// Time returns the time id was made by a generator with g's start
+// time.
+func (g *SonyflakeGenerator) Time(id Sonyflake) time.Time {
+	return sonyflakeTime(g.start, id)
+}
+
+// Stats reports on g.
+func (g *SonyflakeGenerator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
+
+// defaultSonyflakes backs the registered "sonyflake" family.  It uses
+// machine 0, so like the "snowflake" family it is only good for tools.
+var defaultSonyflakes, _ = NewSonyflakeGenerator(SonyflakeSettings{
+	MachineID: func() (uint16, error) { return 0, nil },
+})
+
+func init() {
+	RegisterFamily("sonyflake", Family{
+		New: func() ID { return defaultSonyflakes.Next() },
+		Parse: func(s string) (ID, error) {
+			return ParseSonyflake(s)
+		},
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSonyflakeGenerator(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	g, err := newSonyflakeGenerator(SonyflakeSettings{
+		MachineID: func() (uint16, error) { return 0xbeef, nil },
+	}, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := g.Next()
+	// What sonyflake makes at that time: 10ms units since 2014-09-01,
+	// sequence 0, machine 0xbeef.
+	if want := Sonyflake(uint64(start.Sub(sonyflakeStart)/sonyflakeTimeUnit)<<24 | 0xbeef); first != want {
+		t.Errorf("first ID %d, want %d", first, want)
+	}
+	// Then the sequence number counts up, and the machine stays put.
+	if id := g.Next(); id != first|1<<16 || id.Machine() != 0xbeef {
+		t.Errorf("second ID %d, machine %#x", id, id.Machine())
+	}
+
+	// A start time of its own.
+	g, _ = newSonyflakeGenerator(SonyflakeSettings{
+		StartTime: start.Add(-time.Hour),
+		MachineID: func() (uint16, error) { return 1, nil },
+	}, func() time.Time { return now })
+	id := g.Next()
+	if id>>24 != Sonyflake(time.Hour/sonyflakeTimeUnit) {
+		t.Errorf("%d units since the start time", id>>24)
+	}
+	if ts := g.Time(id); !ts.Equal(now) {
+		t.Errorf("time %s, want %s", ts, now)
+	}
+
+	if _, err := newSonyflakeGenerator(SonyflakeSettings{StartTime: now.Add(time.Hour)}, func() time.Time { return now }); err == nil {
+		t.Error("start time in the future accepted")
+	}
+	if _, err := NewSonyflakeGenerator(SonyflakeSettings{
+		MachineID: func() (uint16, error) { return 0, errors.New("no ID") },
+	}); err == nil {
+		t.Error("machine ID error ignored")
+	}
+}
+
+func TestParseSonyflake(t *testing.T) {
+	want := Sonyflake(123456789<<24 | 5<<16 | 17)
+	id, err := ParseSonyflake(want.String())
+	if err != nil || id != want {
+		t.Errorf("round trip gave %d, %v, want %d", id, err, want)
+	}
+	for _, s := range []string{"", "-1", "x", "18446744073709551615"} {
+		if _, err := ParseSonyflake(s); err == nil {
+			t.Errorf("ParseSonyflake(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Compare returns -1, 0 or 1 as u sorts before, the same as, or after
+// v, byte by byte.
+func (u UUID) Compare(v UUID) int {
+	return bytes.Compare(u[:], v[:])
+}
+
+// timeOrder sorts ids by embedded time, falling back to byte order for
+// ties.  If stable is false, every UUID must have a time.  If it is
+// true, UUIDs without one sort after all the ones with one.
+func timeOrder(ids []UUID, stable bool) error {
+	type keyed struct {
+		u     UUID
+		t     int64
+		timed bool
+	}
+	keys := make([]keyed, len(ids))
+	for i, u := range ids {
+		t, ok := u.Time()
+		if !ok && !stable {
+			return fmt.Errorf("%s is version %d, which has no time; try -stable-across-versions", u, u.Version())
+		}
+		keys[i] = keyed{u, t.UnixNano(), ok}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.timed != b.timed:
+			return a.timed
+		case a.timed && a.t != b.t:
+			return a.t < b.t
+		}
+		return a.u.Compare(b.u) < 0
+	})
+	for i, k := range keys {
+		ids[i] = k.u
+	}
+	return nil
+}
+
+func runSort(args []string) error {
+	fs := flag.NewFlagSet("sort", flag.ExitOnError)
+	by := fs.String("by", "bytes", "sort order: bytes or time")
+	stable := fs.Bool("stable-across-versions", false, "with -by time, sort UUIDs without a time after the rest instead of failing")
+	binary := fs.Bool("binary", false, "read and write raw 16 byte UUIDs instead of lines of text")
+	unique := fs.Bool("unique", false, "drop duplicates")
+	external := fs.Bool("external", false, "sort in chunks written to temporary files, for inputs bigger than memory; only with -by bytes")
+	chunk := fs.Int("chunk", 1<<22, "with -external, how many UUIDs to sort in memory at a time, at 16 bytes each")
+	tmpDir := fs.String("tmpdir", "", "with -external, where to put the chunks, instead of the system temporary directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen sort [flags] [file ...]")
+		fmt.Fprintln(fs.Output(), "Sorts UUIDs read from the files, or stdin.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *by != "bytes" && *by != "time" {
+		return fmt.Errorf("unknown sort order %q", *by)
+	}
+	if *stable && *by != "time" {
+		return errors.New("-stable-across-versions only makes sense with -by time")
+	}
+
+	if *external {
+		if *by != "bytes" {
+			return errors.New("-external only sorts -by bytes")
+		}
+		if *chunk < 1 {
+			return errors.New("-chunk must be positive")
+		}
+		s := &ExternalSorter{ChunkSize: *chunk, TempDir: *tmpDir, Unique: *unique}
+		defer s.Close()
+		if err := forEachUUID(fs.Args(), *binary, s.Add); err != nil {
+			return err
+		}
+		enc := NewEncoder(os.Stdout)
+		enc.SetBinary(*binary)
+		return s.WriteTo(enc)
+	}
+
+	var ids []UUID
+	err := forEachUUID(fs.Args(), *binary, func(u UUID) error {
+		ids = append(ids, u)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if *by == "time" {
+		if err := timeOrder(ids, *stable); err != nil {
+			return err
+		}
+	} else {
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+	}
+	if *unique {
+		ids = dedupe(ids)
+	}
+
+	if *binary {
+		return WriteAll(os.Stdout, ids)
+	}
+	enc := NewEncoder(os.Stdout)
+	for _, u := range ids {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+// dedupe drops repeats of the same UUID next to each other, so all
+// duplicates once ids are sorted.
+func dedupe(ids []UUID) []UUID {
+	out := ids[:0]
+	for i, u := range ids {
+		if i == 0 || u != out[len(out)-1] {
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestTimeOrder(t *testing.T) {
+	// V1 UUIDs don't sort by time byte by byte, but V6 and V7 do, so
+	// interleave versions and check that time wins.
+	var ids []UUID
+	for i := 0; i < 20; i++ {
+		ids = append(ids, NewV1(), NewV6())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) > 0 })
+
+	if err := timeOrder(ids, false); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(ids); i++ {
+		prev, _ := ids[i-1].Time()
+		cur, _ := ids[i].Time()
+		if cur.Before(prev) {
+			t.Fatalf("position %d: %s is before %s", i, cur, prev)
+		}
+	}
+}
+
+func TestTimeOrderUntimed(t *testing.T) {
+	v4, v1 := NewV4(), NewV1()
+	ids := []UUID{v4, v1}
+	if err := timeOrder(ids, false); err == nil {
+		t.Errorf("V4 UUID sorted by time without -stable-across-versions")
+	}
+	if err := timeOrder(ids, true); err != nil {
+		t.Fatal(err)
+	}
+	if ids[0] != v1 || ids[1] != v4 {
+		t.Errorf("got %v, want V1 then V4", ids)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// sqidsAlphabet is the alphabet Sqids uses by default.
+const sqidsAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Sqids turns sequence numbers, such as a HiLoGenerator's, into short
+// strings that don't look sequential, and back, so that auto-increment
+// IDs can be shown to the public without giving away how many there
+// are.  It follows https://sqids.org, so its strings match other Sqids
+// implementations with the same alphabet and minimum length, except
+// that it has no blocklist of words to avoid.
+//
+// This is obfuscation, not encryption: anyone who knows or guesses the
+// alphabet can decode the strings.
+type Sqids struct {
+	alphabet  []byte
+	minLength int
+}
+
+// NewSqids returns a Sqids using alphabet, which must be at least 3
+// distinct ASCII characters, padding strings to at least minLength
+// characters.  An empty alphabet means the Sqids default.  Shuffling
+// the alphabet gives different strings for the same numbers.
+func NewSqids(alphabet string, minLength int) (*Sqids, error) {
+	if alphabet == "" {
+		alphabet = sqidsAlphabet
+	}
+	if len(alphabet) < 3 {
+		return nil, errors.New("sqids alphabet must have at least 3 characters")
+	}
+	seen := map[byte]bool{}
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c >= 0x80 {
+			return nil, fmt.Errorf("sqids alphabet must be ASCII, not %q", alphabet)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("sqids alphabet has %q twice", c)
+		}
+		seen[c] = true
+	}
+	if minLength < 0 || minLength > 255 {
+		return nil, fmt.Errorf("sqids minimum length %d is not between 0 and 255", minLength)
+	}
+	a := []byte(alphabet)
+	sqidsShuffle(a)
+	return &Sqids{alphabet: a, minLength: minLength}, nil
+}
+
+// Encode returns the string for numbers, usually just one.
+func (s *Sqids) Encode(numbers ...uint64) string {
+	if len(numbers) == 0 {
+		return ""
+	}
+	n := len(s.alphabet)
+	offset := len(numbers)
+	for i, v := range numbers {
+		offset += int(s.alphabet[v%uint64(n)]) + i
+	}
+	offset %= n
+
+	alphabet := make([]byte, 0, n)
+	alphabet = append(alphabet, s.alphabet[offset:]...)
+	alphabet = append(alphabet, s.alphabet[:offset]...)
+	prefix := alphabet[0]
+	sqidsReverse(alphabet)
+
+	id := []byte{prefix}
+	for i, v := range numbers {
+		id = sqidsAppend(id, v, alphabet[1:])
+		if i < len(numbers)-1 {
+			id = append(id, alphabet[0])
+			sqidsShuffle(alphabet)
+		}
+	}
+
+	if len(id) < s.minLength {
+		id = append(id, alphabet[0])
+		for len(id) < s.minLength {
+			sqidsShuffle(alphabet)
+			id = append(id, alphabet[:min(s.minLength-len(id), n)]...)
+		}
+	}
+	return string(id)
+}
+
+// Decode returns the numbers id was made from.  It fails for strings
+// Encode doesn't make, including ones that would decode to the same
+// numbers as a different string, so that each number has only one
+// public form.
+func (s *Sqids) Decode(id string) ([]uint64, error) {
+	if id == "" {
+		return nil, errors.New("empty sqid")
+	}
+	offset := -1
+	for i := 0; i < len(id); i++ {
+		j := strings.IndexByte(string(s.alphabet), id[i])
+		if j < 0 {
+			return nil, fmt.Errorf("invalid sqid %q: bad character %q", id, id[i])
+		}
+		if i == 0 {
+			offset = j
+		}
+	}
+
+	alphabet := make([]byte, 0, len(s.alphabet))
+	alphabet = append(alphabet, s.alphabet[offset:]...)
+	alphabet = append(alphabet, s.alphabet[:offset]...)
+	sqidsReverse(alphabet)
+
+	var numbers []uint64
+	rest := id[1:]
+	for rest != "" {
+		chunk, after, more := strings.Cut(rest, string(alphabet[0]))
+		if chunk == "" {
+			// Padding follows.
+			break
+		}
+		v, ok := sqidsNumber(chunk, alphabet[1:])
+		if !ok {
+			return nil, fmt.Errorf("invalid sqid %q: number too big", id)
+		}
+		numbers = append(numbers, v)
+		if more {
+			sqidsShuffle(alphabet)
+		}
+		rest = after
+	}
+	if len(numbers) == 0 || s.Encode(numbers...) != id {
+		return nil, fmt.Errorf("invalid sqid %q", id)
+	}
+	return numbers, nil
+}
+
+// sqidsAppend appends v written in alphabet's base to id.
+func sqidsAppend(id []byte, v uint64, alphabet []byte) []byte {
+	var buf [64]byte
+	i := len(buf)
+	base := uint64(len(alphabet))
+	for {
+		i--
+		buf[i] = alphabet[v%base]
+		v /= base
+		if v == 0 {
+			break
+		}
+	}
+	return append(id, buf[i:]...)
+}
+
+// sqidsNumber reads chunk as a number in alphabet's base.  ok is false
+// if it doesn't fit in a uint64.
+func sqidsNumber(chunk string, alphabet []byte) (v uint64, ok bool) {
+	base := uint64(len(alphabet))
+	for i := 0; i < len(chunk); i++ {
+		d := uint64(strings.IndexByte(string(alphabet), chunk[i]))
+		if v > (math.MaxUint64-d)/base {
+			return 0, false
+		}
+		v = v*base + d
+	}
+	return v, true
+}
+
+// sqidsShuffle is the Sqids shuffle: deterministic, so that encoder
+// and decoder agree.
+func sqidsShuffle(a []byte) {
+	n := len(a)
+	for i, j := 0, n-1; j > 0; i, j = i+1, j-1 {
+		r := (i*j + int(a[i]) + int(a[j])) % n
+		a[i], a[r] = a[r], a[i]
+	}
+}
+
+func sqidsReverse(a []byte) {
+	for i, j := 0, len(a)-1; i < j; i, j = i+1, j-1 {
+		a[i], a[j] = a[j], a[i]
+	}
+}
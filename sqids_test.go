@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSqids(t *testing.T) {
+	// From the Sqids spec's tests.
+	tests := []struct {
+		alphabet  string
+		minLength int
+		numbers   []uint64
+		want      string
+	}{
+		{"", 0, []uint64{1, 2, 3}, "86Rf07"},
+		{"", 0, []uint64{0}, "bM"},
+		{"", 0, []uint64{9}, "nJ"},
+		{"0123456789abcdef", 0, []uint64{1, 2, 3}, "489158"},
+		{"", len(sqidsAlphabet), []uint64{1, 2, 3}, "86Rf07xd4zBmiJXQG6otHEbew02c3PWsUOLZxADhCpKj7aVFv9I8RquYrNlSTM"},
+	}
+	for _, test := range tests {
+		s, err := NewSqids(test.alphabet, test.minLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := s.Encode(test.numbers...)
+		if got != test.want {
+			t.Errorf("%q %d %v: got %q, want %q", test.alphabet, test.minLength, test.numbers, got, test.want)
+		}
+		if back, err := s.Decode(got); err != nil || !reflect.DeepEqual(back, test.numbers) {
+			t.Errorf("decoding %q: %v, %v", got, back, err)
+		}
+	}
+
+	s, _ := NewSqids("", 8)
+	for _, v := range []uint64{0, 1, 62, 1 << 40, math.MaxUint64} {
+		id := s.Encode(v)
+		if len(id) < 8 {
+			t.Errorf("%d: %q is short", v, id)
+		}
+		if back, err := s.Decode(id); err != nil || len(back) != 1 || back[0] != v {
+			t.Errorf("%d: %q decoded to %v, %v", v, id, back, err)
+		}
+	}
+
+	for _, bad := range []string{"", "86Rf0!", "86Rf07x"} {
+		if _, err := s.Decode(bad); err == nil {
+			t.Errorf("decoded %q", bad)
+		}
+	}
+	for _, alphabet := range []string{"ab", "abca", "abcé"} {
+		if _, err := NewSqids(alphabet, 0); err == nil {
+			t.Errorf("alphabet %q accepted", alphabet)
+		}
+	}
+}
+
+func TestSqidsHiLo(t *testing.T) {
+	g, err := NewHiLoGenerator(FileBlockStore{Name: filepath.Join(t.TempDir(), "seq")}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := NewSqids("", 6)
+	seen := map[string]bool{}
+	for i := 0; i < 25; i++ {
+		v, err := g.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := s.Encode(v)
+		if seen[id] {
+			t.Fatalf("%q twice", id)
+		}
+		seen[id] = true
+	}
+}
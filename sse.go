@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxStreamRate caps the rate of a /stream request, in UUIDs a second.
+const maxStreamRate = maxPerRequest
+
+// minStreamTick is the shortest gap between writes to a stream.  Faster
+// rates send several UUIDs a tick rather than ticking faster.
+const minStreamTick = 10 * time.Millisecond
+
+// handleStream sends a Server-Sent Events feed of fresh UUIDs, one an
+// event, at the rate a second the client asks for, until it has sent
+// n, if given, or the client goes away:
+//
+//	GET /stream?rate=100&n=1000
+//
+// Each UUID is made when it is sent, not ahead of time, so a feed of
+// V1 or V7 UUIDs shows the clock ticking.  UUIDs the rate limits won't
+// allow are skipped rather than sent late.
+func (s *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	rate, err := strconv.ParseFloat(r.FormValue("rate"), 64)
+	if err != nil || rate <= 0 || rate > maxStreamRate {
+		http.Error(w, fmt.Sprintf("rate must be above 0 and at most %d", maxStreamRate), http.StatusBadRequest)
+		return
+	}
+	n := 0
+	if v := r.FormValue("n"); v != "" {
+		if n, err = strconv.Atoi(v); err != nil || n < 0 {
+			http.Error(w, "n must be a count, or 0 for no end", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+	bw := bufio.NewWriter(w)
+	client := clientKey(r)
+	info := requestInfoFrom(r.Context())
+
+	tick := max(time.Duration(float64(time.Second)/rate), minStreamTick)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	last := time.Now()
+	owed := 1.0 // Start with one straight away.
+	for sent := 0; n == 0 || sent < n; {
+		k := int(owed)
+		owed -= float64(k)
+		if n > 0 {
+			k = min(k, n-sent)
+		}
+		if k > 0 {
+			if _, ok := s.limiter.allow(client, k); ok {
+				// A tick at a time, so a long stream doesn't hold up a
+				// reload.
+				st := s.acquire()
+				for i := 0; i < k; i++ {
+					fmt.Fprintf(bw, "data: %s\n\n", s.next(st, info))
+				}
+				st.release()
+				sent += k
+				if bw.Flush() != nil || rc.Flush() != nil {
+					return
+				}
+			}
+		}
+		if n > 0 && sent >= n {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case now := <-ticker.C:
+			owed += now.Sub(last).Seconds() * rate
+			last = now
+		}
+	}
+}
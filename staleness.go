@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// stalenessRecorder wraps a V1 generator, recording how old each UUID's
+// timestamp is by the time the caller gets it.  For the channel
+// strategies that is how long it sat in the channel, a cost that ns/op
+// doesn't show.
+type stalenessRecorder struct {
+	g Generator
+	// buckets[i] counts ages of up to 2^i ns, so that recording is
+	// one atomic add and there's no lock to skew the results.
+	buckets [64]atomic.Int64
+	max     atomic.Int64
+}
+
+func (r *stalenessRecorder) New() UUID {
+	u := r.g.New()
+	age := int64(staleness(u))
+	if age < 0 {
+		// The clock went backwards, or the UUID was stamped in the
+		// same tick; either way it isn't stale.
+		age = 0
+	}
+	r.buckets[bits.Len64(uint64(age))].Add(1)
+	for {
+		max := r.max.Load()
+		if age <= max || r.max.CompareAndSwap(max, age) {
+			break
+		}
+	}
+	return u
+}
+
+// staleness returns how old u's V1 timestamp is.
+func staleness(u UUID) time.Duration {
+	return time.Duration(int64(unixTimeFunc()-v1Ticks(u)) * 100)
+}
+
+// percentile returns an upper bound on the p'th percentile age, the
+// top of the power of two bucket it falls in.
+func (r *stalenessRecorder) percentile(p float64) time.Duration {
+	total := int64(0)
+	for i := range r.buckets {
+		total += r.buckets[i].Load()
+	}
+	want := int64(float64(total) * p / 100)
+	seen := int64(0)
+	for i := range r.buckets {
+		seen += r.buckets[i].Load()
+		if seen > want {
+			if i == 0 {
+				return 0
+			}
+			return time.Duration(1) << i
+		}
+	}
+	return time.Duration(r.max.Load())
+}
+
+// stalenessResult is one staleness run, as -staleness reports it.
+type stalenessResult struct {
+	Strategy    string        `json:"strategy"`
+	ChanSize    int           `json:"chan_size"`
+	Parallelism int           `json:"parallelism"`
+	NsPerOp     float64       `json:"ns_per_op"`
+	P50         time.Duration `json:"p50_ns"`
+	P99         time.Duration `json:"p99_ns"`
+	Max         time.Duration `json:"max_ns"`
+}
+
+// stalenessRun is benchRun with g's UUIDs' ages recorded.
+func stalenessRun(g Generator, parallelism int, d time.Duration) stalenessResult {
+	r := &stalenessRecorder{g: g}
+	b := benchRun(r, parallelism, d)
+	return stalenessResult{
+		Parallelism: parallelism,
+		NsPerOp:     b.NsPerOp,
+		P50:         r.percentile(50),
+		P99:         r.percentile(99),
+		Max:         time.Duration(r.max.Load()),
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GeneratorState is what a V1 generator keeps in stable storage, as
+// RFC 4122 section 4.2.1 describes, so that a restart can't repeat its
+// UUIDs: the clock sequence and node ID it used, and a time no earlier
+// than any of its timestamps.
+type GeneratorState struct {
+	Time          time.Time `json:"time"`
+	ClockSequence uint16    `json:"clock_sequence"`
+	Node          [6]byte   `json:"node"`
+}
+
+// A StateStore keeps a generator's state between runs: in a file on
+// a machine that has a disk of its own, in a database a container
+// can share, or in object storage for serverless functions, which
+// have neither.  Load's ok is false if nothing has been saved yet.
+type StateStore interface {
+	Load(ctx context.Context) (st GeneratorState, ok bool, err error)
+	Save(ctx context.Context, st GeneratorState) error
+}
+
+// stateSaveEvery is how often a generator with a StateStore saves its
+// state.  Each save reserves twice that, so that the saved time stays
+// ahead of the generator's timestamps even if a save is late.
+var stateSaveEvery = 10 * time.Second
+
+// stateTimeout bounds each Load and Save.
+const stateTimeout = 5 * time.Second
+
+// restoreState sets st's clock sequence from what store has saved, by
+// the rules of RFC 4122: a new random one, as st has already, if
+// nothing was saved or the node has changed since; the saved one, one
+// on, if the clock is earlier than the saved time; and the saved one
+// as it is otherwise.
+func restoreState(store StateStore, st *v1Start) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	saved, ok, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok || saved.Node != st.hardwareAddr {
+		return nil
+	}
+	st.clockSequence = resumedClockSequence(saved, epochToTime(st.epochFunc()))
+	return nil
+}
+
+// resumedClockSequence is the clock sequence to carry on from saved
+// with, if the clock now says now: saved's, or the next one if now
+// isn't after saved's time.
+func resumedClockSequence(saved GeneratorState, now time.Time) uint16 {
+	if !now.After(saved.Time) {
+		return (saved.ClockSequence + 1) & 0x3fff
+	}
+	return saved.ClockSequence
+}
+
+// A stateReporter is a generator that can say what of its state to
+// save without making a UUID, which would use one up, and, from one
+// that makes them ahead, be behind the latest.
+type stateReporter interface {
+	// v1State returns the latest timestamp the generator has used, or
+	// 0 if it hasn't, its clock sequence and its node ID, with ok
+	// false if it can't tell.
+	v1State() (ticks uint64, seq uint16, node [6]byte, ok bool)
+}
+
+// v1Last is the latest timestamp and the clock sequence of a
+// generator whose state only its goroutine touches, which it sends to
+// whoever asks for it.
+type v1Last struct {
+	ticks uint64
+	seq   uint16
+}
+
+// stateOf returns the state to save for g, with node ID node, now: its
+// clock sequence, and a time that is 2*stateSaveEvery after its latest
+// timestamp, or after now if it hasn't borrowed ticks ahead of the
+// clock.  ok is false if g has no clock sequence, or has another node
+// ID, as the pool strategy's generators do.
+func stateOf(g Generator, node [6]byte, now time.Time) (st GeneratorState, ok bool) {
+	r, ok := g.(stateReporter)
+	if !ok {
+		return st, false
+	}
+	ticks, seq, n, ok := r.v1State()
+	if !ok || n != node {
+		return st, false
+	}
+	t := epochToTime(ticks)
+	if now.After(t) {
+		t = now
+	}
+	// The generators' counters are 16 bits, of which UUIDs keep 14.
+	st.Time, st.ClockSequence, st.Node = t.Add(2*stateSaveEvery).UTC(), seq&0x3fff, node
+	return st, true
+}
+
+// persistedGenerator is a generator whose state a goroutine saves to a
+// StateStore every stateSaveEvery, until Close.
+type persistedGenerator struct {
+	Generator
+	store StateStore
+	st    v1Start
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newPersistedGenerator saves g's state once, so that a store that
+// doesn't work is found out now, and then keeps saving it.  st is what
+// g started from.
+func newPersistedGenerator(g Generator, store StateStore, st v1Start) (*persistedGenerator, error) {
+	p := &persistedGenerator{Generator: g, store: store, st: st, stop: make(chan struct{}), done: make(chan struct{})}
+	if err := p.save(); err != nil {
+		return nil, err
+	}
+	go p.run()
+	return p, nil
+}
+
+func (p *persistedGenerator) run() {
+	defer close(p.done)
+	t := time.NewTicker(stateSaveEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := p.save(); err != nil {
+				slog.Warn("generator state not saved", "err", err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// save saves the generator's state: its clock sequence as it is now,
+// if it has been bumped since the start, and its latest timestamp, if
+// ticks have been borrowed ahead of the clock.  It saves nothing if
+// the generator has no clock sequence of this node's.
+func (p *persistedGenerator) save() error {
+	st, ok := stateOf(p.Generator, p.st.hardwareAddr, epochToTime(p.st.epochFunc()))
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	return p.store.Save(ctx, st)
+}
+
+func (p *persistedGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	if r, ok := p.Generator.(stateReporter); ok {
+		return r.v1State()
+	}
+	return 0, 0, [6]byte{}, false
+}
+
+// Stats reports on the generator's UUIDs.
+func (p *persistedGenerator) Stats() GeneratorStats {
+	if sr, ok := p.Generator.(statsReporter); ok {
+		return sr.Stats()
+	}
+	return GeneratorStats{}
+}
+
+// Close stops saving the state, and stops the generator, if it needs
+// stopping.  The last state saved still holds a time ahead of all the
+// generator's timestamps.
+func (p *persistedGenerator) Close() {
+	close(p.stop)
+	<-p.done
+	if c, ok := p.Generator.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// FileStateStore keeps a generator's state in a file, as JSON, written
+// by writeFileAtomic.
+type FileStateStore struct {
+	Path string
+}
+
+func (f FileStateStore) Load(ctx context.Context) (GeneratorState, bool, error) {
+	var st GeneratorState
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, false, nil
+	}
+	if err != nil {
+		return st, false, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, false, fmt.Errorf("%s: %v", f.Path, err)
+	}
+	return st, true, nil
+}
+
+func (f FileStateStore) Save(ctx context.Context, st GeneratorState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, append(b, '\n'))
+}
+
+// writeFileAtomic writes b to a new file and renames it to path, so
+// that a crash leaves the old file or the new one, never half of it.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if serr := tmp.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
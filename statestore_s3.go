@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3StateStore keeps a generator's state in an object in S3, or any
+// store with its API, such as MinIO or R2, for deployments with no
+// disk that outlives them.  Requests are signed with AWS Signature
+// Version 4, and address the bucket in the path, which all of them
+// support.
+type S3StateStore struct {
+	// Endpoint is the service's URL, such as
+	// https://s3.us-east-1.amazonaws.com.
+	Endpoint    string
+	Region      string
+	Bucket, Key string
+
+	AccessKeyID, SecretAccessKey string
+	// SessionToken is needed with temporary credentials, such as a
+	// Lambda function's.
+	SessionToken string
+
+	// Client is http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (s *S3StateStore) Load(ctx context.Context) (GeneratorState, bool, error) {
+	var st GeneratorState
+	resp, err := s.do(ctx, http.MethodGet, nil)
+	if err != nil {
+		return st, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return st, false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return st, false, fmt.Errorf("s3://%s/%s: %v", s.Bucket, s.Key, err)
+	}
+	return st, true, nil
+}
+
+func (s *S3StateStore) Save(ctx context.Context, st GeneratorState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for the object, returning an error for
+// any status but 200 and, so that Load can tell, 404.
+func (s *S3StateStore) do(ctx context.Context, method string, body []byte) (*http.Response, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + s.Key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	signV4(req, body, "s3", s.Region, s.AccessKeyID, s.SecretAccessKey, s.SessionToken, clockNow())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s s3://%s/%s: %s: %s", method, s.Bucket, s.Key, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+// signV4 signs req, with body, by AWS Signature Version 4, as of now,
+// adding the headers it needs.  Every header req has is signed, along
+// with the host.
+func signV4(req *http.Request, body []byte, service, region, accessKeyID, secret, sessionToken string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		vs := query[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			params = append(params, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if p, err := url.PathUnescape(path); err == nil {
+		path = awsEscape(p, false)
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	// The signing key is derived from the secret by a chain of MACs,
+	// and the last link signs toSign.
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, service, "aws4_request", toSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+// awsEscape percent encodes s as Signature Version 4 wants: everything
+// but letters, digits and -._~, and / too if escapeSlash is set.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
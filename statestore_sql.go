@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteStateStore keeps generators' states in a table of a SQLite
+// database, a row for each name, so that generators sharing a database
+// file on a volume each keep their own.  It only uses database/sql,
+// so works with whichever SQLite driver the program imports, as this
+// one imports none.
+type SQLiteStateStore struct {
+	db   *sql.DB
+	name string
+}
+
+// NewSQLiteStateStore returns a store for the generator called name,
+// creating the table if it isn't there.
+func NewSQLiteStateStore(ctx context.Context, db *sql.DB, name string) (*SQLiteStateStore, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS uuid_generator_state (
+		name TEXT PRIMARY KEY,
+		time_ns INTEGER NOT NULL,
+		clock_sequence INTEGER NOT NULL,
+		node BLOB NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStateStore{db: db, name: name}, nil
+}
+
+func (s *SQLiteStateStore) Load(ctx context.Context) (GeneratorState, bool, error) {
+	var (
+		st   GeneratorState
+		ns   int64
+		seq  int64
+		node []byte
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT time_ns, clock_sequence, node FROM uuid_generator_state WHERE name = ?`, s.name).Scan(&ns, &seq, &node)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, false, nil
+	}
+	if err != nil {
+		return st, false, err
+	}
+	if len(node) != len(st.Node) {
+		return st, false, fmt.Errorf("generator state %s: node is %d bytes", s.name, len(node))
+	}
+	st.Time, st.ClockSequence = time.Unix(0, ns).UTC(), uint16(seq)
+	copy(st.Node[:], node)
+	return st, true, nil
+}
+
+func (s *SQLiteStateStore) Save(ctx context.Context, st GeneratorState) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO uuid_generator_state (name, time_ns, clock_sequence, node) VALUES (?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET time_ns = excluded.time_ns, clock_sequence = excluded.clock_sequence, node = excluded.node`,
+		s.name, st.Time.UnixNano(), int64(st.ClockSequence), st.Node[:])
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import "sync/atomic"
+
+// GeneratorStats describes what a generator has been up to since it
+// was made.  Fields that don't apply to a generator are zero.
+type GeneratorStats struct {
+	// Generated is how many IDs have been handed out.
+	Generated uint64 `json:"generated"`
+	// ClockSeqIncrements is how many times a V1 generator bumped its
+	// clock sequence, because the clock hadn't moved on or had gone
+	// backwards.
+	ClockSeqIncrements uint64 `json:"clock_seq_increments"`
+	// Rollbacks is how many times the clock went backwards.
+	Rollbacks uint64 `json:"rollbacks"`
+	// Borrowed is how many times a generator ran out of room in the
+	// current tick, or saw the clock go backwards, and used a later
+	// tick than the clock said.
+	Borrowed uint64 `json:"borrowed"`
+	// Restamped is how many UUIDs a channel generator threw away for
+	// having sat in its buffer too long.
+	Restamped uint64 `json:"restamped"`
+	// Refills is how many times a channel generator's producer woke to
+	// top up its buffer.
+	Refills uint64 `json:"refills"`
+	// Strategy is the strategy an AutoGenerator is using.
+	Strategy Strategy `json:"strategy"`
+	// ChanLen and ChanCap are how many UUIDs are waiting in a channel
+	// generator's buffer, and how many it can hold.
+	ChanLen int `json:"chan_len"`
+	ChanCap int `json:"chan_cap"`
+}
+
+// statsReporter is implemented by the generators that keep stats.
+type statsReporter interface {
+	Stats() GeneratorStats
+}
+
+// generatorCounters are the counts behind GeneratorStats.  They are
+// atomic, so that Stats can be called from any goroutine, including
+// while a producer goroutine is updating them.
+type generatorCounters struct {
+	generated          atomic.Uint64
+	clockSeqIncrements atomic.Uint64
+	rollbacks          atomic.Uint64
+	borrowed           atomic.Uint64
+	restamped          atomic.Uint64
+	refills            atomic.Uint64
+}
+
+func (c *generatorCounters) stats() GeneratorStats {
+	return GeneratorStats{
+		Generated:          c.generated.Load(),
+		ClockSeqIncrements: c.clockSeqIncrements.Load(),
+		Rollbacks:          c.rollbacks.Load(),
+		Borrowed:           c.borrowed.Load(),
+		Restamped:          c.restamped.Load(),
+		Refills:            c.refills.Load(),
+	}
+}
+
+// countV1Tick records a V1 generator's clock reading against the last
+// one, returning whether the clock sequence needs bumping.
+func (c *generatorCounters) countV1Tick(timeNow, lastTime uint64) bool {
+	if timeNow < lastTime {
+		c.rollbacks.Add(1)
+	}
+	if timeNow <= lastTime {
+		c.clockSeqIncrements.Add(1)
+		return true
+	}
+	return false
+}
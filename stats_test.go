@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSatoriStats(t *testing.T) {
+	// Steps back 5 ticks every 10 calls, so each step back costs a
+	// bump for the step itself and for the 5 repeated ticks after it.
+	g := newSatoriGenerator(newSkewedClock(10, 5).epoch)
+	for i := 0; i < 100; i++ {
+		g.New()
+	}
+	s := g.Stats()
+	if s.Generated != 100 || s.Rollbacks != 10 || s.ClockSeqIncrements < s.Rollbacks {
+		t.Errorf("%+v", s)
+	}
+}
+
+func TestChanneledStats(t *testing.T) {
+	g := NewChanneledGenerator(16)
+	for i := 0; i < 5; i++ {
+		g.New()
+	}
+	s := g.Stats()
+	if s.Generated != 5 || s.ChanCap != 16 || s.ChanLen > 16 {
+		t.Errorf("%+v", s)
+	}
+}
+
+func TestV7Stats(t *testing.T) {
+	start := time.Now()
+	g := newV7Generator(func() time.Time { return start }, WithV7Method(V7Counter))
+	for i := 0; i < 5000; i++ {
+		g.New()
+	}
+	// At most 4096 fit in the first millisecond.
+	if s := g.Stats(); s.Generated != 5000 || s.Borrowed < 5000-4096 || s.Rollbacks != 0 {
+		t.Errorf("%+v", s)
+	}
+}
+
+func TestBenchRunStats(t *testing.T) {
+	r := benchRun(NewSatoriGenerator(), 1, 10*time.Millisecond)
+	if r.Stats == nil || r.Stats.Generated != uint64(r.Ops) {
+		t.Errorf("stats %+v for %d ops", r.Stats, r.Ops)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// An Encoder writes a stream of UUIDs, either in canonical form one
+// per line, or as raw 16 byte values with nothing in between.  It
+// buffers its output, so call Flush when done.
+type Encoder struct {
+	w      *bufio.Writer
+	binary bool
+	buf    [37]byte
+}
+
+// NewEncoder returns an Encoder writing canonical UUIDs, one per
+// line, to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriterSize(w, 64*1024)}
+}
+
+// SetBinary switches the Encoder to writing raw 16 byte UUIDs.
+func (e *Encoder) SetBinary(binary bool) {
+	e.binary = binary
+}
+
+// Encode writes u.  It does not allocate.
+func (e *Encoder) Encode(u UUID) error {
+	// Everything goes through e.buf, because passing u[:] to the
+	// writer would make u escape to the heap.
+	if e.binary {
+		copy(e.buf[:], u[:])
+		_, err := e.w.Write(e.buf[:16])
+		return err
+	}
+	encodeCanonical(e.buf[:], u)
+	e.buf[36] = '\n'
+	_, err := e.w.Write(e.buf[:])
+	return err
+}
+
+// Flush writes any buffered UUIDs.
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+// A Decoder reads a stream of UUIDs written by an Encoder.  In text
+// mode it also skips blank lines, and surrounding white space and
+// carriage returns.
+type Decoder struct {
+	r      *bufio.Reader
+	binary bool
+	line   int
+	buf    [16]byte
+}
+
+// NewDecoder returns a Decoder reading canonical UUIDs, one per line,
+// from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// SetBinary switches the Decoder to reading raw 16 byte UUIDs.
+func (d *Decoder) SetBinary(binary bool) {
+	d.binary = binary
+}
+
+// Decode returns the next UUID, or io.EOF when there are no more.  It
+// does not allocate unless it returns an error.
+func (d *Decoder) Decode() (UUID, error) {
+	u := UUID{}
+	if d.binary {
+		// Read into d.buf, because passing u[:] to the reader would
+		// make u escape to the heap.
+		if _, err := io.ReadFull(d.r, d.buf[:]); err != nil {
+			return u, err
+		}
+		return UUID(d.buf), nil
+	}
+
+	for {
+		line, err := d.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			d.line++
+			return u, fmt.Errorf("line %d: too long", d.line)
+		}
+		if len(line) == 0 && err != nil {
+			return u, err
+		}
+		d.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err != nil {
+				return u, err
+			}
+			continue
+		}
+		u, offset, expected := parse(line)
+		if offset >= 0 {
+			return UUID{}, fmt.Errorf("line %d: %w", d.line, &ParseError{string(line), offset, expected})
+		}
+		// A final line without a newline is fine; io.EOF will come
+		// back on the next call.
+		return u, nil
+	}
+}
+
+// uuidBytes views ids as their packed 16 byte representations, without
+// copying.  UUID is a [16]byte, so a []UUID is already laid out that
+// way.
+func uuidBytes(ids []UUID) []byte {
+	if len(ids) == 0 {
+		return nil
+	}
+	return unsafe.Slice(&ids[0][0], len(ids)*16)
+}
+
+// WriteAll writes ids to w as raw 16 byte UUIDs, in one Write.  It's
+// the same as an Encoder in binary mode, but without the copying, for
+// snapshotting large sets of IDs.
+func WriteAll(w io.Writer, ids []UUID) error {
+	_, err := w.Write(uuidBytes(ids))
+	return err
+}
+
+// ReadAll reads n raw 16 byte UUIDs from r, with a single ReadFull.
+// If r runs out first, it returns the whole UUIDs it did read, along
+// with io.EOF if there were none, or io.ErrUnexpectedEOF if there were
+// some, or a partial one.
+func ReadAll(r io.Reader, n int) ([]UUID, error) {
+	ids := make([]UUID, n)
+	got, err := io.ReadFull(r, uuidBytes(ids))
+	return ids[:got/16], err
+}
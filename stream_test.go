@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	ids := []UUID{NewV1(), NewV4(), NewV7(), {}}
+	for _, binary := range []bool{false, true} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetBinary(binary)
+		for _, u := range ids {
+			if err := enc.Encode(u); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if binary && buf.Len() != 16*len(ids) {
+			t.Errorf("binary encoding is %d bytes, want %d", buf.Len(), 16*len(ids))
+		}
+
+		dec := NewDecoder(&buf)
+		dec.SetBinary(binary)
+		for _, want := range ids {
+			got, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("binary=%v: %v", binary, err)
+			}
+			if got != want {
+				t.Errorf("binary=%v: got %s, want %s", binary, got, want)
+			}
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("binary=%v: got %v at the end, want io.EOF", binary, err)
+		}
+	}
+}
+
+func TestDecoderText(t *testing.T) {
+	u := NewV4()
+	in := "\n  " + u.String() + "\r\n\n" + u.String()
+	dec := NewDecoder(strings.NewReader(in))
+	for i := 0; i < 2; i++ {
+		if got, err := dec.Decode(); err != nil || got != u {
+			t.Fatalf("%d: got %s, %v", i, got, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("got %v at the end, want io.EOF", err)
+	}
+
+	dec = NewDecoder(strings.NewReader(u.String() + "\nnot a uuid\n"))
+	dec.Decode()
+	if _, err := dec.Decode(); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("got %v, want a line 2 error", err)
+	}
+}
+
+func TestEncoderDecoderAllocs(t *testing.T) {
+	u := NewV4()
+	enc := NewEncoder(io.Discard)
+	if n := testing.AllocsPerRun(1000, func() { enc.Encode(u) }); n != 0 {
+		t.Errorf("Encode allocates %v times", n)
+	}
+
+	text := strings.Repeat(u.String()+"\n", 1001)
+	dec := NewDecoder(strings.NewReader(text))
+	if n := testing.AllocsPerRun(1000, func() { dec.Decode() }); n != 0 {
+		t.Errorf("Decode allocates %v times", n)
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	u := NewV4()
+	enc := NewEncoder(io.Discard)
+	for n := 0; n < b.N; n++ {
+		enc.Encode(u)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	text := strings.Repeat(NewV4().String()+"\n", b.N)
+	dec := NewDecoder(strings.NewReader(text))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		dec.Decode()
+	}
+}
+
+func TestWriteAllReadAll(t *testing.T) {
+	ids := []UUID{NewV4(), NewV4(), NewV4()}
+	var buf bytes.Buffer
+	if err := WriteAll(&buf, ids); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 48 || !bytes.Equal(buf.Bytes()[16:32], ids[1][:]) {
+		t.Fatalf("wrote % x", buf.Bytes())
+	}
+
+	got, err := ReadAll(bytes.NewReader(buf.Bytes()), 3)
+	if err != nil || len(got) != 3 || got[2] != ids[2] {
+		t.Errorf("got %v, %v", got, err)
+	}
+
+	// Asking for more than there are.
+	got, err = ReadAll(bytes.NewReader(buf.Bytes()[:40]), 3)
+	if err != io.ErrUnexpectedEOF || len(got) != 2 || got[1] != ids[1] {
+		t.Errorf("short read got %v, %v", got, err)
+	}
+	if got, err := ReadAll(bytes.NewReader(nil), 3); err != io.EOF || len(got) != 0 {
+		t.Errorf("empty read got %v, %v", got, err)
+	}
+	if err := WriteAll(&buf, nil); err != nil {
+		t.Error(err)
+	}
+}
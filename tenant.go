@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// tenantConfig is a tenant's entry in a tenants file.
+type tenantConfig struct {
+	// Node is the tenant's V1 node ID, as parseNode takes it, except
+	// that it can't be empty: this machine's is the server's own.
+	Node string `json:"node"`
+	// Namespace is what /tenant/ns derives names in: dns, url, oid,
+	// x500 or a UUID.  It defaults to the V5 of the tenant's name in
+	// the URL namespace, as urn:uuidgen:tenant:name.
+	Namespace string `json:"namespace,omitempty"`
+	// Strategy is the tenant's V1 strategy, satori if not given.  It
+	// can't be one that shares package level state, mutex or lockfree.
+	Strategy Strategy `json:"strategy,omitempty"`
+}
+
+// tenant is a client with a generator of its own, so that its UUIDs
+// have its node ID, and its clock sequence is its own, and a namespace
+// of its own for name based UUIDs.
+type tenant struct {
+	node      [6]byte
+	namespace UUID
+	strategy  Strategy
+	// store keeps g's state, if there is a state directory.
+	store StateStore
+	g     Generator
+
+	issued  atomic.Uint64
+	derived atomic.Uint64
+}
+
+// tenantStats is what /tenant/stats, and /stats for each tenant,
+// report.
+type tenantStats struct {
+	Node      string `json:"node"`
+	Namespace string `json:"namespace"`
+	// Issued counts /tenant/uuid's UUIDs, and Derived /tenant/ns's.
+	Issued    uint64          `json:"issued"`
+	Derived   uint64          `json:"derived"`
+	Generator *GeneratorStats `json:"generator,omitempty"`
+}
+
+func (t *tenant) stats() tenantStats {
+	ts := tenantStats{
+		Node:      net.HardwareAddr(t.node[:]).String(),
+		Namespace: t.namespace.String(),
+		Issued:    t.issued.Load(),
+		Derived:   t.derived.Load(),
+	}
+	if sr, ok := t.g.(statsReporter); ok {
+		gs := sr.Stats()
+		ts.Generator = &gs
+	}
+	return ts
+}
+
+// loadTenants reads a tenants file: a JSON object from client names,
+// as in the API keys file, to their tenantConfigs.  No two tenants may
+// share a node ID, nor share the server's, or their UUIDs could
+// collide.  If store isn't nil, it returns the store each tenant's
+// generator keeps its state in.
+func loadTenants(name string, store func(tenant string) StateStore) (map[string]*tenant, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var cfgs map[string]tenantConfig
+	if err := json.Unmarshal(b, &cfgs); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("%s: no tenants", name)
+	}
+
+	tenants := make(map[string]*tenant, len(cfgs))
+	nodes := map[[6]byte]string{hardwareAddr: "the server"}
+	var errs []error
+	for _, client := range sortedKeys(cfgs) {
+		var ts StateStore
+		if store != nil {
+			ts = store(client)
+		}
+		t, err := newTenant(client, cfgs[client], ts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: tenant %s: %v", name, client, err))
+			continue
+		}
+		if other, ok := nodes[t.node]; ok {
+			errs = append(errs, fmt.Errorf("%s: tenant %s has the node ID of %s", name, client, other))
+		}
+		nodes[t.node] = "tenant " + client
+		tenants[client] = t
+	}
+	if err := errors.Join(errs...); err != nil {
+		closeTenants(tenants)
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func newTenant(name string, cfg tenantConfig, store StateStore) (*tenant, error) {
+	if cfg.Node == "" {
+		return nil, errors.New("no node")
+	}
+	node, err := parseNode(cfg.Node)
+	if err != nil {
+		return nil, err
+	}
+	t := &tenant{node: *node, strategy: cfg.Strategy, store: store}
+
+	switch ns, ok := namespaces[cfg.Namespace]; {
+	case ok:
+		t.namespace = ns
+	case cfg.Namespace == "":
+		t.namespace = NewV5(NamespaceURL, "urn:uuidgen:tenant:"+name)
+	default:
+		if t.namespace, err = Parse(cfg.Namespace); err != nil {
+			return nil, fmt.Errorf("namespace must be dns, url, oid, x500 or a UUID: %v", err)
+		}
+	}
+
+	if t.strategy == "" {
+		t.strategy = StrategySatori
+	}
+	if t.g, err = t.newGenerator(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *tenant) newGenerator() (Generator, error) {
+	opts := []GeneratorOption{WithNodeID(t.node)}
+	if t.store != nil {
+		opts = append(opts, WithStateStore(t.store))
+	}
+	return NewGenerator(t.strategy, opts...)
+}
+
+// restarted returns a copy of t with a new generator, restored from
+// t's store, and none of t's counts: carryCounts brings them over once
+// t is done with.
+func (t *tenant) restarted() (*tenant, error) {
+	nt := &tenant{node: t.node, namespace: t.namespace, strategy: t.strategy, store: t.store}
+	var err error
+	if nt.g, err = nt.newGenerator(); err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
+
+// restartTenants returns tenants with their generators restarted, or
+// if one of them can't be, closes those that were.
+func restartTenants(tenants map[string]*tenant) (map[string]*tenant, error) {
+	restarted := make(map[string]*tenant, len(tenants))
+	for name, t := range tenants {
+		nt, err := t.restarted()
+		if err != nil {
+			closeTenants(restarted)
+			return nil, fmt.Errorf("tenant %s: %v", name, err)
+		}
+		restarted[name] = nt
+	}
+	return restarted, nil
+}
+
+// carryCounts adds old's counts to t's.
+func (t *tenant) carryCounts(old *tenant) {
+	t.issued.Add(old.issued.Load())
+	t.derived.Add(old.derived.Load())
+}
+
+// tenantStore returns the store for tenant's generator's state in the
+// state directory, nil if there isn't one.  With an ha_lease, it is a
+// haStateStore.
+func (s *server) tenantStore(tenant string) StateStore {
+	if s.stateDir == "" {
+		return nil
+	}
+	store := FileStateStore{Path: filepath.Join(s.stateDir, tenantStateFile(tenant))}
+	if s.lease == nil {
+		return store
+	}
+	return haStateStore{FileStateStore: store, s: s}
+}
+
+// checkTenants checks that each tenant is a client with an API key,
+// as otherwise its entry would do nothing, most likely because of a
+// typo.
+func checkTenants(tenants map[string]*tenant, keys apiKeys) error {
+	clients := map[string]bool{}
+	for _, name := range keys {
+		clients[name] = true
+	}
+	var errs []error
+	for _, name := range sortedKeys(tenants) {
+		if !clients[name] {
+			errs = append(errs, fmt.Errorf("tenant %s has no API key", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeTenants stops the tenants' generators, if they need stopping.
+func closeTenants(tenants map[string]*tenant) {
+	for _, t := range tenants {
+		if c, ok := t.g.(interface{ Close() }); ok {
+			c.Close()
+		}
+	}
+}
+
+// tenantStatsOf returns the stats of every tenant, for /stats.
+func tenantStatsOf(tenants map[string]*tenant) map[string]tenantStats {
+	if len(tenants) == 0 {
+		return nil
+	}
+	stats := make(map[string]tenantStats, len(tenants))
+	for name, t := range tenants {
+		stats[name] = t.stats()
+	}
+	return stats
+}
+
+// tenantOf returns the tenant of r's client in st, or writes an error
+// response and returns nil.
+func tenantOf(st *serverState, w http.ResponseWriter, r *http.Request) *tenant {
+	name, _ := r.Context().Value(clientNameKey{}).(string)
+	t := st.tenants[name]
+	if t == nil {
+		http.Error(w, "not a tenant", http.StatusForbidden)
+	}
+	return t
+}
+
+// handleTenantUUID serves n V1 UUIDs from the client's tenant's
+// generator, like /uuid.
+func (s *server) handleTenantUUID(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+	t := tenantOf(st, w, r)
+	if t == nil {
+		return
+	}
+	n, ok := s.batchSize(w, r, st)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	info := requestInfoFrom(r.Context())
+	e := NewEncoder(w)
+	for i := 0; i < n; i++ {
+		u := s.nextFrom(t.g, 1, string(t.strategy), info)
+		t.issued.Add(1)
+		if err := e.Encode(u); err != nil {
+			return
+		}
+	}
+	e.Flush()
+}
+
+// handleTenantNS serves the V5, or with version=3 the V3, of name in
+// the client's tenant's namespace.
+func (s *server) handleTenantNS(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+	t := tenantOf(st, w, r)
+	if t == nil {
+		return
+	}
+	name := r.FormValue("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	derive := NewV5
+	switch r.FormValue("version") {
+	case "", "5":
+	case "3":
+		derive = NewV3
+	default:
+		http.Error(w, "version must be 3 or 5", http.StatusBadRequest)
+		return
+	}
+	t.derived.Add(1)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, derive(t.namespace, name))
+}
+
+// handleTenantStats serves the client's tenant's stats.
+func (s *server) handleTenantStats(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+	t := tenantOf(st, w, r)
+	if t == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.stats())
+}
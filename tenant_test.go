@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTenants(t *testing.T) {
+	dir := t.TempDir()
+	keysFile := filepath.Join(dir, "keys")
+	os.WriteFile(keysFile, []byte("alice 0123456789abcdef\nbob fedcba9876543210\ncarol 0000111122223333\n"), 0o600)
+	tenantsFile := filepath.Join(dir, "tenants.json")
+	os.WriteFile(tenantsFile, []byte(`{
+		"alice": {"node": "02:00:00:00:00:0a", "namespace": "dns"},
+		"bob": {"node": "02:00:00:00:00:0b", "strategy": "atomic"}
+	}`), 0o600)
+	ts := newTestServer(t, serverConfig{APIKeysFile: keysFile, TenantsFile: tenantsFile})
+
+	get := func(path, key string, want int) string {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("X-API-Key", key)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s as %s: status %d, want %d: %s", path, key, resp.StatusCode, want, body)
+		}
+		return string(body)
+	}
+
+	const alice, bob, carol = "0123456789abcdef", "fedcba9876543210", "0000111122223333"
+	for key, node := range map[string]string{alice: "02:00:00:00:00:0a", bob: "02:00:00:00:00:0b"} {
+		lines := strings.Fields(get("/tenant/uuid?n=3", key, http.StatusOK))
+		if len(lines) != 3 {
+			t.Fatalf("got %q", lines)
+		}
+		for _, line := range lines {
+			u, err := Parse(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := u.Node(); u.Version() != 1 || net.HardwareAddr(n[:]).String() != node {
+				t.Errorf("%s: version %d, node %x, want %s", u, u.Version(), n, node)
+			}
+		}
+	}
+	get("/tenant/uuid", carol, http.StatusForbidden)
+
+	if got, want := get("/tenant/ns?name=www.example.com", alice, http.StatusOK), NewV5(NamespaceDNS, "www.example.com").String()+"\n"; got != want {
+		t.Errorf("alice's V5: got %q, want %q", got, want)
+	}
+	if got, want := get("/tenant/ns?name=x&version=3", bob, http.StatusOK), NewV3(NewV5(NamespaceURL, "urn:uuidgen:tenant:bob"), "x").String()+"\n"; got != want {
+		t.Errorf("bob's V3: got %q, want %q", got, want)
+	}
+	get("/tenant/ns", bob, http.StatusBadRequest)
+
+	var st tenantStats
+	if err := json.Unmarshal([]byte(get("/tenant/stats", bob, http.StatusOK)), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Issued != 3 || st.Derived != 1 || st.Node != "02:00:00:00:00:0b" || st.Generator == nil || st.Generator.Generated != 3 {
+		t.Errorf("bob's stats: %+v", st)
+	}
+	var all serverStats
+	if err := json.Unmarshal([]byte(get("/stats", carol, http.StatusOK)), &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Tenants) != 2 || all.Tenants["alice"].Derived != 1 {
+		t.Errorf("stats: %+v", all.Tenants)
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tenants.json")
+	for _, bad := range []string{
+		`{}`,
+		`{"a": {}}`,
+		`{"a": {"node": "random", "strategy": "mutex"}}`,
+		`{"a": {"node": "random", "namespace": "nope"}}`,
+		`{"a": {"node": "02:00:00:00:00:01"}, "b": {"node": "02:00:00:00:00:01"}}`,
+	} {
+		os.WriteFile(file, []byte(bad), 0o600)
+		if tenants, err := loadTenants(file, nil); err == nil {
+			closeTenants(tenants)
+			t.Errorf("%s accepted", bad)
+		}
+	}
+}
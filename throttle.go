@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Throttled is a Generator that makes UUIDs no faster than a given
+// rate, for load generators and anything else that should produce IDs
+// at a steady pace rather than as fast as it can.  It is a token bucket:
+// a token a UUID, refilled at the rate, saving up to a burst of them.
+// Callers that find the bucket empty queue, each reserving the next
+// token, so however many goroutines share a Throttled, together they
+// get the rate.  It is safe for concurrent use.
+type Throttled struct {
+	g Generator
+
+	mu      sync.Mutex
+	bucket  *tokenBucket
+	nowFunc func() time.Time
+}
+
+// Throttle returns a Generator making g's UUIDs at perSecond a second.
+// Its burst is 10ms worth, or 1 if that is less, which is enough to
+// keep the rate precise however late sleeps wake; SetBurst changes it.
+func Throttle(g Generator, perSecond int) *Throttled {
+	return newThrottle(g, float64(perSecond), time.Now)
+}
+
+// newThrottle lets gen ask for fractions of a UUID a second, and tests
+// inject a fake clock.  It panics if rate isn't positive.
+func newThrottle(g Generator, rate float64, nowFunc func() time.Time) *Throttled {
+	if rate <= 0 {
+		panic("Throttle needs a positive rate")
+	}
+	burst := math.Max(1, math.Floor(rate/100))
+	return &Throttled{g: g, bucket: newTokenBucket(rate, burst, nowFunc()), nowFunc: nowFunc}
+}
+
+// SetBurst lets t make up to n UUIDs at once after being idle, such as
+// for a load generator meant to be bursty.  The rate over time stays
+// the same.
+func (t *Throttled) SetBurst(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bucket.refill(t.nowFunc())
+	t.bucket.burst = math.Max(1, float64(n))
+	t.bucket.tokens = math.Min(t.bucket.tokens, t.bucket.burst)
+}
+
+// Wait blocks until the rate allows another UUID, or ctx is done, in
+// which case it returns ctx's error and gives its turn back.  Next and
+// New call it; it is there on its own for rate limiting IDs of other
+// kinds the same way.
+func (t *Throttled) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	wait := t.bucket.reserve(t.nowFunc())
+	t.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.bucket.tokens = math.Min(t.bucket.burst, t.bucket.tokens+1)
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Next returns a new UUID once the rate allows, or ctx's error if it
+// is done first.
+func (t *Throttled) Next(ctx context.Context) (UUID, error) {
+	if err := t.Wait(ctx); err != nil {
+		return UUID{}, err
+	}
+	return t.g.New(), nil
+}
+
+// New returns a new UUID once the rate allows, however long that is.
+func (t *Throttled) New() UUID {
+	u, _ := t.Next(context.Background())
+	return u
+}
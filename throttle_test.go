@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	th := newThrottle(GeneratorFunc(NewV4), 1000, func() time.Time { return now })
+
+	// The burst of 10 is free, then each UUID is owed a millisecond
+	// later than the one before.
+	for i := 0; i < 10; i++ {
+		if wait := th.bucket.reserve(now); wait != 0 {
+			t.Fatalf("UUID %d of the burst waits %s", i, wait)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		if wait := th.bucket.reserve(now); wait != time.Duration(i)*time.Millisecond {
+			t.Errorf("UUID %d past the burst waits %s", i, wait)
+		}
+	}
+
+	// A cancelled wait gives its turn back.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := th.Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Next with a cancelled context: %v", err)
+	}
+	if wait := th.bucket.reserve(now); wait != 4*time.Millisecond {
+		t.Errorf("after a cancel, next waits %s, want 4ms", wait)
+	}
+
+	th.SetBurst(100)
+	now = now.Add(time.Second)
+	for i := 0; i < 100; i++ {
+		if wait := th.bucket.reserve(now); wait != 0 {
+			t.Fatalf("UUID %d of the new burst waits %s", i, wait)
+		}
+	}
+}
+
+func TestThrottleRate(t *testing.T) {
+	// 2000 a second shared by 4 goroutines: 400 UUIDs, less the burst of
+	// 20, take at least 190ms.
+	th := Throttle(GeneratorFunc(NewV4), 2000)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				th.New()
+			}
+		}()
+	}
+	wg.Wait()
+	if took := time.Since(start); took < 190*time.Millisecond || took > 2*time.Second {
+		t.Errorf("400 UUIDs at 2000/s took %s", took)
+	}
+}
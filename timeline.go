@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// maxBuckets keeps a tiny bucket size over a long time span from
+// producing an enormous histogram.
+const maxBuckets = 1000000
+
+// histogram counts times into buckets of width d.  The result has an
+// entry for every bucket between the earliest and latest time, so
+// that gaps show up as zeros.
+func histogram(times []time.Time, d time.Duration) ([]time.Time, []int, error) {
+	if len(times) == 0 {
+		return nil, nil, nil
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	first := times[0].Truncate(d)
+	last := times[len(times)-1].Truncate(d)
+	n := int64(last.Sub(first)/d) + 1
+	if n > maxBuckets {
+		return nil, nil, fmt.Errorf("%d buckets of %s is too many, use a larger -bucket", n, d)
+	}
+
+	starts := make([]time.Time, n)
+	for i := range starts {
+		starts[i] = first.Add(time.Duration(i) * d)
+	}
+	counts := make([]int, n)
+	for _, t := range times {
+		counts[t.Truncate(d).Sub(first)/d]++
+	}
+	return starts, counts, nil
+}
+
+func runTimeline(args []string) error {
+	fs := flag.NewFlagSet("timeline", flag.ExitOnError)
+	bucket := fs.Duration("bucket", time.Minute, "histogram bucket width")
+	format := fs.String("format", "csv", "output format: csv or gnuplot")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen timeline [flags] [file ...]")
+		fmt.Fprintln(fs.Output(), "Reads UUIDs from the files, or stdin, and prints a histogram of their embedded times.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *bucket <= 0 {
+		return errors.New("-bucket must be positive")
+	}
+	if *format != "csv" && *format != "gnuplot" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var times []time.Time
+	invalid, untimed := 0, 0
+	err := forEachLine(fs.Args(), func(line string) error {
+		u, err := Parse(line)
+		if err != nil {
+			invalid++
+			return nil
+		}
+		t, ok := u.Time()
+		if !ok {
+			untimed++
+			return nil
+		}
+		times = append(times, t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if invalid > 0 || untimed > 0 {
+		slog.Warn("skipped UUIDs", "invalid", invalid, "untimed", untimed)
+	}
+
+	starts, counts, err := histogram(times, *bucket)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if *format == "csv" {
+		fmt.Fprintln(w, "start,count")
+		for i, t := range starts {
+			fmt.Fprintf(w, "%s,%d\n", t.UTC().Format(time.RFC3339Nano), counts[i])
+		}
+	} else {
+		fmt.Fprintf(w, "# plot '-' using 1:2 with boxes, bucket width %s\n", *bucket)
+		fmt.Fprintln(w, "# unix_seconds count")
+		for i, t := range starts {
+			fmt.Fprintf(w, "%.3f %d\n", float64(t.UnixMilli())/1000, counts[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Token sizes, in bits.
+const (
+	MinTokenBits     = 128
+	MaxTokenBits     = 256
+	DefaultTokenBits = 128
+)
+
+// Token is an unguessable random value, for when all that's needed is
+// a secret, unique ID and the structure of a UUID doesn't matter.  All
+// of its bits are random, where a V4 UUID loses 6 to the version and
+// variant.
+//
+// The text form is unpadded base64url, which is what MarshalText,
+// the SQL driver value and String use.
+type Token []byte
+
+// NewToken returns a Token with the given number of random bits,
+// which must be a multiple of 8 between MinTokenBits and MaxTokenBits.
+func NewToken(bits int) (Token, error) {
+	if bits < MinTokenBits || bits > MaxTokenBits || bits%8 != 0 {
+		return nil, fmt.Errorf("token size %d bits is not a multiple of 8 between %d and %d", bits, MinTokenBits, MaxTokenBits)
+	}
+	t := make(Token, bits/8)
+	safeRandom(t)
+	return t, nil
+}
+
+// ParseToken parses the base64url form of a Token.
+func ParseToken(s string) (Token, error) {
+	return checkToken(base64.RawURLEncoding.DecodeString(s))
+}
+
+// ParseTokenHex parses the hex form of a Token.
+func ParseTokenHex(s string) (Token, error) {
+	return checkToken(hex.DecodeString(s))
+}
+
+func checkToken(b []byte, err error) (Token, error) {
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	if len(b)*8 < MinTokenBits || len(b)*8 > MaxTokenBits {
+		return nil, fmt.Errorf("invalid token: %d bits", len(b)*8)
+	}
+	return Token(b), nil
+}
+
+// String returns the base64url form of t.
+func (t Token) String() string {
+	return base64.RawURLEncoding.EncodeToString(t)
+}
+
+// Hex returns the hex form of t.
+func (t Token) Hex() string {
+	return hex.EncodeToString(t)
+}
+
+// Equal reports whether t and other are the same, in constant time so
+// as not to leak the token through timing.
+func (t Token) Equal(other Token) bool {
+	return subtle.ConstantTimeCompare(t, other) == 1
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Token) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Token) UnmarshalText(b []byte) error {
+	tok, err := ParseToken(string(b))
+	if err != nil {
+		return err
+	}
+	*t = tok
+	return nil
+}
+
+// Value implements driver.Valuer, storing t as text.
+func (t Token) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// Scan implements sql.Scanner, reading t from text.
+func (t *Token) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return t.UnmarshalText([]byte(src))
+	case []byte:
+		return t.UnmarshalText(src)
+	default:
+		return fmt.Errorf("cannot scan %T into a Token", src)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestNewToken(t *testing.T) {
+	for _, bits := range []int{128, 192, 256} {
+		tok, err := NewToken(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tok)*8 != bits {
+			t.Errorf("%d bit token is %d bytes", bits, len(tok))
+		}
+		for s, parse := range map[string]func(string) (Token, error){
+			tok.String(): ParseToken,
+			tok.Hex():    ParseTokenHex,
+		} {
+			got, err := parse(s)
+			if err != nil || !got.Equal(tok) {
+				t.Errorf("parse(%s) = %s, %v", s, got, err)
+			}
+		}
+	}
+	for _, bits := range []int{0, 120, 130, 264} {
+		if _, err := NewToken(bits); err == nil {
+			t.Errorf("%d bit token allowed", bits)
+		}
+	}
+}
+
+func TestTokenEqual(t *testing.T) {
+	a, _ := NewToken(128)
+	b := append(Token(nil), a...)
+	if !a.Equal(b) {
+		t.Errorf("%s != %s", a, b)
+	}
+	b[0] ^= 1
+	if a.Equal(b) {
+		t.Errorf("%s == %s", a, b)
+	}
+	if a.Equal(a[:15]) {
+		t.Errorf("token equal to its prefix")
+	}
+}
+
+func TestTokenJSONAndSQL(t *testing.T) {
+	tok, _ := NewToken(256)
+	b, err := json.Marshal(map[string]Token{"t": tok})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]Token
+	if err := json.Unmarshal(b, &m); err != nil || !m["t"].Equal(tok) {
+		t.Errorf("JSON round trip gave %v, %v", m, err)
+	}
+
+	v, _ := tok.Value()
+	var scanned Token
+	if err := scanned.Scan(v); err != nil || !scanned.Equal(tok) {
+		t.Errorf("SQL round trip gave %s, %v", scanned, err)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Errorf("scanned an int")
+	}
+}
+
+func BenchmarkNewToken(b *testing.B) {
+	for _, bits := range []int{128, 256} {
+		b.Run(fmt.Sprintf("bits=%d", bits), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				NewToken(bits)
+			}
+		})
+	}
+}
+
+func BenchmarkNewV4(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		NewV4()
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// traceContext is a W3C Trace Context, which says which trace a request
+// belongs to, and which span in it made the request.
+type traceContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	flags   byte
+}
+
+// parseTraceparent parses a W3C traceparent header, such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.  Later
+// versions may add fields, which are ignored.
+func parseTraceparent(s string) (tc traceContext, ok bool) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return tc, false
+	}
+	var flags [1]byte
+	for _, f := range []struct {
+		dst []byte
+		hex string
+	}{
+		{tc.traceID[:], parts[1]},
+		{tc.spanID[:], parts[2]},
+		{flags[:], parts[3]},
+	} {
+		if len(f.hex) != 2*len(f.dst) || strings.ToLower(f.hex) != f.hex {
+			return tc, false
+		}
+		if _, err := hex.Decode(f.dst, []byte(f.hex)); err != nil {
+			return tc, false
+		}
+	}
+	if tc.traceID == [16]byte{} || tc.spanID == [8]byte{} {
+		return tc, false
+	}
+	tc.flags = flags[0]
+	return tc, true
+}
+
+// child returns the context of a new span in the same trace as tc, or
+// a new trace if tc is the zero value.
+func (tc traceContext) child() traceContext {
+	c := traceContext{traceID: tc.traceID, flags: tc.flags}
+	if c.traceID == [16]byte{} {
+		safeRandom(c.traceID[:])
+	}
+	safeRandom(c.spanID[:])
+	return c
+}
+
+// String formats tc as a traceparent header.
+func (tc traceContext) String() string {
+	return "00-" + hex.EncodeToString(tc.traceID[:]) + "-" + hex.EncodeToString(tc.spanID[:]) + "-" + hex.EncodeToString([]byte{tc.flags})
+}
+
+// requestInfo is what handlers note about a request, for its log
+// line, which stands in for a span: its trace, the batch size, and
+// how long the generator kept it waiting.
+type requestInfo struct {
+	trace, parent traceContext
+	n             int
+	// genWait is only measured when timed is set, since that takes two
+	// clock readings a UUID.
+	timed   bool
+	genWait time.Duration
+}
+
+type requestInfoKey struct{}
+
+// requestInfoFrom returns the requestInfo in ctx, or nil.
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
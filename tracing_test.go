@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const good = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tc, ok := parseTraceparent(good)
+	if !ok || tc.String() != good {
+		t.Fatalf("got %v, %v", tc, ok)
+	}
+	child := tc.child()
+	if child.traceID != tc.traceID || child.spanID == tc.spanID || child.flags != 1 {
+		t.Errorf("child %v of %v", child, tc)
+	}
+	if _, ok := parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"); !ok {
+		t.Errorf("later version with more fields refused")
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bx-01",
+	} {
+		if tc, ok := parseTraceparent(bad); ok {
+			t.Errorf("%q parsed as %v", bad, tc)
+		}
+	}
+}
+
+func TestServeTraceLogging(t *testing.T) {
+	var buf strings.Builder
+	logger, err := newLogger(&buf, "json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+	defer logLevel.Set(logLevel.Level())
+	logLevel.Set(slog.LevelDebug)
+
+	ts := newTestServer(t, serverConfig{})
+	req, _ := http.NewRequest("GET", ts.URL+"/uuid?n=3", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if rec["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || rec["parent_span_id"] != "00f067aa0ba902b7" ||
+		rec["span_id"] == nil || rec["span_id"] == "00f067aa0ba902b7" {
+		t.Errorf("trace: %v", rec)
+	}
+	if rec["batch"] != float64(3) || rec["gen_wait"] == nil {
+		t.Errorf("batch %v, gen_wait %v", rec["batch"], rec["gen_wait"])
+	}
+}
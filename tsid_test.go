@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTSIDGenerator(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range []Strategy{StrategyMutex, StrategyChannel, StrategyAtomic} {
+		g, err := newTSIDGenerator(42, DefaultTSIDNodeBits, s, func() time.Time { return start })
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Milliseconds since 2020, node 42, then a counter that starts
+		// somewhere in the bottom half of its 12 bits and counts up.
+		first := g.Next()
+		counter := int64(first) & (1<<12 - 1)
+		if counter >= 1<<11 {
+			t.Errorf("%s: first counter %d", s, counter)
+		}
+		for i, id := range []TSID{first, g.Next(), g.Next()} {
+			if want := TSID((start.UnixMilli()-tsidEpoch)<<22 | 42<<12 | (counter + int64(i))); id != want {
+				t.Errorf("%s: ID %s, want %s", s, id, want)
+			}
+			if ts, _ := id.Time(); !ts.Equal(start) || id.Node(DefaultTSIDNodeBits) != 42 {
+				t.Errorf("%s: %s has time %s, node %d", s, id, ts, id.Node(DefaultTSIDNodeBits))
+			}
+		}
+		g.Close()
+	}
+
+	for _, bad := range []struct {
+		node     int64
+		nodeBits int
+		s        Strategy
+	}{
+		{1024, 10, StrategyMutex},
+		{0, 21, StrategyMutex},
+		{0, 10, StrategySatori},
+	} {
+		if _, err := NewTSIDGenerator(bad.node, bad.nodeBits, bad.s); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+}
+
+func TestTSIDConcurrent(t *testing.T) {
+	for _, s := range []Strategy{StrategyMutex, StrategyChannel, StrategyAtomic} {
+		g, err := NewTSIDGenerator(3, 4, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			mu   sync.Mutex
+			seen = map[TSID]bool{}
+			wg   sync.WaitGroup
+		)
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ids := make([]TSID, 10000)
+				for i := range ids {
+					ids[i] = g.Next()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, id := range ids {
+					if seen[id] {
+						t.Errorf("%s: %s twice", s, id)
+					}
+					seen[id] = true
+				}
+			}()
+		}
+		wg.Wait()
+		g.Close()
+	}
+}
+
+func TestParseTSID(t *testing.T) {
+	when := time.Date(2022, time.November, 1, 12, 0, 0, 0, time.UTC)
+	want := TSID((when.UnixMilli()-tsidEpoch)<<22 | 5<<12 | 17)
+	s := want.String()
+	if len(s) != 13 {
+		t.Fatalf("%q is not 13 characters", s)
+	}
+	id, err := ParseTSID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != want {
+		t.Errorf("round trip gave %d, want %d", id, want)
+	}
+	if ts, _ := id.Time(); !ts.Equal(when) {
+		t.Errorf("time %s, want %s", ts, when)
+	}
+	if id.Node(10) != 5 {
+		t.Errorf("node %d, want 5", id.Node(10))
+	}
+	// A later one sorts after it as a string too.
+	if later := TSID(int64(want) + 1<<22).String(); later <= s {
+		t.Errorf("%s sorts before %s", later, s)
+	}
+
+	for _, s := range []string{"", "0123456789ABU", "G000000000000", "00000000000000"} {
+		if _, err := ParseTSID(s); err == nil {
+			t.Errorf("ParseTSID(%q) should fail", s)
+		}
+	}
+}
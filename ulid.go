@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// A ULID is a 48 bit Unix time in milliseconds followed by 80 random
+// bits, written as 26 characters of Crockford base32.  See
+// https://github.com/ulid/spec.  It has the same layout as a V7 UUID,
+// without the version and variant bits.
+type ULID [16]byte
+
+// NewULID returns a ULID for the current time.
+func NewULID() ULID {
+	id := ULID{}
+	safeRandom(id[6:])
+	ms := uint64(clockNow().UnixMilli())
+	binary.BigEndian.PutUint16(id[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:], uint32(ms))
+	return id
+}
+
+// ParseULID parses the 26 character form of a ULID, ignoring case.
+func ParseULID(s string) (ULID, error) {
+	u, err := decodeULID(s)
+	return ULID(u), err
+}
+
+func (id ULID) String() string {
+	return encodeULID(UUID(id))
+}
+
+// Bytes returns a copy of id's 16 bytes.
+func (id ULID) Bytes() []byte {
+	b := id
+	return b[:]
+}
+
+// Time returns the time id was made, to the millisecond.  ok is always
+// true.
+func (id ULID) Time() (t time.Time, ok bool) {
+	ms := uint64(binary.BigEndian.Uint16(id[0:]))<<32 | uint64(binary.BigEndian.Uint32(id[2:]))
+	return time.UnixMilli(int64(ms)), true
+}
+
+func init() {
+	RegisterFamily("ulid", Family{
+		New: func() ID { return NewULID() },
+		Parse: func(s string) (ID, error) {
+			return ParseULID(s)
+		},
+	})
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"crypto/rand"
+	"crypto/subtle"
 	"encoding/binary"
 	"encoding/hex"
-	"fmt"
+	"io"
 	"net"
 	"sync"
-	"time"
+	"sync/atomic"
 )
 
 // Stealing just enough of uuid v1 code from
@@ -22,14 +22,72 @@ var (
 	hardwareAddr  [6]byte
 )
 
+// sharedEpochFunc is the clock of NewV1, NewV6 and NewV1LockFree, so
+// that tests can swap in a fake one: with storageMutex held for
+// NewV1, and with the lock-free producer stopped for NewV1LockFree.
+var sharedEpochFunc = unixTimeFunc
+
 func init() {
-	initStorage(&clockSequence, hardwareAddr)
+	initStorage(&clockSequence, &hardwareAddr)
 }
 
+// Lock-free UUID v1 storage.  Only produceLockFreeUUIDs touches
+// these, which is what makes it safe to skip the lock.  They must not
+// be shared with the mutex-guarded storage above.
+var (
+	lockFreeClockSequence uint16
+	lockFreeLastTime      uint64
+)
+
 var ch = make(chan UUID, 10)
 
 func init() {
-	go produceLockFreeUUIDs()
+	lockFreeClockSequence = initClockSequence()
+}
+
+// The lock-free producer goroutine only runs between StartLockFree, or
+// the first NewV1LockFree, and StopLockFree, so that importers that
+// never use it don't get a goroutine they didn't ask for.
+var (
+	lockFreeMu      sync.Mutex
+	lockFreeRunning atomic.Bool
+	lockFreeStop    chan struct{}
+	lockFreeDone    chan struct{}
+	// lockFreeStateReq asks the producer for its state.
+	lockFreeStateReq = make(chan chan v1Last)
+)
+
+// StartLockFree starts the goroutine behind NewV1LockFree, if it isn't
+// running already.  NewV1LockFree starts it too, but starting it
+// ahead of time keeps the first call quick.
+func StartLockFree() {
+	lockFreeMu.Lock()
+	defer lockFreeMu.Unlock()
+	if lockFreeRunning.Load() {
+		return
+	}
+	lockFreeStop = make(chan struct{})
+	lockFreeDone = make(chan struct{})
+	go produceLockFreeUUIDs(lockFreeStop, lockFreeDone)
+	lockFreeRunning.Store(true)
+}
+
+// StopLockFree stops the goroutine behind NewV1LockFree and throws
+// away the UUIDs it had ready, waiting until it has exited.  Nothing
+// may be blocked in NewV1LockFree when it is called, since nothing
+// would be left to wake it.  A later NewV1LockFree starts it again.
+func StopLockFree() {
+	lockFreeMu.Lock()
+	defer lockFreeMu.Unlock()
+	if !lockFreeRunning.Load() {
+		return
+	}
+	close(lockFreeStop)
+	<-lockFreeDone
+	for len(ch) > 0 {
+		<-ch
+	}
+	lockFreeRunning.Store(false)
 }
 
 // Difference in 100-nanosecond intervals between
@@ -45,32 +103,103 @@ func initClockSequence() uint16 {
 	return binary.BigEndian.Uint16(buf)
 }
 
-func initHardwareAddr(addr [6]byte) {
+// nodeOverride, if set, is the node ID to use instead of a MAC address.
+var nodeOverride *[6]byte
+
+// setNodeID makes V1 and V6 UUIDs use node rather than a MAC address,
+// as do generators made after it.  It must be called before making any
+// UUIDs, since the lock-free producer reads the node without a lock.
+func setNodeID(node [6]byte) {
+	storageMutex.Lock()
+	defer storageMutex.Unlock()
+	nodeOverride = &node
+	hardwareAddr = node
+}
+
+// nextClockSequence, if set, is the clock sequence the next V1
+// generator with state of its own starts with, instead of a random
+// one.
+var nextClockSequence *uint16
+
+// setClockSequence makes the V1 generator of strategy start with the
+// clock sequence seq, so that a server can carry on with the one it
+// had before a restart, or before it was moved to another machine.
+// The mutex and lockfree strategies, which share package level state,
+// take it straight away, mutex's for NewV6 too; any other takes the
+// next generator made.  Like setNodeID, it must be called before the
+// generator makes any UUIDs.
+func setClockSequence(strategy string, seq uint16) {
+	storageMutex.Lock()
+	defer storageMutex.Unlock()
+	switch strategy {
+	case "mutex":
+		clockSequence = seq
+	case "lockfree":
+		lockFreeClockSequence = seq
+	default:
+		nextClockSequence = &seq
+	}
+}
+
+func initHardwareAddr(addr *[6]byte) {
+	if nodeOverride != nil {
+		*addr = *nodeOverride
+		return
+	}
 	interfaces, err := net.Interfaces()
 	if err == nil {
 		for _, iface := range interfaces {
 			if len(iface.HardwareAddr) >= 6 {
-				copy(hardwareAddr[:], iface.HardwareAddr)
+				copy(addr[:], iface.HardwareAddr)
 				return
 			}
 		}
 	}
 
-	// Initialize hardwareAddr randomly in case
+	// Initialize addr randomly in case
 	// of real network interfaces absence
-	safeRandom(hardwareAddr[:])
+	safeRandom(addr[:])
 
 	// Set multicast bit as recommended in RFC 4122
-	hardwareAddr[0] |= 0x01
+	addr[0] |= 0x01
 }
 
-func initStorage(seq *uint16, addr [6]byte) {
+func initStorage(seq *uint16, addr *[6]byte) {
 	*seq = initClockSequence()
 	initHardwareAddr(addr)
 }
 
+// v1Start is what a V1 generator with its own state starts from.
+type v1Start struct {
+	epochFunc     func() uint64
+	clockSequence uint16
+	hardwareAddr  [6]byte
+}
+
+// newV1Start returns a v1Start with a random clock sequence, unless
+// setClockSequence has given it one, and this machine's node ID.
+func newV1Start(epochFunc func() uint64) v1Start {
+	st := v1Start{epochFunc: epochFunc}
+	initStorage(&st.clockSequence, &st.hardwareAddr)
+	storageMutex.Lock()
+	if nextClockSequence != nil {
+		st.clockSequence = *nextClockSequence
+		nextClockSequence = nil
+	}
+	storageMutex.Unlock()
+	return st
+}
+
+// fallbackRandom, if set, is where safeRandom turns when crypto/rand
+// fails.
+var fallbackRandom func([]byte) error
+
 func safeRandom(dest []byte) {
-	if _, err := rand.Read(dest); err != nil {
+	_, err := io.ReadFull(entropySource, dest)
+	if err != nil && fallbackRandom != nil {
+		err = fallbackRandom(dest)
+	}
+	if err != nil {
 		panic(err)
 	}
 }
@@ -78,15 +207,15 @@ func safeRandom(dest []byte) {
 // Returns UUID v1/v2 storage state.
 // Returns epoch timestamp, clock sequence, and hardware address.
 func getStorageLockFree() (uint64, uint16, []byte) {
-	timeNow := unixTimeFunc()
+	timeNow := sharedEpochFunc()
 	// Clock changed backwards since last UUID generation.
 	// Should increase clock sequence.
-	if timeNow <= lastTime {
-		clockSequence++
+	if timeNow <= lockFreeLastTime {
+		lockFreeClockSequence++
 	}
-	lastTime = timeNow
+	lockFreeLastTime = timeNow
 
-	return timeNow, clockSequence, hardwareAddr[:]
+	return timeNow, lockFreeClockSequence, hardwareAddr[:]
 }
 
 // Returns UUID v1/v2 storage state.
@@ -95,7 +224,7 @@ func getStorage() (uint64, uint16, []byte) {
 	storageMutex.Lock()
 	defer storageMutex.Unlock()
 
-	timeNow := unixTimeFunc()
+	timeNow := sharedEpochFunc()
 	// Clock changed backwards since last UUID generation.
 	// Should increase clock sequence.
 	if timeNow <= lastTime {
@@ -106,6 +235,21 @@ func getStorage() (uint64, uint16, []byte) {
 	return timeNow, clockSequence, hardwareAddr[:]
 }
 
+// Generator is implemented by each of the strategies for generating
+// UUIDs, so that they can be swapped for each other.
+type Generator interface {
+	New() UUID
+}
+
+// GeneratorFunc adapts an ordinary function, such as NewV1 or a
+// generator's NewV1 method, to a Generator.
+type GeneratorFunc func() UUID
+
+// New returns f().
+func (f GeneratorFunc) New() UUID {
+	return f()
+}
+
 // SatoriGenerator knows how to generate V1 UUIDs in the same way that
 // it is done here:
 // https://github.com/satori/go.uuid/blob/master/uuid.go
@@ -114,12 +258,32 @@ type SatoriGenerator struct {
 	clockSequence uint16
 	lastTime      uint64
 	hardwareAddr  [6]byte
+	epochFunc     func() uint64
+	counters      generatorCounters
 }
 
 func NewSatoriGenerator() *SatoriGenerator {
-	gen := SatoriGenerator{}
-	initStorage(&gen.clockSequence, gen.hardwareAddr)
-	return &gen
+	return newSatoriGenerator(unixTimeFunc)
+}
+
+var _ = registerExperiment(experiment{
+	Name:        "satori",
+	Description: "satori/go.uuid's generator: a mutex around its own state",
+	New:         func(int) Generator { return NewSatoriGenerator() },
+})
+
+// newSatoriGenerator lets tests inject a fake clock.  epochFunc is
+// only ever called with the storage lock held.
+func newSatoriGenerator(epochFunc func() uint64) *SatoriGenerator {
+	return satoriGeneratorFrom(newV1Start(epochFunc))
+}
+
+func satoriGeneratorFrom(st v1Start) *SatoriGenerator {
+	return &SatoriGenerator{
+		epochFunc:     st.epochFunc,
+		clockSequence: st.clockSequence,
+		hardwareAddr:  st.hardwareAddr,
+	}
 }
 
 // Returns UUID v1/v2 storage state.
@@ -128,15 +292,15 @@ func (g *SatoriGenerator) getStorage() (uint64, uint16, []byte) {
 	g.storageMutex.Lock()
 	defer g.storageMutex.Unlock()
 
-	timeNow := unixTimeFunc()
+	timeNow := g.epochFunc()
 	// Clock changed backwards since last UUID generation.
 	// Should increase clock sequence.
-	if timeNow <= lastTime {
+	if g.counters.countV1Tick(timeNow, g.lastTime) {
 		g.clockSequence++
 	}
 	g.lastTime = timeNow
 
-	return timeNow, clockSequence, hardwareAddr[:]
+	return timeNow, g.clockSequence, g.hardwareAddr[:]
 }
 
 // NewV1 returns UUID based on current timestamp and MAC address.
@@ -155,23 +319,106 @@ func (g *SatoriGenerator) NewV1() UUID {
 	u.SetVersion(1)
 	u.SetVariant()
 
+	g.counters.generated.Add(1)
 	return u
 }
 
+// Fill fills dst with V1 UUIDs, taking the lock once rather than once
+// per UUID.
+func (g *SatoriGenerator) Fill(dst []UUID) {
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+
+	for i := range dst {
+		timeNow := g.epochFunc()
+		if g.counters.countV1Tick(timeNow, g.lastTime) {
+			g.clockSequence++
+		}
+		g.lastTime = timeNow
+
+		u := &dst[i]
+		binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
+		binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+		binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+		binary.BigEndian.PutUint16(u[8:], g.clockSequence)
+
+		copy(u[10:], g.hardwareAddr[:])
+
+		u.SetVersion(1)
+		u.SetVariant()
+	}
+	g.counters.generated.Add(uint64(len(dst)))
+}
+
+// New is NewV1, so that SatoriGenerator is a Generator.
+func (g *SatoriGenerator) New() UUID {
+	return g.NewV1()
+}
+
+func (g *SatoriGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+	return g.lastTime, g.clockSequence, g.hardwareAddr, true
+}
+
+// Stats reports on g.  It can be called at any time.
+func (g *SatoriGenerator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
+
 // ChannelGenerator follows the same general outline as
 // Satorigenerator, but instead of locking, it uses a goroutine which
-// communicates over a channel
+// communicates over a channel.  The goroutine fills the channel in
+// bursts: it sleeps until readers have taken it down to a low
+// watermark, a quarter full, then tops it up, rather than waking for
+// every UUID taken.
 type ChanneledGenerator struct {
-	ch            chan UUID
+	ch   chan UUID
+	stop chan struct{}
+	// refill wakes the producer.  It holds one wake-up, so readers
+	// never wait to send one.
+	refill chan struct{}
+	// lowWater is how few UUIDs may be left before a reader wakes
+	// the producer.
+	lowWater int
+	// stateReq asks the producer for its latest timestamp and clock
+	// sequence, for v1State.
+	stateReq      chan chan v1Last
 	clockSequence uint16
 	lastTime      uint64
 	hardwareAddr  [6]byte
+	epochFunc     func() uint64
+	counters      generatorCounters
 }
 
 func NewChanneledGenerator(chanSize int) *ChanneledGenerator {
-	gen := ChanneledGenerator{}
-	gen.ch = make(chan UUID, chanSize)
-	initStorage(&gen.clockSequence, gen.hardwareAddr)
+	return newChanneledGenerator(chanSize, unixTimeFunc)
+}
+
+var _ = registerExperiment(experiment{
+	Name:        "channel",
+	Description: "one goroutine generating into a channel, with no locks",
+	Params:      []string{"chansize"},
+	New:         func(chanSize int) Generator { return NewChanneledGenerator(chanSize) },
+})
+
+// newChanneledGenerator lets tests inject a fake clock.  epochFunc is
+// only ever called from the producer goroutine.
+func newChanneledGenerator(chanSize int, epochFunc func() uint64) *ChanneledGenerator {
+	return channeledGeneratorFrom(chanSize, newV1Start(epochFunc))
+}
+
+func channeledGeneratorFrom(chanSize int, st v1Start) *ChanneledGenerator {
+	gen := ChanneledGenerator{
+		ch:            make(chan UUID, chanSize),
+		stop:          make(chan struct{}),
+		refill:        make(chan struct{}, 1),
+		lowWater:      chanSize / 4,
+		stateReq:      make(chan chan v1Last),
+		epochFunc:     st.epochFunc,
+		clockSequence: st.clockSequence,
+		hardwareAddr:  st.hardwareAddr,
+	}
 	go gen.produceUUIDs()
 	return &gen
 }
@@ -179,40 +426,127 @@ func NewChanneledGenerator(chanSize int) *ChanneledGenerator {
 // Returns UUID v1/v2 storage state.
 // Returns epoch timestamp, clock sequence, and hardware address.
 func (g *ChanneledGenerator) getStorage() (uint64, uint16, []byte) {
-	timeNow := unixTimeFunc()
+	timeNow := g.epochFunc()
 	// Clock changed backwards since last UUID generation.
 	// Should increase clock sequence.
-	if timeNow <= lastTime {
+	if g.counters.countV1Tick(timeNow, g.lastTime) {
 		g.clockSequence++
 	}
 	g.lastTime = timeNow
 
-	return timeNow, clockSequence, hardwareAddr[:]
+	return timeNow, g.clockSequence, g.hardwareAddr[:]
 }
 
-// NewV1 returns UUID based on current timestamp and MAC address.
+// produceUUIDs runs until Close, feeding UUIDs into g.ch.  It is the
+// only goroutine that touches g's storage, so no locking is needed.
 func (g *ChanneledGenerator) produceUUIDs() {
+	if cap(g.ch) == 0 {
+		// There is no buffer to fill, so hand UUIDs over one at a time.
+		u := g.makeUUID()
+		for {
+			select {
+			case g.ch <- u:
+				u = g.makeUUID()
+			case r := <-g.stateReq:
+				r <- v1Last{g.lastTime, g.clockSequence}
+			case <-g.stop:
+				return
+			}
+		}
+	}
 	for {
-		u := UUID{}
+		g.counters.refills.Add(1)
+		// Nothing else sends on g.ch, so while it has room, sending
+		// can't block.
+		for len(g.ch) < cap(g.ch) {
+			g.ch <- g.makeUUID()
+		}
+		if !g.waitForRefill() {
+			return
+		}
+	}
+}
 
-		timeNow, clockSeq, hardwareAddr := g.getStorage()
+// waitForRefill sleeps until a reader wakes the producer, answering
+// v1State meanwhile, and returns false if g has been closed instead.
+func (g *ChanneledGenerator) waitForRefill() bool {
+	for {
+		select {
+		case <-g.refill:
+			return true
+		case r := <-g.stateReq:
+			r <- v1Last{g.lastTime, g.clockSequence}
+		case <-g.stop:
+			return false
+		}
+	}
+}
 
-		binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
-		binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
-		binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
-		binary.BigEndian.PutUint16(u[8:], clockSeq)
+func (g *ChanneledGenerator) makeUUID() UUID {
+	u := UUID{}
 
-		copy(u[10:], hardwareAddr)
+	timeNow, clockSeq, hardwareAddr := g.getStorage()
 
-		u.SetVersion(1)
-		u.SetVariant()
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+	binary.BigEndian.PutUint16(u[8:], clockSeq)
 
-		ch <- u
-	}
+	copy(u[10:], hardwareAddr)
+
+	u.SetVersion(1)
+	u.SetVariant()
+
+	return u
+}
+
+// Close stops g's producer goroutine.  Nothing may call NewV1 after
+// Close, which would wait forever once the channel is empty.
+func (g *ChanneledGenerator) Close() {
+	close(g.stop)
 }
 
 func (g *ChanneledGenerator) NewV1() UUID {
-	return <-ch
+	g.counters.generated.Add(1)
+	u := <-g.ch
+	// Checking after taking one, not before, means whoever takes the
+	// channel down to the watermark is the one who sees it, so the
+	// producer can't sleep through readers waiting on an empty
+	// channel.
+	if len(g.ch) <= g.lowWater {
+		select {
+		case g.refill <- struct{}{}:
+		default:
+		}
+	}
+	return u
+}
+
+// New is NewV1, so that ChanneledGenerator is a Generator.
+func (g *ChanneledGenerator) New() UUID {
+	return g.NewV1()
+}
+
+// v1State asks the producer, the only goroutine that touches g's
+// state, for it, so that it includes the UUIDs in the channel.  ok is
+// false once g has been closed.
+func (g *ChanneledGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	r := make(chan v1Last, 1)
+	select {
+	case g.stateReq <- r:
+		l := <-r
+		return l.ticks, l.seq, g.hardwareAddr, true
+	case <-g.stop:
+		return 0, 0, g.hardwareAddr, false
+	}
+}
+
+// Stats reports on g, including how full its channel is.  It can be
+// called at any time.
+func (g *ChanneledGenerator) Stats() GeneratorStats {
+	s := g.counters.stats()
+	s.ChanLen, s.ChanCap = len(g.ch), cap(g.ch)
+	return s
 }
 
 // UUID representation compliant with specification
@@ -238,13 +572,67 @@ func NewV1() UUID {
 	return u
 }
 
+var _ = registerExperiment(experiment{
+	Name:        "mutex",
+	Description: "NewV1: a mutex around package level state",
+	New:         func(int) Generator { return mutexGenerator{} },
+})
+
+// mutexGenerator is NewV1 as a Generator that can report the package
+// level state it shares with NewV6.
+type mutexGenerator struct{}
+
+func (mutexGenerator) New() UUID {
+	return NewV1()
+}
+
+func (mutexGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	storageMutex.Lock()
+	defer storageMutex.Unlock()
+	return lastTime, clockSequence, hardwareAddr, true
+}
+
 // NewV1LockFree returns UUID based on current timestamp and MAC
 // address, without taking any locks.
 func NewV1LockFree() UUID {
+	if !lockFreeRunning.Load() {
+		StartLockFree()
+	}
 	return <-ch
 }
 
-func produceLockFreeUUIDs() {
+var _ = registerExperiment(experiment{
+	Name:        "lockfree",
+	Description: "NewV1LockFree: a package level goroutine generating into a channel",
+	New:         func(int) Generator { return lockFreeGenerator{} },
+})
+
+// lockFreeGenerator is NewV1LockFree as a Generator that can report
+// the package level state behind it.
+type lockFreeGenerator struct{}
+
+func (lockFreeGenerator) New() UUID {
+	return NewV1LockFree()
+}
+
+// v1State asks the producer for the state, if it is running, since
+// only it may touch it then, so that it includes the UUIDs in ch.
+func (lockFreeGenerator) v1State() (uint64, uint16, [6]byte, bool) {
+	lockFreeMu.Lock()
+	defer lockFreeMu.Unlock()
+	if !lockFreeRunning.Load() {
+		return lockFreeLastTime, lockFreeClockSequence, hardwareAddr, true
+	}
+	r := make(chan v1Last, 1)
+	lockFreeStateReq <- r
+	l := <-r
+	return l.ticks, l.seq, hardwareAddr, true
+}
+
+// produceLockFreeUUIDs feeds ch until stop is closed, then closes
+// done, answering lockFreeGenerator.v1State meanwhile.
+func produceLockFreeUUIDs(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
 	for {
 		u := UUID{}
 
@@ -260,7 +648,17 @@ func produceLockFreeUUIDs() {
 		u.SetVersion(1)
 		u.SetVariant()
 
-		ch <- u
+	send:
+		for {
+			select {
+			case ch <- u:
+				break send
+			case r := <-lockFreeStateReq:
+				r <- v1Last{lockFreeLastTime, lockFreeClockSequence}
+			case <-stop:
+				return
+			}
+		}
 	}
 }
 
@@ -269,6 +667,33 @@ func (u *UUID) SetVersion(v byte) {
 	u[6] = (u[6] & 0x0f) | (v << 4)
 }
 
+// Version returns the version bits.
+func (u UUID) Version() byte {
+	return u[6] >> 4
+}
+
+// UUID layout variants.
+const (
+	VariantNCS = iota
+	VariantRFC4122
+	VariantMicrosoft
+	VariantFuture
+)
+
+// Variant returns the UUID layout variant.
+func (u UUID) Variant() int {
+	switch {
+	case u[8]&0x80 == 0x00:
+		return VariantNCS
+	case u[8]&0xc0 == 0x80:
+		return VariantRFC4122
+	case u[8]&0xe0 == 0xc0:
+		return VariantMicrosoft
+	default:
+		return VariantFuture
+	}
+}
+
 // SetVariant sets variant bits as described in RFC 4122.
 func (u *UUID) SetVariant() {
 	u[8] = (u[8] & 0xbf) | 0x80
@@ -278,7 +703,14 @@ func (u *UUID) SetVariant() {
 // xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
 func (u UUID) String() string {
 	buf := make([]byte, 36)
+	encodeCanonical(buf, u)
+
+	return string(buf)
+}
 
+// encodeCanonical writes the canonical string representation of u to
+// the first 36 bytes of buf.
+func encodeCanonical(buf []byte, u UUID) {
 	hex.Encode(buf[0:8], u[0:4])
 	buf[8] = dash
 	hex.Encode(buf[9:13], u[4:6])
@@ -288,21 +720,24 @@ func (u UUID) String() string {
 	hex.Encode(buf[19:23], u[8:10])
 	buf[23] = dash
 	hex.Encode(buf[24:], u[10:])
+}
 
-	return string(buf)
+// EqualConstantTime reports whether a and b are equal, taking the same
+// time whether they are or not.
+//
+// a == b stops at the first byte that differs, so by timing many
+// guesses an attacker can learn a secret UUID a byte at a time.  That
+// doesn't matter for ordinary IDs, but use this when a UUID is a
+// secret in its own right, such as a bearer token, session ID or
+// password reset link, and you are comparing it against untrusted
+// input.  Only random (V4) UUIDs are fit for that in the first place.
+func EqualConstantTime(a, b UUID) bool {
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
 }
 
 // Returns difference in 100-nanosecond intervals between
 // UUID epoch (October 15, 1582) and current time.
 // This is default epoch calculation function.
 func unixTimeFunc() uint64 {
-	return epochStart + uint64(time.Now().UnixNano()/100)
-}
-
-func main() {
-	fmt.Printf("V1: %s\n", NewV1())
-	fmt.Printf("V1: %s\n", NewV1())
-	fmt.Println()
-	fmt.Printf("V1 lock free: %s\n", NewV1LockFree())
-	fmt.Printf("V1 lock free: %s\n", NewV1LockFree())
+	return epochStart + uint64(clockNow().UnixNano()/100)
 }
@@ -34,11 +34,15 @@ Below are the raw results from running the benchmarks on my desktop:
 
 */
 
+//go:generate go run genresults.go
+
 package main
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 )
 
 func BenchmarkNewV1(b *testing.B) {
@@ -69,8 +73,92 @@ func BenchmarkChanneledNewV1(b *testing.B) {
 
 }
 
+// TestChanneledRefill has many readers take a small channel down past
+// its watermark at once, which hangs if the producer ever sleeps
+// through a wake-up.
+func TestChanneledRefill(t *testing.T) {
+	g := NewChanneledGenerator(8)
+	defer g.Close()
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for w := 0; w < 16; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					g.NewV1()
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("readers stuck waiting on the producer")
+	}
+
+	// A lone reader wakes the producer far less than once a UUID, as
+	// it did before the watermark.
+	g = NewChanneledGenerator(8)
+	defer g.Close()
+	for i := 0; i < 10000; i++ {
+		g.NewV1()
+	}
+	if s := g.Stats(); s.Refills > s.Generated/2 {
+		t.Errorf("%d refills for %d UUIDs", s.Refills, s.Generated)
+	}
+}
+
 func BenchmarkNewV1LockFree(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		NewV1LockFree()
 	}
 }
+
+func TestEqualConstantTime(t *testing.T) {
+	a := NewV4()
+	b := a
+	if !EqualConstantTime(a, b) {
+		t.Errorf("%s != %s", a, b)
+	}
+	b[15] ^= 1
+	if EqualConstantTime(a, b) {
+		t.Errorf("%s == %s", a, b)
+	}
+}
+
+func TestLockFreeStartStop(t *testing.T) {
+	StopLockFree()
+	if lockFreeRunning.Load() {
+		t.Fatal("still running after StopLockFree")
+	}
+	// Stopping twice is fine.
+	StopLockFree()
+
+	// NewV1LockFree starts the producer again.
+	a, b := NewV1LockFree(), NewV1LockFree()
+	if !lockFreeRunning.Load() {
+		t.Fatal("NewV1LockFree didn't start the producer")
+	}
+	if a == b {
+		t.Errorf("got %s twice", a)
+	}
+
+	done := lockFreeDone
+	StopLockFree()
+	select {
+	case <-done:
+	default:
+		t.Error("producer still running after StopLockFree")
+	}
+	if len(ch) != 0 {
+		t.Errorf("%d UUIDs left in the channel", len(ch))
+	}
+	StartLockFree()
+	if c := NewV1LockFree(); c == a || c == b {
+		t.Errorf("got %s again after restarting", c)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// A UUIDMap maps UUIDs to values of type V, like a map[UUID]V, but
+// with a hash made for 16 byte keys: one 64 bit multiply of the two
+// halves, rather than the runtime's general purpose memory hash.  It
+// is an open addressing table with linear probing.  The hash is
+// seeded per map, so that keys chosen to collide in one map, as
+// anyone can choose UUIDs, don't collide in another.  The zero value
+// is an empty map ready to use.  It is not safe for concurrent use.
+type UUIDMap[V any] struct {
+	slots []uuidMapSlot[V]
+	n     int
+	seed  [2]uint64
+}
+
+type uuidMapSlot[V any] struct {
+	key  UUID
+	val  V
+	used bool
+}
+
+// NewUUIDMap returns a map with room for n keys before it grows.
+func NewUUIDMap[V any](n int) *UUIDMap[V] {
+	m := &UUIDMap[V]{}
+	m.init(n)
+	return m
+}
+
+func (m *UUIDMap[V]) init(n int) {
+	var buf [16]byte
+	safeRandom(buf[:])
+	m.seed = [2]uint64{binary.LittleEndian.Uint64(buf[:8]), binary.LittleEndian.Uint64(buf[8:])}
+	size := 8
+	// Keep the table at most 3/4 full.
+	for size*3/4 < n {
+		size *= 2
+	}
+	m.slots = make([]uuidMapSlot[V], size)
+}
+
+// hash mixes both halves of u with the seed, as wyhash does: the high
+// and low halves of the 128 bit product, xored together.
+func (m *UUIDMap[V]) hash(u UUID) uint64 {
+	hi, lo := bits.Mul64(binary.LittleEndian.Uint64(u[:8])^m.seed[0], binary.LittleEndian.Uint64(u[8:])^m.seed[1])
+	return hi ^ lo
+}
+
+// find returns the slot for u: the one holding it if it is there, or
+// else the empty slot where it would go.
+func (m *UUIDMap[V]) find(u UUID) int {
+	mask := len(m.slots) - 1
+	i := int(m.hash(u)) & mask
+	for m.slots[i].used && m.slots[i].key != u {
+		i = (i + 1) & mask
+	}
+	return i
+}
+
+// Get returns the value for u, and whether there was one.
+func (m *UUIDMap[V]) Get(u UUID) (v V, ok bool) {
+	if m.n == 0 {
+		return v, false
+	}
+	s := &m.slots[m.find(u)]
+	return s.val, s.used
+}
+
+// Set sets the value for u.
+func (m *UUIDMap[V]) Set(u UUID, v V) {
+	if m.slots == nil {
+		m.init(0)
+	}
+	i := m.find(u)
+	if m.slots[i].used {
+		m.slots[i].val = v
+		return
+	}
+	if (m.n+1)*4 > len(m.slots)*3 {
+		m.grow()
+		i = m.find(u)
+	}
+	m.slots[i] = uuidMapSlot[V]{key: u, val: v, used: true}
+	m.n++
+}
+
+func (m *UUIDMap[V]) grow() {
+	old := m.slots
+	m.slots = make([]uuidMapSlot[V], 2*len(old))
+	for _, s := range old {
+		if s.used {
+			m.slots[m.find(s.key)] = s
+		}
+	}
+}
+
+// Delete removes u, if it is there.
+func (m *UUIDMap[V]) Delete(u UUID) {
+	if m.n == 0 {
+		return
+	}
+	i := m.find(u)
+	if !m.slots[i].used {
+		return
+	}
+	// Shift later keys of the same run back into the gap, rather than
+	// leaving a tombstone, so that lookups never probe further than
+	// they would have had the key never been set.
+	mask := len(m.slots) - 1
+	for j := (i + 1) & mask; m.slots[j].used; j = (j + 1) & mask {
+		home := int(m.hash(m.slots[j].key)) & mask
+		// The key at j can fill the gap at i unless its home slot is
+		// cyclically after i, up to j.
+		if (j-home)&mask >= (j-i)&mask {
+			m.slots[i] = m.slots[j]
+			i = j
+		}
+	}
+	m.slots[i] = uuidMapSlot[V]{}
+	m.n--
+}
+
+// Len returns the number of keys in m.
+func (m *UUIDMap[V]) Len() int {
+	return m.n
+}
+
+// Range calls f for each key and value in m, in no particular order,
+// until f returns false.  f must not change m.
+func (m *UUIDMap[V]) Range(f func(u UUID, v V) bool) {
+	for _, s := range m.slots {
+		if s.used && !f(s.key, s.val) {
+			return
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/**
+
+UUID keyed maps
+
+ID keyed caches are where most of our IDs end up, so it is worth
+knowing what a lookup costs.  Three ways to key on a UUID: a
+map[UUID]T, a map[string]T of the canonical form, which is what you
+get from code that passes IDs around as strings, and UUIDMap, with its
+hash made for 16 byte keys.
+
+Lookups of V4 and V7 keys, all present, in maps of 1,000,000, on a
+1 CPU Xeon VM:
+
+BenchmarkUUIDMapGet/v4/map-uuid      140.9 ns/op
+BenchmarkUUIDMapGet/v4/map-string    158.1 ns/op
+BenchmarkUUIDMapGet/v4/uuidmap        83.2 ns/op
+BenchmarkUUIDMapGet/v7/map-uuid      113.2 ns/op
+BenchmarkUUIDMapGet/v7/map-string    136.3 ns/op
+BenchmarkUUIDMapGet/v7/uuidmap        88.1 ns/op
+
+And building a map of 1000 V4 keys from empty:
+
+BenchmarkUUIDMapSet/map-uuid       96641 ns/op   108504 B/op   20 allocs/op
+BenchmarkUUIDMapSet/uuidmap        94949 ns/op   130832 B/op   10 allocs/op
+
+Take-aways:
+
+ - String keys cost 12-20% more than UUID keys even when the strings
+   are already made: there are 36 bytes to hash and compare, not 16.
+   Code that calls String() to look an ID up pays for an allocation
+   on top of that.
+
+ - UUIDMap looks up 40% faster than map[UUID]T for V4 keys, and 22%
+   faster for V7.  At a million keys both are mostly waiting on cache
+   misses, and UUIDMap likely has one fewer: a lookup goes straight
+   to the slot, without reading a group's control word first.
+
+ - Building one is no faster; growing dominates either way.  Give
+   NewUUIDMap the size up front when it is known.
+
+ - It is no more concurrent than a map.  A shared cache still wants a
+   lock, or a UUIDMap per shard.
+
+*/
+
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestUUIDMap(t *testing.T) {
+	// A small pool of keys, so that sets, overwrites and deletes all
+	// hit the same keys often, against a map to say what's right.
+	keys := make([]UUID, 200)
+	for i := range keys {
+		keys[i] = NewV4()
+	}
+	var m UUIDMap[int]
+	want := map[UUID]int{}
+	for i := 0; i < 100000; i++ {
+		u := keys[rand.IntN(len(keys))]
+		if rand.IntN(3) == 0 {
+			m.Delete(u)
+			delete(want, u)
+		} else {
+			m.Set(u, i)
+			want[u] = i
+		}
+	}
+	if m.Len() != len(want) {
+		t.Errorf("%d keys, want %d", m.Len(), len(want))
+	}
+	for _, u := range keys {
+		got, ok := m.Get(u)
+		if w, wok := want[u]; got != w || ok != wok {
+			t.Errorf("Get(%s) = %d, %t, want %d, %t", u, got, ok, w, wok)
+		}
+	}
+	n := 0
+	m.Range(func(u UUID, v int) bool {
+		if want[u] != v {
+			t.Errorf("Range gave %s: %d, want %d", u, v, want[u])
+		}
+		n++
+		return true
+	})
+	if n != len(want) {
+		t.Errorf("Range gave %d keys, want %d", n, len(want))
+	}
+}
+
+const uuidMapBenchSize = 1000000
+
+func BenchmarkUUIDMapGet(b *testing.B) {
+	for _, v := range []struct {
+		name string
+		gen  func() UUID
+	}{{"v4", NewV4}, {"v7", NewV7}} {
+		keys := make([]UUID, uuidMapBenchSize)
+		strs := make([]string, len(keys))
+		byUUID := make(map[UUID]int, len(keys))
+		byString := make(map[string]int, len(keys))
+		m := NewUUIDMap[int](len(keys))
+		for i := range keys {
+			keys[i] = v.gen()
+			strs[i] = keys[i].String()
+			byUUID[keys[i]] = i
+			byString[strs[i]] = i
+			m.Set(keys[i], i)
+		}
+
+		b.Run(v.name+"/map-uuid", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sink += byUUID[keys[i%len(keys)]]
+			}
+		})
+		b.Run(v.name+"/map-string", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sink += byString[strs[i%len(strs)]]
+			}
+		})
+		b.Run(v.name+"/uuidmap", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				n, _ := m.Get(keys[i%len(keys)])
+				sink += n
+			}
+		})
+	}
+}
+
+func BenchmarkUUIDMapSet(b *testing.B) {
+	keys := make([]UUID, uuidMapBenchSize)
+	for i := range keys {
+		keys[i] = NewV4()
+	}
+	b.Run("map-uuid", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := map[UUID]int{}
+			for j, u := range keys[:1000] {
+				m[u] = j
+			}
+		}
+	})
+	b.Run("uuidmap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var m UUIDMap[int]
+			for j, u := range keys[:1000] {
+				m.Set(u, j)
+			}
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// V7Method is how a V7Generator keeps the UUIDs it makes within a
+// millisecond in order.  The methods are the ones in RFC 9562 section
+// 6.2.
+type V7Method int
+
+const (
+	// V7Random makes no attempt at order within a millisecond, like
+	// NewV7.
+	V7Random V7Method = iota
+	// V7SubMillisecond is method 3: the 12 bits after the millisecond
+	// timestamp hold the fraction of the millisecond, bumped by one
+	// when the clock hasn't moved on since the last UUID.
+	V7SubMillisecond
+	// V7Counter is method 1: the 12 bits after the timestamp are a
+	// counter, starting somewhere random in its lower half each
+	// millisecond.
+	V7Counter
+	// V7RandomIncrement is method 2: the 74 bits after the timestamp
+	// start random each millisecond, and each UUID after the first
+	// adds a random amount to them.
+	V7RandomIncrement
+)
+
+// A V7Option configures a V7Generator.
+type V7Option func(*V7Generator)
+
+// WithV7Method sets the method a V7Generator uses to keep its UUIDs in
+// order.  The default is V7Random.
+func WithV7Method(m V7Method) V7Option {
+	return func(g *V7Generator) {
+		g.method = m
+	}
+}
+
+// V7Generator makes V7 UUIDs that, unless the method is V7Random,
+// always increase.  If a millisecond runs out of room, or the clock
+// goes backwards, it borrows from the next millisecond rather than
+// repeat or go backwards itself.
+type V7Generator struct {
+	mu      sync.Mutex
+	method  V7Method
+	nowFunc func() time.Time
+
+	// The millisecond of the last UUID, and the 12 bit rand_a and 62
+	// bit rand_b fields after it.
+	lastMS uint64
+	a      uint16
+	b      uint64
+	// lastClockMS is what the clock said last time, which is behind
+	// lastMS while borrowing.
+	lastClockMS uint64
+
+	counters generatorCounters
+	hooks    Hooks
+}
+
+func NewV7Generator(opts ...V7Option) *V7Generator {
+	return newV7Generator(func() time.Time { return clockNow() }, opts...)
+}
+
+// newV7Generator lets tests inject a fake clock.  nowFunc is only ever
+// called with g.mu held.
+func newV7Generator(nowFunc func() time.Time, opts ...V7Option) *V7Generator {
+	g := &V7Generator{nowFunc: nowFunc}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// randomBits returns a random number of up to 64 bits.
+func randomBits(bits uint) uint64 {
+	var buf [8]byte
+	safeRandom(buf[:])
+	return binary.BigEndian.Uint64(buf[:]) >> (64 - bits)
+}
+
+// New returns the next UUID.
+func (g *V7Generator) New() UUID {
+	var ev hookEvents
+	g.mu.Lock()
+	now := g.nowFunc()
+	ms := uint64(now.UnixMilli())
+	clockMS := ms
+	if clockMS < g.lastClockMS {
+		g.counters.rollbacks.Add(1)
+		ev.rolledBack = time.Duration(g.lastClockMS-clockMS) * time.Millisecond
+	}
+	g.lastClockMS = clockMS
+	if g.method != V7Random && ms < g.lastMS {
+		ms = g.lastMS
+	}
+	same := ms == g.lastMS
+
+	switch g.method {
+	case V7Random:
+		g.a = uint16(g.hooks.random(12, &ev))
+		g.b = g.hooks.random(62, &ev)
+
+	case V7SubMillisecond:
+		a := uint16(uint64(now.Nanosecond()%1e6) << 12 / 1e6)
+		if same && a <= g.a {
+			a = g.a + 1
+		}
+		if a > 0xfff {
+			ms, a = ms+1, 0
+			ev.wrapped = true
+		}
+		g.a = a
+		g.b = g.hooks.random(62, &ev)
+
+	case V7Counter:
+		// Starting in the lower half leaves at least 2048 UUIDs of
+		// room before having to borrow.
+		if !same {
+			g.a = uint16(g.hooks.random(11, &ev))
+		} else if g.a++; g.a > 0xfff {
+			ms, g.a = ms+1, uint16(g.hooks.random(11, &ev))
+			ev.wrapped = true
+		}
+		g.b = g.hooks.random(62, &ev)
+
+	case V7RandomIncrement:
+		if !same {
+			g.a, g.b = uint16(g.hooks.random(12, &ev)), g.hooks.random(62, &ev)
+			break
+		}
+		// Increments of up to 32 bits leave plenty of room, and
+		// enough randomness that the next UUID can't be guessed.
+		g.b += g.hooks.random(32, &ev) + 1
+		if g.b >= 1<<62 {
+			g.b -= 1 << 62
+			if g.a++; g.a > 0xfff {
+				ms, g.a, g.b = ms+1, uint16(g.hooks.random(12, &ev)), g.hooks.random(62, &ev)
+				ev.wrapped = true
+			}
+		}
+	}
+
+	if ms > clockMS {
+		g.counters.borrowed.Add(1)
+	}
+	g.lastMS = ms
+	a, b := g.a, g.b
+	g.mu.Unlock()
+	g.counters.generated.Add(1)
+	g.hooks.fire(ev)
+
+	u := UUID{}
+	binary.BigEndian.PutUint16(u[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+	binary.BigEndian.PutUint16(u[6:], a)
+	binary.BigEndian.PutUint64(u[8:], b)
+
+	u.SetVersion(7)
+	u.SetVariant()
+
+	return u
+}
+
+// Stats reports on g.  A V7Generator borrows when the clock goes
+// backwards, or when a millisecond runs out of room.
+func (g *V7Generator) Stats() GeneratorStats {
+	return g.counters.stats()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// validateLine returns "" if line is a valid UUID of the wanted
+// version (any version if version is 0), or else why it is not.
+func validateLine(line string, version int) string {
+	u, err := Parse(line)
+	if err != nil {
+		return err.(*ParseError).Detail()
+	}
+	if version != 0 {
+		if u.Variant() != VariantRFC4122 {
+			return fmt.Sprintf("byte 19: variant is %s, want RFC 4122", variantNames[u.Variant()])
+		}
+		if v := int(u.Version()); v != version {
+			return fmt.Sprintf("byte 14: version %d, want %d", v, version)
+		}
+	}
+	return ""
+}
+
+// validate checks each line read from r, writing a report line for
+// each to w.  It returns the number of invalid lines.  Blank lines are
+// skipped.
+func validate(r io.Reader, w io.Writer, version int, quiet bool) (int, error) {
+	bad := 0
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		reason := validateLine(line, version)
+		switch {
+		case reason != "":
+			bad++
+			fmt.Fprintf(w, "%d: invalid: %s\n", n, reason)
+		case !quiet:
+			fmt.Fprintf(w, "%d: ok\n", n)
+		}
+	}
+	return bad, s.Err()
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	version := fs.Int("version", 0, "require this UUID version, 0 for any")
+	quiet := fs.Bool("q", false, "only report invalid lines")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: uuidgen validate [flags] < ids.txt")
+		fmt.Fprintln(fs.Output(), "Checks that each line of stdin is a canonical UUID, exiting non-zero if any are not.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	w := bufio.NewWriter(os.Stdout)
+	bad, err := validate(os.Stdin, w, *version, *quiet)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d invalid lines", bad)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	in := strings.Join([]string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg",
+		"6ba7b810x9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b810-9dad",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8 ",
+	}, "\n")
+	want := strings.Join([]string{
+		"1: ok",
+		"3: invalid: byte 35: expected hex digit, found 'g'",
+		"4: invalid: byte 8: expected '-', found 'x'",
+		"5: invalid: byte 13: expected more characters, found end of input",
+		"6: invalid: byte 36: expected end of input, found ' '",
+		"",
+	}, "\n")
+
+	var out bytes.Buffer
+	bad, err := validate(strings.NewReader(in), &out, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bad != 4 {
+		t.Errorf("%d bad lines, want 4", bad)
+	}
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	if reason := validateLine(NewV4().String(), 4); reason != "" {
+		t.Errorf("V4 rejected: %s", reason)
+	}
+	if reason := validateLine(NewV1().String(), 4); reason != "byte 14: version 1, want 4" {
+		t.Errorf("V1 accepted as V4: %q", reason)
+	}
+}
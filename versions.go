@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"time"
+)
+
+// Predefined namespace UUIDs from RFC 4122, for V3 and V5.
+var (
+	NamespaceDNS  = mustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceURL  = mustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceOID  = mustParse("6ba7b812-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceX500 = mustParse("6ba7b814-9dad-11d1-80b4-00c04fd430c8")
+)
+
+func mustParse(s string) UUID {
+	u, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// NewV3 returns UUID based on MD5 hash of namespace UUID and name.
+func NewV3(ns UUID, name string) UUID {
+	u := newFromHash(md5.New(), ns, name)
+	u.SetVersion(3)
+	u.SetVariant()
+
+	return u
+}
+
+// NewV4 returns a random UUID.
+func NewV4() UUID {
+	u := UUID{}
+	safeRandom(u[:])
+
+	u.SetVersion(4)
+	u.SetVariant()
+
+	return u
+}
+
+// NewV5 returns UUID based on SHA-1 hash of namespace UUID and name.
+func NewV5(ns UUID, name string) UUID {
+	u := newFromHash(sha1.New(), ns, name)
+	u.SetVersion(5)
+	u.SetVariant()
+
+	return u
+}
+
+// NewV8HMAC returns a V8 UUID made from the HMAC-SHA256 of data under
+// key: the first 16 bytes of the MAC, with the version and variant
+// set.  The same key and data always give the same UUID, but without
+// the key it can't be guessed or reversed, which makes it a consistent
+// pseudonym for an external identifier.
+func NewV8HMAC(key []byte, data []byte) UUID {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	u := UUID(mac.Sum(nil)[:16])
+	u.SetVersion(8)
+	u.SetVariant()
+
+	return u
+}
+
+// Returns UUID based on hashing of namespace UUID and name.
+func newFromHash(h hash.Hash, ns UUID, name string) UUID {
+	u := UUID{}
+	h.Write(ns[:])
+	h.Write([]byte(name))
+	copy(u[:], h.Sum(nil))
+
+	return u
+}
+
+// NewV1At returns a V1 UUID for a given time, clock sequence and node,
+// for backfilling IDs whose timestamps should match when historical
+// records were created.  t must be between 1582 and 5236, the range of
+// V1 timestamps.  Only the low 14 bits of clockSeq are used.
+//
+// Nothing stops two calls with the same arguments from returning the
+// same UUID, so callers need to vary clockSeq between records that
+// share a timestamp.
+func NewV1At(t time.Time, clockSeq uint16, node [6]byte) UUID {
+	u := UUID{}
+
+	timeAt := timeToEpoch(t)
+
+	binary.BigEndian.PutUint32(u[0:], uint32(timeAt))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeAt>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeAt>>48))
+	binary.BigEndian.PutUint16(u[8:], clockSeq)
+
+	copy(u[10:], node[:])
+
+	u.SetVersion(1)
+	u.SetVariant()
+
+	return u
+}
+
+// timeToEpoch returns the number of 100-nanosecond intervals between
+// the UUID epoch and t.  Done in seconds so that dates near the UUID
+// epoch don't overflow int64 nanoseconds.
+func timeToEpoch(t time.Time) uint64 {
+	return uint64(epochStart + t.Unix()*1e7 + int64(t.Nanosecond()/100))
+}
+
+// NewV6 returns UUID based on current timestamp and MAC address, like
+// NewV1, but with the timestamp stored most significant bits first so
+// that the UUIDs sort by creation time.
+func NewV6() UUID {
+	u := UUID{}
+
+	timeNow, clockSeq, hardwareAddr := getStorage()
+
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow>>28))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>12))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow&0x0fff))
+	binary.BigEndian.PutUint16(u[8:], clockSeq)
+
+	copy(u[10:], hardwareAddr)
+
+	u.SetVersion(6)
+	u.SetVariant()
+
+	return u
+}
+
+// v6Generator is NewV6 as a Generator that can report the package
+// level state it shares with NewV1.
+type v6Generator struct{}
+
+func (v6Generator) New() UUID {
+	return NewV6()
+}
+
+func (v6Generator) v1State() (uint64, uint16, [6]byte, bool) {
+	return mutexGenerator{}.v1State()
+}
+
+// NewV7 returns UUID based on the current Unix time in milliseconds
+// followed by random bits, so that the UUIDs sort by creation time.
+func NewV7() UUID {
+	return NewV7At(clockNow())
+}
+
+// NewV7At is NewV7 for a given time, for backfilling IDs whose
+// timestamps should match when historical records were created.  t
+// must be between 1970 and 10889, the range of V7 timestamps.
+func NewV7At(t time.Time) UUID {
+	u := UUID{}
+	safeRandom(u[6:])
+
+	ms := uint64(t.UnixMilli())
+	binary.BigEndian.PutUint16(u[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(u[2:], uint32(ms))
+
+	u.SetVersion(7)
+	u.SetVariant()
+
+	return u
+}
+
+// Time returns the time embedded in time based UUIDs: versions 1, 6
+// and 7.  ok is false for every other version.
+func (u UUID) Time() (t time.Time, ok bool) {
+	switch u.Version() {
+	case 1, 6:
+		return epochToTime(u.ticks()), true
+	case 7:
+		ms := uint64(binary.BigEndian.Uint16(u[0:]))<<32 | uint64(binary.BigEndian.Uint32(u[2:]))
+		return time.UnixMilli(int64(ms)), true
+	}
+	return time.Time{}, false
+}
+
+// ticks returns the timestamp of a version 1 or 6 UUID in 100ns
+// intervals since the UUID epoch, and 0 for every other version.
+func (u UUID) ticks() uint64 {
+	var ts uint64
+	switch u.Version() {
+	case 1:
+		ts = uint64(binary.BigEndian.Uint32(u[0:]))
+		ts |= uint64(binary.BigEndian.Uint16(u[4:])) << 32
+		ts |= uint64(binary.BigEndian.Uint16(u[6:])&0x0fff) << 48
+	case 6:
+		ts = uint64(binary.BigEndian.Uint32(u[0:])) << 28
+		ts |= uint64(binary.BigEndian.Uint16(u[4:])) << 12
+		ts |= uint64(binary.BigEndian.Uint16(u[6:]) & 0x0fff)
+	}
+	return ts
+}
+
+// epochToTime is the inverse of timeToEpoch.  Done in seconds so that
+// dates near the UUID epoch don't overflow int64 nanoseconds.
+func epochToTime(ts uint64) time.Time {
+	d := int64(ts) - epochStart
+	return time.Unix(d/1e7, (d%1e7)*100)
+}
+
+// ClockSequence returns the clock sequence of version 1 and 6 UUIDs.
+// ok is false for every other version.
+func (u UUID) ClockSequence() (seq uint16, ok bool) {
+	if v := u.Version(); v != 1 && v != 6 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(u[8:]) & 0x3fff, true
+}
+
+// Node returns the node ID, normally a MAC address, of version 1 and
+// 6 UUIDs.  ok is false for every other version.
+func (u UUID) Node() (node [6]byte, ok bool) {
+	if v := u.Version(); v != 1 && v != 6 {
+		return node, false
+	}
+	copy(node[:], u[10:])
+	return node, true
+}
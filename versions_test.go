@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestVersion(t *testing.T) {
+	for want, gen := range map[byte]func() UUID{1: NewV1, 4: NewV4, 6: NewV6, 7: NewV7} {
+		if got := gen().Version(); got != want {
+			t.Errorf("version %d, want %d", got, want)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	for _, gen := range []func() UUID{NewV1, NewV6, NewV7} {
+		before := time.Now().Truncate(time.Millisecond)
+		u := gen()
+		after := time.Now()
+
+		got, ok := u.Time()
+		if !ok {
+			t.Fatalf("%s: no time", u)
+		}
+		if got.Before(before) || got.After(after) {
+			t.Errorf("%s: time %s not between %s and %s", u, got, before, after)
+		}
+	}
+
+	if _, ok := NewV4().Time(); ok {
+		t.Errorf("V4 UUID has a time")
+	}
+}
+
+func TestTimeKnown(t *testing.T) {
+	// From the DNS namespace UUID, a V1 UUID minted in 1998.
+	u, err := Parse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := u.Time()
+	want := time.Date(1998, time.February, 4, 22, 13, 53, 151182400, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("time %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestClockSequenceAndNode(t *testing.T) {
+	u, _ := Parse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if seq, ok := u.ClockSequence(); !ok || seq != 0xb4 {
+		t.Errorf("clock sequence %d, %v", seq, ok)
+	}
+	if node, ok := u.Node(); !ok || node != [6]byte{0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8} {
+		t.Errorf("node %x, %v", node, ok)
+	}
+	if u.Variant() != VariantRFC4122 {
+		t.Errorf("variant %d", u.Variant())
+	}
+
+	v4 := NewV4()
+	if _, ok := v4.ClockSequence(); ok {
+		t.Errorf("V4 UUID has a clock sequence")
+	}
+	if _, ok := v4.Node(); ok {
+		t.Errorf("V4 UUID has a node")
+	}
+}
+
+func TestNameBased(t *testing.T) {
+	for _, tt := range []struct {
+		got  UUID
+		want string
+	}{
+		{NewV3(NamespaceDNS, "python.org"), "6fa459ea-ee8a-3ca4-894e-db77e160355e"},
+		{NewV5(NamespaceDNS, "python.org"), "886313e1-3b8a-5372-9b90-0c9aee199e5d"},
+		{NewV5(NamespaceURL, "https://example.com/"), "dd2c1780-811a-5296-81c5-178a0ef488bc"},
+		// RFC 4231 test case 2's MAC, 5bdcc146bf60754e6a04..., with
+		// the version and variant set.
+		{NewV8HMAC([]byte("Jefe"), []byte("what do ya want for nothing?")), "5bdcc146-bf60-854e-aa04-2426089575c7"},
+	} {
+		if tt.got.String() != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
+
+func TestNewV1At(t *testing.T) {
+	when := time.Date(1998, time.February, 4, 22, 13, 53, 151182400, time.UTC)
+	u := NewV1At(when, 0xb4, [6]byte{0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8})
+	if want := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"; u.String() != want {
+		t.Errorf("got %s, want %s", u, want)
+	}
+}
+
+func TestNewV7At(t *testing.T) {
+	when := time.Date(2009, time.November, 10, 23, 0, 0, 123000000, time.UTC)
+	u := NewV7At(when)
+	if u.Version() != 7 {
+		t.Errorf("version %d", u.Version())
+	}
+	if got, _ := u.Time(); !got.Equal(when) {
+		t.Errorf("time %s, want %s", got, when)
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	// The example from MySQL's UUID_TO_BIN documentation.
+	u := mustParse("6ccd780c-baba-1026-9564-5b8c656024db")
+	o := ToOrdered(u)
+	if got := hex.EncodeToString(o[:]); got != "1026baba6ccd780c95645b8c656024db" {
+		t.Errorf("ToOrdered = %s", got)
+	}
+	if FromOrdered(o) != u {
+		t.Errorf("FromOrdered = %s", FromOrdered(o))
+	}
+
+	// Each V1 is later than the one before, so their ordered forms
+	// should sort in the same order, which the V1s themselves don't.
+	node := [6]byte{1, 2, 3, 4, 5, 6}
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	prev := ToOrdered(NewV1At(start, 0, node))
+	for d := time.Millisecond; d < 24*time.Hour; d *= 3 {
+		next := ToOrdered(NewV1At(start.Add(d), 0, node))
+		if prev.Compare(next) >= 0 {
+			t.Fatalf("%s is not before %s", prev, next)
+		}
+		prev = next
+	}
+}
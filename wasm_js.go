@@ -0,0 +1,71 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"syscall/js"
+	"time"
+)
+
+func init() {
+	fallbackRandom = jsRandom
+}
+
+// jsRandom fills dest from crypto.getRandomValues, for hosts where
+// crypto/rand can't find it, such as older browsers that only have
+// msCrypto.  getRandomValues fills at most 64KiB a call.
+func jsRandom(dest []byte) error {
+	crypto := js.Global().Get("crypto")
+	if crypto.IsUndefined() {
+		crypto = js.Global().Get("msCrypto")
+	}
+	if crypto.IsUndefined() {
+		return errors.New("no crypto.getRandomValues")
+	}
+	for len(dest) > 0 {
+		n := min(len(dest), 65536)
+		buf := js.Global().Get("Uint8Array").New(n)
+		crypto.Call("getRandomValues", buf)
+		js.CopyBytesToGo(dest[:n], buf)
+		dest = dest[n:]
+	}
+	return nil
+}
+
+// serveJS puts a uuidgen object in the JavaScript global scope, with
+// GenerateV4, GenerateV7 and Parse functions, and waits for them to be
+// called for as long as the page or process lives.  Parse returns an
+// object with the UUID in canonical form, its version and, if it has
+// one, its time, or else an error.
+//
+// Build with GOOS=js GOARCH=wasm go build -o uuidgen.wasm, and run it
+// with the wasm_exec.js in $(go env GOROOT)/lib/wasm.
+func serveJS() {
+	js.Global().Set("uuidgen", js.ValueOf(map[string]any{
+		"GenerateV4": js.FuncOf(func(js.Value, []js.Value) any {
+			return NewV4().String()
+		}),
+		"GenerateV7": js.FuncOf(func(js.Value, []js.Value) any {
+			return NewV7().String()
+		}),
+		"Parse": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if len(args) != 1 || args[0].Type() != js.TypeString {
+				return map[string]any{"error": "Parse takes a string"}
+			}
+			u, err := Parse(args[0].String())
+			if err != nil {
+				return map[string]any{"error": err.Error()}
+			}
+			res := map[string]any{
+				"uuid":    u.String(),
+				"version": int(u.Version()),
+			}
+			if t, ok := u.Time(); ok {
+				res["time"] = t.UTC().Format(time.RFC3339Nano)
+			}
+			return res
+		}),
+	}))
+	select {}
+}
@@ -0,0 +1,7 @@
+//go:build !(js && wasm)
+
+package main
+
+// serveJS is only on js/wasm, where the generators are exported to
+// JavaScript instead of running a command.
+func serveJS() {}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ways of choosing a Snowflake worker ID, so that a cluster doesn't
+// have to hand them out by hand.  Passing a number in config works for
+// a handful of machines; WorkerIDFromEnv suits Kubernetes
+// StatefulSets; WorkerLease suits everything else.
+
+// WorkerIDFromEnv returns the worker ID in the environment variable
+// name.  The value is either a number, or a name ending in -N, such as
+// a StatefulSet pod's hostname "uuidd-3", whose ordinal is used.
+func WorkerIDFromEnv(name string) (int64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, fmt.Errorf("$%s is not set", name)
+	}
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		v = v[i+1:]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 || n > MaxWorkerID {
+		return 0, fmt.Errorf("$%s=%q: no worker ID between 0 and %d", name, os.Getenv(name), MaxWorkerID)
+	}
+	return n, nil
+}
+
+// ErrLeaseLost is returned when a WorkerLease has expired, or been
+// taken over, before it could be renewed.  The worker ID may now be
+// someone else's, so generating must stop.
+var ErrLeaseLost = errors.New("worker ID lease lost")
+
+// WorkerLease claims a worker ID from a table, for as long as it keeps
+// renewing it.  The table has a row for each worker ID:
+//
+//	CREATE TABLE snowflake_workers (
+//		worker_id INT PRIMARY KEY,
+//		owner TEXT NOT NULL DEFAULT '',
+//		expires TIMESTAMPTZ NOT NULL DEFAULT 'epoch'
+//	);
+//	INSERT INTO snowflake_workers (worker_id) SELECT generate_series(0, 1023);
+//
+// The queries are for Postgres.
+type WorkerLease struct {
+	DB *sql.DB
+	// Owner names this process, for whoever looks at the table.
+	Owner string
+	// TTL is how long a lease lasts without being renewed.
+	TTL time.Duration
+
+	id int64
+	// held is when the lease was last acquired or renewed: just before
+	// the query was sent, which may be some time before the database
+	// set expires, so that it understates, not overstates, how long is
+	// left.
+	held time.Time
+}
+
+// Acquire claims the lowest worker ID whose lease has expired.
+func (l *WorkerLease) Acquire() (int64, error) {
+	l.held = time.Now()
+	err := l.DB.QueryRow(`
+		UPDATE snowflake_workers SET owner = $1, expires = now() + $2 * interval '1 millisecond'
+		WHERE worker_id = (
+			SELECT worker_id FROM snowflake_workers WHERE expires < now()
+			ORDER BY worker_id LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING worker_id`,
+		l.Owner, l.TTL.Milliseconds()).Scan(&l.id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("no free worker IDs")
+	}
+	return l.id, err
+}
+
+// Renew extends the lease by TTL, or returns ErrLeaseLost.  It gives
+// up when ctx is done.
+func (l *WorkerLease) Renew(ctx context.Context) error {
+	start := time.Now()
+	res, err := l.DB.ExecContext(ctx, `
+		UPDATE snowflake_workers SET expires = now() + $3 * interval '1 millisecond'
+		WHERE worker_id = $1 AND owner = $2 AND expires > now()`,
+		l.id, l.Owner, l.TTL.Milliseconds())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLeaseLost
+	}
+	l.held = start
+	return nil
+}
+
+// Release gives the worker ID back straight away.
+func (l *WorkerLease) Release() error {
+	_, err := l.DB.Exec(`UPDATE snowflake_workers SET expires = 'epoch' WHERE worker_id = $1 AND owner = $2`, l.id, l.Owner)
+	return err
+}
+
+// KeepAlive renews the lease every third of its TTL until ctx is done,
+// when it releases it, or renewing fails.  A failed renewal is retried
+// until only a third of the TTL would be left, a margin for the
+// database's clock being ahead of this one, then KeepAlive returns
+// ErrLeaseLost, so that generating stops before anyone else can claim
+// the worker ID.
+func (l *WorkerLease) KeepAlive(ctx context.Context) error {
+	margin := l.TTL / 3
+	t := time.NewTicker(l.TTL / 3)
+	defer t.Stop()
+	lost := time.NewTimer(time.Until(l.held.Add(l.TTL - margin)))
+	defer lost.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return l.Release()
+		case <-lost.C:
+			return ErrLeaseLost
+		case <-t.C:
+		}
+		giveUp := l.held.Add(l.TTL - margin)
+		rctx, cancel := context.WithDeadline(ctx, giveUp)
+		err := l.Renew(rctx)
+		cancel()
+		switch {
+		case err == nil:
+			lost.Reset(time.Until(l.held.Add(l.TTL - margin)))
+		case errors.Is(err, ErrLeaseLost), !time.Now().Before(giveUp):
+			return ErrLeaseLost
+		}
+	}
+}